@@ -3,6 +3,9 @@ package auth
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"tranquil-pages/errors"
 
 	"golang.org/x/oauth2"
@@ -62,3 +65,82 @@ func InitOAuthConfig() error {
 
 	return nil
 }
+
+// TokenCacheConfig controls the in-process cache in front of the token blacklist
+type TokenCacheConfig struct {
+	// Size is the maximum number of negative lookups remembered
+	Size int
+	// PollInterval bounds how long a revocation on another replica can go unnoticed
+	PollInterval time.Duration
+}
+
+func LoadTokenCacheConfig() (TokenCacheConfig, error) {
+	config := TokenCacheConfig{
+		Size:         10000,
+		PollInterval: 30 * time.Second,
+	}
+
+	if value, ok := os.LookupEnv("TOKEN_CACHE_SIZE"); ok {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return config, fmt.Errorf("invalid TOKEN_CACHE_SIZE %q", value)
+		}
+		config.Size = size
+	}
+
+	if value, ok := os.LookupEnv("TOKEN_REVOCATION_POLL_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return config, fmt.Errorf("invalid TOKEN_REVOCATION_POLL_INTERVAL %q", value)
+		}
+		config.PollInterval = interval
+	}
+
+	return config, nil
+}
+
+// UserCacheConfig controls the in-process cache of the users looked up to authorize requests
+type UserCacheConfig struct {
+	// Size is the maximum number of users remembered
+	Size int
+	// TTL bounds how long a suspension or role change made on another replica can go unnoticed
+	TTL time.Duration
+}
+
+func LoadUserCacheConfig() (UserCacheConfig, error) {
+	config := UserCacheConfig{
+		Size: 10000,
+		TTL:  30 * time.Second,
+	}
+
+	if value, ok := os.LookupEnv("USER_CACHE_SIZE"); ok {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return config, fmt.Errorf("invalid USER_CACHE_SIZE %q", value)
+		}
+		config.Size = size
+	}
+
+	if value, ok := os.LookupEnv("USER_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return config, fmt.Errorf("invalid USER_CACHE_TTL %q", value)
+		}
+		config.TTL = ttl
+	}
+
+	return config, nil
+}
+
+// getAdminEmails reads the comma-separated ADMIN_EMAILS list. Users logging in with one of these
+// addresses are granted the admin role, and other users lose it.
+func getAdminEmails() map[string]bool {
+	adminEmails := make(map[string]bool)
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email != "" {
+			adminEmails[email] = true
+		}
+	}
+	return adminEmails
+}
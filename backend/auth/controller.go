@@ -33,8 +33,11 @@ func (c *AuthController) SetupAuthRoutes(router *gin.Engine) {
 
 // Login initiates the OAuth2 flow
 func (c *AuthController) Login(ctx *gin.Context) {
-	url, err := c.authService.GetAuthURL()
+	url, err := c.authService.GetAuthURL(ctx.Request.Context())
 	if err != nil {
+		if abortOnContextError(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate redirect url for OAuth flow"})
 		return
 	}
@@ -56,13 +59,29 @@ func (c *AuthController) Callback(ctx *gin.Context) {
 		return
 	}
 
-	userInfo, err := c.authService.HandleCallback(code, state)
+	userInfo, err := c.authService.HandleCallback(ctx.Request.Context(), code, state)
 	if err != nil {
+		if abortOnContextError(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	token, err := GenerateToken(userInfo)
+	user, err := c.authService.RegisterLogin(ctx.Request.Context(), userInfo)
+	if err != nil {
+		if abortOnContextError(ctx, err) {
+			return
+		}
+		if _, ok := err.(*AccountSuspendedError); ok {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Account has been suspended"})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		}
+		return
+	}
+
+	token, err := GenerateTokenWithRole(userInfo, user.Role)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token after login"})
 		return
@@ -93,7 +112,10 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.authService.Logout(token); err != nil {
+	if err := c.authService.Logout(ctx.Request.Context(), token); err != nil {
+		if abortOnContextError(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
 		return
 	}
@@ -122,5 +144,6 @@ func (c *AuthController) GetCurrentUser(ctx *gin.Context) {
 		"email":   userClaims.Email,
 		"name":    userClaims.Name,
 		"picture": userClaims.Picture,
+		"role":    userClaims.Role,
 	})
 }
@@ -17,6 +17,7 @@ func TestLogin(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -34,7 +35,7 @@ func TestLogin(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	// Create test controller
 	controller := NewAuthController(authService)
@@ -92,6 +93,7 @@ func TestCallback(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -109,7 +111,7 @@ func TestCallback(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	// Create test controller
 	controller := NewAuthController(authService)
@@ -163,7 +165,7 @@ func TestCallback(t *testing.T) {
 			name: "successful callback",
 			setupMock: func() {
 				// Store valid state
-				mockStateRepo.Create(&OAuthState{
+				mockStateRepo.Create(t.Context(), &OAuthState{
 					State: "valid-state",
 				})
 			},
@@ -171,6 +173,18 @@ func TestCallback(t *testing.T) {
 			expectedStatus: http.StatusTemporaryRedirect,
 			expectedBody:   "",
 		},
+		{
+			name: "suspended account",
+			setupMock: func() {
+				mockStateRepo.Create(t.Context(), &OAuthState{
+					State: "suspended-state",
+				})
+				mockUserRepo.users["123"] = &User{ID: "123", Suspended: true}
+			},
+			query:          "?code=valid-code&state=suspended-state",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"Account has been suspended"}`,
+		},
 		{
 			name:           "missing code",
 			setupMock:      func() {},
@@ -227,6 +241,7 @@ func TestLogout(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -244,7 +259,7 @@ func TestLogout(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	// Create test controller
 	controller := NewAuthController(authService)
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"tranquil-pages/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CopyToSQL copies the users and the blacklisted tokens of the Mongo database into the SQL database,
+// replacing the ones already copied, so that a copy that was interrupted can simply be run again. OAuth
+// states are left behind: they expire within minutes, and a login in progress can be started again.
+func CopyToSQL(ctx context.Context, from *database.Database, to *database.SQLDatabase) error {
+	users, err := copyCollection(ctx, from.GetCollection("users"), to,
+		func(tx *sql.Tx, user *User) error {
+			_, err := tx.ExecContext(ctx, to.Rebind(`INSERT INTO users (`+userColumns+`)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET
+					email = excluded.email,
+					name = excluded.name,
+					picture = excluded.picture,
+					role = excluded.role,
+					suspended = excluded.suspended,
+					created_at = excluded.created_at,
+					last_login_at = excluded.last_login_at`),
+				user.ID, user.Email, user.Name, user.Picture, string(user.Role), user.Suspended,
+				int64(user.CreatedAt), int64(user.LastLoginAt))
+			return err
+		})
+	if err != nil {
+		return fmt.Errorf("failed to copy users: %w", err)
+	}
+	log.Printf("Copied %d users", users)
+
+	tokens, err := copyCollection(ctx, from.GetCollection("blacklisted_jwts"), to,
+		func(tx *sql.Tx, token *BlacklistedToken) error {
+			_, err := tx.ExecContext(ctx, to.Rebind(`INSERT INTO blacklisted_jwts (id, token, created_at)
+				VALUES (?, ?, ?)
+				ON CONFLICT (id) DO NOTHING`),
+				token.ID.Hex(), token.Token, int64(token.CreatedAt))
+			return err
+		})
+	if err != nil {
+		return fmt.Errorf("failed to copy blacklisted tokens: %w", err)
+	}
+	log.Printf("Copied %d blacklisted tokens", tokens)
+
+	return nil
+}
+
+// copyBatch is the number of documents written in one transaction
+const copyBatch = 500
+
+// copyCollection decodes every document of the collection and writes it with write, in batches of
+// copyBatch to a transaction. It returns the number of documents it copied.
+func copyCollection[T any](ctx context.Context, collection *mongo.Collection, to *database.SQLDatabase, write func(tx *sql.Tx, doc *T) error) (int, error) {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	copied := 0
+	var batch []*T
+	flush := func() error {
+		err := to.InTransaction(ctx, func(tx *sql.Tx) error {
+			for _, doc := range batch {
+				if err := write(tx, doc); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return copied, err
+		}
+		batch = append(batch, &doc)
+		if len(batch) == copyBatch {
+			if err := flush(); err != nil {
+				return copied, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return copied, err
+	}
+	if err := flush(); err != nil {
+		return copied, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tranquil-pages/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyToSQL(t *testing.T) {
+	// Given
+	mongoDB, err := database.NewTestDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mongoDB.Close()
+
+	sqlDB, err := database.NewTestPostgresDatabase()
+	if errors.Is(err, database.ErrNoTestDatabase) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	from := NewMongoRepositories(mongoDB.Database)
+	alice, err := from.Users.RecordLogin(t.Context(), &User{ID: "alice", Email: "alice@example.com"}, true)
+	assert.NoError(t, err)
+	assert.NoError(t, from.Users.SetSuspended(t.Context(), "alice", true))
+	assert.NoError(t, from.Tokens.Blacklist(t.Context(), "revoked"))
+
+	// When
+	err = CopyToSQL(t.Context(), mongoDB.Database, sqlDB.SQLDatabase)
+	assert.NoError(t, err)
+	assert.NoError(t, CopyToSQL(t.Context(), mongoDB.Database, sqlDB.SQLDatabase))
+
+	// Then
+	to := NewSQLRepositories(sqlDB.SQLDatabase)
+	user, err := to.Users.FindByID(t.Context(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.Equal(t, RoleAdmin, user.Role)
+	assert.True(t, user.Suspended)
+	assert.Equal(t, alice.CreatedAt, user.CreatedAt)
+
+	blacklisted, err := to.Tokens.IsBlacklisted(t.Context(), "revoked")
+	assert.NoError(t, err)
+	assert.True(t, blacklisted)
+
+	tokens, err := to.Tokens.BlacklistedSince(t.Context(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+}
@@ -13,6 +13,10 @@ func (e *StateGenerationError) Error() string {
 	return fmt.Sprintf("OAuth state generation error: %v", e.Err)
 }
 
+func (e *StateGenerationError) Unwrap() error {
+	return e.Err
+}
+
 // AuthURLGenerationError represents an error that occurred while generating the OAuth URL
 type AuthURLGenerationError struct {
 	Err error
@@ -22,6 +26,10 @@ func (e *AuthURLGenerationError) Error() string {
 	return fmt.Sprintf("OAuth auth URL generation error: %v", e.Err)
 }
 
+func (e *AuthURLGenerationError) Unwrap() error {
+	return e.Err
+}
+
 // StateValidationError represents an error that occurred while validating the OAuth state
 type StateValidationError struct {
 	Err error
@@ -31,6 +39,10 @@ func (e *StateValidationError) Error() string {
 	return fmt.Sprintf("OAuth state validation error: %v", e.Err)
 }
 
+func (e *StateValidationError) Unwrap() error {
+	return e.Err
+}
+
 // TokenExchangeError represents an error that occurred during token exchange
 type TokenExchangeError struct {
 	Err error
@@ -40,6 +52,10 @@ func (e *TokenExchangeError) Error() string {
 	return fmt.Sprintf("OAuth token exchange error: %v", e.Err)
 }
 
+func (e *TokenExchangeError) Unwrap() error {
+	return e.Err
+}
+
 // UserInfoError represents an error that occurred while fetching user info
 type UserInfoError struct {
 	Err error
@@ -49,6 +65,10 @@ func (e *UserInfoError) Error() string {
 	return fmt.Sprintf("failed to get user info: %v", e.Err)
 }
 
+func (e *UserInfoError) Unwrap() error {
+	return e.Err
+}
+
 // TokenBlacklistError represents an error that occurred while managing token blacklist
 type TokenBlacklistError struct {
 	Err error
@@ -58,6 +78,10 @@ func (e *TokenBlacklistError) Error() string {
 	return fmt.Sprintf("failed to manage token blacklist: %v", e.Err)
 }
 
+func (e *TokenBlacklistError) Unwrap() error {
+	return e.Err
+}
+
 // TokenRevokedError represents a token that has been revoked by the user
 type TokenRevokedError struct{}
 
@@ -78,3 +102,32 @@ type InvalidAuthHeaderError struct{}
 func (e *InvalidAuthHeaderError) Error() string {
 	return "Invalid authorization header format"
 }
+
+// AccountSuspendedError indicates the user's account has been suspended by an administrator
+type AccountSuspendedError struct{}
+
+func (e *AccountSuspendedError) Error() string {
+	return "account has been suspended"
+}
+
+// UserNotFoundError indicates no user exists with the given ID
+type UserNotFoundError struct {
+	ID string
+}
+
+func (e *UserNotFoundError) Error() string {
+	return fmt.Sprintf("user %s not found", e.ID)
+}
+
+// UserLookupError represents an error that occurred while loading or storing a user
+type UserLookupError struct {
+	Err error
+}
+
+func (e *UserLookupError) Error() string {
+	return fmt.Sprintf("failed to look up user: %v", e.Err)
+}
+
+func (e *UserLookupError) Unwrap() error {
+	return e.Err
+}
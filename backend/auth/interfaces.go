@@ -1,12 +1,34 @@
 package auth
 
+import (
+	"context"
+	"time"
+)
+
 // Repository interfaces
 type TokenRepositoryInterface interface {
-	Blacklist(token string) error
-	IsBlacklisted(token string) (bool, error)
+	Blacklist(ctx context.Context, token string) error
+	IsBlacklisted(ctx context.Context, token string) (bool, error)
+}
+
+// RevocationFeedInterface is a token repository that can also list recent revocations,
+// which lets each replica keep a local copy of the blacklist up to date.
+type RevocationFeedInterface interface {
+	TokenRepositoryInterface
+	BlacklistedSince(ctx context.Context, since time.Time) ([]BlacklistedToken, error)
 }
 
 type OAuthStateRepositoryInterface interface {
-	Create(state *OAuthState) error
-	FindAndDelete(state string) (*OAuthState, error)
+	Create(ctx context.Context, state *OAuthState) error
+	FindAndDelete(ctx context.Context, state string) (*OAuthState, error)
+}
+
+type UserRepositoryInterface interface {
+	// RecordLogin creates or refreshes the user's profile fields and returns the stored user. The
+	// role follows admin on every login, so an admin whose email is no longer configured is demoted.
+	RecordLogin(ctx context.Context, user *User, admin bool) (*User, error)
+	FindByID(ctx context.Context, id string) (*User, error)
+	List(ctx context.Context, offset, limit int) ([]User, error)
+	SetSuspended(ctx context.Context, id string, suspended bool) error
+	Stats(ctx context.Context) (*UserStats, error)
 }
@@ -9,12 +9,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// tokenLifetime is how long an issued token stays valid
+const tokenLifetime = 24 * time.Hour
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	Verified bool   `json:"verified"`
 	Name     string `json:"name"`
 	Picture  string `json:"picture"`
+	Role     Role   `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -33,7 +37,19 @@ func getSecretKey() ([]byte, error) {
 	return decoded, nil
 }
 
+// HasRole reports whether the token grants the given role. Tokens issued before roles existed carry none and count as regular users.
+func (c *Claims) HasRole(role Role) bool {
+	if c.Role == "" {
+		return role == RoleUser
+	}
+	return c.Role == role
+}
+
 func GenerateToken(user *GoogleUserInfo) (string, error) {
+	return GenerateTokenWithRole(user, RoleUser)
+}
+
+func GenerateTokenWithRole(user *GoogleUserInfo, role Role) (string, error) {
 	// Get and decode JWT secret
 	secret, err := getSecretKey()
 	if err != nil {
@@ -47,8 +63,9 @@ func GenerateToken(user *GoogleUserInfo) (string, error) {
 		Verified: user.VerifiedEmail,
 		Name:     user.Name,
 		Picture:  user.Picture,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenLifetime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryRepositories returns empty repositories that keep everything in memory, for running the
+// server or its tests without a database. They behave like the Mongo repositories, down to the errors
+// they return.
+func NewMemoryRepositories() *Repositories {
+	return &Repositories{
+		States: NewMemoryOAuthStateRepository(),
+		Tokens: NewMemoryTokenRepository(),
+		Users:  NewMemoryUserRepository(),
+	}
+}
+
+type MemoryOAuthStateRepository struct {
+	mu     sync.Mutex
+	states []OAuthState
+}
+
+func NewMemoryOAuthStateRepository() *MemoryOAuthStateRepository {
+	return &MemoryOAuthStateRepository{}
+}
+
+func (r *MemoryOAuthStateRepository) Create(ctx context.Context, state *OAuthState) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to insert state: %w", err)
+	}
+
+	now := time.Now()
+	state.CreatedAt = primitive.NewDateTimeFromTime(now)
+	state.ExpiresAt = primitive.NewDateTimeFromTime(now.Add(15 * time.Minute))
+	if state.ID.IsZero() {
+		state.ID = primitive.NewObjectID()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, *state)
+	return nil
+}
+
+func (r *MemoryOAuthStateRepository) FindAndDelete(ctx context.Context, state string) (*OAuthState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find and delete state: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	i := slices.IndexFunc(r.states, func(stored OAuthState) bool {
+		return stored.State == state && stored.ExpiresAt > now
+	})
+	if i < 0 {
+		return nil, nil
+	}
+
+	found := r.states[i]
+	r.states = slices.Delete(r.states, i, i+1)
+	return &found, nil
+}
+
+type MemoryTokenRepository struct {
+	mu     sync.RWMutex
+	tokens []BlacklistedToken
+}
+
+func NewMemoryTokenRepository() *MemoryTokenRepository {
+	return &MemoryTokenRepository{}
+}
+
+func (r *MemoryTokenRepository) Blacklist(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = append(r.tokens, BlacklistedToken{
+		ID:        primitive.NewObjectID(),
+		Token:     token,
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	})
+	return nil
+}
+
+func (r *MemoryTokenRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("failed to check blacklisted token: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.ContainsFunc(r.tokens, func(stored BlacklistedToken) bool { return stored.Token == token }), nil
+}
+
+func (r *MemoryTokenRepository) BlacklistedSince(ctx context.Context, since time.Time) ([]BlacklistedToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list blacklisted tokens: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	from := primitive.NewDateTimeFromTime(since)
+	var tokens []BlacklistedToken
+	for _, token := range r.tokens {
+		if token.CreatedAt >= from {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+type MemoryUserRepository struct {
+	mu    sync.RWMutex
+	users []User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{}
+}
+
+func (r *MemoryUserRepository) RecordLogin(ctx context.Context, user *User, admin bool) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	i := slices.IndexFunc(r.users, func(stored User) bool { return stored.ID == user.ID })
+	if i < 0 {
+		r.users = append(r.users, User{ID: user.ID, Role: RoleUser, CreatedAt: now})
+		i = len(r.users) - 1
+	}
+
+	stored := &r.users[i]
+	stored.Email, stored.Name, stored.Picture = user.Email, user.Name, user.Picture
+	stored.LastLoginAt = now
+	stored.Role = RoleUser
+	if admin {
+		stored.Role = RoleAdmin
+	}
+
+	result := *stored
+	return &result, nil
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i := slices.IndexFunc(r.users, func(stored User) bool { return stored.ID == id })
+	if i < 0 {
+		return nil, nil
+	}
+	result := r.users[i]
+	return &result, nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, offset, limit int) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	r.mu.RLock()
+	users := slices.Clone(r.users)
+	r.mu.RUnlock()
+
+	slices.SortFunc(users, func(a, b User) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	if offset >= len(users) {
+		return nil, nil
+	}
+	users = users[offset:]
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *MemoryUserRepository) SetSuspended(ctx context.Context, id string, suspended bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.users, func(stored User) bool { return stored.ID == id })
+	if i < 0 {
+		return &UserNotFoundError{ID: id}
+	}
+	r.users[i].Suspended = suspended
+	return nil
+}
+
+func (r *MemoryUserRepository) Stats(ctx context.Context) (*UserStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &UserStats{Total: int64(len(r.users))}
+	activeSince := primitive.NewDateTimeFromTime(time.Now().Add(-30 * 24 * time.Hour))
+	for _, user := range r.users {
+		if user.Role == RoleAdmin {
+			stats.Admins++
+		}
+		if user.Suspended {
+			stats.Suspended++
+		}
+		if user.LastLoginAt >= activeSince {
+			stats.ActiveLast30Days++
+		}
+	}
+	return stats, nil
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"net/http"
 	"strings"
+	appErrors "tranquil-pages/errors"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +31,17 @@ func getTokenFromRequest(c *gin.Context) (string, error) {
 	return parts[1], nil
 }
 
+// abortOnContextError aborts a request whose lookups stopped because the client went away or the
+// request ran out of time, and reports whether it did
+func abortOnContextError(c *gin.Context, err error) bool {
+	status, ctxErr := appErrors.ContextStatus(err)
+	if ctxErr == nil {
+		return false
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": ctxErr.Error()})
+	return true
+}
+
 // AuthMiddleware checks for a valid session token
 func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -45,11 +57,17 @@ func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := authService.ValidateAuthenticationToken(token)
+		claims, err := authService.ValidateAuthenticationToken(c.Request.Context(), token)
 		if err != nil {
-			if _, ok := err.(*TokenRevokedError); ok {
+			if abortOnContextError(c, err) {
+				return
+			}
+			switch err.(type) {
+			case *TokenRevokedError:
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
-			} else {
+			case *AccountSuspendedError:
+				c.JSON(http.StatusForbidden, gin.H{"error": "Account has been suspended"})
+			default:
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			}
 			c.Abort()
@@ -61,3 +79,24 @@ func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireRole only lets requests through whose claims carry the given role. It must run after AuthMiddleware.
+func RequireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		userClaims, ok := claims.(*Claims)
+		if !ok || !userClaims.HasRole(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +25,7 @@ func TestAuthMiddleware(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -40,7 +43,7 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	// Create test controller
 	controller := NewAuthController(authService)
@@ -105,7 +108,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "revoked token",
 			setupMock: func() {
-				mockTokenRepo.Blacklist(validToken)
+				mockTokenRepo.Blacklist(t.Context(), validToken)
 			},
 			setupRequest: func() *http.Request {
 				req, _ := http.NewRequest("GET", "/test", nil)
@@ -115,6 +118,19 @@ func TestAuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   `{"error":"Token has been revoked"}`,
 		},
+		{
+			name: "suspended account",
+			setupMock: func() {
+				mockUserRepo.users["123"] = &User{ID: "123", Suspended: true}
+			},
+			setupRequest: func() *http.Request {
+				req, _ := http.NewRequest("GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+validToken)
+				return req
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"Account has been suspended"}`,
+		},
 		{
 			name:      "valid token",
 			setupMock: func() {},
@@ -126,6 +142,32 @@ func TestAuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   `{"status":"success"}`,
 		},
+		{
+			name:      "cancelled request",
+			setupMock: func() {},
+			setupRequest: func() *http.Request {
+				ctx, cancel := context.WithCancel(t.Context())
+				cancel()
+				req, _ := http.NewRequestWithContext(ctx, "GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+validToken)
+				return req
+			},
+			expectedStatus: 499,
+			expectedBody:   `{"error":"The request was cancelled"}`,
+		},
+		{
+			name:      "request out of time",
+			setupMock: func() {},
+			setupRequest: func() *http.Request {
+				ctx, cancel := context.WithDeadline(t.Context(), time.Now())
+				t.Cleanup(cancel)
+				req, _ := http.NewRequestWithContext(ctx, "GET", "/test", nil)
+				req.Header.Set("Authorization", "Bearer "+validToken)
+				return req
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"error":"The request took too long"}`,
+		},
 		{
 			name:      "valid token from cookie",
 			setupMock: func() {},
@@ -148,6 +190,7 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset mock repository state
 			mockTokenRepo.Reset()
+			mockUserRepo.users = make(map[string]*User)
 			tt.setupMock()
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, tt.setupRequest())
@@ -157,3 +200,100 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireRole(t *testing.T) {
+	setupTestEnv(t)
+
+	router := setupTestRouter()
+	router.GET("/admin", func(c *gin.Context) {
+		claims, err := ValidateToken(c.GetHeader("X-Test-Token"))
+		if err == nil {
+			c.Set("claims", claims)
+		}
+		c.Next()
+	}, RequireRole(RoleAdmin), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	})
+
+	testUser := &GoogleUserInfo{ID: "123", Email: "test@test.com", VerifiedEmail: true}
+	userToken, err := GenerateToken(testUser)
+	assert.NoError(t, err)
+	adminToken, err := GenerateTokenWithRole(testUser, RoleAdmin)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no claims",
+			token:          "",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"User not authenticated"}`,
+		},
+		{
+			name:           "regular user",
+			token:          userToken,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"Insufficient permissions"}`,
+		},
+		{
+			name:           "admin",
+			token:          adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/admin", nil)
+			req.Header.Set("X-Test-Token", tt.token)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestRequireRole_FollowsTheStoredRole(t *testing.T) {
+	// Given
+	setupTestEnv(t)
+	mockUserRepo := NewMockUserRepository()
+	authService := NewAuthService(&oauth2.Config{}, NewMockOAuthStateRepository(), NewMockTokenRepository(), mockUserRepo)
+	router := setupTestRouter()
+	router.GET("/admin", AuthMiddleware(authService), RequireRole(RoleAdmin), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	})
+	admin := &GoogleUserInfo{ID: "123", Email: "admin@test.com", VerifiedEmail: true}
+	_, err := mockUserRepo.RecordLogin(t.Context(), &User{ID: admin.ID, Email: admin.Email}, true)
+	assert.NoError(t, err)
+	token, err := GenerateTokenWithRole(admin, RoleAdmin)
+	assert.NoError(t, err)
+	requestAdmin := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, requestAdmin())
+
+	// When
+	_, err = mockUserRepo.RecordLogin(t.Context(), &User{ID: admin.ID, Email: admin.Email}, false)
+	assert.NoError(t, err)
+
+	// Then
+	assert.Equal(t, http.StatusForbidden, requestAdmin())
+
+	// When
+	_, err = mockUserRepo.RecordLogin(t.Context(), &User{ID: admin.ID, Email: admin.Email}, true)
+	assert.NoError(t, err)
+
+	// Then
+	assert.Equal(t, http.StatusOK, requestAdmin())
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,31 +9,52 @@ import (
 
 // MockTokenRepository implements TokenRepositoryInterface for testing
 type MockTokenRepository struct {
-	blacklistedTokens map[string]bool
-	blacklistFunc     func(token string) error
+	blacklistedTokens  map[string]time.Time
+	blacklistFunc      func(token string) error
+	isBlacklistedCalls int
 }
 
 func NewMockTokenRepository() *MockTokenRepository {
 	return &MockTokenRepository{
-		blacklistedTokens: make(map[string]bool),
+		blacklistedTokens: make(map[string]time.Time),
 	}
 }
 
 func (m *MockTokenRepository) Reset() {
-	m.blacklistedTokens = make(map[string]bool)
+	m.blacklistedTokens = make(map[string]time.Time)
 	m.blacklistFunc = nil
+	m.isBlacklistedCalls = 0
 }
 
-func (m *MockTokenRepository) Blacklist(token string) error {
+func (m *MockTokenRepository) Blacklist(ctx context.Context, token string) error {
 	if m.blacklistFunc != nil {
 		return m.blacklistFunc(token)
 	}
-	m.blacklistedTokens[token] = true
+	m.blacklistedTokens[token] = time.Now()
 	return nil
 }
 
-func (m *MockTokenRepository) IsBlacklisted(token string) (bool, error) {
-	return m.blacklistedTokens[token], nil
+// IsBlacklisted fails like a database would once the context has ended
+func (m *MockTokenRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	m.isBlacklistedCalls++
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	_, exists := m.blacklistedTokens[token]
+	return exists, nil
+}
+
+func (m *MockTokenRepository) BlacklistedSince(ctx context.Context, since time.Time) ([]BlacklistedToken, error) {
+	var tokens []BlacklistedToken
+	for token, createdAt := range m.blacklistedTokens {
+		if !createdAt.Before(since) {
+			tokens = append(tokens, BlacklistedToken{
+				Token:     token,
+				CreatedAt: primitive.NewDateTimeFromTime(createdAt),
+			})
+		}
+	}
+	return tokens, nil
 }
 
 // MockOAuthStateRepository implements OAuthStateRepositoryInterface for testing
@@ -54,7 +76,7 @@ func (m *MockOAuthStateRepository) Reset() {
 }
 
 // Create stores a new OAuth state
-func (m *MockOAuthStateRepository) Create(state *OAuthState) error {
+func (m *MockOAuthStateRepository) Create(ctx context.Context, state *OAuthState) error {
 	if m.createFunc != nil {
 		return m.createFunc(state)
 	}
@@ -67,10 +89,90 @@ func (m *MockOAuthStateRepository) Create(state *OAuthState) error {
 	return nil
 }
 
-func (m *MockOAuthStateRepository) FindAndDelete(state string) (*OAuthState, error) {
+func (m *MockOAuthStateRepository) FindAndDelete(ctx context.Context, state string) (*OAuthState, error) {
 	if s, exists := m.states[state]; exists {
 		delete(m.states, state)
 		return s, nil
 	}
 	return nil, nil
 }
+
+// MockUserRepository implements UserRepositoryInterface for testing
+type MockUserRepository struct {
+	users         map[string]*User
+	findByIDCalls int
+}
+
+func NewMockUserRepository() *MockUserRepository {
+	return &MockUserRepository{
+		users: make(map[string]*User),
+	}
+}
+
+func (m *MockUserRepository) RecordLogin(ctx context.Context, user *User, admin bool) (*User, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	stored, exists := m.users[user.ID]
+	if !exists {
+		stored = &User{ID: user.ID, Role: RoleUser, CreatedAt: now}
+		m.users[user.ID] = stored
+	}
+	stored.Email = user.Email
+	stored.Name = user.Name
+	stored.Picture = user.Picture
+	stored.LastLoginAt = now
+	stored.Role = RoleUser
+	if admin {
+		stored.Role = RoleAdmin
+	}
+
+	result := *stored
+	return &result, nil
+}
+
+func (m *MockUserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	m.findByIDCalls++
+	if user, exists := m.users[id]; exists {
+		result := *user
+		return &result, nil
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) List(ctx context.Context, offset, limit int) ([]User, error) {
+	var users []User
+	for _, user := range m.users {
+		users = append(users, *user)
+	}
+	if offset >= len(users) {
+		return nil, nil
+	}
+	users = users[offset:]
+	if limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *MockUserRepository) SetSuspended(ctx context.Context, id string, suspended bool) error {
+	user, exists := m.users[id]
+	if !exists {
+		return &UserNotFoundError{ID: id}
+	}
+	user.Suspended = suspended
+	return nil
+}
+
+func (m *MockUserRepository) Stats(ctx context.Context) (*UserStats, error) {
+	stats := &UserStats{}
+	for _, user := range m.users {
+		stats.Total++
+		if user.Role == RoleAdmin {
+			stats.Admins++
+		}
+		if user.Suspended {
+			stats.Suspended++
+		}
+	}
+	return stats, nil
+}
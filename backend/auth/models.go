@@ -16,3 +16,31 @@ type BlacklistedToken struct {
 	Token     string             `bson:"token"`
 	CreatedAt primitive.DateTime `bson:"created_at"`
 }
+
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
+// User is the account record kept for everyone who has logged in
+type User struct {
+	ID          string             `bson:"_id" json:"id"`
+	Email       string             `bson:"email" json:"email"`
+	Name        string             `bson:"name" json:"name"`
+	Picture     string             `bson:"picture" json:"picture"`
+	Role        Role               `bson:"role" json:"role"`
+	Suspended   bool               `bson:"suspended" json:"suspended"`
+	CreatedAt   primitive.DateTime `bson:"created_at" json:"created_at"`
+	LastLoginAt primitive.DateTime `bson:"last_login_at" json:"last_login_at"`
+}
+
+// UserStats summarises the user base for administrators
+type UserStats struct {
+	Total     int64 `json:"total"`
+	Admins    int64 `json:"admins"`
+	Suspended int64 `json:"suspended"`
+	// ActiveLast30Days counts users who logged in within the last 30 days
+	ActiveLast30Days int64 `json:"active_last_30_days"`
+}
@@ -19,6 +19,7 @@ func TestGetAuthURL(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -36,7 +37,7 @@ func TestGetAuthURL(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	tests := []struct {
 		name           string
@@ -87,7 +88,7 @@ func TestGetAuthURL(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock()
-			url, err := authService.GetAuthURL()
+			url, err := authService.GetAuthURL(t.Context())
 			tt.validateResult(t, url, err)
 		})
 	}
@@ -100,6 +101,7 @@ func TestHandleCallback(t *testing.T) {
 	// Create mock repositories
 	mockTokenRepo := NewMockTokenRepository()
 	mockStateRepo := NewMockOAuthStateRepository()
+	mockUserRepo := NewMockUserRepository()
 
 	// Create test OAuth config
 	config := &oauth2.Config{
@@ -117,7 +119,7 @@ func TestHandleCallback(t *testing.T) {
 	}
 
 	// Create test auth service
-	authService := NewAuthService(config, mockStateRepo, mockTokenRepo)
+	authService := NewAuthService(config, mockStateRepo, mockTokenRepo, mockUserRepo)
 
 	// Create test user info
 	testUser := &GoogleUserInfo{
@@ -171,7 +173,7 @@ func TestHandleCallback(t *testing.T) {
 			setupMock: func() {
 				// Store valid state
 				now := time.Now()
-				mockStateRepo.Create(&OAuthState{
+				mockStateRepo.Create(t.Context(), &OAuthState{
 					State:     "valid-state",
 					CreatedAt: primitive.NewDateTimeFromTime(now),
 					ExpiresAt: primitive.NewDateTimeFromTime(now.Add(15 * time.Minute)),
@@ -212,8 +214,53 @@ func TestHandleCallback(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock()
-			user, err := authService.HandleCallback(tt.code, tt.state)
+			user, err := authService.HandleCallback(t.Context(), tt.code, tt.state)
 			tt.validateResult(t, user, err)
 		})
 	}
 }
+
+func TestRegisterLogin(t *testing.T) {
+	mockUserRepo := NewMockUserRepository()
+	authService := NewAuthService(&oauth2.Config{}, NewMockOAuthStateRepository(), NewMockTokenRepository(), mockUserRepo)
+	authService.adminEmails = map[string]bool{"admin@test.com": true}
+
+	tests := []struct {
+		name         string
+		userInfo     *GoogleUserInfo
+		expectedRole Role
+	}{
+		{
+			name:         "regular user",
+			userInfo:     &GoogleUserInfo{ID: "1", Email: "user@test.com", VerifiedEmail: true},
+			expectedRole: RoleUser,
+		},
+		{
+			name:         "configured admin",
+			userInfo:     &GoogleUserInfo{ID: "2", Email: "Admin@test.com", VerifiedEmail: true},
+			expectedRole: RoleAdmin,
+		},
+		{
+			name:         "unverified admin email",
+			userInfo:     &GoogleUserInfo{ID: "3", Email: "admin@test.com", VerifiedEmail: false},
+			expectedRole: RoleUser,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := authService.RegisterLogin(t.Context(), tt.userInfo)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRole, user.Role)
+			assert.Equal(t, tt.userInfo.Email, user.Email)
+		})
+	}
+
+	t.Run("suspended user", func(t *testing.T) {
+		assert.NoError(t, authService.SetUserSuspended(t.Context(), "1", true))
+
+		user, err := authService.RegisterLogin(t.Context(), &GoogleUserInfo{ID: "1", Email: "user@test.com"})
+		assert.Nil(t, user)
+		assert.IsType(t, &AccountSuspendedError{}, err)
+	})
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -10,8 +11,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Repositories holds the repositories of the auth package, all backed by the same store
+type Repositories struct {
+	States OAuthStateRepositoryInterface
+	Tokens RevocationFeedInterface
+	Users  UserRepositoryInterface
+}
+
+// NewMongoRepositories returns repositories that store everything in the database
+func NewMongoRepositories(db *database.Database) *Repositories {
+	return &Repositories{
+		States: NewOAuthStateRepository(db),
+		Tokens: NewTokenRepository(db),
+		Users:  NewUserRepository(db),
+	}
+}
+
 type OAuthStateRepository struct {
 	collection *mongo.Collection
 }
@@ -22,8 +40,8 @@ func NewOAuthStateRepository(db *database.Database) *OAuthStateRepository {
 	}
 }
 
-func (r *OAuthStateRepository) Create(state *OAuthState) error {
-	ctx, cancel := database.WithTimeout()
+func (r *OAuthStateRepository) Create(ctx context.Context, state *OAuthState) error {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	now := time.Now()
@@ -38,8 +56,8 @@ func (r *OAuthStateRepository) Create(state *OAuthState) error {
 	return nil
 }
 
-func (r *OAuthStateRepository) FindAndDelete(state string) (*OAuthState, error) {
-	ctx, cancel := database.WithTimeout()
+func (r *OAuthStateRepository) FindAndDelete(ctx context.Context, state string) (*OAuthState, error) {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	filter := bson.M{
@@ -71,8 +89,8 @@ func NewTokenRepository(db *database.Database) *TokenRepository {
 	}
 }
 
-func (r *TokenRepository) Blacklist(token string) error {
-	ctx, cancel := database.WithTimeout()
+func (r *TokenRepository) Blacklist(ctx context.Context, token string) error {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	blacklistedToken := &BlacklistedToken{
@@ -88,8 +106,8 @@ func (r *TokenRepository) Blacklist(token string) error {
 	return nil
 }
 
-func (r *TokenRepository) IsBlacklisted(token string) (bool, error) {
-	ctx, cancel := database.WithTimeout()
+func (r *TokenRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	filter := bson.M{"token": token}
@@ -101,3 +119,148 @@ func (r *TokenRepository) IsBlacklisted(token string) (bool, error) {
 
 	return count > 0, nil
 }
+
+func (r *TokenRepository) BlacklistedSince(ctx context.Context, since time.Time) ([]BlacklistedToken, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{
+		"created_at": bson.M{
+			"$gte": primitive.NewDateTimeFromTime(since),
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blacklisted tokens: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []BlacklistedToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode blacklisted tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+type UserRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUserRepository(db *database.Database) *UserRepository {
+	return &UserRepository{
+		collection: db.GetCollection("users"),
+	}
+}
+
+func (r *UserRepository) RecordLogin(ctx context.Context, user *User, admin bool) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	role := RoleUser
+	if admin {
+		role = RoleAdmin
+	}
+	set := bson.M{
+		"email":         user.Email,
+		"name":          user.Name,
+		"picture":       user.Picture,
+		"role":          role,
+		"last_login_at": now,
+	}
+	setOnInsert := bson.M{
+		"suspended":  false,
+		"created_at": now,
+	}
+
+	update := bson.M{"$set": set, "$setOnInsert": setOnInsert}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var result User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": user.ID}, update, opts).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	return &result, nil
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var result User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return &result, nil
+}
+
+func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) SetSuspended(ctx context.Context, id string, suspended bool) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"suspended": suspended}})
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return &UserNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Stats(ctx context.Context) (*UserStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	stats := &UserStats{}
+	activeSince := primitive.NewDateTimeFromTime(time.Now().Add(-30 * 24 * time.Hour))
+
+	counts := map[*int64]bson.M{
+		&stats.Total:            {},
+		&stats.Admins:           {"role": RoleAdmin},
+		&stats.Suspended:        {"suspended": true},
+		&stats.ActiveLast30Days: {"last_login_at": bson.M{"$gte": activeSince}},
+	}
+	for target, filter := range counts {
+		count, err := r.collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count users: %w", err)
+		}
+		*target = count
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"tranquil-pages/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// repositoryConformance runs a test against every implementation of the auth repositories, each
+// starting out empty, so that the stores are held to the same behaviour
+func repositoryConformance(t *testing.T, test func(t *testing.T, repos *Repositories)) {
+	t.Run("mongo", func(t *testing.T) {
+		testDB, err := database.NewTestDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, NewMongoRepositories(testDB.Database))
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		testDB, err := database.NewTestPostgresDatabase()
+		if errors.Is(err, database.ErrNoTestDatabase) {
+			t.Skip(err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, NewSQLRepositories(testDB.SQLDatabase))
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		testDB, err := database.NewTestSQLiteDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, NewSQLRepositories(testDB.SQLDatabase))
+	})
+}
+
+func TestOAuthStateRepository_StatesAreUsedOnce(t *testing.T) {
+	repositoryConformance(t, func(t *testing.T, repos *Repositories) {
+		// Given
+		state := &OAuthState{State: "state"}
+		assert.NoError(t, repos.States.Create(t.Context(), state))
+
+		// When
+		found, firstErr := repos.States.FindAndDelete(t.Context(), "state")
+		again, secondErr := repos.States.FindAndDelete(t.Context(), "state")
+		unknown, unknownErr := repos.States.FindAndDelete(t.Context(), "unknown")
+
+		// Then
+		assert.NoError(t, firstErr)
+		assert.Equal(t, "state", found.State)
+		assert.Equal(t, state.ExpiresAt, found.ExpiresAt)
+		assert.NoError(t, secondErr)
+		assert.Nil(t, again)
+		assert.NoError(t, unknownErr)
+		assert.Nil(t, unknown)
+	})
+}
+
+func TestTokenRepository_Blacklist(t *testing.T) {
+	repositoryConformance(t, func(t *testing.T, repos *Repositories) {
+		// Given
+		before := time.Now().Add(-time.Second)
+		assert.NoError(t, repos.Tokens.Blacklist(t.Context(), "revoked"))
+
+		// When
+		revoked, revokedErr := repos.Tokens.IsBlacklisted(t.Context(), "revoked")
+		valid, validErr := repos.Tokens.IsBlacklisted(t.Context(), "valid")
+		recent, recentErr := repos.Tokens.BlacklistedSince(t.Context(), before)
+		future, futureErr := repos.Tokens.BlacklistedSince(t.Context(), time.Now().Add(time.Hour))
+
+		// Then
+		assert.NoError(t, revokedErr)
+		assert.True(t, revoked)
+		assert.NoError(t, validErr)
+		assert.False(t, valid)
+		assert.NoError(t, recentErr)
+		assert.Len(t, recent, 1)
+		assert.Equal(t, "revoked", recent[0].Token)
+		assert.NoError(t, futureErr)
+		assert.Empty(t, future)
+	})
+}
+
+func TestTokenRepository_CancelledContext(t *testing.T) {
+	repositoryConformance(t, func(t *testing.T, repos *Repositories) {
+		// Given
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		// When
+		_, err := repos.Tokens.IsBlacklisted(ctx, "token")
+
+		// Then
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestUserRepository_RecordLogin(t *testing.T) {
+	repositoryConformance(t, func(t *testing.T, repos *Repositories) {
+		// Given
+		user := &User{ID: "alice", Email: "alice@example.com", Name: "Alice"}
+
+		// When
+		first, firstErr := repos.Users.RecordLogin(t.Context(), user, true)
+		user.Name = "Alice Liddell"
+		second, secondErr := repos.Users.RecordLogin(t.Context(), user, false)
+		suspendErr := repos.Users.SetSuspended(t.Context(), "alice", true)
+		missingErr := repos.Users.SetSuspended(t.Context(), "bob", true)
+		found, findErr := repos.Users.FindByID(t.Context(), "alice")
+		missing, missingFindErr := repos.Users.FindByID(t.Context(), "bob")
+		stats, statsErr := repos.Users.Stats(t.Context())
+
+		// Then
+		assert.NoError(t, firstErr)
+		assert.Equal(t, RoleAdmin, first.Role)
+		assert.NoError(t, secondErr)
+		assert.Equal(t, RoleUser, second.Role, "an admin no longer configured is demoted on login")
+		assert.Equal(t, "Alice Liddell", second.Name)
+		assert.Equal(t, first.CreatedAt, second.CreatedAt)
+		assert.NoError(t, suspendErr)
+		var notFound *UserNotFoundError
+		assert.ErrorAs(t, missingErr, &notFound)
+		assert.NoError(t, findErr)
+		assert.True(t, found.Suspended)
+		assert.NoError(t, missingFindErr)
+		assert.Nil(t, missing)
+		assert.NoError(t, statsErr)
+		assert.Equal(t, &UserStats{Total: 1, Admins: 0, Suspended: 1, ActiveLast30Days: 1}, stats)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/oauth2"
 )
@@ -26,14 +27,18 @@ type AuthService struct {
 	config      *oauth2.Config
 	stateRepo   OAuthStateRepositoryInterface
 	tokenRepo   TokenRepositoryInterface
+	userRepo    UserRepositoryInterface
+	adminEmails map[string]bool
 	userInfoURL string
 }
 
-func NewAuthService(config *oauth2.Config, stateRepo OAuthStateRepositoryInterface, tokenRepo TokenRepositoryInterface) *AuthService {
+func NewAuthService(config *oauth2.Config, stateRepo OAuthStateRepositoryInterface, tokenRepo TokenRepositoryInterface, userRepo UserRepositoryInterface) *AuthService {
 	return &AuthService{
 		config:      config,
 		stateRepo:   stateRepo,
 		tokenRepo:   tokenRepo,
+		userRepo:    userRepo,
+		adminEmails: getAdminEmails(),
 		userInfoURL: "https://www.googleapis.com/oauth2/v2/userinfo",
 	}
 }
@@ -48,7 +53,7 @@ func GenerateRandomState() (string, error) {
 }
 
 // GetAuthURL generates the OAuth2 authorization URL
-func (s *AuthService) GetAuthURL() (string, error) {
+func (s *AuthService) GetAuthURL(ctx context.Context) (string, error) {
 	state, err := GenerateRandomState()
 	if err != nil {
 		return "", &StateGenerationError{Err: err}
@@ -58,7 +63,7 @@ func (s *AuthService) GetAuthURL() (string, error) {
 		State: state,
 	}
 
-	if err := s.stateRepo.Create(oauthState); err != nil {
+	if err := s.stateRepo.Create(ctx, oauthState); err != nil {
 		return "", &AuthURLGenerationError{Err: fmt.Errorf("failed to store state: %w", err)}
 	}
 
@@ -66,8 +71,8 @@ func (s *AuthService) GetAuthURL() (string, error) {
 }
 
 // HandleCallback processes the OAuth2 callback and returns user info
-func (s *AuthService) HandleCallback(code, state string) (*GoogleUserInfo, error) {
-	oauthState, err := s.stateRepo.FindAndDelete(state)
+func (s *AuthService) HandleCallback(ctx context.Context, code, state string) (*GoogleUserInfo, error) {
+	oauthState, err := s.stateRepo.FindAndDelete(ctx, state)
 	if err != nil {
 		return nil, &StateValidationError{Err: fmt.Errorf("failed to validate state: %w", err)}
 	}
@@ -75,12 +80,12 @@ func (s *AuthService) HandleCallback(code, state string) (*GoogleUserInfo, error
 		return nil, &StateValidationError{Err: fmt.Errorf("invalid or expired state")}
 	}
 
-	token, err := s.config.Exchange(context.Background(), code)
+	token, err := s.config.Exchange(ctx, code)
 	if err != nil {
 		return nil, &TokenExchangeError{Err: fmt.Errorf("failed to exchange code for token: %w", err)}
 	}
 
-	client := s.config.Client(context.Background(), token)
+	client := s.config.Client(ctx, token)
 	resp, err := client.Get(s.userInfoURL)
 	if err != nil {
 		return nil, &UserInfoError{Err: fmt.Errorf("failed to get user info: %w", err)}
@@ -101,14 +106,14 @@ func (s *AuthService) HandleCallback(code, state string) (*GoogleUserInfo, error
 }
 
 // Logout disables the given token, ensuring that it cannot be used for further authentication
-func (s *AuthService) Logout(token string) error {
+func (s *AuthService) Logout(ctx context.Context, token string) error {
 	_, err := ValidateToken(token)
 	if err != nil {
 		return fmt.Errorf("invalid token: %w", err)
 	}
 
 	// Blacklist the token until its expiration
-	if err := s.tokenRepo.Blacklist(token); err != nil {
+	if err := s.tokenRepo.Blacklist(ctx, token); err != nil {
 		return &TokenBlacklistError{Err: fmt.Errorf("failed to blacklist token: %w", err)}
 	}
 
@@ -116,14 +121,14 @@ func (s *AuthService) Logout(token string) error {
 }
 
 // ValidateAuthenticationToken checks if a token is valid and active, extracting Claims for further use if so.
-func (s *AuthService) ValidateAuthenticationToken(tokenString string) (*Claims, error) {
+func (s *AuthService) ValidateAuthenticationToken(ctx context.Context, tokenString string) (*Claims, error) {
 	claims, err := ValidateToken(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
 	// Check if token is blacklisted
-	isBlacklisted, err := s.tokenRepo.IsBlacklisted(tokenString)
+	isBlacklisted, err := s.tokenRepo.IsBlacklisted(ctx, tokenString)
 	if err != nil {
 		return nil, &TokenBlacklistError{Err: fmt.Errorf("failed to check token blacklist: %w", err)}
 	}
@@ -131,5 +136,75 @@ func (s *AuthService) ValidateAuthenticationToken(tokenString string) (*Claims,
 		return nil, &TokenRevokedError{}
 	}
 
+	// Suspension applies to tokens issued before it too. With a CachedUserRepository, it reaches other
+	// replicas within the cache TTL.
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, &UserLookupError{Err: err}
+	}
+	if user != nil && user.Suspended {
+		return nil, &AccountSuspendedError{}
+	}
+
+	// The role is taken from the account rather than the token, so that demoting or reinstating a user
+	// applies to the tokens they already hold. Tokens of users without an account grant no role.
+	claims.Role = ""
+	if user != nil {
+		claims.Role = user.Role
+	}
+
 	return claims, nil
 }
+
+// RegisterLogin stores the user's account on login. The admin role is granted to configured admin emails
+// and taken away from users whose email is no longer configured, which also applies to tokens already
+// issued, since requests are authorized with the stored role.
+func (s *AuthService) RegisterLogin(ctx context.Context, userInfo *GoogleUserInfo) (*User, error) {
+	user := &User{
+		ID:      userInfo.ID,
+		Email:   userInfo.Email,
+		Name:    userInfo.Name,
+		Picture: userInfo.Picture,
+	}
+	admin := userInfo.VerifiedEmail && s.adminEmails[strings.ToLower(userInfo.Email)]
+
+	stored, err := s.userRepo.RecordLogin(ctx, user, admin)
+	if err != nil {
+		return nil, &UserLookupError{Err: err}
+	}
+	if stored.Suspended {
+		return nil, &AccountSuspendedError{}
+	}
+
+	return stored, nil
+}
+
+// ListUsers returns a page of all registered users
+func (s *AuthService) ListUsers(ctx context.Context, offset, limit int) ([]User, error) {
+	users, err := s.userRepo.List(ctx, offset, limit)
+	if err != nil {
+		return nil, &UserLookupError{Err: err}
+	}
+	return users, nil
+}
+
+// SetUserSuspended suspends or reinstates a user's account
+func (s *AuthService) SetUserSuspended(ctx context.Context, id string, suspended bool) error {
+	err := s.userRepo.SetSuspended(ctx, id, suspended)
+	if _, ok := err.(*UserNotFoundError); ok {
+		return err
+	}
+	if err != nil {
+		return &UserLookupError{Err: err}
+	}
+	return nil
+}
+
+// GetUserStats returns instance-wide user statistics
+func (s *AuthService) GetUserStats(ctx context.Context) (*UserStats, error) {
+	stats, err := s.userRepo.Stats(ctx)
+	if err != nil {
+		return nil, &UserLookupError{Err: err}
+	}
+	return stats, nil
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"tranquil-pages/database"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewSQLRepositories returns repositories that store everything in the tables of a SQL database. They
+// behave like the Mongo repositories, down to the errors they return.
+func NewSQLRepositories(db *database.SQLDatabase) *Repositories {
+	return &Repositories{
+		States: NewSQLOAuthStateRepository(db),
+		Tokens: NewSQLTokenRepository(db),
+		Users:  NewSQLUserRepository(db),
+	}
+}
+
+type SQLOAuthStateRepository struct {
+	db *database.SQLDatabase
+}
+
+func NewSQLOAuthStateRepository(db *database.SQLDatabase) *SQLOAuthStateRepository {
+	return &SQLOAuthStateRepository{db: db}
+}
+
+func (r *SQLOAuthStateRepository) Create(ctx context.Context, state *OAuthState) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	state.CreatedAt = primitive.NewDateTimeFromTime(now)
+	state.ExpiresAt = primitive.NewDateTimeFromTime(now.Add(15 * time.Minute))
+	if state.ID.IsZero() {
+		state.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		r.db.Rebind("INSERT INTO oauth_states (id, state, created_at, expires_at) VALUES (?, ?, ?, ?)"),
+		state.ID.Hex(), state.State, int64(state.CreatedAt), int64(state.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLOAuthStateRepository) FindAndDelete(ctx context.Context, state string) (*OAuthState, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// Deleting by id means that of two callers racing for the same state, only one gets a row back
+	row := r.db.QueryRowContext(ctx, r.db.Rebind(`DELETE FROM oauth_states
+		WHERE id = (SELECT id FROM oauth_states WHERE state = ? AND expires_at > ? LIMIT 1)
+		RETURNING id, state, created_at, expires_at`),
+		state, int64(primitive.NewDateTimeFromTime(time.Now())))
+
+	var result OAuthState
+	var id string
+	err := row.Scan(&id, &result.State, &result.CreatedAt, &result.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err == nil {
+		result.ID, err = primitive.ObjectIDFromHex(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find and delete state: %w", err)
+	}
+
+	return &result, nil
+}
+
+type SQLTokenRepository struct {
+	db *database.SQLDatabase
+}
+
+func NewSQLTokenRepository(db *database.SQLDatabase) *SQLTokenRepository {
+	return &SQLTokenRepository{db: db}
+}
+
+func (r *SQLTokenRepository) Blacklist(ctx context.Context, token string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx,
+		r.db.Rebind("INSERT INTO blacklisted_jwts (id, token, created_at) VALUES (?, ?, ?)"),
+		primitive.NewObjectID().Hex(), token, int64(primitive.NewDateTimeFromTime(time.Now())))
+	if err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLTokenRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, r.db.Rebind("SELECT COUNT(*) FROM blacklisted_jwts WHERE token = ?"), token).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check blacklisted token: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *SQLTokenRepository) BlacklistedSince(ctx context.Context, since time.Time) ([]BlacklistedToken, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.db.Rebind("SELECT id, token, created_at FROM blacklisted_jwts WHERE created_at >= ? ORDER BY id"),
+		int64(primitive.NewDateTimeFromTime(since)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blacklisted tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []BlacklistedToken
+	for rows.Next() {
+		var token BlacklistedToken
+		var id string
+		if err := rows.Scan(&id, &token.Token, &token.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to decode blacklisted tokens: %w", err)
+		}
+		if token.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+			return nil, fmt.Errorf("failed to decode blacklisted tokens: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode blacklisted tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+type SQLUserRepository struct {
+	db *database.SQLDatabase
+}
+
+func NewSQLUserRepository(db *database.SQLDatabase) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+const userColumns = "id, email, name, picture, role, suspended, created_at, last_login_at"
+
+// scanner is a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Picture, &user.Role, &user.Suspended, &user.CreatedAt, &user.LastLoginAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLUserRepository) RecordLogin(ctx context.Context, user *User, admin bool) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := int64(primitive.NewDateTimeFromTime(time.Now()))
+	role := RoleUser
+	if admin {
+		role = RoleAdmin
+	}
+
+	// A user never loses a suspension or their creation time by logging in again
+	row := r.db.QueryRowContext(ctx, r.db.Rebind(`INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, FALSE, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			email = excluded.email,
+			name = excluded.name,
+			picture = excluded.picture,
+			role = excluded.role,
+			last_login_at = excluded.last_login_at
+		RETURNING `+userColumns),
+		user.ID, user.Email, user.Name, user.Picture, string(role), now, now)
+
+	result, err := scanUser(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *SQLUserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := scanUser(r.db.QueryRowContext(ctx, r.db.Rebind("SELECT "+userColumns+" FROM users WHERE id = ?"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *SQLUserRepository) List(ctx context.Context, offset, limit int) ([]User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// Like Mongo, a limit of zero means no limit
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		r.db.Rebind("SELECT "+userColumns+" FROM users ORDER BY created_at, id LIMIT ? OFFSET ?"), int64(limit), offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode users: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *SQLUserRepository) SetSuspended(ctx context.Context, id string, suspended bool) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind("UPDATE users SET suspended = ? WHERE id = ?"), suspended, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	matched, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if matched == 0 {
+		return &UserNotFoundError{ID: id}
+	}
+
+	return nil
+}
+
+func (r *SQLUserRepository) Stats(ctx context.Context) (*UserStats, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	stats := &UserStats{}
+	activeSince := int64(primitive.NewDateTimeFromTime(time.Now().Add(-30 * 24 * time.Hour)))
+
+	err := r.db.QueryRowContext(ctx, r.db.Rebind(`SELECT
+			COUNT(*),
+			COUNT(CASE WHEN role = ? THEN 1 END),
+			COUNT(CASE WHEN suspended THEN 1 END),
+			COUNT(CASE WHEN last_login_at >= ? THEN 1 END)
+		FROM users`),
+		string(RoleAdmin), activeSince).
+		Scan(&stats.Total, &stats.Admins, &stats.Suspended, &stats.ActiveLast30Days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// CachedTokenRepository wraps a token repository with an in-process cache, so that checking
+// a token on every request doesn't cost a database round trip.
+//
+// Two things are cached: an LRU of tokens recently found not to be blacklisted, and the set of
+// tokens known to be revoked. Revocations made on other replicas are picked up by polling the
+// underlying repository for new entries. Negative lookups are only trusted for one poll interval,
+// so a revocation takes effect everywhere within PollInterval even if polling falls behind.
+type CachedTokenRepository struct {
+	inner  RevocationFeedInterface
+	config TokenCacheConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	revoked  map[string]time.Time
+	order    *list.List
+	negative map[string]*list.Element
+	lastSync time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type negativeLookup struct {
+	token     string
+	checkedAt time.Time
+}
+
+func NewCachedTokenRepository(inner RevocationFeedInterface, config TokenCacheConfig) *CachedTokenRepository {
+	return &CachedTokenRepository{
+		inner:    inner,
+		config:   config,
+		now:      time.Now,
+		revoked:  make(map[string]time.Time),
+		order:    list.New(),
+		negative: make(map[string]*list.Element),
+	}
+}
+
+// Start loads the current blacklist and begins polling for revocations in the background.
+func (r *CachedTokenRepository) Start() {
+	if err := r.Sync(context.Background()); err != nil {
+		log.Printf("Initial token blacklist sync failed: %v", err)
+	}
+
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.poll(r.stop, r.done)
+}
+
+// Stop ends background polling. It is safe to call Stop without Start.
+func (r *CachedTokenRepository) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+func (r *CachedTokenRepository) poll(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := r.Sync(context.Background()); err != nil {
+				log.Printf("Token blacklist sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// Sync fetches revocations made since the last successful sync and applies them to the cache.
+func (r *CachedTokenRepository) Sync(ctx context.Context) error {
+	startedAt := r.now()
+
+	r.mu.Lock()
+	since := r.lastSync.Add(-r.config.PollInterval) // overlap to tolerate clock skew between replicas
+	if r.lastSync.IsZero() {
+		since = startedAt.Add(-tokenLifetime)
+	}
+	r.mu.Unlock()
+
+	tokens, err := r.inner.BlacklistedSince(ctx, since)
+	if err != nil {
+		return &TokenBlacklistError{Err: err}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range tokens {
+		r.markRevoked(token.Token, token.CreatedAt.Time())
+	}
+
+	// Tokens expire on their own after tokenLifetime, so older revocations can be forgotten
+	for token, revokedAt := range r.revoked {
+		if startedAt.Sub(revokedAt) > tokenLifetime {
+			delete(r.revoked, token)
+		}
+	}
+
+	r.lastSync = startedAt
+	return nil
+}
+
+func (r *CachedTokenRepository) Blacklist(ctx context.Context, token string) error {
+	if err := r.inner.Blacklist(ctx, token); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.markRevoked(token, r.now())
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *CachedTokenRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	r.mu.Lock()
+	if _, ok := r.revoked[token]; ok {
+		r.mu.Unlock()
+		return true, nil
+	}
+	if element, ok := r.negative[token]; ok {
+		if r.now().Sub(element.Value.(*negativeLookup).checkedAt) < r.config.PollInterval {
+			r.order.MoveToFront(element)
+			r.mu.Unlock()
+			return false, nil
+		}
+		r.removeNegative(token)
+	}
+	r.mu.Unlock()
+
+	checkedAt := r.now()
+	isBlacklisted, err := r.inner.IsBlacklisted(ctx, token)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if isBlacklisted {
+		r.markRevoked(token, checkedAt)
+		return true, nil
+	}
+
+	// A sync may have seen the revocation while we were waiting on the lookup
+	if _, ok := r.revoked[token]; ok {
+		return true, nil
+	}
+	r.addNegative(token, checkedAt)
+
+	return false, nil
+}
+
+func (r *CachedTokenRepository) markRevoked(token string, revokedAt time.Time) {
+	r.revoked[token] = revokedAt
+	r.removeNegative(token)
+}
+
+func (r *CachedTokenRepository) addNegative(token string, checkedAt time.Time) {
+	if r.config.Size <= 0 {
+		return
+	}
+
+	r.removeNegative(token)
+	r.negative[token] = r.order.PushFront(&negativeLookup{token: token, checkedAt: checkedAt})
+
+	for r.order.Len() > r.config.Size {
+		oldest := r.order.Back()
+		r.removeNegative(oldest.Value.(*negativeLookup).token)
+	}
+}
+
+func (r *CachedTokenRepository) removeNegative(token string) {
+	if element, ok := r.negative[token]; ok {
+		r.order.Remove(element)
+		delete(r.negative, token)
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTokenCache(size int) (*CachedTokenRepository, *MockTokenRepository, *time.Time) {
+	mockTokenRepo := NewMockTokenRepository()
+	cache := NewCachedTokenRepository(mockTokenRepo, TokenCacheConfig{
+		Size:         size,
+		PollInterval: time.Minute,
+	})
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	return cache, mockTokenRepo, &now
+}
+
+func TestCachedTokenRepository_CachesNegativeLookups(t *testing.T) {
+	cache, mockTokenRepo, _ := newTestTokenCache(10)
+
+	for i := 0; i < 3; i++ {
+		isBlacklisted, err := cache.IsBlacklisted(t.Context(), "token")
+		assert.NoError(t, err)
+		assert.False(t, isBlacklisted)
+	}
+
+	assert.Equal(t, 1, mockTokenRepo.isBlacklistedCalls)
+}
+
+func TestCachedTokenRepository_LocalBlacklistTakesEffectImmediately(t *testing.T) {
+	cache, _, _ := newTestTokenCache(10)
+
+	isBlacklisted, _ := cache.IsBlacklisted(t.Context(), "token")
+	assert.False(t, isBlacklisted)
+
+	assert.NoError(t, cache.Blacklist(t.Context(), "token"))
+
+	isBlacklisted, err := cache.IsBlacklisted(t.Context(), "token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+}
+
+func TestCachedTokenRepository_SyncPicksUpRemoteRevocations(t *testing.T) {
+	cache, mockTokenRepo, _ := newTestTokenCache(10)
+	assert.NoError(t, cache.Sync(t.Context()))
+
+	isBlacklisted, _ := cache.IsBlacklisted(t.Context(), "token")
+	assert.False(t, isBlacklisted)
+
+	// Another replica revokes the token
+	mockTokenRepo.Blacklist(t.Context(), "token")
+	assert.NoError(t, cache.Sync(t.Context()))
+
+	callsBefore := mockTokenRepo.isBlacklistedCalls
+	isBlacklisted, err := cache.IsBlacklisted(t.Context(), "token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+	assert.Equal(t, callsBefore, mockTokenRepo.isBlacklistedCalls)
+}
+
+func TestCachedTokenRepository_NegativeLookupsExpireAfterPollInterval(t *testing.T) {
+	cache, mockTokenRepo, now := newTestTokenCache(10)
+
+	isBlacklisted, _ := cache.IsBlacklisted(t.Context(), "token")
+	assert.False(t, isBlacklisted)
+
+	// Another replica revokes the token, but this replica never syncs
+	mockTokenRepo.Blacklist(t.Context(), "token")
+	*now = now.Add(time.Minute)
+
+	isBlacklisted, err := cache.IsBlacklisted(t.Context(), "token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+}
+
+func TestCachedTokenRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, mockTokenRepo, _ := newTestTokenCache(2)
+
+	cache.IsBlacklisted(t.Context(), "first")
+	cache.IsBlacklisted(t.Context(), "second")
+	cache.IsBlacklisted(t.Context(), "first")
+	cache.IsBlacklisted(t.Context(), "third")
+	assert.Equal(t, 3, mockTokenRepo.isBlacklistedCalls)
+
+	cache.IsBlacklisted(t.Context(), "first")
+	assert.Equal(t, 3, mockTokenRepo.isBlacklistedCalls)
+
+	cache.IsBlacklisted(t.Context(), "second")
+	assert.Equal(t, 4, mockTokenRepo.isBlacklistedCalls)
+}
+
+func TestCachedTokenRepository_StartAndStop(t *testing.T) {
+	cache, mockTokenRepo, _ := newTestTokenCache(10)
+	mockTokenRepo.Blacklist(t.Context(), "token")
+
+	cache.Start()
+	defer cache.Stop()
+
+	isBlacklisted, err := cache.IsBlacklisted(t.Context(), "token")
+	assert.NoError(t, err)
+	assert.True(t, isBlacklisted)
+	assert.Equal(t, 0, mockTokenRepo.isBlacklistedCalls)
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// CachedUserRepository wraps a user repository with an in-process cache of users looked up by ID, so
+// that checking on every request whether the user is suspended doesn't cost a database round trip.
+//
+// A cached user is only trusted for the configured TTL, so a suspension or role change made on another
+// replica can take up to one TTL to take effect here. Suspensions and logins made through this
+// repository take effect immediately.
+type CachedUserRepository struct {
+	inner  UserRepositoryInterface
+	config UserCacheConfig
+	now    func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	users map[string]*list.Element
+	// changes counts the suspensions made through the repository, so that a lookup that raced one
+	// doesn't cache what it read before it
+	changes uint64
+}
+
+type cachedUser struct {
+	id string
+	// user is nil for IDs that have no account
+	user      *User
+	checkedAt time.Time
+}
+
+func NewCachedUserRepository(inner UserRepositoryInterface, config UserCacheConfig) *CachedUserRepository {
+	return &CachedUserRepository{
+		inner:  inner,
+		config: config,
+		now:    time.Now,
+		order:  list.New(),
+		users:  make(map[string]*list.Element),
+	}
+}
+
+func (r *CachedUserRepository) RecordLogin(ctx context.Context, user *User, admin bool) (*User, error) {
+	checkedAt := r.now()
+	stored, err := r.inner.RecordLogin(ctx, user, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.add(stored.ID, stored, checkedAt)
+	r.mu.Unlock()
+
+	return copyUser(stored), nil
+}
+
+func (r *CachedUserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	r.mu.Lock()
+	if element, ok := r.users[id]; ok {
+		cached := element.Value.(*cachedUser)
+		if r.now().Sub(cached.checkedAt) < r.config.TTL {
+			r.order.MoveToFront(element)
+			user := copyUser(cached.user)
+			r.mu.Unlock()
+			return user, nil
+		}
+		r.remove(id)
+	}
+	changes := r.changes
+	r.mu.Unlock()
+
+	checkedAt := r.now()
+	user, err := r.inner.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.changes == changes {
+		r.add(id, user, checkedAt)
+	}
+	r.mu.Unlock()
+
+	return copyUser(user), nil
+}
+
+func (r *CachedUserRepository) List(ctx context.Context, offset, limit int) ([]User, error) {
+	return r.inner.List(ctx, offset, limit)
+}
+
+func (r *CachedUserRepository) SetSuspended(ctx context.Context, id string, suspended bool) error {
+	err := r.inner.SetSuspended(ctx, id, suspended)
+
+	// The next lookup reads the user again, whether or not the change went through
+	r.mu.Lock()
+	r.changes++
+	r.remove(id)
+	r.mu.Unlock()
+
+	return err
+}
+
+func (r *CachedUserRepository) Stats(ctx context.Context) (*UserStats, error) {
+	return r.inner.Stats(ctx)
+}
+
+func (r *CachedUserRepository) add(id string, user *User, checkedAt time.Time) {
+	if r.config.Size <= 0 {
+		return
+	}
+
+	r.remove(id)
+	r.users[id] = r.order.PushFront(&cachedUser{id: id, user: copyUser(user), checkedAt: checkedAt})
+
+	for r.order.Len() > r.config.Size {
+		oldest := r.order.Back()
+		r.remove(oldest.Value.(*cachedUser).id)
+	}
+}
+
+func (r *CachedUserRepository) remove(id string) {
+	if element, ok := r.users[id]; ok {
+		r.order.Remove(element)
+		delete(r.users, id)
+	}
+}
+
+// copyUser keeps callers from changing the cached user
+func copyUser(user *User) *User {
+	if user == nil {
+		return nil
+	}
+	result := *user
+	return &result
+}
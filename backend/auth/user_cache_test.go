@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUserCache(size int) (*CachedUserRepository, *MockUserRepository, *time.Time) {
+	mockUserRepo := NewMockUserRepository()
+	cache := NewCachedUserRepository(mockUserRepo, UserCacheConfig{
+		Size: size,
+		TTL:  time.Minute,
+	})
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	return cache, mockUserRepo, &now
+}
+
+func TestCachedUserRepository_CachesLookups(t *testing.T) {
+	cache, mockUserRepo, _ := newTestUserCache(10)
+	_, err := mockUserRepo.RecordLogin(t.Context(), &User{ID: "alice"}, false)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		user, err := cache.FindByID(t.Context(), "alice")
+		assert.NoError(t, err)
+		assert.Equal(t, "alice", user.ID)
+	}
+
+	assert.Equal(t, 1, mockUserRepo.findByIDCalls)
+}
+
+func TestCachedUserRepository_LocalSuspensionTakesEffectImmediately(t *testing.T) {
+	cache, _, _ := newTestUserCache(10)
+	_, err := cache.RecordLogin(t.Context(), &User{ID: "alice"}, false)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.SetSuspended(t.Context(), "alice", true))
+
+	user, err := cache.FindByID(t.Context(), "alice")
+	assert.NoError(t, err)
+	assert.True(t, user.Suspended)
+}
+
+func TestCachedUserRepository_LookupsExpireAfterTTL(t *testing.T) {
+	cache, mockUserRepo, now := newTestUserCache(10)
+	_, err := cache.RecordLogin(t.Context(), &User{ID: "alice"}, false)
+	assert.NoError(t, err)
+
+	// Another replica suspends the user
+	assert.NoError(t, mockUserRepo.SetSuspended(t.Context(), "alice", true))
+	user, _ := cache.FindByID(t.Context(), "alice")
+	assert.False(t, user.Suspended)
+
+	*now = now.Add(time.Minute)
+
+	user, err = cache.FindByID(t.Context(), "alice")
+	assert.NoError(t, err)
+	assert.True(t, user.Suspended)
+}
+
+func TestCachedUserRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, mockUserRepo, _ := newTestUserCache(2)
+
+	for _, id := range []string{"alice", "bob", "carol"} {
+		_, err := cache.FindByID(t.Context(), id)
+		assert.NoError(t, err)
+	}
+	_, err := cache.FindByID(t.Context(), "alice")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 4, mockUserRepo.findByIDCalls)
+}
@@ -0,0 +1,107 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+	appErrors "tranquil-pages/errors"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+const azureTimeout = 30 * time.Second
+
+// AzureBlobStore keeps blobs in an Azure Storage container. It works against Azurite as well,
+// given a connection string with a BlobEndpoint pointing at the emulator.
+type AzureBlobStore struct {
+	client    *azblob.Client
+	container string
+}
+
+// NewAzureBlobStore connects to the storage account and creates the container if it doesn't exist yet
+func NewAzureBlobStore(connectionString, container string) (*AzureBlobStore, error) {
+	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+
+	_, err = client.CreateContainer(ctx, container, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return nil, err
+	}
+
+	return &AzureBlobStore{client: client, container: container}, nil
+}
+
+func (s *AzureBlobStore) handleError(err error, operation, key string) error {
+	if err != nil {
+		log.Printf("Blob store error in %s of %s: %v", operation, key, err)
+		return appErrors.ErrStorage
+	}
+	return nil
+}
+
+func (s *AzureBlobStore) Put(key string, data []byte, contentType string) error {
+	if !validKey(key) {
+		return appErrors.ErrInvalidBlobKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+
+	_, err := s.client.UploadBuffer(ctx, s.container, key, data, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return s.handleError(err, "Put", key)
+}
+
+// Get streams the blob. The download is bounded by a timeout that starts with the request,
+// which is released when the returned reader is closed.
+func (s *AzureBlobStore) Get(key string) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, appErrors.ErrInvalidBlobKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+
+	response, err := s.client.DownloadStream(ctx, s.container, key, nil)
+	if err != nil {
+		cancel()
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, s.handleError(err, "Get", key)
+	}
+	return &cancelOnClose{ReadCloser: response.Body, cancel: cancel}, nil
+}
+
+func (s *AzureBlobStore) Delete(key string) error {
+	if !validKey(key) {
+		return appErrors.ErrInvalidBlobKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+
+	_, err := s.client.DeleteBlob(ctx, s.container, key, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return s.handleError(err, "Delete", key)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
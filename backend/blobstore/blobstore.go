@@ -0,0 +1,57 @@
+package blobstore
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"tranquil-pages/errors"
+)
+
+// BlobStore keeps binary files, such as cover images, under slash-separated keys
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	// Get returns appErrors.ErrNotFound if there is no blob with the key
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(key string) error
+}
+
+var keySegmentPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// validKey reports whether every segment of the key is a plain file name, so that
+// keys can't escape the store's root
+func validKey(key string) bool {
+	for _, segment := range strings.Split(key, "/") {
+		if !keySegmentPattern.MatchString(segment) || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// NewBlobStoreFromEnv creates the store selected by BLOB_STORE: "local" (the default) keeps files
+// under BLOB_STORE_PATH, "azure" uses the container AZURE_STORAGE_CONTAINER of the account in
+// AZURE_STORAGE_CONNECTION_STRING.
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	switch kind := os.Getenv("BLOB_STORE"); kind {
+	case "", "local":
+		path := os.Getenv("BLOB_STORE_PATH")
+		if path == "" {
+			path = "data/blobs"
+		}
+		return NewLocalBlobStore(path), nil
+	case "azure":
+		connectionString, ok := os.LookupEnv("AZURE_STORAGE_CONNECTION_STRING")
+		if !ok {
+			return nil, errors.ErrEnvNotSet("AZURE_STORAGE_CONNECTION_STRING")
+		}
+		container := os.Getenv("AZURE_STORAGE_CONTAINER")
+		if container == "" {
+			container = "user-files"
+		}
+		return NewAzureBlobStore(connectionString, container)
+	default:
+		return nil, errors.ErrUnknownBlobStore(kind)
+	}
+}
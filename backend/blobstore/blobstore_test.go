@@ -0,0 +1,63 @@
+package blobstore
+
+import (
+	"io"
+	"os"
+	"testing"
+	appErrors "tranquil-pages/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBlobStore checks the behaviour every BlobStore implementation must share
+func testBlobStore(t *testing.T, store BlobStore) {
+	t.Run("stores and replaces blobs", func(t *testing.T) {
+		assert.NoError(t, store.Put("covers/book/first", []byte("one"), "image/png"))
+		assert.NoError(t, store.Put("covers/book/first", []byte("two"), "image/png"))
+
+		reader, err := store.Get("covers/book/first")
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "two", string(data))
+	})
+
+	t.Run("missing blobs are not found", func(t *testing.T) {
+		_, err := store.Get("covers/book/missing")
+		assert.Equal(t, appErrors.ErrNotFound, err)
+	})
+
+	t.Run("deletes blobs", func(t *testing.T) {
+		assert.NoError(t, store.Put("covers/book/deleted", []byte("data"), "image/jpeg"))
+		assert.NoError(t, store.Delete("covers/book/deleted"))
+		assert.NoError(t, store.Delete("covers/book/deleted"))
+
+		_, err := store.Get("covers/book/deleted")
+		assert.Equal(t, appErrors.ErrNotFound, err)
+	})
+
+	t.Run("rejects keys outside the store", func(t *testing.T) {
+		for _, key := range []string{"", "../escape", "covers/../../escape", "/absolute", "covers//double", "covers/.hidden"} {
+			assert.Equal(t, appErrors.ErrInvalidBlobKey, store.Put(key, []byte("data"), "image/png"), key)
+			_, err := store.Get(key)
+			assert.Equal(t, appErrors.ErrInvalidBlobKey, err, key)
+		}
+	})
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	testBlobStore(t, NewLocalBlobStore(t.TempDir()))
+}
+
+// TestAzureBlobStore runs against Azurite, e.g. started with
+// docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0
+func TestAzureBlobStore(t *testing.T) {
+	connectionString, ok := os.LookupEnv("AZURITE_CONNECTION_STRING")
+	if !ok {
+		t.Skip("AZURITE_CONNECTION_STRING not set")
+	}
+
+	store, err := NewAzureBlobStore(connectionString, "test-blobs")
+	assert.NoError(t, err)
+	testBlobStore(t, store)
+}
@@ -0,0 +1,90 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	appErrors "tranquil-pages/errors"
+)
+
+// LocalBlobStore keeps blobs as files below a root directory, which is created on first use
+type LocalBlobStore struct {
+	root string
+}
+
+func NewLocalBlobStore(root string) *LocalBlobStore {
+	return &LocalBlobStore{root: root}
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", appErrors.ErrInvalidBlobKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalBlobStore) handleError(err error, operation, key string) error {
+	if err != nil {
+		log.Printf("Blob store error in %s of %s: %v", operation, key, err)
+		return appErrors.ErrStorage
+	}
+	return nil
+}
+
+// Put writes to a temporary file first, so readers never see a partially written blob
+func (s *LocalBlobStore) Put(key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return s.handleError(err, "Put", key)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return s.handleError(err, "Put", key)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return s.handleError(err, "Put", key)
+	}
+	if err := file.Close(); err != nil {
+		return s.handleError(err, "Put", key)
+	}
+	return s.handleError(os.Rename(file.Name(), path), "Put", key)
+}
+
+func (s *LocalBlobStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, appErrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, s.handleError(err, "Get", key)
+	}
+	return file, nil
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return s.handleError(err, "Delete", key)
+}
@@ -0,0 +1,51 @@
+// Command mongo-to-postgres copies the data of a TranquilPages MongoDB database into a Postgres
+// database, creating its tables if needed. It can be run again, for example after an interruption:
+// documents already copied are replaced by their current version in Mongo.
+//
+// Stop the server before copying, so that nothing changes in Mongo while it runs, and start it on
+// Postgres once the copy is done.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/repository"
+)
+
+var (
+	from = flag.String("from", os.Getenv("DB_URL"), "connection string of the MongoDB server to copy from, DB_URL by default")
+	to   = flag.String("to", os.Getenv("POSTGRES_URL"), "connection string of the Postgres database to copy to, POSTGRES_URL by default")
+)
+
+func main() {
+	flag.Parse()
+	if *from == "" || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	mongo, err := database.Connect(*from)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB:", err)
+	}
+	defer mongo.Close()
+
+	postgres, err := database.OpenPostgres(*to)
+	if err != nil {
+		log.Fatal("Failed to connect to Postgres:", err)
+	}
+	defer postgres.Close()
+
+	ctx := context.Background()
+	if err := repository.CopyToSQL(ctx, mongo, postgres); err != nil {
+		log.Fatal("Failed to copy data:", err)
+	}
+	if err := auth.CopyToSQL(ctx, mongo, postgres); err != nil {
+		log.Fatal("Failed to copy users:", err)
+	}
+	log.Println("Copied everything to Postgres")
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"net/http"
+	"tranquil-pages/auth"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	authService   *auth.AuthService
+	bookService   *services.BookService
+	authorService *services.AuthorService
+}
+
+func NewAdminController(authService *auth.AuthService, bookService *services.BookService, authorService *services.AuthorService) *AdminController {
+	return &AdminController{authService: authService, bookService: bookService, authorService: authorService}
+}
+
+// SetupAdminRoutes configures the operator routes. The router group must already require the admin role.
+func (ac *AdminController) SetupAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/users", ac.ListUsers)
+	router.POST("/users/:id/suspend", ac.SuspendUser)
+	router.POST("/users/:id/unsuspend", ac.UnsuspendUser)
+	router.GET("/stats", ac.GetStats)
+	router.POST("/migrations/authors", ac.MigrateAuthors)
+}
+
+func (ac *AdminController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch err.(type) {
+	case *auth.UserNotFoundError:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (ac *AdminController) ListUsers(c *gin.Context) {
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := ac.authService.ListUsers(c.Request.Context(), page.Offset, page.Limit)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	if users == nil {
+		users = []auth.User{}
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (ac *AdminController) SuspendUser(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if c.Param("id") == claims.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot suspend your own account"})
+		return
+	}
+
+	if err := ac.authService.SetUserSuspended(c.Request.Context(), c.Param("id"), true); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AdminController) UnsuspendUser(c *gin.Context) {
+	if err := ac.authService.SetUserSuspended(c.Request.Context(), c.Param("id"), false); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AdminController) GetStats(c *gin.Context) {
+	userStats, err := ac.authService.GetUserStats(c.Request.Context())
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	bookCount, err := ac.bookService.CountBooks(c.Request.Context())
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": userStats,
+		"books": gin.H{"total": bookCount},
+	})
+}
+
+// MigrateAuthors links books saved with only an author string to author entities. It can be run repeatedly.
+func (ac *AdminController) MigrateAuthors(c *gin.Context) {
+	summary, err := ac.authorService.MigrateAuthorStrings(c.Request.Context())
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
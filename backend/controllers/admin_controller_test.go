@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func getAdminTestDependencies() (*gin.Engine, *auth.AuthService, *services.BookService, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookService := newTestBookService(testDB)
+	authService := auth.NewAuthService(
+		&oauth2.Config{},
+		auth.NewOAuthStateRepository(testDB.Database),
+		auth.NewTokenRepository(testDB.Database),
+		auth.NewUserRepository(testDB.Database),
+	)
+	// The author service gets a book service of its own, so books created through bookService keep
+	// only their author string, as books saved before authors existed did
+	authorService := services.NewAuthorService(repository.NewAuthorRepository(testDB.Database), newTestBookService(testDB))
+	adminController := NewAdminController(authService, bookService, authorService)
+
+	api := router.Group("/admin")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: "admin-id", Role: auth.RoleAdmin})
+		c.Next()
+	})
+	adminController.SetupAdminRoutes(api)
+
+	return router, authService, bookService, testDB
+}
+
+func TestAdminController_StatsCountUsersAndBooks(t *testing.T) {
+	// Given
+	router, authService, bookService, testDB := getAdminTestDependencies()
+	defer testDB.Close()
+
+	_, err := authService.RegisterLogin(t.Context(), &auth.GoogleUserInfo{ID: "user-1", Email: "one@test.com"})
+	assert.NoError(t, err)
+	_, err = authService.RegisterLogin(t.Context(), &auth.GoogleUserInfo{ID: "user-2", Email: "two@test.com"})
+	assert.NoError(t, err)
+	assert.NoError(t, bookService.CreateBook(t.Context(), makeRandomBook()))
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/admin/stats", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stats struct {
+		Users auth.UserStats `json:"users"`
+		Books struct {
+			Total int64 `json:"total"`
+		} `json:"books"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	assert.Equal(t, int64(2), stats.Users.Total)
+	assert.Equal(t, int64(2), stats.Users.ActiveLast30Days)
+	assert.Equal(t, int64(1), stats.Books.Total)
+}
+
+func TestAdminController_CanSuspendAndListUsers(t *testing.T) {
+	// Given
+	router, authService, _, testDB := getAdminTestDependencies()
+	defer testDB.Close()
+
+	_, err := authService.RegisterLogin(t.Context(), &auth.GoogleUserInfo{ID: "user-1", Email: "one@test.com"})
+	assert.NoError(t, err)
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/users/user-1/suspend", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/users", nil)
+	router.ServeHTTP(w, req)
+
+	var users []auth.User
+	_ = json.Unmarshal(w.Body.Bytes(), &users)
+	assert.Equal(t, 1, len(users))
+	assert.True(t, users[0].Suspended)
+}
+
+func TestAdminController_SuspendingUnknownUserReturnsNotFound(t *testing.T) {
+	// Given
+	router, _, _, testDB := getAdminTestDependencies()
+	defer testDB.Close()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/users/nobody/suspend", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminController_CannotSuspendSelf(t *testing.T) {
+	// Given
+	router, _, _, testDB := getAdminTestDependencies()
+	defer testDB.Close()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/users/admin-id/suspend", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminController_MigratesAuthorStrings(t *testing.T) {
+	// Given
+	router, _, bookService, testDB := getAdminTestDependencies()
+	defer testDB.Close()
+
+	books := []*models.Book{
+		{UserID: "alice", Title: "The Dispossessed", Author: "Ursula K. Le Guin"},
+		{UserID: "alice", Title: "The Left Hand of Darkness", Author: "ursula k le guin"},
+		{UserID: "alice", Title: "Good Omens", Author: "Terry Pratchett & Neil Gaiman"},
+		{UserID: "bob", Title: "Lathe of Heaven", Author: "Ursula K. Le Guin"},
+		{UserID: "bob", Title: "Untitled"},
+	}
+	for _, book := range books {
+		assert.NoError(t, bookService.CreateBook(t.Context(), book))
+	}
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/migrations/authors", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	var summary models.AuthorMigrationSummary
+	_ = json.Unmarshal(w.Body.Bytes(), &summary)
+	assert.Equal(t, models.AuthorMigrationSummary{BooksUpdated: 4, AuthorsCreated: 4}, summary)
+
+	dispossessed, _ := bookService.GetBook(t.Context(), books[0].ID.Hex(), "alice")
+	leftHand, _ := bookService.GetBook(t.Context(), books[1].ID.Hex(), "alice")
+	goodOmens, _ := bookService.GetBook(t.Context(), books[2].ID.Hex(), "alice")
+	assert.Equal(t, dispossessed.Authors, leftHand.Authors)
+	assert.Equal(t, "ursula k le guin", leftHand.Author)
+	assert.Len(t, goodOmens.Authors, 2)
+
+	// When run again
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/migrations/authors", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	_ = json.Unmarshal(w.Body.Bytes(), &summary)
+	assert.Equal(t, models.AuthorMigrationSummary{}, summary)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuthorController struct {
+	authorService *services.AuthorService
+}
+
+func NewAuthorController(authorService *services.AuthorService) *AuthorController {
+	return &AuthorController{authorService: authorService}
+}
+
+func (ac *AuthorController) SetupAuthorRoutes(router *gin.RouterGroup) {
+	router.POST("/authors", ac.CreateAuthor)
+	router.GET("/authors", ac.ListAuthors)
+	router.GET("/authors/:id", ac.GetAuthor)
+	router.PUT("/authors/:id", ac.UpdateAuthor)
+	router.DELETE("/authors/:id", ac.DeleteAuthor)
+	router.GET("/authors/:id/books", ac.ListAuthorBooks)
+	router.POST("/authors/:id/merge", ac.MergeAuthor)
+}
+
+func (ac *AuthorController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+	case errors.Is(err, appErrors.ErrDuplicateAuthor), errors.Is(err, appErrors.ErrAuthorInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidAuthor),
+		errors.Is(err, appErrors.ErrInvalidMerge),
+		errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (ac *AuthorController) CreateAuthor(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var author models.Author
+	if err := c.ShouldBindJSON(&author); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author.ID = primitive.NilObjectID
+	author.UserID = claims.UserID
+	if err := ac.authorService.CreateAuthor(c.Request.Context(), &author); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+func (ac *AuthorController) ListAuthors(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	authors, err := ac.authorService.GetAuthorsByUserID(c.Request.Context(), claims.UserID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	if authors == nil {
+		authors = []models.Author{}
+	}
+
+	c.JSON(http.StatusOK, authors)
+}
+
+func (ac *AuthorController) GetAuthor(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	author, err := ac.authorService.GetAuthor(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+func (ac *AuthorController) UpdateAuthor(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Author
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author, err := ac.authorService.UpdateAuthor(c.Request.Context(), c.Param("id"), claims.UserID, &update)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+func (ac *AuthorController) DeleteAuthor(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := ac.authorService.DeleteAuthor(c.Request.Context(), c.Param("id"), claims.UserID); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AuthorController) ListAuthorBooks(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	books, err := ac.authorService.GetAuthorBooks(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	if books == nil {
+		books = []models.Book{}
+	}
+
+	if err := scaleBookList(c, books); err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
+// MergeAuthor moves the books of the author in the path to the author given as "into" and deletes it
+func (ac *AuthorController) MergeAuthor(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var request struct {
+		Into string `json:"into" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author, err := ac.authorService.MergeAuthor(c.Request.Context(), c.Param("id"), request.Into, claims.UserID)
+	if err != nil {
+		ac.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getAuthorTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	groupRepo := repository.NewGroupRepository(testDB.Database)
+	bookService := newTestBookService(testDB)
+	groupService := services.NewGroupService(groupRepo, services.NewPermissionEvaluator(groupRepo))
+	authorService := services.NewAuthorService(repository.NewAuthorRepository(testDB.Database), bookService)
+	services.NewSeriesService(repository.NewSeriesRepository(testDB.Database), bookService)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewGroupController(groupService, bookService).SetupGroupRoutes(api)
+	NewAuthorController(authorService).SetupAuthorRoutes(api)
+
+	return router, testDB
+}
+
+func createAuthor(t *testing.T, router *gin.Engine, userID, name string, aliases ...string) *models.Author {
+	w := requestAs(router, userID, "POST", "/authors", gin.H{"name": name, "aliases": aliases})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var author models.Author
+	_ = json.Unmarshal(w.Body.Bytes(), &author)
+	return &author
+}
+
+func listAuthors(router *gin.Engine, userID string) []models.Author {
+	var authors []models.Author
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/authors", nil).Body.Bytes(), &authors)
+	return authors
+}
+
+func getBookAs(router *gin.Engine, userID string, bookID primitive.ObjectID) *models.Book {
+	var book models.Book
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/books/"+bookID.Hex(), nil).Body.Bytes(), &book)
+	return &book
+}
+
+func TestAuthorController_LinksAuthorStringsByNameOrAlias(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	leGuin := createAuthor(t, router, "alice", "Ursula K. Le Guin", "Le Guin", "le  guin", "Ursula K. Le Guin")
+
+	// When
+	first := createBookViaApiAs(router, "alice", &models.Book{Title: "The Dispossessed", Author: "Le Guin"})
+	second := createBookViaApiAs(router, "alice", &models.Book{Title: "Always Coming Home", Author: "ursula k le guin"})
+	third := createBookViaApiAs(router, "alice", &models.Book{Title: "Good Omens", Author: "Terry Pratchett; Neil Gaiman"})
+
+	// Then
+	assert.Equal(t, []string{"Le Guin"}, leGuin.Aliases)
+	expected := []models.BookAuthor{{AuthorID: leGuin.ID, Role: models.AuthorRoleAuthor}}
+	assert.Equal(t, expected, first.Authors)
+	assert.Equal(t, expected, second.Authors)
+	assert.Equal(t, "Le Guin", first.Author)
+	assert.Len(t, third.Authors, 2)
+
+	var names []string
+	for _, author := range listAuthors(router, "alice") {
+		names = append(names, author.Name)
+	}
+	assert.Equal(t, []string{"Neil Gaiman", "Terry Pratchett", "Ursula K. Le Guin"}, names)
+}
+
+func TestAuthorController_RejectedBooksCreateNoAuthors(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	unknownSeries := primitive.NewObjectID()
+
+	// When a book naming a new author is rejected by a later check
+	w := requestAs(router, "alice", "POST", "/books", &models.Book{Title: "Ficciones", Author: "Jorge Luis Borges", SeriesID: &unknownSeries})
+
+	// Then the author isn't created
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, listAuthors(router, "alice"))
+}
+
+func TestAuthorController_BooksCanHaveSeveralAuthorsWithRoles(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	tolstoy := createAuthor(t, router, "alice", "Leo Tolstoy")
+	translator := createAuthor(t, router, "alice", "Richard Pevear")
+	editor := createAuthor(t, router, "alice", "Larissa Volokhonsky")
+
+	// When
+	book := createBookViaApiAs(router, "alice", &models.Book{
+		Title:  "War and Peace",
+		Author: "Tolstoy",
+		Authors: []models.BookAuthor{
+			{AuthorID: tolstoy.ID},
+			{AuthorID: translator.ID, Role: models.AuthorRoleTranslator},
+			{AuthorID: editor.ID, Role: models.AuthorRoleEditor},
+			{AuthorID: tolstoy.ID, Role: models.AuthorRoleAuthor},
+		},
+	})
+
+	// Then
+	assert.Equal(t, "Leo Tolstoy", book.Author)
+	assert.Equal(t, []models.BookAuthor{
+		{AuthorID: tolstoy.ID, Role: models.AuthorRoleAuthor},
+		{AuthorID: translator.ID, Role: models.AuthorRoleTranslator},
+		{AuthorID: editor.ID, Role: models.AuthorRoleEditor},
+	}, book.Authors)
+
+	// When
+	var books []models.Book
+	w := requestAs(router, "alice", "GET", fmt.Sprintf("/authors/%s/books", translator.ID.Hex()), nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &books)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, books, 1)
+	assert.Equal(t, book.ID, books[0].ID)
+}
+
+func TestAuthorController_RejectsInvalidAuthorLinks(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	author := createAuthor(t, router, "alice", "Italo Calvino")
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleEditor)
+
+	tests := []struct {
+		name   string
+		userID string
+		book   *models.Book
+	}{
+		{"unknown role", "alice", &models.Book{Title: "Invisible Cities", Authors: []models.BookAuthor{{AuthorID: author.ID, Role: "illustrator"}}}},
+		{"unknown author", "alice", &models.Book{Title: "Invisible Cities", Authors: []models.BookAuthor{{AuthorID: primitive.NewObjectID()}}}},
+		{"someone else's author", "bob", &models.Book{Title: "Invisible Cities", Authors: []models.BookAuthor{{AuthorID: author.ID}}}},
+		{"group book", "alice", &models.Book{Title: "Invisible Cities", GroupID: &group.ID, Authors: []models.BookAuthor{{AuthorID: author.ID}}}},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, test.userID, "POST", "/books", test.book)
+
+		// Then
+		assert.Equal(t, http.StatusBadRequest, w.Code, test.name)
+	}
+}
+
+func TestAuthorController_NamesAndAliasesAreUnique(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	createAuthor(t, router, "alice", "Ursula K. Le Guin", "Le Guin")
+	other := createAuthor(t, router, "alice", "Ursula Le Guin")
+
+	// When
+	duplicate := requestAs(router, "alice", "POST", "/authors", gin.H{"name": "le guin"})
+	alias := requestAs(router, "alice", "PUT", "/authors/"+other.ID.Hex(), gin.H{"name": "Ursula Le Guin", "aliases": []string{"LE GUIN"}})
+	otherUser := requestAs(router, "bob", "POST", "/authors", gin.H{"name": "Le Guin"})
+
+	// Then
+	assert.Equal(t, http.StatusConflict, duplicate.Code)
+	assert.Equal(t, http.StatusConflict, alias.Code)
+	assert.Equal(t, http.StatusOK, otherUser.Code)
+}
+
+func TestAuthorController_RenameUpdatesBooks(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", &models.Book{Title: "Kindred", Author: "O. Butler"})
+	author := listAuthors(router, "alice")[0]
+
+	// When
+	w := requestAs(router, "alice", "PUT", "/authors/"+author.ID.Hex(), gin.H{"name": "Octavia E. Butler", "aliases": []string{"O. Butler"}})
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Octavia E. Butler", getBookAs(router, "alice", book.ID).Author)
+}
+
+func TestAuthorController_MergeRepointsBooks(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	first := createBookViaApiAs(router, "alice", &models.Book{Title: "The Dispossessed", Author: "Le Guin"})
+	second := createBookViaApiAs(router, "alice", &models.Book{Title: "Earthsea", Author: "Ursula K. Le Guin"})
+	both := createBookViaApiAs(router, "alice", &models.Book{Title: "Collected", Author: "Le Guin & Ursula K. Le Guin"})
+	source, target := first.Authors[0].AuthorID, second.Authors[0].AuthorID
+
+	// When
+	w := requestAs(router, "alice", "POST", fmt.Sprintf("/authors/%s/merge", source.Hex()), gin.H{"into": target.Hex()})
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	var merged models.Author
+	_ = json.Unmarshal(w.Body.Bytes(), &merged)
+	assert.Equal(t, "Ursula K. Le Guin", merged.Name)
+	assert.Equal(t, []string{"Le Guin"}, merged.Aliases)
+	assert.Len(t, listAuthors(router, "alice"), 1)
+
+	expected := []models.BookAuthor{{AuthorID: target, Role: models.AuthorRoleAuthor}}
+	assert.Equal(t, expected, getBookAs(router, "alice", first.ID).Authors)
+	assert.Equal(t, "Ursula K. Le Guin", getBookAs(router, "alice", first.ID).Author)
+	assert.Equal(t, expected, getBookAs(router, "alice", both.ID).Authors)
+
+	// New books under the merged name link to the remaining author
+	third := createBookViaApiAs(router, "alice", &models.Book{Title: "Lavinia", Author: "Le Guin"})
+	assert.Equal(t, expected, third.Authors)
+}
+
+func TestAuthorController_MergeChecksBothAuthors(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	alices := createAuthor(t, router, "alice", "Jorge Luis Borges")
+	bobs := createAuthor(t, router, "bob", "Borges")
+
+	// When
+	self := requestAs(router, "alice", "POST", fmt.Sprintf("/authors/%s/merge", alices.ID.Hex()), gin.H{"into": alices.ID.Hex()})
+	foreign := requestAs(router, "alice", "POST", fmt.Sprintf("/authors/%s/merge", alices.ID.Hex()), gin.H{"into": bobs.ID.Hex()})
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, self.Code)
+	assert.Equal(t, http.StatusNotFound, foreign.Code)
+}
+
+func TestAuthorController_OnlyUnusedAuthorsCanBeDeleted(t *testing.T) {
+	// Given
+	router, testDB := getAuthorTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", &models.Book{Title: "Ficciones", Author: "Borges"})
+	path := "/authors/" + book.Authors[0].AuthorID.Hex()
+
+	// When
+	inUse := requestAs(router, "alice", "DELETE", path, nil)
+	requestAs(router, "alice", "DELETE", "/books/"+book.ID.Hex(), nil)
+	unused := requestAs(router, "alice", "DELETE", path, nil)
+
+	// Then
+	assert.Equal(t, http.StatusConflict, inUse.Code)
+	assert.Equal(t, http.StatusNoContent, unused.Code)
+	assert.Empty(t, listAuthors(router, "alice"))
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"tranquil-pages/auth"
 	appErrors "tranquil-pages/errors"
 	"tranquil-pages/models"
@@ -24,13 +26,72 @@ func (bc *BookController) SetupBookRoutes(router *gin.RouterGroup) {
 	router.POST("/books", bc.CreateBook)
 	router.GET("/books", bc.ListBooks)
 	router.GET("/books/:id", bc.GetBook)
+	router.PUT("/books/:id", bc.UpdateBook)
 	router.DELETE("/books/:id", bc.DeleteBook)
+	router.PUT("/books/:id/progress", bc.UpdateProgress)
+	router.GET("/books/:id/reads", bc.ListReads)
+	router.POST("/books/:id/reads", bc.AddRead)
+	router.PUT("/books/:id/reads/:readId", bc.UpdateRead)
+	router.DELETE("/books/:id/reads/:readId", bc.DeleteRead)
+	router.GET("/reads/yearly", bc.GetYearlyReads)
+	router.POST("/books/:id/loan", bc.LendBook)
+	router.POST("/books/:id/return", bc.ReturnBook)
+	router.GET("/loans", bc.ListLoans)
+}
+
+// parseBookFilter reads the list filters from the query string
+func parseBookFilter(c *gin.Context) (models.BookFilter, error) {
+	var filter models.BookFilter
+
+	if value := c.Query("overdue"); value != "" {
+		overdue, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("overdue must be true or false")
+		}
+		filter.Overdue = overdue
+	}
+
+	if value := c.Query("format"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			format := models.BookFormat(strings.TrimSpace(name))
+			if format == "" || !format.IsValid() {
+				return filter, appErrors.ErrInvalidFormat
+			}
+			filter.Formats = append(filter.Formats, format)
+		}
+	}
+
+	return filter, nil
 }
 
 func (bc *BookController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
 	switch {
 	case errors.Is(err, appErrors.ErrNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Book with id %s not found", c.Param("id"))})
+	case errors.Is(err, appErrors.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrBookOnLoan),
+		errors.Is(err, appErrors.ErrBookNotOnLoan),
+		errors.Is(err, appErrors.ErrReadInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidLoan),
+		errors.Is(err, appErrors.ErrInvalidRating),
+		errors.Is(err, appErrors.ErrInvalidVisibility),
+		errors.Is(err, appErrors.ErrInvalidStatus),
+		errors.Is(err, appErrors.ErrInvalidISBN),
+		errors.Is(err, appErrors.ErrInvalidVolume),
+		errors.Is(err, appErrors.ErrUnknownSeries),
+		errors.Is(err, appErrors.ErrInvalidAuthor),
+		errors.Is(err, appErrors.ErrUnknownAuthor),
+		errors.Is(err, appErrors.ErrInvalidFormat),
+		errors.Is(err, appErrors.ErrInvalidEdition),
+		errors.Is(err, appErrors.ErrInvalidReview),
+		errors.Is(err, appErrors.ErrInvalidProgress),
+		errors.Is(err, appErrors.ErrInvalidRead):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	case errors.Is(err, appErrors.ErrDatabase):
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	default:
@@ -48,8 +109,16 @@ func (bc *BookController) CreateBook(c *gin.Context) {
 	}
 
 	book.UserID = claims.UserID
-	err := bc.bookService.CreateBook(&book)
-	if err != nil {
+	if err := scoreBook(c, &book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	if err := bc.bookService.CreateBook(c.Request.Context(), &book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, &book); err != nil {
 		bc.handleError(c, err)
 		return
 	}
@@ -60,7 +129,13 @@ func (bc *BookController) CreateBook(c *gin.Context) {
 func (bc *BookController) ListBooks(c *gin.Context) {
 	claims := c.MustGet("claims").(*auth.Claims)
 
-	books, err := bc.bookService.GetBooksByUserID(claims.UserID)
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	books, err := bc.bookService.GetBooksByUserID(c.Request.Context(), claims.UserID, filter)
 	if err != nil {
 		bc.handleError(c, err)
 		return
@@ -70,28 +145,254 @@ func (bc *BookController) ListBooks(c *gin.Context) {
 		books = []models.Book{} // Ensure an empty slice instead of nil
 	}
 
+	if err := scaleBookList(c, books); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, books)
 }
 
 func (bc *BookController) GetBook(c *gin.Context) {
 	claims := c.MustGet("claims").(*auth.Claims)
 
-	book, err := bc.bookService.GetBook(c.Param("id"), claims.UserID)
+	book, err := bc.bookService.GetBook(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+func (bc *BookController) UpdateBook(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Book
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := scoreBook(c, &update); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	scale, err := ratingScale(c)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	book, err := bc.bookService.UpdateBook(c.Request.Context(), c.Param("id"), claims.UserID, &update, scale)
 	if err != nil {
 		bc.handleError(c, err)
 		return
 	}
 
+	if err := scaleBooks(c, book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, book)
 }
 
 func (bc *BookController) DeleteBook(c *gin.Context) {
 	claims := c.MustGet("claims").(*auth.Claims)
 
-	err := bc.bookService.DeleteBook(c.Param("id"), claims.UserID)
+	err := bc.bookService.DeleteBook(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateProgress takes {"page": n} for printed books and ebooks and {"minutes": n} for audiobooks
+func (bc *BookController) UpdateProgress(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var progress models.ReadingProgress
+	if err := c.ShouldBindJSON(&progress); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, err := bc.bookService.UpdateProgress(c.Request.Context(), c.Param("id"), claims.UserID, &progress)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+// ListReads returns the book's reading history, oldest first
+func (bc *BookController) ListReads(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	reads, err := bc.bookService.GetReads(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleReadList(c, reads); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reads)
+}
+
+func (bc *BookController) AddRead(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var read models.Read
+	if err := c.ShouldBindJSON(&read); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := scoreRead(c, &read); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	if err := bc.bookService.AddRead(c.Request.Context(), c.Param("id"), claims.UserID, &read); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleReads(c, &read); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, read)
+}
+
+func (bc *BookController) UpdateRead(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Read
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := scoreRead(c, &update); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+	scale, err := ratingScale(c)
 	if err != nil {
 		bc.handleError(c, err)
 		return
 	}
+
+	read, err := bc.bookService.UpdateRead(c.Request.Context(), c.Param("id"), c.Param("readId"), claims.UserID, &update, scale)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleReads(c, read); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, read)
+}
+
+func (bc *BookController) DeleteRead(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := bc.bookService.DeleteRead(c.Request.Context(), c.Param("id"), c.Param("readId"), claims.UserID); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
 	c.Status(http.StatusNoContent)
 }
+
+// GetYearlyReads counts the finished reads of the user's books per year
+func (bc *BookController) GetYearlyReads(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	yearly, err := bc.bookService.GetYearlyReads(c.Request.Context(), claims.UserID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, yearly)
+}
+
+func (bc *BookController) LendBook(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var loan models.Loan
+	if err := c.ShouldBindJSON(&loan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	book, err := bc.bookService.LendBook(c.Request.Context(), c.Param("id"), claims.UserID, &loan)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+func (bc *BookController) ReturnBook(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	book, err := bc.bookService.ReturnBook(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, book); err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+func (bc *BookController) ListLoans(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loans, err := bc.bookService.GetOutstandingLoans(c.Request.Context(), claims.UserID, filter.Overdue)
+	if err != nil {
+		bc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loans)
+}
@@ -2,12 +2,16 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	"tranquil-pages/auth"
 	"tranquil-pages/database"
 	"tranquil-pages/models"
@@ -17,9 +21,21 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// newTestBookService wires a BookService and its dependencies against the test database
+func newTestBookService(testDB *database.TestDatabase) *services.BookService {
+	permissions := services.NewPermissionEvaluator(repository.NewGroupRepository(testDB.Database))
+	return services.NewBookService(
+		repository.NewBookRepository(testDB.Database),
+		repository.NewActivityRepository(testDB.Database),
+		repository.NewHighlightRepository(testDB.Database),
+		permissions,
+	)
+}
+
 func getTestDependencies() (*gin.Engine, *database.TestDatabase) {
 	router := gin.Default()
 
@@ -29,9 +45,7 @@ func getTestDependencies() (*gin.Engine, *database.TestDatabase) {
 	}
 
 	// Setup book controller
-	bookRepo := repository.NewBookRepository(testDB.Database)
-	bookService := services.NewBookService(bookRepo)
-	bookController := NewBookController(bookService)
+	bookController := NewBookController(newTestBookService(testDB))
 
 	// Create api group with test middleware that sets auth claims
 	api := router.Group("/")
@@ -55,7 +69,7 @@ func makeRandomBook() *models.Book {
 		Author:  "Author " + test_utils.RandomString(12),
 		Title:   "Title " + test_utils.RandomString(20),
 		Comment: "Comment " + test_utils.RandomString(20),
-		Rating:  rand.Intn(6),
+		Rating:  float64(rand.Intn(6)),
 	}
 }
 
@@ -226,3 +240,560 @@ func TestBookController_CanDeleteNonExistentBook(t *testing.T) {
 	// Then
 	assert.Equal(t, http.StatusNoContent, w.Code)
 }
+
+func TestBookController_CancelledRequestIsNotADatabaseError(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	createdBook := createBookViaApi(router, makeRandomBook())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("/books/%s", createdBook.ID.Hex()), nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, 499, w.Code)
+	assert.JSONEq(t, `{"error":"The request was cancelled"}`, w.Body.String())
+}
+
+func TestBookController_RequestOutOfTimeIsUnavailable(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	createdBook := createBookViaApi(router, makeRandomBook())
+
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now())
+	defer cancel()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("/books/%s", createdBook.ID.Hex()), nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"The request took too long"}`, w.Body.String())
+
+	// And Then the book is still there
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/books/%s", createdBook.ID.Hex()), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBookController_CanUpdateExistingBook(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	createdBook := createBookViaApi(router, makeRandomBook())
+
+	update := makeRandomBook()
+	update.Visibility = models.BookVisibilityPrivate
+
+	// When
+	w := httptest.NewRecorder()
+	updateJson, _ := json.Marshal(update)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/books/%s", createdBook.ID.Hex()), bytes.NewReader(updateJson))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	// Then
+	var updatedBook *models.Book
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &updatedBook)
+	assert.Equal(t, createdBook.ID, updatedBook.ID)
+	assert.True(t, models.CompareBooks(update, updatedBook))
+}
+
+func TestBookController_UpdateRejectsInvalidRating(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	createdBook := createBookViaApi(router, makeRandomBook())
+
+	update := makeRandomBook()
+	update.Rating = 9
+
+	// When
+	w := httptest.NewRecorder()
+	updateJson, _ := json.Marshal(update)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/books/%s", createdBook.ID.Hex()), bytes.NewReader(updateJson))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func lendBook(router *gin.Engine, userID string, bookID primitive.ObjectID, lentAt, dueAt time.Time) *httptest.ResponseRecorder {
+	return requestAs(router, userID, "POST", fmt.Sprintf("/books/%s/loan", bookID.Hex()), gin.H{
+		"borrower_name": "Carol",
+		"lent_at":       lentAt.Format(time.RFC3339),
+		"due_at":        dueAt.Format(time.RFC3339),
+	})
+}
+
+func TestBookController_LendAndReturnBook(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	now := time.Now()
+
+	// When
+	w := lendBook(router, "alice", book.ID, now, now.Add(14*24*time.Hour))
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusConflict, lendBook(router, "alice", book.ID, now, now.Add(time.Hour)).Code)
+
+	// When
+	w = requestAs(router, "alice", "POST", fmt.Sprintf("/books/%s/return", book.ID.Hex()), nil)
+
+	// Then
+	var returned models.Book
+	_ = json.Unmarshal(w.Body.Bytes(), &returned)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, returned.CurrentLoan)
+	assert.Equal(t, 1, len(returned.LoanHistory))
+	assert.Equal(t, "Carol", returned.LoanHistory[0].BorrowerName)
+	assert.NotNil(t, returned.LoanHistory[0].ReturnedAt)
+	assert.Equal(t, http.StatusConflict, requestAs(router, "alice", "POST", fmt.Sprintf("/books/%s/return", book.ID.Hex()), nil).Code)
+}
+
+func TestBookController_ConcurrentLendsOnlyOneSucceeds(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	skipOnFerretDB(t, testDB, "doesn't make concurrent conditional updates atomic")
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	now := time.Now()
+
+	// When
+	codes := make(chan int, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- lendBook(router, "alice", book.ID, now, now.Add(time.Hour)).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	// Then
+	succeeded := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestBookController_LendRejectsInvalidLoan(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	now := time.Now()
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, lendBook(router, "alice", book.ID, now, now.Add(-time.Hour)).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "POST", fmt.Sprintf("/books/%s/loan", book.ID.Hex()), gin.H{"due_at": now.Add(time.Hour).Format(time.RFC3339)}).Code)
+	assert.Equal(t, http.StatusNotFound, lendBook(router, "bob", book.ID, now, now.Add(time.Hour)).Code)
+}
+
+func TestBookController_FiltersOverdueLoans(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleEditor)
+	overdue := createBookViaApiAs(router, "alice", makeRandomBook())
+	dueLater := createBookViaApiAs(router, "alice", makeRandomBook())
+	groupBook, _ := createGroupBook(router, "bob", group.ID)
+	createBookViaApiAs(router, "alice", makeRandomBook())
+	now := time.Now()
+
+	lendBook(router, "alice", overdue.ID, now.Add(-30*24*time.Hour), now.Add(-24*time.Hour))
+	lendBook(router, "alice", dueLater.ID, now, now.Add(24*time.Hour))
+	lendBook(router, "bob", groupBook.ID, now.Add(-30*24*time.Hour), now.Add(-time.Hour))
+
+	// When
+	var books []models.Book
+	w := requestAs(router, "alice", "GET", "/books?overdue=true", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &books)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(books))
+	assert.Equal(t, overdue.ID, books[0].ID)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/books?overdue=maybe", nil).Code)
+
+	// When
+	var loans []models.OutstandingLoan
+	w = requestAs(router, "alice", "GET", "/loans", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &loans)
+
+	// Then loans across personal and group books come back ordered by due date
+	assert.Equal(t, 3, len(loans))
+	assert.Equal(t, overdue.ID, loans[0].BookID)
+	assert.Equal(t, groupBook.ID, loans[1].BookID)
+	assert.Equal(t, dueLater.ID, loans[2].BookID)
+
+	// When
+	w = requestAs(router, "alice", "GET", "/loans?overdue=true", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &loans)
+
+	// Then
+	assert.Equal(t, 2, len(loans))
+	assert.True(t, loans[0].Overdue)
+	assert.True(t, loans[1].Overdue)
+}
+
+func createBookViaApiAs(router *gin.Engine, userID string, book *models.Book) *models.Book {
+	var created *models.Book
+	_ = json.Unmarshal(requestAs(router, userID, "POST", "/books", book).Body.Bytes(), &created)
+	return created
+}
+
+// skipOnFerretDB skips a test when the test database is FerretDB, which can stand in for MongoDB on a
+// development machine but doesn't make conditional updates atomic or support conditions in $pull
+func skipOnFerretDB(t *testing.T, testDB *database.TestDatabase, reason string) {
+	var info bson.M
+	command := bson.D{{Key: "buildInfo", Value: 1}}
+	if err := testDB.GetCollection("books").Database().Client().Database("admin").RunCommand(t.Context(), command).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if _, isFerretDB := info["ferretdbVersion"]; isFerretDB {
+		t.Skip("FerretDB " + reason)
+	}
+}
+
+func TestBookController_ValidatesEditionAgainstFormat(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+
+	tests := []struct {
+		name     string
+		format   models.BookFormat
+		edition  *models.Edition
+		expected int
+	}{
+		{"hardcover with pages", models.FormatHardcover, &models.Edition{Publisher: " Ace ", Year: 1969, PageCount: 304}, http.StatusOK},
+		{"audiobook with duration", models.FormatAudiobook, &models.Edition{DurationMinutes: 522}, http.StatusOK},
+		{"audiobook with pages", models.FormatAudiobook, &models.Edition{PageCount: 304}, http.StatusBadRequest},
+		{"ebook with duration", models.FormatEbook, &models.Edition{DurationMinutes: 522}, http.StatusBadRequest},
+		{"negative year", models.FormatPaperback, &models.Edition{Year: -1}, http.StatusBadRequest},
+		{"unknown format", "scroll", nil, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		book := makeRandomBook()
+		book.Format = test.format
+		book.Edition = test.edition
+
+		// When
+		w := requestAs(router, "test-user-id", "POST", "/books", book)
+
+		// Then
+		assert.Equal(t, test.expected, w.Code, test.name)
+	}
+}
+
+func TestBookController_TracksProgressInPagesOrMinutes(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+
+	paper := makeRandomBook()
+	paper.Format = models.FormatPaperback
+	paper.Edition = &models.Edition{PageCount: 300}
+	paper = createBookViaApi(router, paper)
+
+	audio := makeRandomBook()
+	audio.Format = models.FormatAudiobook
+	audio.Edition = &models.Edition{DurationMinutes: 600}
+	audio = createBookViaApi(router, audio)
+
+	tests := []struct {
+		name     string
+		book     *models.Book
+		progress gin.H
+		expected int
+	}{
+		{"page of a paperback", paper, gin.H{"page": 120}, http.StatusOK},
+		{"minutes of an audiobook", audio, gin.H{"minutes": 95}, http.StatusOK},
+		{"minutes of a paperback", paper, gin.H{"minutes": 95}, http.StatusBadRequest},
+		{"page of an audiobook", audio, gin.H{"page": 120}, http.StatusBadRequest},
+		{"past the last page", paper, gin.H{"page": 301}, http.StatusBadRequest},
+		{"negative", audio, gin.H{"minutes": -5}, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, "test-user-id", "PUT", fmt.Sprintf("/books/%s/progress", test.book.ID.Hex()), test.progress)
+
+		// Then
+		assert.Equal(t, test.expected, w.Code, test.name)
+	}
+
+	var stored models.Book
+	_ = json.Unmarshal(requestAs(router, "test-user-id", "GET", "/books/"+audio.ID.Hex(), nil).Body.Bytes(), &stored)
+	assert.Equal(t, 95, stored.Progress.Minutes)
+	assert.Equal(t, 0, stored.Progress.Page)
+}
+
+func TestBookController_ChangingBetweenAudioAndPrintResetsProgress(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Format = models.FormatHardcover
+	book = createBookViaApi(router, book)
+	path := fmt.Sprintf("/books/%s/progress", book.ID.Hex())
+	assert.Equal(t, http.StatusOK, requestAs(router, "test-user-id", "PUT", path, gin.H{"page": 42}).Code)
+
+	// When switching to another paper format
+	book.Format = models.FormatPaperback
+	var updated models.Book
+	_ = json.Unmarshal(requestAs(router, "test-user-id", "PUT", "/books/"+book.ID.Hex(), book).Body.Bytes(), &updated)
+
+	// Then
+	assert.Equal(t, 42, updated.Progress.Page)
+
+	// When switching to audio
+	book.Format = models.FormatAudiobook
+	updated = models.Book{}
+	_ = json.Unmarshal(requestAs(router, "test-user-id", "PUT", "/books/"+book.ID.Hex(), book).Body.Bytes(), &updated)
+
+	// Then
+	assert.Nil(t, updated.Progress)
+}
+
+func TestBookController_FiltersByFormat(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	for _, format := range []models.BookFormat{models.FormatHardcover, models.FormatAudiobook, models.FormatEbook, ""} {
+		book := makeRandomBook()
+		book.Format = format
+		createBookViaApi(router, book)
+	}
+
+	tests := []struct {
+		query    string
+		expected []models.BookFormat
+	}{
+		{"?format=audiobook", []models.BookFormat{models.FormatAudiobook}},
+		{"?format=hardcover,ebook", []models.BookFormat{models.FormatHardcover, models.FormatEbook}},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, "test-user-id", "GET", "/books"+test.query, nil)
+
+		// Then
+		assert.Equal(t, http.StatusOK, w.Code)
+		var books []models.Book
+		_ = json.Unmarshal(w.Body.Bytes(), &books)
+		var formats []models.BookFormat
+		for _, book := range books {
+			formats = append(formats, book.Format)
+		}
+		assert.ElementsMatch(t, test.expected, formats, test.query)
+	}
+
+	// When
+	w := requestAs(router, "test-user-id", "GET", "/books?format=vinyl", nil)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func dateTime(year int, month time.Month, day int) *primitive.DateTime {
+	value := primitive.NewDateTimeFromTime(time.Date(year, month, day, 12, 0, 0, 0, time.UTC))
+	return &value
+}
+
+func listReads(router *gin.Engine, bookID primitive.ObjectID) []models.Read {
+	var reads []models.Read
+	_ = json.Unmarshal(requestAs(router, "test-user-id", "GET", fmt.Sprintf("/books/%s/reads", bookID.Hex()), nil).Body.Bytes(), &reads)
+	return reads
+}
+
+func TestBookController_RereadingKeepsEarlierReads(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Status = models.StatusFinished
+	book.Rating = 4
+	book.Comment = "Loved it"
+	book = createBookViaApi(router, book)
+	path := "/books/" + book.ID.Hex()
+
+	// When
+	book.Status = models.StatusReading
+	assert.Equal(t, http.StatusOK, requestAs(router, "test-user-id", "PUT", path, book).Code)
+	book.Status = models.StatusFinished
+	book.Rating = 5
+	book.Comment = "Even better the second time"
+	assert.Equal(t, http.StatusOK, requestAs(router, "test-user-id", "PUT", path, book).Code)
+
+	// Then
+	reads := listReads(router, book.ID)
+	assert.Len(t, reads, 2)
+	assert.Equal(t, 4.0, reads[0].Rating)
+	assert.Equal(t, "Loved it", reads[0].Comment)
+	assert.Nil(t, reads[0].StartedAt)
+	assert.Equal(t, 5.0, reads[1].Rating)
+	assert.Equal(t, "Even better the second time", reads[1].Comment)
+	assert.NotNil(t, reads[1].StartedAt)
+	assert.NotNil(t, reads[1].FinishedAt)
+}
+
+func TestBookController_ManagesReads(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApi(router, makeRandomBook())
+	path := fmt.Sprintf("/books/%s/reads", book.ID.Hex())
+
+	// When
+	var read models.Read
+	w := requestAs(router, "test-user-id", "POST", path, models.Read{StartedAt: dateTime(2015, 3, 1), FinishedAt: dateTime(2015, 3, 20), Rating: 3})
+	_ = json.Unmarshal(w.Body.Bytes(), &read)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, read.ID.IsZero())
+
+	// When
+	read.Comment = "Slow start"
+	w = requestAs(router, "test-user-id", "PUT", fmt.Sprintf("%s/%s", path, read.ID.Hex()), read)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Slow start", listReads(router, book.ID)[0].Comment)
+}
+
+func TestBookController_DeletesReads(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	skipOnFerretDB(t, testDB, "doesn't support conditions in $pull")
+	book := createBookViaApi(router, makeRandomBook())
+	path := fmt.Sprintf("/books/%s/reads", book.ID.Hex())
+	var read models.Read
+	_ = json.Unmarshal(requestAs(router, "test-user-id", "POST", path, models.Read{FinishedAt: dateTime(2015, 3, 20)}).Body.Bytes(), &read)
+
+	// When
+	w := requestAs(router, "test-user-id", "DELETE", fmt.Sprintf("%s/%s", path, read.ID.Hex()), nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, listReads(router, book.ID))
+}
+
+func TestBookController_RejectsInvalidReads(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApi(router, makeRandomBook())
+	path := fmt.Sprintf("/books/%s/reads", book.ID.Hex())
+	assert.Equal(t, http.StatusOK, requestAs(router, "test-user-id", "POST", path, models.Read{StartedAt: dateTime(2024, 1, 1)}).Code)
+
+	tests := []struct {
+		name     string
+		read     models.Read
+		expected int
+	}{
+		{"no dates", models.Read{Rating: 3}, http.StatusBadRequest},
+		{"finished before started", models.Read{StartedAt: dateTime(2020, 5, 1), FinishedAt: dateTime(2020, 4, 1)}, http.StatusBadRequest},
+		{"rating out of range", models.Read{FinishedAt: dateTime(2020, 4, 1), Rating: 6}, http.StatusBadRequest},
+		{"second read in progress", models.Read{StartedAt: dateTime(2024, 2, 1)}, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, "test-user-id", "POST", path, test.read)
+
+		// Then
+		assert.Equal(t, test.expected, w.Code, test.name)
+	}
+}
+
+func TestBookController_YearlyCountsEveryFinishedRead(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	favourite := createBookViaApi(router, makeRandomBook())
+	other := createBookViaApi(router, makeRandomBook())
+	reads := []struct {
+		book *models.Book
+		read models.Read
+	}{
+		{favourite, models.Read{FinishedAt: dateTime(2019, 2, 1)}},
+		{favourite, models.Read{FinishedAt: dateTime(2019, 11, 30)}},
+		{favourite, models.Read{StartedAt: dateTime(2021, 6, 1), FinishedAt: dateTime(2021, 7, 1)}},
+		{other, models.Read{FinishedAt: dateTime(2021, 1, 5)}},
+		{other, models.Read{StartedAt: dateTime(2022, 1, 5)}},
+	}
+	for _, r := range reads {
+		assert.Equal(t, http.StatusOK, requestAs(router, "test-user-id", "POST", fmt.Sprintf("/books/%s/reads", r.book.ID.Hex()), r.read).Code)
+	}
+
+	// When
+	w := requestAs(router, "test-user-id", "GET", "/reads/yearly", nil)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	var yearly []models.YearlyReads
+	_ = json.Unmarshal(w.Body.Bytes(), &yearly)
+	assert.Equal(t, []models.YearlyReads{{Year: 2021, Finished: 2}, {Year: 2019, Finished: 2}}, yearly)
+}
+
+func TestBookController_RendersReviewWithSpoilers(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Review = "## Verdict\n\nLoved it <script>alert(1)</script>\n\n:::spoiler Ending\nThe butler did it.\n:::\n"
+	created := createBookViaApi(router, book)
+
+	// When
+	var fetched models.Book
+	w := requestAs(router, "test-user-id", "GET", "/books/"+created.ID.Hex(), nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &fetched)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, book.Review, fetched.Review)
+	assert.Contains(t, fetched.ReviewHTML, "<h2>Verdict</h2>")
+	assert.Contains(t, fetched.ReviewHTML, `<details class="spoiler"><summary>Ending</summary>`)
+	assert.Contains(t, fetched.ReviewHTML, "The butler did it.")
+	assert.NotContains(t, fetched.ReviewHTML, "<script")
+}
+
+func TestBookController_RejectsOverlongReview(t *testing.T) {
+	// Given
+	router, testDB := getTestDependencies()
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Review = strings.Repeat("a", 50001)
+
+	// When
+	w := requestAs(router, "test-user-id", "POST", "/books", book)
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
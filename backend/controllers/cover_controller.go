@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// coverCacheControl lets browsers keep covers for a day. Covers are only served to signed in
+// users, so shared caches must not store them.
+const coverCacheControl = "private, max-age=86400"
+
+type CoverController struct {
+	coverService *services.CoverService
+}
+
+func NewCoverController(coverService *services.CoverService) *CoverController {
+	return &CoverController{coverService: coverService}
+}
+
+func (cc *CoverController) SetupCoverRoutes(router *gin.RouterGroup) {
+	router.POST("/books/:id/cover", cc.UploadCover)
+	router.DELETE("/books/:id/cover", cc.DeleteCover)
+	router.GET("/books/:id/cover", cc.GetCover)
+}
+
+func (cc *CoverController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Cover images must not be larger than %d MB", services.MaxCoverSize>>20)})
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cover not found"})
+	case errors.Is(err, appErrors.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidCover),
+		errors.Is(err, appErrors.ErrInvalidCoverSize),
+		errors.Is(err, appErrors.ErrInvalidImportFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (cc *CoverController) UploadCover(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	file, err := openUpload(c, services.MaxCoverSize)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+
+	book, err := cc.coverService.UploadCover(c.Request.Context(), c.Param("id"), claims.UserID, data)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBooks(c, book); err != nil {
+		cc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, book)
+}
+
+func (cc *CoverController) DeleteCover(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := cc.coverService.DeleteCover(c.Request.Context(), c.Param("id"), claims.UserID); err != nil {
+		cc.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCover serves the cover in the size given by the size query parameter: original, small or medium
+func (cc *CoverController) GetCover(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	size := c.Query("size")
+	if size == "" {
+		size = services.CoverOriginal
+	}
+
+	file, cover, contentType, err := cc.coverService.GetCover(c.Request.Context(), c.Param("id"), claims.UserID, size)
+	if err != nil {
+		cc.handleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	// The cover ID changes with every upload, so it identifies the image contents
+	etag := fmt.Sprintf(`"%s-%s"`, cover.ID, size)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", coverCacheControl)
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
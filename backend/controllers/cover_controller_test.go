@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/blobstore"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// tinyWebP is a 1x1 lossless WebP image
+const tinyWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func getCoverTestDependencies(t *testing.T) (*gin.Engine, string, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	blobRoot := t.TempDir()
+	groupRepo := repository.NewGroupRepository(testDB.Database)
+	bookService := newTestBookService(testDB)
+	groupService := services.NewGroupService(groupRepo, services.NewPermissionEvaluator(groupRepo))
+	coverService := services.NewCoverService(bookService, blobstore.NewLocalBlobStore(blobRoot))
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewGroupController(groupService, bookService).SetupGroupRoutes(api)
+	NewCoverController(coverService).SetupCoverRoutes(api)
+
+	return router, blobRoot, testDB
+}
+
+func makePNG(width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x*height/width, color.NRGBA{R: 200, A: 255})
+	}
+	var buffer bytes.Buffer
+	_ = png.Encode(&buffer, img)
+	return buffer.Bytes()
+}
+
+func uploadCover(router *gin.Engine, userID string, book *models.Book, data []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/books/%s/cover", book.ID.Hex()), bytes.NewReader(data))
+	// The declared type is ignored in favour of the content
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Test-User", userID)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func countFiles(root string) int {
+	count := 0
+	_ = filepath.Walk(root, func(_ string, info os.FileInfo, _ error) error {
+		if info != nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestCoverController_UploadsCoverWithThumbnails(t *testing.T) {
+	// Given
+	router, blobRoot, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	original := makePNG(600, 900)
+
+	// When
+	w := uploadCover(router, "alice", book, original)
+
+	// Then
+	var updated models.Book
+	_ = json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", updated.Cover.ContentType)
+	assert.Equal(t, 600, updated.Cover.Width)
+	assert.Equal(t, 900, updated.Cover.Height)
+
+	w = requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/cover", book.ID.Hex()), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, original, w.Body.Bytes())
+
+	w = requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/cover?size=small", book.ID.Hex()), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+	thumbnail, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 160, thumbnail.Width)
+	assert.Equal(t, 240, thumbnail.Height)
+	assert.Equal(t, 3, countFiles(blobRoot))
+
+	// And the book itself still shows the cover after an update
+	requestAs(router, "alice", "PUT", "/books/"+book.ID.Hex(), book)
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books/"+book.ID.Hex(), nil).Body.Bytes(), &updated)
+	assert.NotNil(t, updated.Cover)
+}
+
+func TestCoverController_CoversAreOnlySetByUpload(t *testing.T) {
+	// Given
+	router, _, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Cover = &models.BookCover{ContentType: "image/png", Width: 600, Height: 900}
+
+	// When
+	created := createBookViaApiAs(router, "alice", book)
+
+	// Then
+	assert.Nil(t, created.Cover)
+	w := requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/cover", created.ID.Hex()), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCoverController_ServesCoversWithCacheHeaders(t *testing.T) {
+	// Given
+	router, _, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	uploadCover(router, "alice", book, makePNG(100, 150))
+	path := fmt.Sprintf("/books/%s/cover?size=medium", book.ID.Hex())
+
+	// When
+	w := requestAs(router, "alice", "GET", path, nil)
+
+	// Then
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "private, max-age=86400", w.Header().Get("Cache-Control"))
+
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("X-Test-User", "alice")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.Bytes())
+
+	// And covers are only served to users who can see the book
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "GET", path, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/cover?size=huge", book.ID.Hex()), nil).Code)
+}
+
+func TestCoverController_ValidatesUploads(t *testing.T) {
+	// Given
+	router, blobRoot, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+	truncated := makePNG(100, 100)[:60]
+
+	// Then
+	assert.Equal(t, http.StatusOK, uploadCover(router, "alice", book, webp).Code)
+	assert.Equal(t, http.StatusBadRequest, uploadCover(router, "alice", book, []byte("GIF89a not really a cover")).Code)
+	assert.Equal(t, http.StatusBadRequest, uploadCover(router, "alice", book, []byte("<svg xmlns='http://www.w3.org/2000/svg'/>")).Code)
+	assert.Equal(t, http.StatusBadRequest, uploadCover(router, "alice", book, truncated).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, uploadCover(router, "alice", book, make([]byte, services.MaxCoverSize+1)).Code)
+	assert.Equal(t, http.StatusNotFound, uploadCover(router, "bob", book, webp).Code)
+	assert.Equal(t, 3, countFiles(blobRoot))
+}
+
+func TestCoverController_ReplacingAndDeletingRemovesFiles(t *testing.T) {
+	// Given
+	router, blobRoot, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	uploadCover(router, "alice", book, makePNG(100, 150))
+
+	// When
+	uploadCover(router, "alice", book, makePNG(200, 300))
+
+	// Then
+	assert.Equal(t, 3, countFiles(blobRoot))
+
+	// When
+	w := requestAs(router, "alice", "DELETE", fmt.Sprintf("/books/%s/cover", book.ID.Hex()), nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, 0, countFiles(blobRoot))
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/cover", book.ID.Hex()), nil).Code)
+
+	// When the book is deleted
+	uploadCover(router, "alice", book, makePNG(100, 150))
+	requestAs(router, "alice", "DELETE", "/books/"+book.ID.Hex(), nil)
+
+	// Then its cover goes with it
+	assert.Equal(t, 0, countFiles(blobRoot))
+}
+
+func TestCoverController_GroupViewersCannotChangeCovers(t *testing.T) {
+	// Given
+	router, _, testDB := getCoverTestDependencies(t)
+	defer testDB.Close()
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleViewer)
+	book, _ := createGroupBook(router, "alice", group.ID)
+	uploadCover(router, "alice", book, makePNG(100, 150))
+
+	// Then
+	assert.Equal(t, http.StatusForbidden, uploadCover(router, "bob", book, makePNG(100, 150)).Code)
+	assert.Equal(t, http.StatusForbidden, requestAs(router, "bob", "DELETE", fmt.Sprintf("/books/%s/cover", book.ID.Hex()), nil).Code)
+	assert.Equal(t, http.StatusOK, requestAs(router, "bob", "GET", fmt.Sprintf("/books/%s/cover?size=small", book.ID.Hex()), nil).Code)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedController struct {
+	feedService *services.FeedService
+}
+
+func NewFeedController(feedService *services.FeedService) *FeedController {
+	return &FeedController{feedService: feedService}
+}
+
+func (fc *FeedController) SetupFeedRoutes(router *gin.RouterGroup) {
+	router.GET("/following", fc.ListFollowing)
+	router.POST("/following/:handle", fc.Follow)
+	router.DELETE("/following/:handle", fc.Unfollow)
+	router.GET("/feed", fc.GetFeed)
+}
+
+func (fc *FeedController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, appErrors.ErrFollowSelf), errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (fc *FeedController) ListFollowing(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	actors, err := fc.feedService.GetFollowing(c.Request.Context(), claims.UserID)
+	if err != nil {
+		fc.handleError(c, err)
+		return
+	}
+
+	if actors == nil {
+		actors = []models.Actor{}
+	}
+
+	c.JSON(http.StatusOK, actors)
+}
+
+func (fc *FeedController) Follow(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := fc.feedService.Follow(c.Request.Context(), claims.UserID, strings.ToLower(c.Param("handle"))); err != nil {
+		fc.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (fc *FeedController) Unfollow(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := fc.feedService.Unfollow(c.Request.Context(), claims.UserID, strings.ToLower(c.Param("handle"))); err != nil {
+		fc.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (fc *FeedController) GetFeed(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spoilers, err := parseSpoilers(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	feed, err := fc.feedService.GetFeed(c.Request.Context(), claims.UserID, c.Query("before"), limit, spoilers)
+	if err != nil {
+		fc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// getFeedTestDependencies sets up a router where the X-Test-User header selects the authenticated user
+func getFeedTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookRepo := repository.NewBookRepository(testDB.Database)
+	profileRepo := repository.NewProfileRepository(testDB.Database)
+	activityRepo := repository.NewActivityRepository(testDB.Database)
+	followRepo := repository.NewFollowRepository(testDB.Database)
+
+	bookController := NewBookController(newTestBookService(testDB))
+	profileController := NewProfileController(services.NewProfileService(profileRepo, bookRepo))
+	feedController := NewFeedController(services.NewFeedService(followRepo, activityRepo, profileRepo, bookRepo))
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	bookController.SetupBookRoutes(api)
+	profileController.SetupProfileRoutes(api)
+	feedController.SetupFeedRoutes(api)
+
+	return router, testDB
+}
+
+func requestAs(router *gin.Engine, userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func getFeed(router *gin.Engine, userID, query string) *models.Feed {
+	w := requestAs(router, userID, "GET", "/feed"+query, nil)
+
+	var feed *models.Feed
+	_ = json.Unmarshal(w.Body.Bytes(), &feed)
+	return feed
+}
+
+func TestFeedController_ShowsActivityOfFollowedUsers(t *testing.T) {
+	// Given
+	router, testDB := getFeedTestDependencies()
+	defer testDB.Close()
+
+	requestAs(router, "alice", "PUT", "/profile", &models.Profile{Handle: "alice", Visibility: models.VisibilityPublic, ShareRatings: true})
+	assert.Equal(t, http.StatusNoContent, requestAs(router, "bob", "POST", "/following/alice", nil).Code)
+
+	book := makeRandomBook()
+	book.Rating = 0
+	w := requestAs(router, "alice", "POST", "/books", book)
+	var created models.Book
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	created.Status = models.StatusFinished
+	created.Rating = 4
+	requestAs(router, "alice", "PUT", fmt.Sprintf("/books/%s", created.ID.Hex()), &created)
+
+	// When
+	feed := getFeed(router, "bob", "")
+
+	// Then
+	assert.Equal(t, 3, len(feed.Items))
+	assert.Equal(t, models.ActivityRated, feed.Items[0].Type)
+	assert.Equal(t, 4.0, *feed.Items[0].Rating)
+	assert.Equal(t, models.ActivityFinished, feed.Items[1].Type)
+	assert.Equal(t, models.ActivityAdded, feed.Items[2].Type)
+	assert.Equal(t, "alice", feed.Items[2].Actor.Handle)
+	assert.Equal(t, created.Title, feed.Items[2].Book.Title)
+
+	// And the owner's own feed stays empty
+	assert.Equal(t, 0, len(getFeed(router, "alice", "").Items))
+}
+
+func TestFeedController_HidesPrivateBooksAndUnsharedRatings(t *testing.T) {
+	// Given
+	router, testDB := getFeedTestDependencies()
+	defer testDB.Close()
+
+	requestAs(router, "alice", "PUT", "/profile", &models.Profile{Handle: "alice", Visibility: models.VisibilityPublic})
+	requestAs(router, "bob", "POST", "/following/alice", nil)
+
+	privateBook := makeRandomBook()
+	privateBook.Visibility = models.BookVisibilityPrivate
+	requestAs(router, "alice", "POST", "/books", privateBook)
+
+	ratedBook := makeRandomBook()
+	ratedBook.Rating = 5
+	requestAs(router, "alice", "POST", "/books", ratedBook)
+
+	// When
+	feed := getFeed(router, "bob", "")
+
+	// Then
+	assert.Equal(t, 1, len(feed.Items))
+	assert.Equal(t, models.ActivityAdded, feed.Items[0].Type)
+	assert.Equal(t, ratedBook.Title, feed.Items[0].Book.Title)
+	assert.Nil(t, feed.Items[0].Book.Rating)
+}
+
+func TestFeedController_PaginatesWithBeforeCursor(t *testing.T) {
+	// Given
+	router, testDB := getFeedTestDependencies()
+	defer testDB.Close()
+
+	requestAs(router, "alice", "PUT", "/profile", &models.Profile{Handle: "alice", Visibility: models.VisibilityPublic})
+	requestAs(router, "bob", "POST", "/following/alice", nil)
+
+	for i := 0; i < 5; i++ {
+		book := makeRandomBook()
+		book.Rating = 0
+		requestAs(router, "alice", "POST", "/books", book)
+	}
+
+	// When
+	firstPage := getFeed(router, "bob", "?limit=3")
+	secondPage := getFeed(router, "bob", "?limit=3&before="+firstPage.NextBefore)
+
+	// Then
+	assert.Equal(t, 3, len(firstPage.Items))
+	assert.NotEmpty(t, firstPage.NextBefore)
+	assert.Equal(t, 2, len(secondPage.Items))
+	assert.Empty(t, secondPage.NextBefore)
+	assert.True(t, firstPage.Items[2].ID.Timestamp().Unix() >= secondPage.Items[0].ID.Timestamp().Unix())
+	assert.NotEqual(t, firstPage.Items[2].ID, secondPage.Items[0].ID)
+}
+
+func TestFeedController_StopsShowingUnfollowedAndPrivateUsers(t *testing.T) {
+	// Given
+	router, testDB := getFeedTestDependencies()
+	defer testDB.Close()
+
+	requestAs(router, "alice", "PUT", "/profile", &models.Profile{Handle: "alice", Visibility: models.VisibilityPublic})
+	requestAs(router, "carol", "PUT", "/profile", &models.Profile{Handle: "carol", Visibility: models.VisibilityPublic})
+	requestAs(router, "bob", "POST", "/following/alice", nil)
+	requestAs(router, "bob", "POST", "/following/carol", nil)
+	requestAs(router, "alice", "POST", "/books", makeRandomBook())
+	requestAs(router, "carol", "POST", "/books", makeRandomBook())
+
+	// When
+	requestAs(router, "bob", "DELETE", "/following/alice", nil)
+	requestAs(router, "carol", "PUT", "/profile", &models.Profile{Handle: "carol", Visibility: models.VisibilityPrivate})
+
+	// Then
+	assert.Equal(t, 0, len(getFeed(router, "bob", "").Items))
+	assert.Equal(t, "[]", requestAs(router, "bob", "GET", "/following", nil).Body.String())
+}
+
+func TestFeedController_CannotFollowPrivateOrSelf(t *testing.T) {
+	// Given
+	router, testDB := getFeedTestDependencies()
+	defer testDB.Close()
+
+	requestAs(router, "alice", "PUT", "/profile", &models.Profile{Handle: "alice", Visibility: models.VisibilityUnlisted})
+	requestAs(router, "bob", "PUT", "/profile", &models.Profile{Handle: "bob", Visibility: models.VisibilityPublic})
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "POST", "/following/alice", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "bob", "POST", "/following/bob", nil).Code)
+}
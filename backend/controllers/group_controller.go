@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupController struct {
+	groupService *services.GroupService
+	bookService  *services.BookService
+}
+
+func NewGroupController(groupService *services.GroupService, bookService *services.BookService) *GroupController {
+	return &GroupController{groupService: groupService, bookService: bookService}
+}
+
+func (gc *GroupController) SetupGroupRoutes(router *gin.RouterGroup) {
+	router.POST("/groups", gc.CreateGroup)
+	router.GET("/groups", gc.ListGroups)
+	router.GET("/groups/:id", gc.GetGroup)
+	router.GET("/groups/:id/books", gc.ListGroupBooks)
+	router.PUT("/groups/:id/members/:userId", gc.SetMemberRole)
+	router.DELETE("/groups/:id/members/:userId", gc.RemoveMember)
+	router.POST("/groups/:id/invites", gc.CreateInvite)
+	router.GET("/groups/:id/invites", gc.ListInvites)
+	router.DELETE("/groups/:id/invites/:token", gc.RevokeInvite)
+	router.POST("/invites/:token/accept", gc.AcceptInvite)
+}
+
+func (gc *GroupController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+	case errors.Is(err, appErrors.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInviteExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrGroupChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidGroupName),
+		errors.Is(err, appErrors.ErrInvalidGroupRole),
+		errors.Is(err, appErrors.ErrLastOwner):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (gc *GroupController) CreateGroup(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := gc.groupService.CreateGroup(c.Request.Context(), claims.UserID, request.Name)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (gc *GroupController) ListGroups(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	groups, err := gc.groupService.GetGroupsForUser(c.Request.Context(), claims.UserID)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	if groups == nil {
+		groups = []models.Group{}
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func (gc *GroupController) GetGroup(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	group, err := gc.groupService.GetGroup(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (gc *GroupController) ListGroupBooks(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	books, err := gc.bookService.GetBooksByGroupID(c.Request.Context(), c.Param("id"), claims.UserID, filter)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	if books == nil {
+		books = []models.Book{}
+	}
+
+	if err := scaleBookList(c, books); err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
+
+func (gc *GroupController) SetMemberRole(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var request struct {
+		Role models.GroupRole `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := gc.groupService.SetMemberRole(c.Request.Context(), c.Param("id"), claims.UserID, c.Param("userId"), request.Role)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func (gc *GroupController) RemoveMember(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := gc.groupService.RemoveMember(c.Request.Context(), c.Param("id"), claims.UserID, c.Param("userId")); err != nil {
+		gc.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (gc *GroupController) CreateInvite(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var request struct {
+		Role          models.GroupRole `json:"role"`
+		ValidForHours int              `json:"valid_for_hours"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validFor := time.Duration(request.ValidForHours) * time.Hour
+	invite, err := gc.groupService.CreateInvite(c.Request.Context(), c.Param("id"), claims.UserID, request.Role, validFor)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invite)
+}
+
+func (gc *GroupController) ListInvites(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	invites, err := gc.groupService.ListInvites(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func (gc *GroupController) RevokeInvite(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := gc.groupService.RevokeInvite(c.Request.Context(), c.Param("id"), claims.UserID, c.Param("token")); err != nil {
+		gc.handleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (gc *GroupController) AcceptInvite(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	group, err := gc.groupService.AcceptInvite(c.Request.Context(), c.Param("token"), claims.UserID)
+	if err != nil {
+		gc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getGroupTestDependencies() (*gin.Engine, repository.GroupRepository, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	groupRepo := repository.NewGroupRepository(testDB.Database)
+	setupGroupTestRoutes(router, testDB, groupRepo)
+
+	return router, groupRepo, testDB
+}
+
+// setupGroupTestRoutes serves the book and group routes, keeping groups in groupRepo
+func setupGroupTestRoutes(router *gin.Engine, testDB *database.TestDatabase, groupRepo repository.GroupRepository) {
+	bookService := newTestBookService(testDB)
+	groupService := services.NewGroupService(groupRepo, services.NewPermissionEvaluator(groupRepo))
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewGroupController(groupService, bookService).SetupGroupRoutes(api)
+}
+
+// createGroupWithMember creates a group owned by owner and adds member with the given role through an invite
+func createGroupWithMember(t *testing.T, router *gin.Engine, owner, member string, role models.GroupRole) *models.Group {
+	var group models.Group
+	w := requestAs(router, owner, "POST", "/groups", gin.H{"name": "Office library"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &group)
+
+	var invite models.GroupInvite
+	w = requestAs(router, owner, "POST", fmt.Sprintf("/groups/%s/invites", group.ID.Hex()), gin.H{"role": role})
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &invite)
+
+	w = requestAs(router, member, "POST", fmt.Sprintf("/invites/%s/accept", invite.Token), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &group)
+	return &group
+}
+
+func createGroupBook(router *gin.Engine, userID string, groupID primitive.ObjectID) (*models.Book, int) {
+	book := makeRandomBook()
+	book.GroupID = &groupID
+
+	w := requestAs(router, userID, "POST", "/books", book)
+	var created *models.Book
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	return created, w.Code
+}
+
+func TestGroupController_EditorsShareBooksWithMembersOnly(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleEditor)
+
+	// When
+	book, code := createGroupBook(router, "bob", group.ID)
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, len(group.Members))
+
+	var groupBooks []models.Book
+	w := requestAs(router, "alice", "GET", fmt.Sprintf("/groups/%s/books", group.ID.Hex()), nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &groupBooks)
+	assert.Equal(t, 1, len(groupBooks))
+	assert.Equal(t, http.StatusOK, requestAs(router, "alice", "GET", "/books/"+book.ID.Hex(), nil).Code)
+
+	// And the book is neither in the personal library nor visible to outsiders
+	assert.Equal(t, "[]", requestAs(router, "bob", "GET", "/books", nil).Body.String())
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "carol", "GET", "/books/"+book.ID.Hex(), nil).Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "carol", "GET", fmt.Sprintf("/groups/%s/books", group.ID.Hex()), nil).Code)
+}
+
+func TestGroupController_ViewersCannotChangeBooks(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleViewer)
+	book, _ := createGroupBook(router, "alice", group.ID)
+
+	// Then
+	_, code := createGroupBook(router, "bob", group.ID)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, http.StatusOK, requestAs(router, "bob", "GET", "/books/"+book.ID.Hex(), nil).Code)
+	assert.Equal(t, http.StatusForbidden, requestAs(router, "bob", "PUT", "/books/"+book.ID.Hex(), book).Code)
+	assert.Equal(t, http.StatusForbidden, requestAs(router, "bob", "DELETE", "/books/"+book.ID.Hex(), nil).Code)
+}
+
+func TestGroupController_OwnersManageMembers(t *testing.T) {
+	// Given
+	router, _, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleViewer)
+	membersPath := fmt.Sprintf("/groups/%s/members", group.ID.Hex())
+
+	// Then
+	assert.Equal(t, http.StatusForbidden, requestAs(router, "bob", "PUT", membersPath+"/bob", gin.H{"role": "owner"}).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "DELETE", membersPath+"/alice", nil).Code)
+
+	w := requestAs(router, "alice", "PUT", membersPath+"/bob", gin.H{"role": "owner"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusNoContent, requestAs(router, "alice", "DELETE", membersPath+"/alice", nil).Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", "/groups/"+group.ID.Hex(), nil).Code)
+}
+
+func TestGroupController_ExpiredAndRevokedInvitesCannotBeUsed(t *testing.T) {
+	// Given
+	router, groupRepo, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+
+	var group models.Group
+	w := requestAs(router, "alice", "POST", "/groups", gin.H{"name": "Book club"})
+	_ = json.Unmarshal(w.Body.Bytes(), &group)
+	invitesPath := fmt.Sprintf("/groups/%s/invites", group.ID.Hex())
+
+	var revoked, expired models.GroupInvite
+	_ = json.Unmarshal(requestAs(router, "alice", "POST", invitesPath, gin.H{"role": "viewer"}).Body.Bytes(), &revoked)
+	_ = json.Unmarshal(requestAs(router, "alice", "POST", invitesPath, gin.H{"role": "viewer"}).Body.Bytes(), &expired)
+
+	// When
+	assert.Equal(t, http.StatusNoContent, requestAs(router, "alice", "DELETE", invitesPath+"/"+revoked.Token, nil).Code)
+
+	stored, _ := groupRepo.FindByID(t.Context(), group.ID.Hex())
+	for i := range stored.Invites {
+		if stored.Invites[i].Token == expired.Token {
+			stored.Invites[i].ExpiresAt = primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute))
+		}
+	}
+	assert.NoError(t, groupRepo.Update(t.Context(), stored))
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "POST", "/invites/"+revoked.Token+"/accept", nil).Code)
+	assert.Equal(t, http.StatusGone, requestAs(router, "bob", "POST", "/invites/"+expired.Token+"/accept", nil).Code)
+	assert.Equal(t, "[]", requestAs(router, "alice", "GET", invitesPath, nil).Body.String())
+}
+
+func TestGroupController_ConcurrentJoinsAreAllKept(t *testing.T) {
+	t.Run("mongo", func(t *testing.T) {
+		router, groupRepo, testDB := getGroupTestDependencies()
+		defer testDB.Close()
+		skipOnFerretDB(t, testDB, "doesn't make concurrent conditional updates atomic")
+		testConcurrentJoins(t, router, groupRepo)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		testDB, err := database.NewTestDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+		router := gin.Default()
+		groupRepo := repository.NewMemoryGroupRepository()
+		setupGroupTestRoutes(router, testDB, groupRepo)
+		testConcurrentJoins(t, router, groupRepo)
+	})
+}
+
+func testConcurrentJoins(t *testing.T, router *gin.Engine, groupRepo repository.GroupRepository) {
+	// Given
+	var group models.Group
+	_ = json.Unmarshal(requestAs(router, "alice", "POST", "/groups", gin.H{"name": "Book club"}).Body.Bytes(), &group)
+	var invite models.GroupInvite
+	_ = json.Unmarshal(requestAs(router, "alice", "POST", fmt.Sprintf("/groups/%s/invites", group.ID.Hex()), gin.H{"role": "viewer"}).Body.Bytes(), &invite)
+
+	// When
+	joiners := []string{"bob", "carol", "dave", "erin"}
+	var wg sync.WaitGroup
+	codes := make([]int, len(joiners))
+	for i, user := range joiners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = requestAs(router, user, "POST", "/invites/"+invite.Token+"/accept", nil).Code
+		}()
+	}
+	wg.Wait()
+
+	// Then
+	for _, code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	stored, err := groupRepo.FindByID(t.Context(), group.ID.Hex())
+	assert.NoError(t, err)
+	for _, user := range joiners {
+		_, isMember := stored.MemberRole(user)
+		assert.True(t, isMember, "%s joined", user)
+	}
+}
+
+func TestGroupRepository_UpdateRejectsStaleGroup(t *testing.T) {
+	// Given
+	router, groupRepo, testDB := getGroupTestDependencies()
+	defer testDB.Close()
+
+	var group models.Group
+	_ = json.Unmarshal(requestAs(router, "alice", "POST", "/groups", gin.H{"name": "Book club"}).Body.Bytes(), &group)
+	first, _ := groupRepo.FindByID(t.Context(), group.ID.Hex())
+	second, _ := groupRepo.FindByID(t.Context(), group.ID.Hex())
+
+	// When
+	first.Name = "Reading circle"
+	firstErr := groupRepo.Update(t.Context(), first)
+	second.Name = "Readers"
+	secondErr := groupRepo.Update(t.Context(), second)
+
+	// Then
+	assert.NoError(t, firstErr)
+	assert.ErrorIs(t, secondErr, appErrors.ErrGroupChanged)
+	stored, _ := groupRepo.FindByID(t.Context(), group.ID.Hex())
+	assert.Equal(t, "Reading circle", stored.Name)
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HighlightController struct {
+	highlightService *services.HighlightService
+}
+
+func NewHighlightController(highlightService *services.HighlightService) *HighlightController {
+	return &HighlightController{highlightService: highlightService}
+}
+
+func (hc *HighlightController) SetupHighlightRoutes(router *gin.RouterGroup) {
+	router.POST("/books/:id/highlights", hc.CreateHighlight)
+	router.GET("/books/:id/highlights", hc.ListHighlights)
+	router.PUT("/books/:id/highlights/:highlightId", hc.UpdateHighlight)
+	router.DELETE("/books/:id/highlights/:highlightId", hc.DeleteHighlight)
+	router.GET("/highlights", hc.SearchHighlights)
+}
+
+func (hc *HighlightController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Highlight not found"})
+	case errors.Is(err, appErrors.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidHighlight),
+		errors.Is(err, appErrors.ErrInvalidColor),
+		errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (hc *HighlightController) CreateHighlight(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var highlight models.Highlight
+	if err := c.ShouldBindJSON(&highlight); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := hc.highlightService.CreateHighlight(c.Request.Context(), c.Param("id"), claims.UserID, &highlight); err != nil {
+		hc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, highlight)
+}
+
+func (hc *HighlightController) ListHighlights(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	highlights, err := hc.highlightService.GetHighlights(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+
+	if highlights == nil {
+		highlights = []models.Highlight{}
+	}
+
+	c.JSON(http.StatusOK, highlights)
+}
+
+func (hc *HighlightController) UpdateHighlight(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Highlight
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	highlight, err := hc.highlightService.UpdateHighlight(c.Request.Context(), c.Param("id"), c.Param("highlightId"), claims.UserID, &update)
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, highlight)
+}
+
+func (hc *HighlightController) DeleteHighlight(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := hc.highlightService.DeleteHighlight(c.Request.Context(), c.Param("id"), c.Param("highlightId"), claims.UserID); err != nil {
+		hc.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SearchHighlights accepts q, book_id, tag and color filters along with offset and limit
+func (hc *HighlightController) SearchHighlights(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := models.HighlightFilter{
+		Query: c.Query("q"),
+		Tag:   c.Query("tag"),
+		Color: models.HighlightColor(c.Query("color")),
+	}
+	if value := c.Query("book_id"); value != "" {
+		bookID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			hc.handleError(c, appErrors.ErrInvalidID)
+			return
+		}
+		filter.BookID = &bookID
+	}
+
+	highlights, err := hc.highlightService.SearchHighlights(c.Request.Context(), claims.UserID, filter, page.Offset, page.Limit)
+	if err != nil {
+		hc.handleError(c, err)
+		return
+	}
+
+	if highlights == nil {
+		highlights = []models.Highlight{}
+	}
+
+	c.JSON(http.StatusOK, highlights)
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getHighlightTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	groupRepo := repository.NewGroupRepository(testDB.Database)
+	bookService := newTestBookService(testDB)
+	groupService := services.NewGroupService(groupRepo, services.NewPermissionEvaluator(groupRepo))
+	highlightService := services.NewHighlightService(repository.NewHighlightRepository(testDB.Database), bookService)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewGroupController(groupService, bookService).SetupGroupRoutes(api)
+	NewHighlightController(highlightService).SetupHighlightRoutes(api)
+
+	return router, testDB
+}
+
+func createHighlight(router *gin.Engine, userID string, bookID primitive.ObjectID, highlight *models.Highlight) (*models.Highlight, int) {
+	w := requestAs(router, userID, "POST", fmt.Sprintf("/books/%s/highlights", bookID.Hex()), highlight)
+	var created *models.Highlight
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	return created, w.Code
+}
+
+func searchHighlights(router *gin.Engine, userID, query string) []models.Highlight {
+	var highlights []models.Highlight
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/highlights"+query, nil).Body.Bytes(), &highlights)
+	return highlights
+}
+
+func TestHighlightController_CanCreateUpdateAndDeleteHighlights(t *testing.T) {
+	// Given
+	router, testDB := getHighlightTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+
+	// When
+	highlight, code := createHighlight(router, "alice", book.ID, &models.Highlight{
+		Text:  "  It was the best of times  ",
+		Page:  1,
+		Color: models.HighlightYellow,
+		Tags:  []string{"Opening", "opening", " "},
+	})
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "It was the best of times", highlight.Text)
+	assert.Equal(t, []string{"opening"}, highlight.Tags)
+	assert.Equal(t, book.ID, highlight.BookID)
+
+	// When
+	path := fmt.Sprintf("/books/%s/highlights/%s", book.ID.Hex(), highlight.ID.Hex())
+	highlight.Note = "Famous opening line"
+	w := requestAs(router, "alice", "PUT", path, highlight)
+
+	// Then
+	var highlights []models.Highlight
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/highlights", book.ID.Hex()), nil).Body.Bytes(), &highlights)
+	assert.Equal(t, 1, len(highlights))
+	assert.Equal(t, "Famous opening line", highlights[0].Note)
+
+	// When
+	w = requestAs(router, "alice", "DELETE", path, nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "[]", requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/highlights", book.ID.Hex()), nil).Body.String())
+}
+
+func TestHighlightController_RejectsInvalidHighlights(t *testing.T) {
+	// Given
+	router, testDB := getHighlightTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+
+	// Then
+	_, code := createHighlight(router, "alice", book.ID, &models.Highlight{Text: " "})
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = createHighlight(router, "alice", book.ID, &models.Highlight{Text: "text", Page: -1})
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = createHighlight(router, "alice", book.ID, &models.Highlight{Text: "text", Color: "mauve"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestHighlightController_AccessFollowsTheBook(t *testing.T) {
+	// Given
+	router, testDB := getHighlightTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	highlight, _ := createHighlight(router, "alice", book.ID, &models.Highlight{Text: "Private thoughts"})
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleViewer)
+	groupBook, _ := createGroupBook(router, "alice", group.ID)
+	createHighlight(router, "alice", groupBook.ID, &models.Highlight{Text: "Shared passage"})
+
+	// Then other users can't see or change highlights on personal books
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "GET", fmt.Sprintf("/books/%s/highlights", book.ID.Hex()), nil).Code)
+	_, code := createHighlight(router, "bob", book.ID, &models.Highlight{Text: "Intrusion"})
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "PUT", fmt.Sprintf("/books/%s/highlights/%s", book.ID.Hex(), highlight.ID.Hex()), highlight).Code)
+
+	// And a highlight can't be reached through a different book
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "PUT", fmt.Sprintf("/books/%s/highlights/%s", groupBook.ID.Hex(), highlight.ID.Hex()), highlight).Code)
+
+	// And group viewers can read but not add highlights
+	assert.Equal(t, http.StatusOK, requestAs(router, "bob", "GET", fmt.Sprintf("/books/%s/highlights", groupBook.ID.Hex()), nil).Code)
+	_, code = createHighlight(router, "bob", groupBook.ID, &models.Highlight{Text: "Viewer passage"})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// And search only covers books the user can view
+	results := searchHighlights(router, "bob", "")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Shared passage", results[0].Text)
+	assert.Equal(t, 2, len(searchHighlights(router, "alice", "")))
+}
+
+func TestHighlightController_SearchesAcrossBooks(t *testing.T) {
+	// Given
+	router, testDB := getHighlightTestDependencies()
+	defer testDB.Close()
+	first := createBookViaApiAs(router, "alice", makeRandomBook())
+	second := createBookViaApiAs(router, "alice", makeRandomBook())
+	createHighlight(router, "alice", first.ID, &models.Highlight{Text: "All happy families are alike", Tags: []string{"family"}})
+	createHighlight(router, "alice", first.ID, &models.Highlight{Text: "Something else", Note: "about (happy) endings", Color: models.HighlightBlue})
+	createHighlight(router, "alice", second.ID, &models.Highlight{Text: "Unhappy in its own way", Tags: []string{"family"}})
+
+	// Then
+	assert.Equal(t, 3, len(searchHighlights(router, "alice", "?q=HAPPY")))
+	assert.Equal(t, 1, len(searchHighlights(router, "alice", "?q=(happy)")))
+	assert.Equal(t, 2, len(searchHighlights(router, "alice", "?tag=family")))
+	assert.Equal(t, 1, len(searchHighlights(router, "alice", "?color=blue")))
+	assert.Equal(t, 1, len(searchHighlights(router, "alice", "?q=happy&book_id="+second.ID.Hex())))
+	assert.Equal(t, 2, len(searchHighlights(router, "alice", "?limit=2")))
+	assert.Equal(t, "All happy families are alike", searchHighlights(router, "alice", "?offset=2")[0].Text)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/highlights?book_id=nope", nil).Code)
+
+	// When the book is deleted
+	requestAs(router, "alice", "DELETE", "/books/"+first.ID.Hex(), nil)
+
+	// Then its highlights go with it
+	assert.Equal(t, 1, len(searchHighlights(router, "alice", "")))
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/importers"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportSize bounds the size of an uploaded import file
+	maxImportSize = 10 << 20
+	// maxCalibreImportSize bounds the size of an uploaded Calibre library, whose metadata.db is larger
+	// than other import files
+	maxCalibreImportSize = 100 << 20
+)
+
+type ImportController struct {
+	importService *services.ImportService
+}
+
+func NewImportController(importService *services.ImportService) *ImportController {
+	return &ImportController{importService: importService}
+}
+
+func (ic *ImportController) SetupImportRoutes(router *gin.RouterGroup) {
+	router.POST("/import/kindle-clippings", ic.ImportKindleClippings)
+	// Calibre libraries are a zip of metadata.opf files or a metadata.db, and LibraryThing exports
+	// are TSV or JSON
+	router.POST("/import/calibre", ic.importBooks("calibre", importers.CalibreImporter{}, maxCalibreImportSize))
+	router.POST("/import/storygraph", ic.importBooks("storygraph", importers.StoryGraphImporter{}, maxImportSize))
+	router.POST("/import/librarything", ic.importBooks("librarything", importers.LibraryThingImporter{}, maxImportSize))
+}
+
+func (ic *ImportController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The import file is too large"})
+	case errors.Is(err, appErrors.ErrInvalidImportFile):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// openUpload returns the uploaded file, sent either as the "file" field of a multipart form or as the raw request body.
+// Reading more than maxSize bytes fails with *http.MaxBytesError.
+func openUpload(c *gin.Context, maxSize int64) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, err
+		}
+		return nil, appErrors.ErrInvalidImportFile
+	}
+	return header.Open()
+}
+
+func (ic *ImportController) ImportKindleClippings(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	file, err := openUpload(c, maxImportSize)
+	if err != nil {
+		ic.handleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	summary, err := ic.importService.ImportKindleClippings(c.Request.Context(), claims.UserID, file)
+	if err != nil {
+		ic.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// importBooks returns a handler importing the books of an export in the importer's format. The import
+// runs as a background job, which the handler returns as soon as it is queued. With dry_run set, the
+// handler instead reports what the import would do, without storing anything.
+func (ic *ImportController) importBooks(format string, importer importers.Importer, maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*auth.Claims)
+
+		dryRun := false
+		if value := c.Query("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+				return
+			}
+		}
+
+		file, err := openUpload(c, maxSize)
+		if err != nil {
+			ic.handleError(c, err)
+			return
+		}
+		defer file.Close()
+
+		if dryRun {
+			summary, err := ic.importService.PreviewImport(c.Request.Context(), claims.UserID, importer, file)
+			if err != nil {
+				ic.handleError(c, err)
+				return
+			}
+			books := make([]*models.Book, len(summary.Books))
+			for i := range summary.Books {
+				books[i] = summary.Books[i].Book
+			}
+			if err := scaleBooks(c, books...); err != nil {
+				ic.handleError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, summary)
+			return
+		}
+
+		job, err := ic.importService.StartImport(c.Request.Context(), claims.UserID, format, importer, file)
+		if err != nil {
+			ic.handleError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
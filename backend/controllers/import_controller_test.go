@@ -0,0 +1,646 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/importers"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const kindleClippings = "The Great Gatsby (F. Scott Fitzgerald)\r\n" +
+	"- Your Highlight on page 1 | Location 170-172 | Added on Sunday, 3 March 2019 10:15:30\r\n" +
+	"\r\n" +
+	"In my younger and more vulnerable years\r\n" +
+	"==========\r\n" +
+	"The Great Gatsby (F. Scott Fitzgerald)\r\n" +
+	"- Your Note on page 1 | Location 172 | Added on Sunday, 3 March 2019 10:16:00\r\n" +
+	"\r\n" +
+	"Father's advice\r\n" +
+	"==========\r\n" +
+	"Sapiens: A Brief History of Humankind (Harari, Yuval Noah)\r\n" +
+	"- Your Highlight on Location 400-401 | Added on Monday, 4 March 2019 08:00:00\r\n" +
+	"\r\n" +
+	"Culture tends to argue that it forbids only that which is unnatural.\r\n" +
+	"==========\r\n" +
+	"Sapiens: A Brief History of Humankind (Harari, Yuval Noah)\r\n" +
+	"- Your Bookmark on Location 900 | Added on Monday, 4 March 2019 09:00:00\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"==========\r\n" +
+	"Dune (Frank Herbert)\r\n" +
+	"- Your Highlight on Location 10-12 | Added on Tuesday, 5 March 2019 08:00:00\r\n" +
+	"\r\n" +
+	"Fear is the mind-killer.\r\n" +
+	"==========\r\n"
+
+func getImportTestDependencies(t *testing.T) (*gin.Engine, *database.TestDatabase) {
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+	return setupImportRoutes(t, testDB), testDB
+}
+
+// setupImportRoutes sets up the routes over the test database the way the server does when it starts,
+// including resuming interrupted jobs
+func setupImportRoutes(t *testing.T, testDB *database.TestDatabase) *gin.Engine {
+	router := gin.Default()
+	highlightRepo := repository.NewHighlightRepository(testDB.Database)
+	bookService := newTestBookService(testDB)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewHighlightController(services.NewHighlightService(highlightRepo, bookService)).SetupHighlightRoutes(api)
+	// One worker, since claiming a job isn't atomic on FerretDB, which the tests may run against
+	jobService := services.NewJobService(repository.NewJobRepository(testDB.Database), services.JobConfig{Workers: 1, PollInterval: 50 * time.Millisecond})
+	NewImportController(services.NewImportService(bookService, highlightRepo, repository.NewSeriesRepository(testDB.Database), jobService)).SetupImportRoutes(api)
+	NewJobController(jobService).SetupJobRoutes(api)
+	jobService.Start()
+	t.Cleanup(jobService.Stop)
+
+	return router
+}
+
+func importClippings(router *gin.Engine, userID, contents string) (*models.ImportSummary, int) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/import/kindle-clippings", strings.NewReader(contents))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Test-User", userID)
+	router.ServeHTTP(w, req)
+
+	var summary *models.ImportSummary
+	_ = json.Unmarshal(w.Body.Bytes(), &summary)
+	return summary, w.Code
+}
+
+func TestImportController_ImportsKindleClippings(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	gatsby := createBookViaApiAs(router, "alice", &models.Book{Title: "The great Gatsby", Author: "Fitzgerald, F. Scott"})
+	sapiens := createBookViaApiAs(router, "alice", &models.Book{Title: "Sapiens", Author: "Yuval Noah Harari"})
+
+	// When
+	summary, code := importClippings(router, "alice", kindleClippings)
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, summary.BooksMatched)
+	assert.Equal(t, 1, summary.BooksCreated)
+	assert.Equal(t, 4, summary.Imported)
+	assert.Equal(t, []models.SkippedEntry{
+		{Entry: 4, Title: "Sapiens: A Brief History of Humankind", Reason: "Bookmarks have no text"},
+	}, summary.Skipped)
+
+	var highlights []models.Highlight
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/highlights", gatsby.ID.Hex()), nil).Body.Bytes(), &highlights)
+	assert.Equal(t, 1, len(highlights))
+	assert.Equal(t, "Father's advice", highlights[0].Note)
+	assert.Equal(t, "170-172", highlights[0].Location)
+	assert.Equal(t, 2019, highlights[0].CreatedAt.Time().Year())
+
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/highlights", sapiens.ID.Hex()), nil).Body.Bytes(), &highlights)
+	assert.Equal(t, 1, len(highlights))
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 3, len(books))
+	assert.Equal(t, "[]", requestAs(router, "bob", "GET", "/books", nil).Body.String())
+}
+
+func TestImportController_ReimportSkipsDuplicates(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	importClippings(router, "alice", kindleClippings)
+
+	// When
+	summary, code := importClippings(router, "alice", kindleClippings)
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 3, summary.BooksMatched)
+	assert.Equal(t, 0, summary.BooksCreated)
+	assert.Equal(t, 0, summary.Imported)
+	assert.Equal(t, 5, len(summary.Skipped))
+	assert.Equal(t, 3, len(searchHighlightsFor(router, "alice")))
+}
+
+func TestImportController_AcceptsMultipartUploads(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "My Clippings.txt")
+	_, _ = part.Write([]byte(kindleClippings))
+	_ = form.Close()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/import/kindle-clippings", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Test-User", "alice")
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, len(searchHighlightsFor(router, "alice")))
+}
+
+func TestImportController_RejectsOversizedFiles(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+
+	// When
+	_, code := importClippings(router, "alice", strings.Repeat(kindleClippings, maxImportSize/len(kindleClippings)+1))
+
+	// Then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+}
+
+func searchHighlightsFor(router *gin.Engine, userID string) []models.Highlight {
+	var highlights []models.Highlight
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/highlights", nil).Body.Bytes(), &highlights)
+	return highlights
+}
+
+// calibreOPF returns the metadata.opf Calibre writes for a book
+func calibreOPF(title, author, extra string) string {
+	return `<?xml version='1.0' encoding='utf-8'?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:title>` + title + `</dc:title>
+    <dc:creator opf:role="aut">` + author + `</dc:creator>
+    ` + extra + `
+  </metadata>
+</package>`
+}
+
+func calibreZip(files map[string]string) []byte {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, contents := range files {
+		file, _ := archive.Create(name)
+		_, _ = file.Write([]byte(contents))
+	}
+	_ = archive.Close()
+	return buffer.Bytes()
+}
+
+var calibreLibrary = calibreZip(map[string]string{
+	"Frank Herbert/Dune (1)/metadata.opf": calibreOPF("Dune", "Frank Herbert", `<dc:identifier opf:scheme="ISBN">9780441172719</dc:identifier>`),
+	"Frank Herbert/Dune Messiah (2)/metadata.opf": calibreOPF("Dune Messiah", "Frank Herbert", `
+    <dc:identifier opf:scheme="ISBN">0-441-17269-5</dc:identifier>
+    <dc:subject>Sci-Fi</dc:subject>
+    <dc:description>&lt;p&gt;Twelve years &amp;amp; counting&lt;/p&gt;</dc:description>
+    <meta name="calibre:series" content="Dune Chronicles"/>
+    <meta name="calibre:series_index" content="2.0"/>
+    <meta name="calibre:rating" content="8"/>`),
+	"Unknown/Untitled (3)/metadata.opf": calibreOPF("", "Unknown", ""),
+})
+
+// importBooks posts an export and waits for the import job it queues. It returns the summary of the
+// job, or of the dry run, and the status code of the upload.
+func importBooks(router *gin.Engine, userID, path, contentType string, contents io.Reader) (*models.ImportSummary, int) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", path, contents)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Test-User", userID)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		var summary *models.ImportSummary
+		_ = json.Unmarshal(w.Body.Bytes(), &summary)
+		return summary, w.Code
+	}
+
+	var job models.Job
+	_ = json.Unmarshal(w.Body.Bytes(), &job)
+	return waitForJob(router, userID, job.ID.Hex()).Summary, w.Code
+}
+
+// waitForJob polls the job until it completes or fails
+func waitForJob(router *gin.Engine, userID, id string) *models.Job {
+	var job models.Job
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(20 * time.Millisecond) {
+		_ = json.Unmarshal(requestAs(router, userID, "GET", "/jobs/"+id, nil).Body.Bytes(), &job)
+		if job.Status == models.JobCompleted || job.Status == models.JobFailed {
+			break
+		}
+	}
+	return &job
+}
+
+func importCalibre(router *gin.Engine, userID string, contents []byte) (*models.ImportSummary, int) {
+	return importBooks(router, userID, "/import/calibre", "application/octet-stream", bytes.NewReader(contents))
+}
+
+// libraryBook returns the user's book with the title
+func libraryBook(router *gin.Engine, userID, title string) models.Book {
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/books", nil).Body.Bytes(), &books)
+	for _, book := range books {
+		if book.Title == title {
+			return book
+		}
+	}
+	return models.Book{}
+}
+
+func TestImportController_ImportsCalibreLibraries(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	createBookViaApiAs(router, "alice", &models.Book{Title: "Dune", Author: "Herbert, Frank"})
+
+	// When
+	summary, code := importCalibre(router, "alice", calibreLibrary)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, 1, summary.BooksMatched)
+	assert.Equal(t, 1, summary.BooksCreated)
+	assert.Equal(t, 1, summary.BooksUpdated)
+	assert.Equal(t, 2, summary.Imported)
+	assert.Equal(t, []models.SkippedEntry{{Entry: 3, Reason: "Missing title"}}, summary.Skipped)
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 2, len(books))
+	assert.Equal(t, "9780441172719", libraryBook(router, "alice", "Dune").ISBN13)
+	messiah := libraryBook(router, "alice", "Dune Messiah")
+	assert.Equal(t, "Frank Herbert", messiah.Author)
+	assert.Equal(t, "9780441172696", messiah.ISBN13)
+	assert.Equal(t, []string{"sci-fi"}, messiah.Tags)
+	assert.Equal(t, 4.0, messiah.Rating)
+	assert.Equal(t, "Twelve years & counting", messiah.Comment)
+	assert.NotNil(t, messiah.SeriesID)
+	assert.Equal(t, 2.0, *messiah.Volume)
+}
+
+func TestImportController_CalibreReimportSkipsBooksAlreadyImported(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	importCalibre(router, "alice", calibreLibrary)
+
+	// When the library is imported again with a renamed book that keeps its ISBN
+	summary, code := importCalibre(router, "alice", calibreZip(map[string]string{
+		"a/metadata.opf": calibreOPF("Dune", "Frank Herbert", ""),
+		"b/metadata.opf": calibreOPF("Dune Messiah (Dune Chronicles 2)", "Frank Herbert", `<dc:identifier opf:scheme="ISBN">9780441172696</dc:identifier>`),
+	}))
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, 2, summary.BooksMatched)
+	assert.Equal(t, 0, summary.BooksCreated)
+	assert.Equal(t, 2, len(summary.Skipped))
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 2, len(books))
+}
+
+func TestImportController_RejectsFilesThatAreNotCalibreLibraries(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+
+	// Then
+	_, code := importCalibre(router, "alice", []byte(kindleClippings))
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = importCalibre(router, "alice", calibreZip(map[string]string{"notes.txt": "hello"}))
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+const storyGraphExport = "Title,Authors,ISBN/UID,Format,Read Status,Last Date Read,Dates Read,Star Rating,Review,Tags\n" +
+	"Good Omens,\"Terry Pratchett, Neil Gaiman\",9780060853983,paperback,read,2023/02/10,2023/02/01-2023/02/10,4.5,Funny,\"fantasy, humour\"\n" +
+	"Piranesi,Susanna Clarke,,digital,currently-reading,,,,,\n" +
+	"Broken,Someone,,,read,,,seven,,\n"
+
+const libraryThingExport = "Title\tPrimary Author\tRating\tComment\tReview\tMedia\tPage Count\tDate Read\tTags\tISBNs\tSeries\n" +
+	"Dune\tHerbert, Frank\t5\tFrom the import\tStill great\tPaperback\t535\t2021-02-01\tsci-fi\t9780441172719\tDune Chronicles (1)\n"
+
+func importExport(router *gin.Engine, userID, format, query, contents string) (*models.ImportSummary, int) {
+	return importBooks(router, userID, "/import/"+format+query, "text/plain", strings.NewReader(contents))
+}
+
+func TestImportController_ImportsStoryGraphExports(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+
+	// When
+	summary, code := importExport(router, "alice", "storygraph", "", storyGraphExport)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, 2, summary.BooksCreated)
+	assert.Equal(t, []models.SkippedEntry{
+		{Entry: 3, Title: "Broken", Reason: `Star Rating: "seven" is not a rating from 0 to 5`},
+	}, summary.Skipped)
+
+	omens := getBookAs(router, "alice", libraryBook(router, "alice", "Good Omens").ID)
+	assert.Equal(t, "Terry Pratchett & Neil Gaiman", omens.Author)
+	assert.Equal(t, models.StatusFinished, omens.Status)
+	assert.Equal(t, 5.0, omens.Rating)
+	assert.Equal(t, []string{"fantasy", "humour"}, omens.Tags)
+	assert.Equal(t, "2023-02-10", omens.FinishedAt.Time().UTC().Format(time.DateOnly))
+	assert.Equal(t, 1, len(omens.Reads))
+	assert.Equal(t, "2023-02-01", omens.Reads[0].StartedAt.Time().UTC().Format(time.DateOnly))
+	assert.Equal(t, "2023-02-10", omens.Reads[0].FinishedAt.Time().UTC().Format(time.DateOnly))
+
+	piranesi := libraryBook(router, "alice", "Piranesi")
+	assert.Equal(t, models.StatusReading, piranesi.Status)
+	assert.Equal(t, models.FormatEbook, piranesi.Format)
+}
+
+func TestImportController_DryRunPreviewsWithoutStoring(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	createBookViaApiAs(router, "alice", &models.Book{Title: "Good Omens", Author: "Neil Gaiman & Terry Pratchett"})
+
+	// When
+	summary, code := importExport(router, "alice", "storygraph", "?dry_run=true", storyGraphExport)
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, summary.DryRun)
+	assert.Equal(t, 1, summary.BooksUpdated)
+	assert.Equal(t, 1, summary.BooksCreated)
+	assert.Equal(t, 1, len(summary.Skipped))
+	assert.Equal(t, models.ImportUpdated, summary.Books[0].Action)
+	assert.Equal(t, []string{"isbn", "format", "rating", "status", "review", "tags"}, summary.Books[0].Fields)
+	assert.Equal(t, models.ImportCreated, summary.Books[1].Action)
+	assert.Equal(t, "Piranesi", summary.Books[1].Book.Title)
+
+	// And nothing was stored
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 1, len(books))
+	assert.Empty(t, books[0].ISBN13)
+	assert.Empty(t, books[0].Status)
+
+	// When a dry run would create a series
+	summary, _ = importExport(router, "alice", "librarything", "?dry_run=1", libraryThingExport)
+
+	// Then the book is previewed with its rating, and the series isn't stored either
+	assert.Equal(t, 5.0, summary.Books[0].Book.Rating)
+	assert.Equal(t, "Dune Chronicles", summary.Books[0].Series)
+	series, err := repository.NewSeriesRepository(testDB.Database).FindByUserID(t.Context(), "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, series)
+
+	// And dry_run must be a boolean
+	_, code = importExport(router, "alice", "storygraph", "?dry_run=maybe", storyGraphExport)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestImportController_LibraryThingFillsInWithoutOverwriting(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	dune := createBookViaApiAs(router, "alice", &models.Book{Title: "Dune", Author: "Frank Herbert", Rating: 3, Comment: "Mine", Tags: []string{"favourite"}})
+
+	// When
+	summary, code := importExport(router, "alice", "librarything", "", libraryThingExport)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, 1, summary.BooksMatched)
+	assert.Equal(t, 1, summary.BooksUpdated)
+
+	book := getBookAs(router, "alice", dune.ID)
+	assert.Equal(t, 3.0, book.Rating)
+	assert.Equal(t, "Mine", book.Comment)
+	assert.Equal(t, "Still great", book.Review)
+	assert.Equal(t, "9780441172719", book.ISBN13)
+	assert.Equal(t, 535, book.Edition.PageCount)
+	assert.Equal(t, 1.0, *book.Volume)
+	assert.Equal(t, []string{"favourite", "sci-fi"}, book.Tags)
+	assert.Equal(t, "2021-02-01", book.FinishedAt.Time().UTC().Format(time.DateOnly))
+
+	// When the export is imported again
+	summary, _ = importExport(router, "alice", "librarything", "", libraryThingExport)
+
+	// Then there is nothing left to fill in
+	assert.Equal(t, 0, summary.BooksUpdated)
+	assert.Equal(t, []models.SkippedEntry{{Entry: 1, Title: "Dune", Reason: "Already in library"}}, summary.Skipped)
+}
+
+func TestImportController_ImportsRunAsJobs(t *testing.T) {
+	// Given
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/import/storygraph", strings.NewReader(storyGraphExport))
+	req.Header.Set("X-Test-User", "alice")
+	router.ServeHTTP(w, req)
+
+	// Then the upload returns the queued job straight away
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var queued models.Job
+	_ = json.Unmarshal(w.Body.Bytes(), &queued)
+	assert.Equal(t, models.JobImportBooks, queued.Type)
+	assert.Equal(t, "storygraph", queued.Format)
+	assert.Equal(t, 3, queued.Total)
+
+	// And the job reports its summary once it has completed
+	job := waitForJob(router, "alice", queued.ID.Hex())
+	assert.Equal(t, models.JobCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, 2, job.Summary.BooksCreated)
+	assert.Equal(t, 1, len(job.Summary.Skipped))
+
+	// And only its owner can see it
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "GET", "/jobs/"+queued.ID.Hex(), nil).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/jobs/not-an-id", nil).Code)
+}
+
+func TestImportController_LargeImportsAreStoredInBatches(t *testing.T) {
+	// Given an export with more entries than fit in one batch, the last repeating the first
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	export := "Title,Authors,Read Status\n"
+	for i := 1; i <= 1200; i++ {
+		export += fmt.Sprintf("Book %d,Author %d,to-read\n", i, i)
+	}
+	export += "Book 1,Author 1,read\n"
+
+	// When
+	summary, code := importExport(router, "alice", "storygraph", "", export)
+
+	// Then
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, 1200, summary.BooksCreated)
+	assert.Equal(t, []models.SkippedEntry{{Entry: 1201, Title: "Book 1", Reason: "Already in library"}}, summary.Skipped)
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 1200, len(books))
+	assert.Equal(t, models.StatusWantToRead, libraryBook(router, "alice", "Book 1").Status)
+}
+
+func TestImportController_SkippedEntriesAreCapped(t *testing.T) {
+	// Given an export with more bad entries than a summary lists
+	router, testDB := getImportTestDependencies(t)
+	defer testDB.Close()
+	export := "Title,Authors,Read Status\n"
+	for i := 1; i <= models.MaxSkippedEntries+5; i++ {
+		export += fmt.Sprintf(",Author %d,to-read\n", i)
+	}
+
+	// When
+	summary, code := importExport(router, "alice", "storygraph", "", export)
+
+	// Then the summary lists the first of them and counts the rest
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, models.MaxSkippedEntries, len(summary.Skipped))
+	assert.Equal(t, 1, summary.Skipped[0].Entry)
+	assert.Equal(t, 5, summary.MoreSkipped)
+}
+
+func TestImportController_InterruptedImportsResume(t *testing.T) {
+	// Given an import that was interrupted after committing its first entry
+	testDB, err := database.NewTestDatabase()
+	assert.NoError(t, err)
+	defer testDB.Close()
+	records, err := importers.StoryGraphImporter{}.Parse(strings.NewReader(storyGraphExport))
+	assert.NoError(t, err)
+
+	jobRepo := repository.NewJobRepository(testDB.Database)
+	job := &models.Job{
+		ID:        primitive.NewObjectID(),
+		UserID:    "alice",
+		Type:      models.JobImportBooks,
+		Format:    "storygraph",
+		Status:    models.JobRunning,
+		Total:     3,
+		Processed: 1,
+		Summary:   &models.ImportSummary{BooksCreated: 1, Imported: 1, Skipped: []models.SkippedEntry{}},
+	}
+	assert.NoError(t, jobRepo.AddRecords(t.Context(), job.ID, records))
+	assert.NoError(t, jobRepo.Create(t.Context(), job))
+
+	// When the server starts again
+	router := setupImportRoutes(t, testDB)
+
+	// Then the job carries on after the committed entry
+	resumed := waitForJob(router, "alice", job.ID.Hex())
+	assert.Equal(t, models.JobCompleted, resumed.Status)
+	assert.Equal(t, 3, resumed.Processed)
+	assert.Equal(t, 2, resumed.Summary.BooksCreated)
+	assert.Equal(t, 2, resumed.Summary.Imported)
+	assert.Equal(t, 1, len(resumed.Summary.Skipped))
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Equal(t, 1, len(books))
+	assert.Equal(t, "Piranesi", books[0].Title)
+
+	// And its entries are cleaned up
+	remaining, err := jobRepo.FindRecords(t.Context(), job.ID, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestImportController_JobsHeldByAnotherReplicaAreNotResumed(t *testing.T) {
+	// Given an import another replica is running, and still holds the lease of
+	testDB, err := database.NewTestDatabase()
+	assert.NoError(t, err)
+	defer testDB.Close()
+	records, err := importers.StoryGraphImporter{}.Parse(strings.NewReader(storyGraphExport))
+	assert.NoError(t, err)
+
+	jobRepo := repository.NewJobRepository(testDB.Database)
+	lease := primitive.NewDateTimeFromTime(time.Now().Add(time.Hour))
+	job := &models.Job{
+		ID:             primitive.NewObjectID(),
+		UserID:         "alice",
+		Type:           models.JobImportBooks,
+		Format:         "storygraph",
+		Status:         models.JobRunning,
+		Total:          3,
+		Processed:      1,
+		Owner:          "another-replica",
+		LeaseExpiresAt: &lease,
+		Summary:        &models.ImportSummary{BooksCreated: 1, Imported: 1, Skipped: []models.SkippedEntry{}},
+	}
+	assert.NoError(t, jobRepo.AddRecords(t.Context(), job.ID, records))
+	assert.NoError(t, jobRepo.Create(t.Context(), job))
+
+	// When this server starts and its workers look for jobs
+	router := setupImportRoutes(t, testDB)
+	time.Sleep(200 * time.Millisecond)
+
+	// Then the job is left to the replica running it
+	var current models.Job
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/jobs/"+job.ID.Hex(), nil).Body.Bytes(), &current)
+	assert.Equal(t, models.JobRunning, current.Status)
+	assert.Equal(t, 1, current.Processed)
+
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/books", nil).Body.Bytes(), &books)
+	assert.Empty(t, books)
+}
+
+func TestImportController_JobsAreTakenOverOnceTheirLeaseExpires(t *testing.T) {
+	// Given an import held by a replica that stopped without anyone restarting it
+	testDB, err := database.NewTestDatabase()
+	assert.NoError(t, err)
+	defer testDB.Close()
+	records, err := importers.StoryGraphImporter{}.Parse(strings.NewReader(storyGraphExport))
+	assert.NoError(t, err)
+
+	jobRepo := repository.NewJobRepository(testDB.Database)
+	lease := primitive.NewDateTimeFromTime(time.Now().Add(300 * time.Millisecond))
+	job := &models.Job{
+		ID:             primitive.NewObjectID(),
+		UserID:         "alice",
+		Type:           models.JobImportBooks,
+		Format:         "storygraph",
+		Status:         models.JobRunning,
+		Total:          3,
+		Owner:          "stopped-replica",
+		LeaseExpiresAt: &lease,
+		Summary:        &models.ImportSummary{Skipped: []models.SkippedEntry{}},
+	}
+	assert.NoError(t, jobRepo.AddRecords(t.Context(), job.ID, records))
+	assert.NoError(t, jobRepo.Create(t.Context(), job))
+
+	// When this server is already running as the lease expires
+	router := setupImportRoutes(t, testDB)
+
+	// Then it takes the job over and completes it
+	completed := waitForJob(router, "alice", job.ID.Hex())
+	assert.Equal(t, models.JobCompleted, completed.Status)
+	assert.Equal(t, 3, completed.Processed)
+}
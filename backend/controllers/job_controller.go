@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobController struct {
+	jobService *services.JobService
+}
+
+func NewJobController(jobService *services.JobService) *JobController {
+	return &JobController{jobService: jobService}
+}
+
+func (jc *JobController) SetupJobRoutes(router *gin.RouterGroup) {
+	router.GET("/jobs/:id", jc.GetJob)
+}
+
+func (jc *JobController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetJob reports the progress of a background job, such as an import, and its summary once it has
+// completed
+func (jc *JobController) GetJob(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	job, err := jc.jobService.GetJob(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const dateLayout = "2006-01-02"
+
+type JournalController struct {
+	journalService *services.JournalService
+}
+
+func NewJournalController(journalService *services.JournalService) *JournalController {
+	return &JournalController{journalService: journalService}
+}
+
+func (jc *JournalController) SetupJournalRoutes(router *gin.RouterGroup) {
+	router.POST("/books/:id/journal", jc.CreateEntry)
+	router.GET("/books/:id/journal", jc.ListEntries)
+	router.PUT("/books/:id/journal/:entryId", jc.UpdateEntry)
+	router.DELETE("/books/:id/journal/:entryId", jc.DeleteEntry)
+	router.GET("/journal", jc.GetJournal)
+}
+
+func (jc *JournalController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Journal entry not found"})
+	case errors.Is(err, appErrors.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidEntry),
+		errors.Is(err, appErrors.ErrInvalidDateRange),
+		errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (jc *JournalController) CreateEntry(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var entry models.JournalEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := jc.journalService.CreateEntry(c.Request.Context(), c.Param("id"), claims.UserID, &entry); err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (jc *JournalController) ListEntries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	entries, err := jc.journalService.GetEntries(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	if entries == nil {
+		entries = []models.JournalEntry{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+func (jc *JournalController) UpdateEntry(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.JournalEntry
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := jc.journalService.UpdateEntry(c.Request.Context(), c.Param("id"), c.Param("entryId"), claims.UserID, &update)
+	if err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (jc *JournalController) DeleteEntry(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := jc.journalService.DeleteEntry(c.Request.Context(), c.Param("id"), c.Param("entryId"), claims.UserID); err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseDate reads a date query parameter, either a day or an RFC 3339 time. A day given as the end of
+// a range includes the whole day.
+func parseDate(c *gin.Context, name string, endOfRange bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	if day, err := time.Parse(dateLayout, value); err == nil {
+		if endOfRange {
+			day = day.AddDate(0, 0, 1)
+		}
+		return &day, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, appErrors.ErrInvalidDateRange
+	}
+	return &at, nil
+}
+
+// GetJournal accepts from, to and book_id filters along with offset and limit
+func (jc *JournalController) GetJournal(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var filter models.JournalFilter
+	if filter.From, err = parseDate(c, "from", false); err != nil {
+		jc.handleError(c, err)
+		return
+	}
+	if filter.To, err = parseDate(c, "to", true); err != nil {
+		jc.handleError(c, err)
+		return
+	}
+	if value := c.Query("book_id"); value != "" {
+		bookID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			jc.handleError(c, appErrors.ErrInvalidID)
+			return
+		}
+		filter.BookID = &bookID
+	}
+
+	entries, err := jc.journalService.GetJournal(c.Request.Context(), claims.UserID, filter, page.Offset, page.Limit)
+	if err != nil {
+		jc.handleError(c, err)
+		return
+	}
+
+	if entries == nil {
+		entries = []models.JournalEntry{}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getJournalTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	groupRepo := repository.NewGroupRepository(testDB.Database)
+	bookService := newTestBookService(testDB)
+	groupService := services.NewGroupService(groupRepo, services.NewPermissionEvaluator(groupRepo))
+	journalService := services.NewJournalService(repository.NewJournalRepository(testDB.Database), bookService)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewGroupController(groupService, bookService).SetupGroupRoutes(api)
+	NewJournalController(journalService).SetupJournalRoutes(api)
+
+	return router, testDB
+}
+
+func createEntry(router *gin.Engine, userID string, bookID primitive.ObjectID, entry *models.JournalEntry) (*models.JournalEntry, int) {
+	w := requestAs(router, userID, "POST", fmt.Sprintf("/books/%s/journal", bookID.Hex()), entry)
+	var created *models.JournalEntry
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	return created, w.Code
+}
+
+func getJournal(router *gin.Engine, userID, query string) []models.JournalEntry {
+	var entries []models.JournalEntry
+	_ = json.Unmarshal(requestAs(router, userID, "GET", "/journal"+query, nil).Body.Bytes(), &entries)
+	return entries
+}
+
+func TestJournalController_CanCreateUpdateAndDeleteEntries(t *testing.T) {
+	// Given
+	router, testDB := getJournalTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+
+	// When
+	entry, code := createEntry(router, "alice", book.ID, &models.JournalEntry{
+		Date:    *dateTime(2024, 3, 2),
+		Chapter: " 4 ",
+		Text:    "  I *think* the narrator is lying  ",
+	})
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "I *think* the narrator is lying", entry.Text)
+	assert.Contains(t, entry.TextHTML, "<em>think</em>")
+	assert.Equal(t, "4", entry.Chapter)
+	assert.Equal(t, book.ID, entry.BookID)
+
+	// When
+	path := fmt.Sprintf("/books/%s/journal/%s", book.ID.Hex(), entry.ID.Hex())
+	entry.Text = "Confirmed: the narrator is lying"
+	entry.Page = 120
+	w := requestAs(router, "alice", "PUT", path, entry)
+
+	// Then
+	var entries []models.JournalEntry
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/journal", book.ID.Hex()), nil).Body.Bytes(), &entries)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "Confirmed: the narrator is lying", entries[0].Text)
+	assert.Equal(t, 120, entries[0].Page)
+	assert.Equal(t, *dateTime(2024, 3, 2), entries[0].Date)
+
+	// When
+	w = requestAs(router, "alice", "DELETE", path, nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "[]", requestAs(router, "alice", "GET", fmt.Sprintf("/books/%s/journal", book.ID.Hex()), nil).Body.String())
+}
+
+func TestJournalController_RejectsInvalidEntries(t *testing.T) {
+	// Given
+	router, testDB := getJournalTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+
+	// Then
+	_, code := createEntry(router, "alice", book.ID, &models.JournalEntry{Text: " "})
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = createEntry(router, "alice", book.ID, &models.JournalEntry{Text: "text", Page: -1})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// And an entry without a date is dated when it is saved
+	entry, code := createEntry(router, "alice", book.ID, &models.JournalEntry{Text: "today"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotZero(t, entry.Date)
+}
+
+func TestJournalController_AccessFollowsTheBook(t *testing.T) {
+	// Given
+	router, testDB := getJournalTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApiAs(router, "alice", makeRandomBook())
+	entry, _ := createEntry(router, "alice", book.ID, &models.JournalEntry{Text: "Private thoughts"})
+	group := createGroupWithMember(t, router, "alice", "bob", models.GroupRoleViewer)
+	groupBook, _ := createGroupBook(router, "alice", group.ID)
+	createEntry(router, "alice", groupBook.ID, &models.JournalEntry{Text: "Shared thoughts"})
+
+	// Then other users can't see or change entries on personal books, which are reported as missing
+	w := requestAs(router, "bob", "GET", fmt.Sprintf("/books/%s/journal", book.ID.Hex()), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Book not found")
+	_, code := createEntry(router, "bob", book.ID, &models.JournalEntry{Text: "Intrusion"})
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "PUT", fmt.Sprintf("/books/%s/journal/%s", book.ID.Hex(), entry.ID.Hex()), entry).Code)
+
+	// And an entry can't be reached through a different book
+	w = requestAs(router, "alice", "PUT", fmt.Sprintf("/books/%s/journal/%s", groupBook.ID.Hex(), entry.ID.Hex()), entry)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Journal entry not found")
+
+	// And group viewers can read but not write entries
+	assert.Equal(t, http.StatusOK, requestAs(router, "bob", "GET", fmt.Sprintf("/books/%s/journal", groupBook.ID.Hex()), nil).Code)
+	_, code = createEntry(router, "bob", groupBook.ID, &models.JournalEntry{Text: "Viewer thoughts"})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// And the journal only covers books the user can view
+	results := getJournal(router, "bob", "")
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Shared thoughts", results[0].Text)
+	assert.Equal(t, 2, len(getJournal(router, "alice", "")))
+}
+
+func TestJournalController_ListsJournalChronologicallyWithinDates(t *testing.T) {
+	// Given
+	router, testDB := getJournalTestDependencies()
+	defer testDB.Close()
+	first := createBookViaApiAs(router, "alice", makeRandomBook())
+	second := createBookViaApiAs(router, "alice", makeRandomBook())
+	createEntry(router, "alice", first.ID, &models.JournalEntry{Date: *dateTime(2024, 3, 10), Text: "march"})
+	createEntry(router, "alice", second.ID, &models.JournalEntry{Date: *dateTime(2024, 1, 5), Text: "january"})
+	createEntry(router, "alice", first.ID, &models.JournalEntry{Date: *dateTime(2024, 2, 29), Text: "february"})
+
+	// Then
+	texts := func(entries []models.JournalEntry) []string {
+		result := []string{}
+		for _, entry := range entries {
+			result = append(result, entry.Text)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"january", "february", "march"}, texts(getJournal(router, "alice", "")))
+	assert.Equal(t, []string{"february", "march"}, texts(getJournal(router, "alice", "?from=2024-02-01")))
+	assert.Equal(t, []string{"january", "february"}, texts(getJournal(router, "alice", "?to=2024-02-29")))
+	assert.Equal(t, []string{"february"}, texts(getJournal(router, "alice", "?from=2024-02-01T00:00:00Z&to=2024-03-01T00:00:00Z")))
+	assert.Equal(t, []string{"february", "march"}, texts(getJournal(router, "alice", "?book_id="+first.ID.Hex())))
+	assert.Equal(t, []string{"february"}, texts(getJournal(router, "alice", "?offset=1&limit=1")))
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/journal?from=yesterday", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/journal?from=2024-03-01&to=2024-02-01", nil).Code)
+
+	// When the book is deleted
+	requestAs(router, "alice", "DELETE", "/books/"+first.ID.Hex(), nil)
+
+	// Then its entries go with it
+	assert.Equal(t, []string{"january"}, texts(getJournal(router, "alice", "")))
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LookupController struct {
+	lookupService *services.LookupService
+}
+
+func NewLookupController(lookupService *services.LookupService) *LookupController {
+	return &LookupController{lookupService: lookupService}
+}
+
+func (lc *LookupController) SetupLookupRoutes(router *gin.RouterGroup) {
+	router.GET("/lookup/isbn/:isbn", lc.LookupISBN)
+}
+
+func (lc *LookupController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No book found with this ISBN"})
+	case errors.Is(err, appErrors.ErrInvalidISBN):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrMetadataProvider):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (lc *LookupController) LookupISBN(c *gin.Context) {
+	metadata, err := lc.lookupService.LookupISBN(c.Request.Context(), c.Param("isbn"))
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, metadata)
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/metadata"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOpenLibrary serves a single known book and counts the requests it receives
+func fakeOpenLibrary(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.URL.Query().Get("bibkeys") != "ISBN:9780140328721" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ISBN:9780140328721": {"title": "Fantastic Mr. Fox", "authors": [{"name": "Roald Dahl"}]}}`))
+	}))
+}
+
+func getLookupTestDependencies(baseURL string) (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	lookupService := services.NewLookupService(
+		metadata.NewOpenLibraryProvider(baseURL),
+		repository.NewMetadataCacheRepository(testDB.Database),
+	)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(newTestBookService(testDB)).SetupBookRoutes(api)
+	NewLookupController(lookupService).SetupLookupRoutes(api)
+
+	return router, testDB
+}
+
+func TestLookupController_LooksUpAndCachesISBN(t *testing.T) {
+	// Given
+	var requests int32
+	server := fakeOpenLibrary(&requests)
+	defer server.Close()
+	router, testDB := getLookupTestDependencies(server.URL)
+	defer testDB.Close()
+
+	// When
+	w := requestAs(router, "alice", "GET", "/lookup/isbn/978-0-14-032872-1", nil)
+
+	// Then
+	var book models.BookMetadata
+	_ = json.Unmarshal(w.Body.Bytes(), &book)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Fantastic Mr. Fox", book.Title)
+	assert.Equal(t, []string{"Roald Dahl"}, book.Authors)
+	assert.Equal(t, "0140328726", book.ISBN10)
+
+	// And the ISBN-10 form is served from the cache
+	assert.Equal(t, http.StatusOK, requestAs(router, "alice", "GET", "/lookup/isbn/0140328726", nil).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestLookupController_CachesUnknownISBNs(t *testing.T) {
+	// Given
+	var requests int32
+	server := fakeOpenLibrary(&requests)
+	defer server.Close()
+	router, testDB := getLookupTestDependencies(server.URL)
+	defer testDB.Close()
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", "/lookup/isbn/9780306406157", nil).Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", "/lookup/isbn/9780306406157", nil).Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestLookupController_RejectsInvalidISBNs(t *testing.T) {
+	// Given
+	var requests int32
+	server := fakeOpenLibrary(&requests)
+	defer server.Close()
+	router, testDB := getLookupTestDependencies(server.URL)
+	defer testDB.Close()
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/lookup/isbn/9780140328722", nil).Code)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
+
+func TestLookupController_ReportsUnavailableProvider(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	router, testDB := getLookupTestDependencies(server.URL)
+	defer testDB.Close()
+
+	// Then
+	assert.Equal(t, http.StatusBadGateway, requestAs(router, "alice", "GET", "/lookup/isbn/9780140328721", nil).Code)
+}
+
+func TestBookController_NormalizesISBNs(t *testing.T) {
+	// Given
+	router, testDB := getLookupTestDependencies("")
+	defer testDB.Close()
+
+	// When
+	book := createBookViaApiAs(router, "alice", &models.Book{Title: "Fantastic Mr. Fox", ISBN10: "0-14-032872-6"})
+
+	// Then
+	assert.Equal(t, "0140328726", book.ISBN10)
+	assert.Equal(t, "9780140328721", book.ISBN13)
+
+	// And invalid or mismatched ISBNs are rejected
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "POST", "/books", &models.Book{Title: "Typo", ISBN13: "9780140328722"}).Code)
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "POST", "/books", &models.Book{Title: "Mismatch", ISBN10: "0140328726", ISBN13: "9780306406157"}).Code)
+	book.ISBN10, book.ISBN13 = "", "978-0-306-40615-7"
+	w := requestAs(router, "alice", "PUT", "/books/"+book.ID.Hex(), book)
+	_ = json.Unmarshal(w.Body.Bytes(), &book)
+	assert.Equal(t, "0306406152", book.ISBN10)
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type pagination struct {
+	Offset int
+	Limit  int
+}
+
+// parsePagination reads the offset and limit query parameters, applying defaults and bounds
+func parsePagination(c *gin.Context) (pagination, error) {
+	page := pagination{Offset: 0}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return page, fmt.Errorf("offset must be a non-negative integer")
+		}
+		page.Offset = offset
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	return page, nil
+}
+
+// parseLimit reads the limit query parameter for routes that page with a cursor instead of an offset
+func parseLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultPageSize, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+	}
+	return limit, nil
+}
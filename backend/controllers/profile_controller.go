@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileController struct {
+	profileService *services.ProfileService
+}
+
+func NewProfileController(profileService *services.ProfileService) *ProfileController {
+	return &ProfileController{profileService: profileService}
+}
+
+// SetupProfileRoutes configures the routes a user manages their sharing settings with
+func (pc *ProfileController) SetupProfileRoutes(router *gin.RouterGroup) {
+	router.GET("/profile", pc.GetProfile)
+	router.PUT("/profile", pc.UpdateProfile)
+	router.POST("/profile/share-token", pc.RotateShareToken)
+}
+
+// SetupPublicRoutes configures the unauthenticated routes visitors use to view shared libraries
+func (pc *ProfileController) SetupPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/u/:handle", pc.GetLibraryByHandle)
+	router.GET("/share/:token", pc.GetLibraryByShareToken)
+}
+
+func (pc *ProfileController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Library not found"})
+	case errors.Is(err, appErrors.ErrInvalidHandle),
+		errors.Is(err, appErrors.ErrHandleMissing),
+		errors.Is(err, appErrors.ErrInvalidVisibility),
+		errors.Is(err, appErrors.ErrInvalidScale):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrHandleTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (pc *ProfileController) GetProfile(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	profile, err := pc.profileService.GetProfile(c.Request.Context(), claims.UserID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (pc *ProfileController) UpdateProfile(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Profile
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := pc.profileService.UpdateProfile(c.Request.Context(), claims.UserID, &update)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (pc *ProfileController) RotateShareToken(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	profile, err := pc.profileService.RotateShareToken(c.Request.Context(), claims.UserID)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (pc *ProfileController) GetLibraryByHandle(c *gin.Context) {
+	spoilers, err := parseSpoilers(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	library, err := pc.profileService.GetLibraryByHandle(c.Request.Context(), strings.ToLower(c.Param("handle")), spoilers)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, library)
+}
+
+func (pc *ProfileController) GetLibraryByShareToken(c *gin.Context) {
+	spoilers, err := parseSpoilers(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	library, err := pc.profileService.GetLibraryByShareToken(c.Request.Context(), c.Param("token"), spoilers)
+	if err != nil {
+		pc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, library)
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getProfileTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookRepo := repository.NewBookRepository(testDB.Database)
+	profileRepo := repository.NewProfileRepository(testDB.Database)
+	bookController := NewBookController(newTestBookService(testDB))
+	profileController := NewProfileController(services.NewProfileService(profileRepo, bookRepo))
+
+	profileController.SetupPublicRoutes(router.Group("/public"))
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: "test-user-id"})
+		c.Next()
+	})
+	bookController.SetupBookRoutes(api)
+	profileController.SetupProfileRoutes(api)
+
+	return router, testDB
+}
+
+func updateProfileViaApi(router *gin.Engine, profile *models.Profile) (*models.Profile, int) {
+	w := httptest.NewRecorder()
+	profileJson, _ := json.Marshal(profile)
+	req, _ := http.NewRequest("PUT", "/profile", bytes.NewReader(profileJson))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var result *models.Profile
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	return result, w.Code
+}
+
+func getSharedLibrary(router *gin.Engine, path string) (*models.SharedLibrary, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	router.ServeHTTP(w, req)
+
+	var library *models.SharedLibrary
+	_ = json.Unmarshal(w.Body.Bytes(), &library)
+	return library, w
+}
+
+func TestProfileController_NewProfileIsPrivate(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/profile", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	var profile models.Profile
+	_ = json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.VisibilityPrivate, profile.Visibility)
+	assert.Empty(t, profile.ShareToken)
+}
+
+func TestProfileController_PublicProfileSharesOnlyOptedInFields(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+
+	sharedBook := createBookViaApi(router, makeRandomBook())
+	privateBook := makeRandomBook()
+	privateBook.Visibility = models.BookVisibilityPrivate
+	createBookViaApi(router, privateBook)
+
+	_, code := updateProfileViaApi(router, &models.Profile{
+		Handle:       "reader",
+		DisplayName:  "A Reader",
+		Visibility:   models.VisibilityPublic,
+		ShareRatings: true,
+	})
+	assert.Equal(t, http.StatusOK, code)
+
+	// When
+	library, w := getSharedLibrary(router, "/public/u/reader")
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "test-user-id")
+	assert.NotContains(t, w.Body.String(), sharedBook.Comment)
+	assert.Equal(t, "A Reader", library.DisplayName)
+	assert.Equal(t, 1, len(library.Books))
+	assert.Equal(t, sharedBook.Title, library.Books[0].Title)
+	assert.Equal(t, sharedBook.Rating, *library.Books[0].Rating)
+}
+
+func TestProfileController_UnlistedProfileIsOnlyReachableByShareLink(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+	book := createBookViaApi(router, makeRandomBook())
+
+	profile, _ := updateProfileViaApi(router, &models.Profile{
+		Handle:        "reader",
+		Visibility:    models.VisibilityUnlisted,
+		ShareComments: true,
+	})
+
+	// When
+	_, byHandle := getSharedLibrary(router, "/public/u/reader")
+	library, byToken := getSharedLibrary(router, fmt.Sprintf("/public/share/%s", profile.ShareToken))
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, byHandle.Code)
+	assert.Equal(t, http.StatusOK, byToken.Code)
+	assert.Empty(t, library.Handle)
+	assert.Equal(t, 1, len(library.Books))
+	assert.Equal(t, book.Comment, library.Books[0].Comment)
+	assert.Nil(t, library.Books[0].Rating)
+}
+
+func TestProfileController_RotatingShareTokenInvalidatesOldLink(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+	profile, _ := updateProfileViaApi(router, &models.Profile{Visibility: models.VisibilityUnlisted})
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/profile/share-token", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	var rotated models.Profile
+	_ = json.Unmarshal(w.Body.Bytes(), &rotated)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, profile.ShareToken, rotated.ShareToken)
+
+	_, oldLink := getSharedLibrary(router, "/public/share/"+profile.ShareToken)
+	_, newLink := getSharedLibrary(router, "/public/share/"+rotated.ShareToken)
+	assert.Equal(t, http.StatusNotFound, oldLink.Code)
+	assert.Equal(t, http.StatusOK, newLink.Code)
+}
+
+func TestProfileController_PublicProfileRequiresHandle(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+
+	// When
+	_, code := updateProfileViaApi(router, &models.Profile{Visibility: models.VisibilityPublic})
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestProfileController_SharedReviewsHideSpoilersUnlessRequested(t *testing.T) {
+	// Given
+	router, testDB := getProfileTestDependencies()
+	defer testDB.Close()
+	book := makeRandomBook()
+	book.Review = "Worth it.\n\n:::spoiler\nThe butler did it.\n:::\n"
+	createBookViaApi(router, book)
+
+	_, code := updateProfileViaApi(router, &models.Profile{
+		Handle:        "reader",
+		Visibility:    models.VisibilityPublic,
+		ShareComments: true,
+	})
+	assert.Equal(t, http.StatusOK, code)
+
+	// When
+	hidden, _ := getSharedLibrary(router, "/public/u/reader")
+	shown, _ := getSharedLibrary(router, "/public/u/reader?spoilers=true")
+	_, invalid := getSharedLibrary(router, "/public/u/reader?spoilers=maybe")
+
+	// Then
+	assert.Contains(t, hidden.Books[0].ReviewHTML, "Worth it.")
+	assert.Contains(t, hidden.Books[0].ReviewHTML, "Spoiler hidden")
+	assert.NotContains(t, hidden.Books[0].ReviewHTML, "butler")
+	assert.Contains(t, shown.Books[0].ReviewHTML, "The butler did it.")
+	assert.Equal(t, http.StatusBadRequest, invalid.Code)
+}
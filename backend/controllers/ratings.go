@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"fmt"
+	"sync"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ratingScaleKey = "ratingScale"
+
+// RatingScaleMiddleware makes the signed in user's rating scale available to handlers. The profile
+// is only looked up once a handler converts a rating.
+func RatingScaleMiddleware(profileService *services.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*auth.Claims)
+		c.Set(ratingScaleKey, sync.OnceValues(func() (models.RatingScale, error) {
+			return profileService.GetRatingScale(c.Request.Context(), claims.UserID)
+		}))
+		c.Next()
+	}
+}
+
+// ratingScale returns the user's rating scale, or the default scale on routes without RatingScaleMiddleware
+func ratingScale(c *gin.Context) (models.RatingScale, error) {
+	if lookup, ok := c.Get(ratingScaleKey); ok {
+		return lookup.(func() (models.RatingScale, error))()
+	}
+	return models.DefaultRatingScale, nil
+}
+
+func toScore(scale models.RatingScale, rating float64) (int, error) {
+	score, ok := scale.ToScore(rating)
+	if !ok {
+		return 0, fmt.Errorf("%w: ratings go %s", appErrors.ErrInvalidRating, scale.Describe())
+	}
+	return score, nil
+}
+
+// scoreBook converts the rating entered for the book from the user's scale to its stored score
+func scoreBook(c *gin.Context, book *models.Book) error {
+	scale, err := ratingScale(c)
+	if err != nil {
+		return err
+	}
+	book.RatingScore, err = toScore(scale, book.Rating)
+	return err
+}
+
+// scoreRead converts the rating entered for the read from the user's scale to its stored score
+func scoreRead(c *gin.Context, read *models.Read) error {
+	scale, err := ratingScale(c)
+	if err != nil {
+		return err
+	}
+	read.RatingScore, err = toScore(scale, read.Rating)
+	return err
+}
+
+// scaleBooks fills in the ratings of the books and their reads on the user's scale
+func scaleBooks(c *gin.Context, books ...*models.Book) error {
+	scale, err := ratingScale(c)
+	if err != nil {
+		return err
+	}
+	for _, book := range books {
+		book.Rating = scale.FromScore(book.RatingScore)
+		for i := range book.Reads {
+			book.Reads[i].Rating = scale.FromScore(book.Reads[i].RatingScore)
+		}
+	}
+	return nil
+}
+
+// scaleBookList is scaleBooks for a slice of books
+func scaleBookList(c *gin.Context, books []models.Book) error {
+	pointers := make([]*models.Book, len(books))
+	for i := range books {
+		pointers[i] = &books[i]
+	}
+	return scaleBooks(c, pointers...)
+}
+
+// scaleReads fills in the ratings of the reads on the user's scale
+func scaleReads(c *gin.Context, reads ...*models.Read) error {
+	scale, err := ratingScale(c)
+	if err != nil {
+		return err
+	}
+	for _, read := range reads {
+		read.Rating = scale.FromScore(read.RatingScore)
+	}
+	return nil
+}
+
+// scaleReadList is scaleReads for a slice of reads
+func scaleReadList(c *gin.Context, reads []models.Read) error {
+	pointers := make([]*models.Read, len(reads))
+	for i := range reads {
+		pointers[i] = &reads[i]
+	}
+	return scaleReads(c, pointers...)
+}
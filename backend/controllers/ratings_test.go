@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// getRatingTestDependencies sets up a router that converts ratings to each user's scale, where the
+// X-Test-User header selects the authenticated user
+func getRatingTestDependencies() (*gin.Engine, *services.BookService, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookService := newTestBookService(testDB)
+	profileService := services.NewProfileService(repository.NewProfileRepository(testDB.Database), repository.NewBookRepository(testDB.Database))
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	}, RatingScaleMiddleware(profileService))
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewProfileController(profileService).SetupProfileRoutes(api)
+	NewShelfController(services.NewShelfService(repository.NewShelfRepository(testDB.Database), bookService)).SetupShelfRoutes(api)
+
+	return router, bookService, testDB
+}
+
+func setRatingScale(router *gin.Engine, userID string, scale models.RatingScale) int {
+	profile := &models.Profile{Visibility: models.VisibilityPrivate, RatingScale: scale}
+	return requestAs(router, userID, "PUT", "/profile", profile).Code
+}
+
+func TestRatings_AreShownOnTheUsersScale(t *testing.T) {
+	// Given
+	router, _, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleHalfStars))
+
+	book := makeRandomBook()
+	book.Rating = 3.5
+	book.Status = models.StatusFinished
+	created := createBookViaApiAs(router, "alice", book)
+	assert.Equal(t, 3.5, created.Rating)
+
+	// When
+	var stored bson.M
+	err := testDB.Database.GetCollection("books").FindOne(t.Context(), bson.M{"_id": created.ID}).Decode(&stored)
+
+	// Then
+	assert.NoError(t, err)
+	assert.EqualValues(t, 70, stored["rating_score"])
+
+	tests := []struct {
+		scale    models.RatingScale
+		expected float64
+	}{
+		{models.RatingScaleTen, 7},
+		{models.RatingScaleStars, 4},
+		{models.RatingScaleHalfStars, 3.5},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.scale), func(t *testing.T) {
+			assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", tt.scale))
+
+			fetched := getBookAs(router, "alice", created.ID)
+			assert.Equal(t, tt.expected, fetched.Rating)
+			assert.Equal(t, tt.expected, fetched.Reads[0].Rating)
+		})
+	}
+}
+
+func TestRatings_ShelvesKeepTheScaleTheyWereSavedOn(t *testing.T) {
+	// Given a shelf of books rated 4.5 half stars
+	router, _, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleHalfStars))
+
+	book := makeRandomBook()
+	book.Rating = 4.5
+	book.Status = models.StatusFinished
+	createBookViaApiAs(router, "alice", book)
+	shelf, code := createShelf(router, "alice", "Nearly perfect", "rating:4.5")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.RatingScaleHalfStars, shelf.RatingScale)
+
+	// When alice switches to whole stars
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleStars))
+
+	// Then the shelf still holds the same books
+	assert.Equal(t, []string{book.Title}, getShelfTitles(router, "alice", shelf, ""))
+}
+
+func TestRatings_SavingBackARoundedRatingKeepsTheStoredScore(t *testing.T) {
+	// Given a book rated 4.5 half stars, shown as 5 once alice switches to whole stars
+	router, _, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleHalfStars))
+	book := makeRandomBook()
+	book.Rating = 4.5
+	book.Status = models.StatusFinished
+	created := createBookViaApiAs(router, "alice", book)
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleStars))
+	fetched := getBookAs(router, "alice", created.ID)
+	assert.Equal(t, 5.0, fetched.Rating)
+
+	// When she saves the book and its read back as she got them
+	assert.Equal(t, http.StatusOK, requestAs(router, "alice", "PUT", "/books/"+created.ID.Hex(), fetched).Code)
+	read := fetched.Reads[0]
+	assert.Equal(t, http.StatusOK, requestAs(router, "alice", "PUT", fmt.Sprintf("/books/%s/reads/%s", created.ID.Hex(), read.ID.Hex()), read).Code)
+
+	// Then the stored scores are unchanged and she isn't reported as having rated the book again
+	var stored models.Book
+	assert.NoError(t, testDB.Database.GetCollection("books").FindOne(t.Context(), bson.M{"_id": created.ID}).Decode(&stored))
+	assert.Equal(t, 90, stored.RatingScore)
+	assert.Equal(t, 90, stored.Reads[0].RatingScore)
+	rated, err := testDB.Database.GetCollection("activities").CountDocuments(t.Context(), bson.M{"book_id": created.ID, "type": models.ActivityRated})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, rated)
+
+	// When she does change the rating
+	fetched.Rating = 4
+	updated := &models.Book{}
+	_ = json.Unmarshal(requestAs(router, "alice", "PUT", "/books/"+created.ID.Hex(), fetched).Body.Bytes(), updated)
+
+	// Then it is stored
+	assert.Equal(t, 4.0, updated.Rating)
+}
+
+func TestRatings_RejectRatingsOffTheUsersScale(t *testing.T) {
+	// Given
+	router, _, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+	assert.Equal(t, http.StatusOK, setRatingScale(router, "alice", models.RatingScaleHalfStars))
+
+	tests := []struct {
+		name   string
+		rating float64
+	}{
+		{"between steps", 3.25},
+		{"above the top", 5.5},
+		{"negative", -0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := makeRandomBook()
+			book.Rating = tt.rating
+
+			// When
+			w := requestAs(router, "alice", "POST", "/books", book)
+
+			// Then
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "from 0 to 5 in steps of 0.5")
+		})
+	}
+}
+
+func TestRatings_UnknownScaleIsRejected(t *testing.T) {
+	// Given
+	router, _, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+
+	// When
+	code := setRatingScale(router, "alice", "percent")
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestRatings_MigrateLegacyStarRatings(t *testing.T) {
+	// Given
+	router, bookService, testDB := getRatingTestDependencies()
+	defer testDB.Close()
+
+	id := primitive.NewObjectID()
+	legacy := bson.M{
+		"_id":     id,
+		"user_id": "alice",
+		"title":   "Legacy",
+		"author":  "Someone",
+		"rating":  4,
+		"status":  models.StatusFinished,
+		"reads":   bson.A{bson.M{"_id": primitive.NewObjectID(), "finished_at": *dateTime(2020, 1, 1), "rating": 3, "comment": ""}},
+	}
+	_, err := testDB.Database.GetCollection("books").InsertOne(t.Context(), legacy)
+	assert.NoError(t, err)
+	unratedID := primitive.NewObjectID()
+	unrated := bson.M{"_id": unratedID, "user_id": "alice", "title": "Unrated", "author": "Someone", "rating": nil, "status": models.StatusWantToRead}
+	_, err = testDB.Database.GetCollection("books").InsertOne(t.Context(), unrated)
+	assert.NoError(t, err)
+
+	// When
+	assert.NoError(t, bookService.MigrateRatings(t.Context()))
+	assert.NoError(t, bookService.MigrateRatings(t.Context()))
+
+	// Then
+	var stored bson.M
+	assert.NoError(t, testDB.Database.GetCollection("books").FindOne(t.Context(), bson.M{"_id": id}).Decode(&stored))
+	assert.NotContains(t, stored, "rating")
+	assert.EqualValues(t, 80, stored["rating_score"])
+
+	// And a null rating is dropped, leaving the book unrated
+	stored = nil
+	assert.NoError(t, testDB.Database.GetCollection("books").FindOne(t.Context(), bson.M{"_id": unratedID}).Decode(&stored))
+	assert.NotContains(t, stored, "rating")
+	assert.NotContains(t, stored, "rating_score")
+
+	book := getBookAs(router, "alice", id)
+	assert.Equal(t, 4.0, book.Rating)
+	assert.Equal(t, 3.0, book.Reads[0].Rating)
+}
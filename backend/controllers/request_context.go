@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+	appErrors "tranquil-pages/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+// LoadRequestTimeout reads how long a request may take from REQUEST_TIMEOUT
+func LoadRequestTimeout() (time.Duration, error) {
+	value, ok := os.LookupEnv("REQUEST_TIMEOUT")
+	if !ok {
+		return defaultRequestTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid REQUEST_TIMEOUT %q", value)
+	}
+	return timeout, nil
+}
+
+// RequestTimeoutMiddleware gives each request a deadline. The database operations of a request derive
+// their own deadlines from it, so a slow request gives up as a whole instead of one query at a time.
+func RequestTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// respondContextError answers a request whose work stopped because the client went away or the request
+// ran out of time, and reports whether it did
+func respondContextError(c *gin.Context, err error) bool {
+	status, ctxErr := appErrors.ContextStatus(err)
+	if ctxErr == nil {
+		return false
+	}
+	c.JSON(status, gin.H{"error": ctxErr.Error()})
+	return true
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeoutMiddleware_GivesRequestsADeadline(t *testing.T) {
+	// Given
+	router := gin.New()
+	router.Use(RequestTimeoutMiddleware(time.Minute))
+
+	var deadline time.Time
+	router.GET("/", func(c *gin.Context) {
+		deadline, _ = c.Request.Context().Deadline()
+		c.Status(http.StatusNoContent)
+	})
+
+	// When
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func TestLoadRequestTimeout(t *testing.T) {
+	timeout, err := LoadRequestTimeout()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultRequestTimeout, timeout)
+
+	t.Setenv("REQUEST_TIMEOUT", "5s")
+	timeout, err = LoadRequestTimeout()
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+
+	t.Setenv("REQUEST_TIMEOUT", "soon")
+	_, err = LoadRequestTimeout()
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SeriesController struct {
+	seriesService *services.SeriesService
+}
+
+func NewSeriesController(seriesService *services.SeriesService) *SeriesController {
+	return &SeriesController{seriesService: seriesService}
+}
+
+func (sc *SeriesController) SetupSeriesRoutes(router *gin.RouterGroup) {
+	router.POST("/series", sc.CreateSeries)
+	router.GET("/series", sc.ListSeries)
+	router.GET("/series/:id", sc.GetSeries)
+	router.PUT("/series/:id", sc.UpdateSeries)
+	router.DELETE("/series/:id", sc.DeleteSeries)
+	router.GET("/series/:id/missing", sc.GetMissingVolumes)
+	router.GET("/series/:id/next", sc.GetNextVolume)
+}
+
+func (sc *SeriesController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+	case errors.Is(err, appErrors.ErrNoUnreadVolume):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, appErrors.ErrInvalidSeries),
+		errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (sc *SeriesController) CreateSeries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var series models.Series
+	if err := c.ShouldBindJSON(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series.UserID = claims.UserID
+	if err := sc.seriesService.CreateSeries(c.Request.Context(), &series); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (sc *SeriesController) ListSeries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	series, err := sc.seriesService.GetSeriesByUserID(c.Request.Context(), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	if series == nil {
+		series = []models.Series{}
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetSeries returns the series with its books in volume order
+func (sc *SeriesController) GetSeries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	series, err := sc.seriesService.GetSeries(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	if err := scaleBookList(c, series.Books); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (sc *SeriesController) UpdateSeries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Series
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := sc.seriesService.UpdateSeries(c.Request.Context(), c.Param("id"), claims.UserID, &update)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+func (sc *SeriesController) DeleteSeries(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := sc.seriesService.DeleteSeries(c.Request.Context(), c.Param("id"), claims.UserID); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (sc *SeriesController) GetMissingVolumes(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	missing, err := sc.seriesService.GetMissingVolumes(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"missing": missing})
+}
+
+func (sc *SeriesController) GetNextVolume(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	next, err := sc.seriesService.GetNextVolume(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	if next.Book != nil {
+		if err := scaleBooks(c, next.Book); err != nil {
+			sc.handleError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, next)
+}
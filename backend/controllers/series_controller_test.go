@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func getSeriesTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookService := newTestBookService(testDB)
+	seriesService := services.NewSeriesService(repository.NewSeriesRepository(testDB.Database), bookService)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewSeriesController(seriesService).SetupSeriesRoutes(api)
+
+	return router, testDB
+}
+
+func createSeries(t *testing.T, router *gin.Engine, userID, name string, totalVolumes int) *models.Series {
+	w := requestAs(router, userID, "POST", "/series", gin.H{"name": name, "total_volumes": totalVolumes})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var series models.Series
+	_ = json.Unmarshal(w.Body.Bytes(), &series)
+	return &series
+}
+
+func addVolume(t *testing.T, router *gin.Engine, userID string, seriesID primitive.ObjectID, volume float64, status models.ReadingStatus) *models.Book {
+	book := makeRandomBook()
+	book.SeriesID = &seriesID
+	book.Volume = &volume
+	book.Status = status
+
+	w := requestAs(router, userID, "POST", "/books", book)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var created models.Book
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	return &created
+}
+
+func TestSeriesController_ListsVolumesInOrder(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	series := createSeries(t, router, "alice", "  The Expanse  ", 9)
+	third := addVolume(t, router, "alice", series.ID, 3, models.StatusReading)
+	novella := addVolume(t, router, "alice", series.ID, 2.5, models.StatusFinished)
+	first := addVolume(t, router, "alice", series.ID, 1, models.StatusFinished)
+
+	unnumbered := makeRandomBook()
+	unnumbered.SeriesID = &series.ID
+	unnumbered = createBookViaApiAs(router, "alice", unnumbered)
+
+	// When
+	w := requestAs(router, "alice", "GET", "/series/"+series.ID.Hex(), nil)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	var volumes models.SeriesVolumes
+	_ = json.Unmarshal(w.Body.Bytes(), &volumes)
+	assert.Equal(t, "The Expanse", volumes.Name)
+	assert.Equal(t, 9, volumes.TotalVolumes)
+	assert.Equal(t, 2, volumes.Finished)
+
+	var order []primitive.ObjectID
+	for _, book := range volumes.Books {
+		order = append(order, book.ID)
+	}
+	assert.Equal(t, []primitive.ObjectID{first.ID, novella.ID, third.ID, unnumbered.ID}, order)
+}
+
+func TestSeriesController_ListsMissingVolumes(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	known := createSeries(t, router, "alice", "Discworld", 5)
+	unknown := createSeries(t, router, "alice", "Wheel of Time", 0)
+	for _, volume := range []float64{0, 2, 2.5, 4} {
+		addVolume(t, router, "alice", known.ID, volume, models.StatusWantToRead)
+		addVolume(t, router, "alice", unknown.ID, volume, models.StatusWantToRead)
+	}
+
+	tests := []struct {
+		series   *models.Series
+		expected []int
+	}{
+		{known, []int{1, 3, 5}},
+		// Without a known total, only the gaps before the highest volume count
+		{unknown, []int{1, 3}},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, "alice", "GET", fmt.Sprintf("/series/%s/missing", test.series.ID.Hex()), nil)
+
+		// Then
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Missing []int `json:"missing"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, test.expected, response.Missing, test.series.Name)
+	}
+}
+
+func TestSeriesController_SuggestsNextUnreadVolume(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	series := createSeries(t, router, "alice", "Earthsea", 3)
+	addVolume(t, router, "alice", series.ID, 1, models.StatusFinished)
+	second := addVolume(t, router, "alice", series.ID, 2, models.StatusWantToRead)
+	path := fmt.Sprintf("/series/%s/next", series.ID.Hex())
+
+	// When
+	w := requestAs(router, "alice", "GET", path, nil)
+
+	// Then
+	assert.Equal(t, http.StatusOK, w.Code)
+	var next models.NextVolume
+	_ = json.Unmarshal(w.Body.Bytes(), &next)
+	assert.Equal(t, 2.0, next.Volume)
+	assert.Equal(t, second.ID, next.Book.ID)
+
+	// When the second volume is read, the next one is the third, which alice doesn't have yet
+	second.Status = models.StatusFinished
+	assert.Equal(t, http.StatusOK, requestAs(router, "alice", "PUT", "/books/"+second.ID.Hex(), second).Code)
+	next = models.NextVolume{}
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", path, nil).Body.Bytes(), &next)
+
+	// Then
+	assert.Equal(t, 3.0, next.Volume)
+	assert.Nil(t, next.Book)
+
+	// When everything is read
+	addVolume(t, router, "alice", series.ID, 3, models.StatusFinished)
+	w = requestAs(router, "alice", "GET", path, nil)
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSeriesController_RejectsInvalidSeriesAndVolumes(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	series := createSeries(t, router, "alice", "Dune", 6)
+	volume := 1.0
+	negative := -1.0
+	missing := primitive.NewObjectID()
+
+	tests := []struct {
+		name   string
+		userID string
+		book   *models.Book
+	}{
+		{"volume without series", "alice", &models.Book{Title: "Dune", Volume: &volume}},
+		{"negative volume", "alice", &models.Book{Title: "Dune", SeriesID: &series.ID, Volume: &negative}},
+		{"unknown series", "alice", &models.Book{Title: "Dune", SeriesID: &missing}},
+		{"someone else's series", "bob", &models.Book{Title: "Dune", SeriesID: &series.ID}},
+	}
+
+	for _, test := range tests {
+		// When
+		w := requestAs(router, test.userID, "POST", "/books", test.book)
+
+		// Then
+		assert.Equal(t, http.StatusBadRequest, w.Code, test.name)
+	}
+
+	// When
+	w := requestAs(router, "alice", "POST", "/series", gin.H{"name": " ", "total_volumes": 3})
+
+	// Then
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSeriesController_SeriesArePrivate(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	series := createSeries(t, router, "alice", "Foundation", 7)
+
+	// When
+	get := requestAs(router, "bob", "GET", "/series/"+series.ID.Hex(), nil)
+	update := requestAs(router, "bob", "PUT", "/series/"+series.ID.Hex(), gin.H{"name": "Mine now"})
+	var list []models.Series
+	_ = json.Unmarshal(requestAs(router, "bob", "GET", "/series", nil).Body.Bytes(), &list)
+
+	// Then
+	assert.Equal(t, http.StatusNotFound, get.Code)
+	assert.Equal(t, http.StatusNotFound, update.Code)
+	assert.Empty(t, list)
+}
+
+func TestSeriesController_DeletingSeriesKeepsBooks(t *testing.T) {
+	// Given
+	router, testDB := getSeriesTestDependencies()
+	defer testDB.Close()
+	series := createSeries(t, router, "alice", "Hyperion", 4)
+	book := addVolume(t, router, "alice", series.ID, 1, models.StatusReading)
+
+	// When
+	w := requestAs(router, "alice", "DELETE", "/series/"+series.ID.Hex(), nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", "/series/"+series.ID.Hex(), nil).Code)
+
+	var stored models.Book
+	w = requestAs(router, "alice", "GET", "/books/"+book.ID.Hex(), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(w.Body.Bytes(), &stored)
+	assert.Nil(t, stored.SeriesID)
+	assert.Nil(t, stored.Volume)
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"tranquil-pages/auth"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShelfController struct {
+	shelfService *services.ShelfService
+}
+
+func NewShelfController(shelfService *services.ShelfService) *ShelfController {
+	return &ShelfController{shelfService: shelfService}
+}
+
+func (sc *ShelfController) SetupShelfRoutes(router *gin.RouterGroup) {
+	router.POST("/shelves", sc.CreateShelf)
+	router.GET("/shelves", sc.ListShelves)
+	router.GET("/shelves/:id", sc.GetShelf)
+	router.PUT("/shelves/:id", sc.UpdateShelf)
+	router.DELETE("/shelves/:id", sc.DeleteShelf)
+	router.GET("/shelves/:id/books", sc.ListShelfBooks)
+}
+
+func (sc *ShelfController) handleError(c *gin.Context, err error) {
+	if respondContextError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, appErrors.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Shelf not found"})
+	case errors.Is(err, appErrors.ErrInvalidShelf),
+		errors.Is(err, appErrors.ErrInvalidQuery),
+		errors.Is(err, appErrors.ErrInvalidID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (sc *ShelfController) CreateShelf(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var shelf models.Shelf
+	if err := c.ShouldBindJSON(&shelf); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scale, err := ratingScale(c)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	shelf.UserID = claims.UserID
+	if err := sc.shelfService.CreateShelf(c.Request.Context(), &shelf, scale); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelf)
+}
+
+func (sc *ShelfController) ListShelves(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	shelves, err := sc.shelfService.GetShelvesByUserID(c.Request.Context(), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	if shelves == nil {
+		shelves = []models.Shelf{}
+	}
+
+	c.JSON(http.StatusOK, shelves)
+}
+
+func (sc *ShelfController) GetShelf(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	shelf, err := sc.shelfService.GetShelf(c.Request.Context(), c.Param("id"), claims.UserID)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelf)
+}
+
+func (sc *ShelfController) UpdateShelf(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	var update models.Shelf
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scale, err := ratingScale(c)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	shelf, err := sc.shelfService.UpdateShelf(c.Request.Context(), c.Param("id"), claims.UserID, &update, scale)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelf)
+}
+
+func (sc *ShelfController) DeleteShelf(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	if err := sc.shelfService.DeleteShelf(c.Request.Context(), c.Param("id"), claims.UserID); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListShelfBooks runs the shelf's query, paged with offset and limit
+func (sc *ShelfController) ListShelfBooks(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+
+	page, err := parsePagination(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scale, err := ratingScale(c)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	books, err := sc.shelfService.GetShelfBooks(c.Request.Context(), c.Param("id"), claims.UserID, scale, page.Offset, page.Limit)
+	if err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	if books == nil {
+		books = []models.Book{}
+	}
+
+	if err := scaleBookList(c, books); err != nil {
+		sc.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, books)
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"tranquil-pages/auth"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"tranquil-pages/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getShelfTestDependencies() (*gin.Engine, *database.TestDatabase) {
+	router := gin.Default()
+
+	testDB, err := database.NewTestDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	bookService := newTestBookService(testDB)
+	shelfService := services.NewShelfService(repository.NewShelfRepository(testDB.Database), bookService)
+
+	api := router.Group("/")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &auth.Claims{UserID: c.GetHeader("X-Test-User")})
+		c.Next()
+	})
+	NewBookController(bookService).SetupBookRoutes(api)
+	NewShelfController(shelfService).SetupShelfRoutes(api)
+
+	return router, testDB
+}
+
+func createShelf(router *gin.Engine, userID, name, query string) (*models.Shelf, int) {
+	w := requestAs(router, userID, "POST", "/shelves", &models.Shelf{Name: name, Query: query})
+	var created *models.Shelf
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	return created, w.Code
+}
+
+// getShelfTitles returns the titles of the books on the shelf, for the given query string
+func getShelfTitles(router *gin.Engine, userID string, shelf *models.Shelf, query string) []string {
+	var books []models.Book
+	_ = json.Unmarshal(requestAs(router, userID, "GET", fmt.Sprintf("/shelves/%s/books%s", shelf.ID.Hex(), query), nil).Body.Bytes(), &books)
+	titles := []string{}
+	for _, book := range books {
+		titles = append(titles, book.Title)
+	}
+	return titles
+}
+
+func TestShelfController_CanCreateUpdateAndDeleteShelves(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+
+	// When
+	shelf, code := createShelf(router, "alice", " Unrated sci-fi ", " tag:sci-fi rating:0 ")
+
+	// Then
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Unrated sci-fi", shelf.Name)
+	assert.Equal(t, "tag:sci-fi rating:0", shelf.Query)
+
+	// When
+	shelf.Query = "tag:sci-fi"
+	w := requestAs(router, "alice", "PUT", "/shelves/"+shelf.ID.Hex(), shelf)
+
+	// Then
+	var shelves []models.Shelf
+	assert.Equal(t, http.StatusOK, w.Code)
+	_ = json.Unmarshal(requestAs(router, "alice", "GET", "/shelves", nil).Body.Bytes(), &shelves)
+	assert.Equal(t, 1, len(shelves))
+	assert.Equal(t, "tag:sci-fi", shelves[0].Query)
+
+	// When
+	w = requestAs(router, "alice", "DELETE", "/shelves/"+shelf.ID.Hex(), nil)
+
+	// Then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "alice", "GET", "/shelves/"+shelf.ID.Hex(), nil).Code)
+	assert.Equal(t, "[]", requestAs(router, "alice", "GET", "/shelves", nil).Body.String())
+}
+
+func TestShelfController_RejectsInvalidShelves(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+
+	// Then
+	_, code := createShelf(router, "alice", " ", "tag:sci-fi")
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = createShelf(router, "alice", "Broken", "status:abandoned")
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = createShelf(router, "alice", "Broken", "(tag:sci-fi")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// And the error explains what is wrong
+	w := requestAs(router, "alice", "POST", "/shelves", &models.Shelf{Name: "Broken", Query: "rating:9"})
+	assert.Contains(t, w.Body.String(), "ratings go from 0 to 5")
+}
+
+func TestShelfController_ShelvesArePrivate(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+	shelf, _ := createShelf(router, "alice", "Everything", "")
+	createBookViaApiAs(router, "alice", makeRandomBook())
+	createBookViaApiAs(router, "bob", makeRandomBook())
+
+	// Then other users can't see, change or run the shelf
+	path := "/shelves/" + shelf.ID.Hex()
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "GET", path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "PUT", path, shelf).Code)
+	assert.Equal(t, http.StatusNotFound, requestAs(router, "bob", "GET", path+"/books", nil).Code)
+	assert.Equal(t, "[]", requestAs(router, "bob", "GET", "/shelves", nil).Body.String())
+
+	// And the shelf only holds its owner's books
+	assert.Equal(t, 1, len(getShelfTitles(router, "alice", shelf, "")))
+}
+
+func TestShelfController_ListsBooksMatchingTheQuery(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+	books := []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", Tags: []string{"Sci-Fi", "classic"}, Status: models.StatusFinished, Rating: 5},
+		{Title: "The Dispossessed", Author: "Ursula K. Le Guin", Tags: []string{"sci-fi"}, Status: models.StatusReading},
+		{Title: "A Wizard of Earthsea", Author: "Ursula K. Le Guin", Tags: []string{"fantasy"}, Rating: 4},
+		{Title: "Middlemarch", Author: "George Eliot", Tags: []string{"classic"}, Status: models.StatusWantToRead},
+	}
+	for _, book := range books {
+		createBookViaApiAs(router, "alice", book)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"", []string{"Middlemarch", "A Wizard of Earthsea", "The Dispossessed", "Dune"}},
+		{"tag:sci-fi rating:0 added:this-year", []string{"The Dispossessed"}},
+		{"tag:sci-fi OR tag:fantasy", []string{"A Wizard of Earthsea", "The Dispossessed", "Dune"}},
+		{`author:"le guin" -status:reading`, []string{"A Wizard of Earthsea"}},
+		{"rating>=4", []string{"A Wizard of Earthsea", "Dune"}},
+		{"status:finished finished:this-year", []string{"Dune"}},
+		{"tag:classic NOT (dune OR rating>4)", []string{"Middlemarch"}},
+		{"title=dune", []string{"Dune"}},
+		{"title=dun", []string{}},
+		{"added<2000", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			shelf, code := createShelf(router, "alice", "Shelf", tt.query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.expected, getShelfTitles(router, "alice", shelf, ""))
+		})
+	}
+}
+
+func TestShelfController_PagesThroughBooks(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+	for _, title := range []string{"First", "Second", "Third"} {
+		createBookViaApiAs(router, "alice", &models.Book{Title: title, Author: "Someone"})
+	}
+	shelf, _ := createShelf(router, "alice", "Everything", "author:someone")
+
+	// Then
+	assert.Equal(t, []string{"Second"}, getShelfTitles(router, "alice", shelf, "?offset=1&limit=1"))
+	assert.Equal(t, []string{"First"}, getShelfTitles(router, "alice", shelf, "?offset=2"))
+	assert.Equal(t, http.StatusBadRequest, requestAs(router, "alice", "GET", "/shelves/"+shelf.ID.Hex()+"/books?limit=-1", nil).Code)
+}
+
+func TestShelfController_MatchesOperatorsInValuesLiterally(t *testing.T) {
+	// Given
+	router, testDB := getShelfTestDependencies()
+	defer testDB.Close()
+	createBookViaApiAs(router, "alice", &models.Book{Title: "Regular", Author: "Someone"})
+	createBookViaApiAs(router, "alice", &models.Book{Title: `{"$ne": null}`, Author: ".*"})
+
+	// Then values that look like filters or patterns only match themselves
+	for _, query := range []string{`title="{\"$ne\": null}"`, `author=".*"`, `author:.*`, `title:"{\"$ne\""`} {
+		t.Run(query, func(t *testing.T) {
+			shelf, code := createShelf(router, "alice", "Injection", query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, []string{`{"$ne": null}`}, getShelfTitles(router, "alice", shelf, ""))
+		})
+	}
+	shelf, _ := createShelf(router, "alice", "Injection", `tag:{"$ne":null}`)
+	assert.Equal(t, []string{}, getShelfTitles(router, "alice", shelf, ""))
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"tranquil-pages/markdown"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseSpoilers reads the spoilers query parameter. Reviews shown to other people hide their spoilers
+// unless the viewer asks for them with spoilers=true.
+func parseSpoilers(c *gin.Context) (markdown.Spoilers, error) {
+	value := c.Query("spoilers")
+	if value == "" {
+		return markdown.HideSpoilers, nil
+	}
+
+	show, err := strconv.ParseBool(value)
+	if err != nil {
+		return markdown.HideSpoilers, fmt.Errorf("spoilers must be true or false")
+	}
+	if show {
+		return markdown.ShowSpoilers, nil
+	}
+	return markdown.HideSpoilers, nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"time"
+	"tranquil-pages/errors"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,9 +15,10 @@ import (
 // DefaultTimeout is the default timeout for database operations
 const DefaultTimeout = 10 * time.Second
 
-// WithTimeout creates a new context with the DefaultTimeout
-func WithTimeout() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), DefaultTimeout)
+// WithTimeout derives the context of a database operation from ctx, usually the context of the request
+// it serves. The operation ends with ctx, and gets at most DefaultTimeout even if ctx has more time left.
+func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, DefaultTimeout)
 }
 
 type Database struct {
@@ -31,15 +33,27 @@ func GetDatabase() (*Database, error) {
 		return globalDB, nil
 	}
 
+	// Servers keeping their data in SQLite have no DB_URL, so this is an error rather than a panic
 	connectionString, ok := os.LookupEnv("DB_URL")
 	if !ok {
-		panic("Environment variable DB_URL is not set")
+		return nil, errors.ErrEnvNotSet("DB_URL")
 	}
 
-	ctx, cancel := WithTimeout()
+	db, err := Connect(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	globalDB = db
+	return globalDB, nil
+}
+
+// Connect opens a connection of its own to the MongoDB server at url. Unlike GetDatabase, it doesn't
+// share the connection, so the caller closes it when done.
+func Connect(url string) (*Database, error) {
+	ctx, cancel := WithTimeout(context.Background())
 	defer cancel()
 
-	clientOptions := options.Client().ApplyURI(connectionString)
+	clientOptions := options.Client().ApplyURI(url)
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
@@ -48,23 +62,38 @@ func GetDatabase() (*Database, error) {
 	// Ping the database to verify connection
 	err = client.Ping(ctx, nil)
 	if err != nil {
+		client.Disconnect(ctx)
 		return nil, err
 	}
 
-	globalDB = &Database{
+	return &Database{
 		client: client,
 		db:     client.Database("tranquil_pages"),
-	}
-	return globalDB, nil
+	}, nil
 }
 
 func (d *Database) GetCollection(name string) *mongo.Collection {
 	return d.db.Collection(name)
 }
 
+// EnsureIndexes creates the given indexes on a collection if they don't exist yet.
+// Failures are logged rather than returned, so a missing index degrades performance or
+// uniqueness guarantees but never prevents startup.
+func (d *Database) EnsureIndexes(collection string, indexes ...mongo.IndexModel) {
+	ctx, cancel := WithTimeout(context.Background())
+	defer cancel()
+
+	if _, err := d.GetCollection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		log.Printf("Error creating indexes on %s: %v", collection, err)
+	}
+}
+
 func (d *Database) Close() error {
+	if d == globalDB {
+		globalDB = nil
+	}
 	if d.client != nil {
-		ctx, cancel := WithTimeout()
+		ctx, cancel := WithTimeout(context.Background())
 		defer cancel()
 		if err := d.client.Disconnect(ctx); err != nil {
 			log.Printf("Error disconnecting from MongoDB: %v", err)
@@ -84,7 +113,7 @@ func NewTestDatabase() (*TestDatabase, error) {
 		connectionString = "mongodb://localhost:27017" // Default localhost
 	}
 
-	ctx, cancel := WithTimeout()
+	ctx, cancel := WithTimeout(context.Background())
 	defer cancel()
 
 	clientOptions := options.Client().ApplyURI(connectionString)
@@ -115,7 +144,7 @@ func NewTestDatabase() (*TestDatabase, error) {
 }
 
 func (td *TestDatabase) Close() error {
-	ctx, cancel := WithTimeout()
+	ctx, cancel := WithTimeout(context.Background())
 	defer cancel()
 
 	if err := td.db.Drop(ctx); err != nil {
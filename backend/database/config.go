@@ -0,0 +1,62 @@
+package database
+
+import (
+	"os"
+	"tranquil-pages/errors"
+)
+
+// Backend names a store the server can keep its data in
+type Backend string
+
+const (
+	BackendMongo    Backend = "mongo"
+	BackendPostgres Backend = "postgres"
+	// BackendSQLite keeps everything in a single file, for running the server without a database server
+	BackendSQLite Backend = "sqlite"
+	// BackendMemory keeps everything in memory, where it is lost when the server stops
+	BackendMemory Backend = "memory"
+)
+
+// Config says where the server keeps its data
+type Config struct {
+	Backend Backend
+	// URL is the connection string of the database server. SQLite and the memory backend don't use one.
+	URL string
+	// Path is the file SQLite keeps the database in
+	Path string
+}
+
+// LoadConfig reads the backend from DB_BACKEND, the connection string of a database server from DB_URL
+// and the file of a SQLite database from DB_PATH. Without DB_BACKEND, the backend is sqlite if only
+// DB_PATH is set, and mongo otherwise.
+func LoadConfig() (Config, error) {
+	config := Config{Backend: BackendMongo}
+
+	_, hasURL := os.LookupEnv("DB_URL")
+	_, hasPath := os.LookupEnv("DB_PATH")
+	if value, ok := os.LookupEnv("DB_BACKEND"); ok && value != "" {
+		config.Backend = Backend(value)
+	} else if hasPath && !hasURL {
+		config.Backend = BackendSQLite
+	}
+
+	switch config.Backend {
+	case BackendMongo, BackendPostgres:
+		url, ok := os.LookupEnv("DB_URL")
+		if !ok {
+			return config, errors.ErrEnvNotSet("DB_URL")
+		}
+		config.URL = url
+	case BackendSQLite:
+		path, ok := os.LookupEnv("DB_PATH")
+		if !ok || path == "" {
+			return config, errors.ErrEnvNotSet("DB_PATH")
+		}
+		config.Path = path
+	case BackendMemory:
+	default:
+		return config, errors.ErrUnknownDatabaseBackend(string(config.Backend))
+	}
+
+	return config, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("DB_BACKEND", "")
+	t.Setenv("DB_URL", "mongodb://localhost:27017")
+	config, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Backend: BackendMongo, URL: "mongodb://localhost:27017"}, config)
+
+	t.Setenv("DB_BACKEND", "postgres")
+	t.Setenv("DB_URL", "postgres://localhost:5432/tranquil_pages")
+	config, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Backend: BackendPostgres, URL: "postgres://localhost:5432/tranquil_pages"}, config)
+
+	t.Setenv("DB_BACKEND", "sqlite")
+	t.Setenv("DB_PATH", "data/tranquil-pages.db")
+	config, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Backend: BackendSQLite, Path: "data/tranquil-pages.db"}, config)
+
+	t.Setenv("DB_BACKEND", "memory")
+	config, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, BackendMemory, config.Backend)
+
+	t.Setenv("DB_BACKEND", "cassandra")
+	_, err = LoadConfig()
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_PathWithoutURLSelectsSQLite(t *testing.T) {
+	t.Setenv("DB_BACKEND", "")
+	t.Setenv("DB_PATH", "data/tranquil-pages.db")
+	t.Setenv("DB_URL", "")
+	os.Unsetenv("DB_URL")
+
+	config, err := LoadConfig()
+
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Backend: BackendSQLite, Path: "data/tranquil-pages.db"}, config)
+}
+
+func TestGetDatabase_WithoutURL(t *testing.T) {
+	t.Setenv("DB_URL", "")
+	os.Unsetenv("DB_URL")
+
+	db, err := GetDatabase()
+
+	assert.Nil(t, db)
+	assert.Error(t, err)
+}
@@ -20,12 +20,12 @@ func TestDatabaseWrite(t *testing.T) {
 
 	// Create test book
 	book := models.Book{
-		Title:     "The Go Programming Language",
-		Author:    "Rob Pike",
-		Comment:   "sus",
-		Rating:    2,
-		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
-		UpdatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		Title:       "The Go Programming Language",
+		Author:      "Rob Pike",
+		Comment:     "sus",
+		RatingScore: 40,
+		CreatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+		UpdatedAt:   primitive.NewDateTimeFromTime(time.Now()),
 	}
 
 	// Insert book
@@ -48,5 +48,5 @@ func TestDatabaseWrite(t *testing.T) {
 	assert.Equal(t, book.Title, retrievedBook.Title)
 	assert.Equal(t, book.Author, retrievedBook.Author)
 	assert.Equal(t, book.Comment, retrievedBook.Comment)
-	assert.Equal(t, book.Rating, retrievedBook.Rating)
+	assert.Equal(t, book.RatingScore, retrievedBook.RatingScore)
 }
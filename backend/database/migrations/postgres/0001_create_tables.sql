@@ -0,0 +1,163 @@
+-- The repositories keep each document as canonical extended JSON in a document column, the way it
+-- would be stored in MongoDB. The other columns copy the fields lookups go by, and the tables named
+-- after a table and a field hold the values of fields that are lists.
+--
+-- seq keeps the order documents were inserted in, which is the order lookups return them in.
+
+CREATE TABLE books (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    owner TEXT,
+    series_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX books_owner ON books (owner);
+CREATE INDEX books_series_id ON books (series_id);
+
+CREATE TABLE books_author_id (
+    id TEXT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (id, value)
+);
+CREATE INDEX books_author_id_value ON books_author_id (value);
+
+CREATE TABLE profiles (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    handle TEXT UNIQUE,
+    share_token TEXT UNIQUE,
+    document JSONB NOT NULL
+);
+
+CREATE TABLE follows (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    follower_id TEXT,
+    followee_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX follows_follower_id ON follows (follower_id);
+
+CREATE TABLE activities (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX activities_user_id ON activities (user_id);
+
+CREATE TABLE groups (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    document JSONB NOT NULL
+);
+
+CREATE TABLE groups_member_id (
+    id TEXT NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (id, value)
+);
+CREATE INDEX groups_member_id_value ON groups_member_id (value);
+
+CREATE TABLE groups_invite_token (
+    id TEXT NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (id, value)
+);
+CREATE INDEX groups_invite_token_value ON groups_invite_token (value);
+
+CREATE TABLE highlights (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    owner TEXT,
+    book_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX highlights_owner ON highlights (owner);
+CREATE INDEX highlights_book_id ON highlights (book_id);
+
+CREATE TABLE metadata_cache (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    document JSONB NOT NULL
+);
+
+CREATE TABLE series (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX series_user_id ON series (user_id);
+
+CREATE TABLE authors (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX authors_user_id ON authors (user_id);
+
+CREATE TABLE journal (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    owner TEXT,
+    book_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX journal_owner ON journal (owner);
+CREATE INDEX journal_book_id ON journal (book_id);
+
+CREATE TABLE shelves (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX shelves_user_id ON shelves (user_id);
+
+CREATE TABLE jobs (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    status TEXT,
+    document JSONB NOT NULL
+);
+CREATE INDEX jobs_status ON jobs (status);
+
+CREATE TABLE job_records (
+    seq BIGINT GENERATED ALWAYS AS IDENTITY,
+    id TEXT PRIMARY KEY,
+    job_id TEXT,
+    entry BIGINT,
+    document JSONB NOT NULL
+);
+CREATE INDEX job_records_job_id_entry ON job_records (job_id, entry);
+
+-- The auth tables are plain tables. Times are Unix milliseconds, the precision MongoDB keeps.
+
+CREATE TABLE oauth_states (
+    id TEXT PRIMARY KEY,
+    state TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL
+);
+CREATE INDEX oauth_states_state ON oauth_states (state);
+
+CREATE TABLE blacklisted_jwts (
+    id TEXT PRIMARY KEY,
+    token TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+CREATE INDEX blacklisted_jwts_token ON blacklisted_jwts (token);
+CREATE INDEX blacklisted_jwts_created_at ON blacklisted_jwts (created_at);
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL,
+    picture TEXT NOT NULL,
+    role TEXT NOT NULL,
+    suspended BOOLEAN NOT NULL,
+    created_at BIGINT NOT NULL,
+    last_login_at BIGINT NOT NULL
+);
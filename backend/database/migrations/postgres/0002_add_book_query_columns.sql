@@ -0,0 +1,38 @@
+-- Shelf queries select books by their status, format, rating, dates and tags, so these get columns of
+-- their own. Dates are milliseconds since the epoch, as Mongo stores them. The columns of books stored
+-- before are filled in from their documents.
+
+ALTER TABLE books
+    ADD COLUMN status TEXT,
+    ADD COLUMN format TEXT,
+    ADD COLUMN rating_score BIGINT,
+    ADD COLUMN created_at BIGINT,
+    ADD COLUMN finished_at BIGINT;
+
+UPDATE books SET
+    status = document->>'status',
+    format = document->>'format',
+    rating_score = COALESCE(
+        (document->'rating_score'->>'$numberInt')::BIGINT,
+        (document->'rating_score'->>'$numberLong')::BIGINT,
+        0
+    ),
+    created_at = (document->'created_at'->'$date'->>'$numberLong')::BIGINT,
+    finished_at = (document->'finished_at'->'$date'->>'$numberLong')::BIGINT;
+
+CREATE INDEX books_owner_status ON books (owner, status);
+CREATE INDEX books_owner_format ON books (owner, format);
+CREATE INDEX books_owner_rating_score ON books (owner, rating_score);
+CREATE INDEX books_owner_created_at ON books (owner, created_at);
+CREATE INDEX books_owner_finished_at ON books (owner, finished_at);
+
+CREATE TABLE books_tag (
+    id TEXT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (id, value)
+);
+CREATE INDEX books_tag_value ON books_tag (value);
+
+INSERT INTO books_tag (id, value)
+SELECT DISTINCT books.id, tag
+FROM books, jsonb_array_elements_text(CASE WHEN jsonb_typeof(books.document->'tags') = 'array' THEN books.document->'tags' END) AS tag;
@@ -0,0 +1,36 @@
+-- Shelf queries select books by their status, format, rating, dates and tags, so these get columns of
+-- their own. Dates are milliseconds since the epoch, as Mongo stores them. The columns of books stored
+-- before are filled in from their documents.
+
+ALTER TABLE books ADD COLUMN status TEXT;
+ALTER TABLE books ADD COLUMN format TEXT;
+ALTER TABLE books ADD COLUMN rating_score INTEGER;
+ALTER TABLE books ADD COLUMN created_at INTEGER;
+ALTER TABLE books ADD COLUMN finished_at INTEGER;
+
+UPDATE books SET
+    status = json_extract(document, '$.status'),
+    format = json_extract(document, '$.format'),
+    rating_score = COALESCE(
+        CAST(json_extract(document, '$.rating_score."$numberInt"') AS INTEGER),
+        CAST(json_extract(document, '$.rating_score."$numberLong"') AS INTEGER),
+        0
+    ),
+    created_at = CAST(json_extract(document, '$.created_at."$date"."$numberLong"') AS INTEGER),
+    finished_at = CAST(json_extract(document, '$.finished_at."$date"."$numberLong"') AS INTEGER);
+
+CREATE INDEX books_owner_status ON books (owner, status);
+CREATE INDEX books_owner_format ON books (owner, format);
+CREATE INDEX books_owner_rating_score ON books (owner, rating_score);
+CREATE INDEX books_owner_created_at ON books (owner, created_at);
+CREATE INDEX books_owner_finished_at ON books (owner, finished_at);
+
+CREATE TABLE books_tag (
+    id TEXT NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    PRIMARY KEY (id, value)
+);
+CREATE INDEX books_tag_value ON books_tag (value);
+
+INSERT OR IGNORE INTO books_tag (id, value)
+SELECT books.id, tag.value FROM books, json_each(books.document, '$.tags') AS tag WHERE tag.type = 'text';
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// migrations holds the SQL scripts that create and change the tables, in a folder per backend. Each
+// script is named after its version, like 0001_create_tables.sql, and runs once, in version order.
+//
+//go:embed migrations
+var migrations embed.FS
+
+// dialect is what sets the SQL databases apart from each other
+type dialect struct {
+	backend Backend
+	// numberedParameters says the driver takes $1, $2, ... rather than ? as parameters
+	numberedParameters bool
+	// lockRows is appended to a query selecting rows to update, so that updates of the same rows wait
+	// for each other
+	lockRows string
+	// lockMigrations runs before a migration, in its transaction, so that servers starting at the same
+	// time don't apply it twice
+	lockMigrations    string
+	isUniqueViolation func(err error) bool
+}
+
+var postgres = &dialect{
+	backend:            BackendPostgres,
+	numberedParameters: true,
+	lockRows:           " FOR UPDATE",
+	lockMigrations:     "LOCK TABLE schema_migrations IN EXCLUSIVE MODE",
+	isUniqueViolation: func(err error) bool {
+		var pgErr *pgconn.PgError
+		return errors.As(err, &pgErr) && pgErr.Code == "23505"
+	},
+}
+
+// SQLDatabase is a connection pool to a SQL database whose tables are laid out by the migrations.
+// Queries are written with ? for parameters and passed through Rebind, so that they run on every backend.
+type SQLDatabase struct {
+	*sql.DB
+	dialect *dialect
+}
+
+// OpenPostgres connects to the Postgres database at url and brings its tables up to date
+func OpenPostgres(url string) (*SQLDatabase, error) {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	return openSQL(stdlib.OpenDB(*config), postgres)
+}
+
+func openSQL(db *sql.DB, dialect *dialect) (*SQLDatabase, error) {
+	ctx, cancel := WithTimeout(context.Background())
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	database := &SQLDatabase{DB: db, dialect: dialect}
+	if err := database.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return database, nil
+}
+
+// Backend returns the kind of database this is
+func (d *SQLDatabase) Backend() Backend {
+	return d.dialect.backend
+}
+
+// Rebind rewrites the ? parameters of a query into the ones the database takes
+func (d *SQLDatabase) Rebind(query string) string {
+	if !d.dialect.numberedParameters {
+		return query
+	}
+
+	var rebound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteRune(r)
+	}
+	return rebound.String()
+}
+
+// LockRows returns the clause that makes a SELECT lock the rows it returns until the end of its
+// transaction, or nothing if the database locks in another way
+func (d *SQLDatabase) LockRows() string {
+	return d.dialect.lockRows
+}
+
+// IsUniqueViolation reports whether err says a row would have had the same value as another in a
+// column that must be unique
+func (d *SQLDatabase) IsUniqueViolation(err error) bool {
+	return d.dialect.isUniqueViolation(err)
+}
+
+// InTransaction runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise
+func (d *SQLDatabase) InTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrate applies the migrations of the backend that haven't been applied yet
+func (d *SQLDatabase) migrate(ctx context.Context) error {
+	_, err := d.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	folder := path.Join("migrations", string(d.dialect.backend))
+	entries, err := migrations.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return fmt.Errorf("migration %s has no version", entry.Name())
+		}
+		script, err := migrations.ReadFile(path.Join(folder, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := d.applyMigration(ctx, version, string(script)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (d *SQLDatabase) applyMigration(ctx context.Context, version int64, script string) error {
+	return d.InTransaction(ctx, func(tx *sql.Tx) error {
+		if d.dialect.lockMigrations != "" {
+			if _, err := tx.ExecContext(ctx, d.dialect.lockMigrations); err != nil {
+				return err
+			}
+		}
+
+		var applied int
+		err := tx.QueryRowContext(ctx, d.Rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), version).Scan(&applied)
+		if err != nil || applied > 0 {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"), version, time.Now().UnixMilli())
+		return err
+	})
+}
+
+// ErrNoTestDatabase is returned when the tests have no server of a kind of database to run against
+var ErrNoTestDatabase = errors.New("no test database configured")
+
+// TestSQLDatabase is an empty SQL database for a test, removed again by Close
+type TestSQLDatabase struct {
+	*SQLDatabase
+	drop func() error
+}
+
+// NewTestPostgresDatabase creates a schema of its own on the Postgres server at TEST_POSTGRES_URL, and
+// returns ErrNoTestDatabase if that isn't set
+func NewTestPostgresDatabase() (*TestSQLDatabase, error) {
+	url, ok := os.LookupEnv("TEST_POSTGRES_URL")
+	if !ok || url == "" {
+		return nil, fmt.Errorf("%w: TEST_POSTGRES_URL is not set", ErrNoTestDatabase)
+	}
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := WithTimeout(context.Background())
+	defer cancel()
+
+	server := stdlib.OpenDB(*config.Copy())
+	schema := "tranquil_pages_test_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	if _, err := server.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		server.Close()
+		return nil, err
+	}
+	drop := func() error {
+		ctx, cancel := WithTimeout(context.Background())
+		defer cancel()
+		defer server.Close()
+		_, err := server.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+		return err
+	}
+
+	config.RuntimeParams["search_path"] = schema
+	db, err := openSQL(stdlib.OpenDB(*config), postgres)
+	if err != nil {
+		drop()
+		return nil, err
+	}
+	return &TestSQLDatabase{SQLDatabase: db, drop: drop}, nil
+}
+
+func (td *TestSQLDatabase) Close() error {
+	if err := td.SQLDatabase.Close(); err != nil {
+		log.Printf("Error closing test database: %v", err)
+	}
+	if err := td.drop(); err != nil {
+		log.Printf("Error dropping test database: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var sqliteDialect = &dialect{
+	backend: BackendSQLite,
+	isUniqueViolation: func(err error) bool {
+		var sqliteErr *sqlite.Error
+		return errors.As(err, &sqliteErr) &&
+			(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+	},
+}
+
+// OpenSQLite opens the SQLite database in the file at path, creating it if it doesn't exist, and brings
+// its tables up to date. The database is kept in write-ahead log mode, which lets requests read while
+// another writes.
+func OpenSQLite(path string) (*SQLDatabase, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{}
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "busy_timeout(10000)")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	// Transactions take the write lock when they begin, so that one reading rows to update them
+	// can't be overtaken by another writer
+	query.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return openSQL(db, sqliteDialect)
+}
+
+// NewTestSQLiteDatabase creates a SQLite database in a temporary folder
+func NewTestSQLiteDatabase() (*TestSQLDatabase, error) {
+	dir, err := os.MkdirTemp("", "tranquil_pages_test_")
+	if err != nil {
+		return nil, err
+	}
+	drop := func() error { return os.RemoveAll(dir) }
+
+	db, err := OpenSQLite(filepath.Join(dir, "tranquil-pages.db"))
+	if err != nil {
+		drop()
+		return nil, err
+	}
+	return &TestSQLDatabase{SQLDatabase: db, drop: drop}, nil
+}
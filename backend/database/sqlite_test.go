@@ -0,0 +1,36 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "data", "tranquil-pages.db")
+	db, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.Close())
+
+	// When
+	db, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Then
+	var journalMode string
+	assert.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+
+	scripts, err := migrations.ReadDir("migrations/sqlite")
+	assert.NoError(t, err)
+	var applied int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied))
+	assert.Equal(t, len(scripts), applied, "reopening the database doesn't apply migrations again")
+}
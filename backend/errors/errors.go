@@ -1,19 +1,109 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 var (
-	ErrNotFound      = errors.New("Record not found")
-	ErrDatabase      = errors.New("Database Error")
-	ErrInvalidID     = errors.New("Invalid ID format")
-	ErrInvalidRating = errors.New("Rating must be between 0 and 5")
-	ErrDuplicateBook = errors.New("A book with this title already exists")
-	ErrConnection    = errors.New("Failed to connect to database")
+	ErrNotFound          = errors.New("Record not found")
+	ErrDatabase          = errors.New("Database Error")
+	ErrCanceled          = errors.New("The request was cancelled")
+	ErrTimeout           = errors.New("The request took too long")
+	ErrInvalidID         = errors.New("Invalid ID format")
+	ErrInvalidRating     = errors.New("Invalid rating")
+	ErrInvalidScale      = errors.New("Rating scale must be one of stars, half_stars or ten")
+	ErrDuplicateBook     = errors.New("A book with this title already exists")
+	ErrConnection        = errors.New("Failed to connect to database")
+	ErrInvalidHandle     = errors.New("Handle must be 3 to 30 characters of lowercase letters, digits, '-' or '_'")
+	ErrHandleTaken       = errors.New("This handle is already taken")
+	ErrHandleMissing     = errors.New("A public profile needs a handle")
+	ErrInvalidVisibility = errors.New("Invalid visibility")
+	ErrInvalidStatus     = errors.New("Status must be one of want_to_read, reading or finished")
+	ErrFollowSelf        = errors.New("You cannot follow yourself")
+	ErrForbidden         = errors.New("You don't have permission to do that")
+	ErrInvalidGroupName  = errors.New("Group name must not be empty")
+	ErrInvalidGroupRole  = errors.New("Role must be one of owner, editor or viewer")
+	ErrInviteExpired     = errors.New("This invite link has expired")
+	ErrLastOwner         = errors.New("A group needs at least one owner")
+	ErrInvalidLoan       = errors.New("A loan needs a borrower and a due date after the lending date")
+	ErrBookOnLoan        = errors.New("This book is already lent out")
+	ErrBookNotOnLoan     = errors.New("This book is not lent out")
+	ErrInvalidHighlight  = errors.New("A highlight needs text and a page that is not negative")
+	ErrInvalidColor      = errors.New("Color must be one of yellow, green, blue, pink or orange")
+	ErrInvalidImportFile = errors.New("The import file could not be read")
+	ErrInvalidISBN       = errors.New("Invalid ISBN")
+	ErrMetadataProvider  = errors.New("The book metadata provider is unavailable")
+	ErrStorage           = errors.New("File storage error")
+	ErrInvalidBlobKey    = errors.New("Invalid file key")
+	ErrInvalidCover      = errors.New("Cover must be a JPEG, PNG or WebP image")
+	ErrInvalidCoverSize  = errors.New("Size must be one of original, small or medium")
+	ErrInvalidSeries     = errors.New("Series must have a name and a total of volumes that is not negative")
+	ErrInvalidVolume     = errors.New("Volume must be a number from 0 to 10000 and needs a series")
+	ErrUnknownSeries     = errors.New("Series not found among your series; only personal books can be in a series")
+	ErrInvalidAuthor     = errors.New("Authors need a name, and a book's authors a role of author, translator or editor")
+	ErrUnknownAuthor     = errors.New("Author not found among your authors; only personal books can link authors")
+	ErrDuplicateAuthor   = errors.New("Another author already has this name or alias")
+	ErrAuthorInUse       = errors.New("Author still has books; merge it into another author instead")
+	ErrInvalidMerge      = errors.New("An author can't be merged into itself")
+	ErrInvalidFormat     = errors.New("Format must be one of hardcover, paperback, ebook or audiobook")
+	ErrInvalidEdition    = errors.New("Edition year, page count and duration can't be negative; audiobooks have a duration and other formats a page count")
+	ErrInvalidProgress   = errors.New("Progress is measured in minutes for audiobooks and in pages for other formats, and can't go past the end")
+	ErrInvalidRead       = errors.New("A read needs a start or finish date and can't finish before it starts")
+	ErrInvalidReview     = errors.New("Review must be at most 50000 characters")
+	ErrInvalidEntry      = errors.New("A journal entry needs text of at most 50000 characters, and its page can't be negative")
+	ErrInvalidDateRange  = errors.New("Dates must look like 2006-01-02 or 2006-01-02T15:04:05Z, and from can't be after to")
+	ErrInvalidShelf      = errors.New("Shelf needs a name")
+	ErrInvalidQuery      = errors.New("Invalid shelf query")
+	ErrReadInProgress    = errors.New("This book already has a read in progress")
+	ErrNoUnreadVolume    = errors.New("There are no unread volumes left in this series")
+	ErrGroupChanged      = errors.New("The group was changed at the same time; please try again")
+	ErrLeaseLost         = errors.New("The job is no longer held by this worker")
 )
 
+// ErrBookNotFound is ErrNotFound for the book that something else belongs to, so that a missing book
+// can be told apart from a missing journal entry of the book
+var ErrBookNotFound = fmt.Errorf("%w: book", ErrNotFound)
+
+// FromContext returns ErrCanceled if err comes from an operation whose context was cancelled, such as a
+// request the client gave up on, ErrTimeout if the context ran out of time, and nil otherwise. Errors
+// FromContext already translated are recognized too.
+func FromContext(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrCanceled):
+		return ErrCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrTimeout):
+		return ErrTimeout
+	}
+	return nil
+}
+
+// StatusClientClosedRequest is the non-standard status for a request the client gave up on
+const StatusClientClosedRequest = 499
+
+// ContextStatus translates err like FromContext and returns the status a request that stopped with it
+// is answered with: StatusClientClosedRequest if the client went away, 503 Service Unavailable if the
+// request ran out of time, and 0 with a nil error otherwise
+func ContextStatus(err error) (int, error) {
+	switch ctxErr := FromContext(err); ctxErr {
+	case ErrCanceled:
+		return StatusClientClosedRequest, ctxErr
+	case ErrTimeout:
+		return http.StatusServiceUnavailable, ctxErr
+	}
+	return 0, nil
+}
+
 func ErrEnvNotSet(varName string) error {
 	return fmt.Errorf("environment variable %s not set", varName)
 }
+
+func ErrUnknownBlobStore(kind string) error {
+	return fmt.Errorf("unknown blob store %q, expected local or azure", kind)
+}
+
+func ErrUnknownDatabaseBackend(backend string) error {
+	return fmt.Errorf("unknown database backend %q, expected mongo, postgres, sqlite or memory", backend)
+}
@@ -0,0 +1,263 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"tranquil-pages/models"
+)
+
+// maxOPFSize bounds the size of a single metadata.opf file in a zip
+const maxOPFSize = 1 << 20
+
+var (
+	zipSignature    = []byte("PK\x03\x04")
+	sqliteSignature = []byte("SQLite format 3\x00")
+)
+
+// ErrNotCalibre is returned for files that are neither a zip of metadata.opf files nor a Calibre metadata.db
+var ErrNotCalibre = fmt.Errorf("%w: not a zip of Calibre metadata.opf files or a Calibre metadata.db", ErrInvalidExport)
+
+// CalibreImporter reads a Calibre library. Calibre rates books from 0 to 10, two for each star, and
+// keeps comments as HTML, which are imported as plain text.
+type CalibreImporter struct{}
+
+// Parse accepts either a zip of the library's metadata.opf files or its metadata.db. The upload is
+// kept in a temporary file while it is read, as neither can be read as a stream.
+func (CalibreImporter) Parse(r io.Reader) ([]BookRecord, error) {
+	file, err := os.CreateTemp("", "calibre-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return nil, err
+	}
+	return ParseCalibreFile(file)
+}
+
+// ParseCalibreFile reads the books of a Calibre library from either a zip of metadata.opf files, as
+// found in each book's folder of the library, or from the library's metadata.db. Any file that can't be
+// read is taken to be an invalid export.
+func ParseCalibreFile(file *os.File) ([]BookRecord, error) {
+	books, err := parseCalibreFile(file)
+	if err != nil && !errors.Is(err, ErrInvalidExport) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExport, err)
+	}
+	return books, err
+}
+
+func parseCalibreFile(file *os.File) ([]BookRecord, error) {
+	header := make([]byte, len(sqliteSignature))
+	n, err := file.ReadAt(header, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, zipSignature):
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return ParseCalibreOPFZip(file, info.Size())
+	case bytes.Equal(header, sqliteSignature):
+		return ParseCalibreDatabase(file.Name())
+	}
+	return nil, ErrNotCalibre
+}
+
+// ParseCalibreOPFZip reads every metadata.opf file in the zip, in the order of their paths. Other files
+// are ignored.
+func ParseCalibreOPFZip(r io.ReaderAt, size int64) ([]BookRecord, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotCalibre, err)
+	}
+
+	var files []*zip.File
+	for _, file := range archive.File {
+		if path.Base(file.Name) == "metadata.opf" && !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: the zip has no metadata.opf files", ErrNotCalibre)
+	}
+	if len(files) > maxRecords {
+		return nil, fmt.Errorf("%w: the zip has more than %d books", ErrInvalidExport, maxRecords)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	books := make([]BookRecord, 0, len(files))
+	for i, file := range files {
+		book, err := readOPFFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		book.Entry = i + 1
+		books = append(books, book)
+	}
+	return books, nil
+}
+
+func readOPFFile(file *zip.File) (BookRecord, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return BookRecord{}, err
+	}
+	defer reader.Close()
+
+	contents, err := io.ReadAll(io.LimitReader(reader, maxOPFSize+1))
+	if err != nil {
+		return BookRecord{}, err
+	}
+	if len(contents) > maxOPFSize {
+		return BookRecord{}, fmt.Errorf("larger than %d bytes", maxOPFSize)
+	}
+	return ParseOPF(bytes.NewReader(contents))
+}
+
+// opfPackage holds the parts of an OPF package document Calibre fills in. Elements and attributes
+// are matched by their local names, so both the dc: and opf: prefixes and unprefixed forms are read.
+type opfPackage struct {
+	Metadata struct {
+		Titles      []string        `xml:"title"`
+		Creators    []opfCreator    `xml:"creator"`
+		Subjects    []string        `xml:"subject"`
+		Description string          `xml:"description"`
+		Identifiers []opfIdentifier `xml:"identifier"`
+		Metas       []opfMeta       `xml:"meta"`
+	} `xml:"metadata"`
+}
+
+type opfCreator struct {
+	Role string `xml:"role,attr"`
+	Name string `xml:",chardata"`
+}
+
+type opfIdentifier struct {
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Name    string `xml:"name,attr"`
+	Content string `xml:"content,attr"`
+}
+
+// ParseOPF reads the metadata.opf file Calibre writes to the folder of each book
+func ParseOPF(r io.Reader) (BookRecord, error) {
+	var pkg opfPackage
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&pkg); err != nil {
+		return BookRecord{}, err
+	}
+	metadata := pkg.Metadata
+
+	var book BookRecord
+	if len(metadata.Titles) > 0 {
+		book.Title = strings.TrimSpace(metadata.Titles[0])
+	}
+	for _, creator := range metadata.Creators {
+		if name := strings.TrimSpace(creator.Name); name != "" && (creator.Role == "" || creator.Role == "aut") {
+			book.Authors = append(book.Authors, name)
+		}
+	}
+	for _, subject := range metadata.Subjects {
+		if tag := strings.TrimSpace(subject); tag != "" {
+			book.Tags = append(book.Tags, tag)
+		}
+	}
+	book.Comment = htmlToText(metadata.Description)
+
+	for _, identifier := range metadata.Identifiers {
+		if isbn, ok := opfISBN(identifier); ok {
+			book.ISBN = isbn
+			break
+		}
+	}
+
+	var seriesIndex string
+	for _, meta := range metadata.Metas {
+		content := strings.TrimSpace(meta.Content)
+		switch meta.Name {
+		case "calibre:series":
+			book.Series = content
+		case "calibre:series_index":
+			seriesIndex = content
+		case "calibre:rating":
+			rating, err := strconv.ParseFloat(content, 64)
+			if err == nil {
+				book.RatingScore = calibreScore(rating)
+			}
+		}
+	}
+	if book.Series != "" {
+		if index, err := strconv.ParseFloat(seriesIndex, 64); err == nil {
+			book.SeriesIndex = &index
+		}
+	}
+	return book, nil
+}
+
+// opfISBN recognises ISBNs given either with an ISBN scheme or as an isbn: URN
+func opfISBN(identifier opfIdentifier) (string, bool) {
+	value := strings.TrimSpace(identifier.Value)
+	if strings.EqualFold(identifier.Scheme, "isbn") {
+		return value, value != ""
+	}
+	lower := strings.ToLower(value)
+	for _, prefix := range []string{"urn:isbn:", "isbn:"} {
+		if strings.HasPrefix(lower, prefix) {
+			value = strings.TrimSpace(value[len(prefix):])
+			return value, value != ""
+		}
+	}
+	return "", false
+}
+
+// calibreScore converts a rating on Calibre's scale of 0 to 10 to a score, rounding to Calibre's
+// whole steps
+func calibreScore(rating float64) int {
+	switch {
+	case rating < 0:
+		return 0
+	case rating > 10:
+		return models.MaxRatingScore
+	}
+	return int(rating+0.5) * models.MaxRatingScore / 10
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6])>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+	blankLines = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// htmlToText turns the HTML Calibre keeps comments in into plain text, with paragraphs separated by
+// blank lines. Book comments are plain text, so this only needs to be readable.
+func htmlToText(source string) string {
+	text := htmlBreaks.ReplaceAllString(source, "\n\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,165 @@
+package importers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// ParseCalibreDatabase reads the books of a Calibre library from its metadata.db, in the order they
+// were added to the library. The database is opened read-only.
+func ParseCalibreDatabase(path string) ([]BookRecord, error) {
+	source := (&url.URL{Scheme: "file", OmitHost: true, Path: path, RawQuery: "mode=ro&immutable=1"}).String()
+	db, err := sql.Open("sqlite", source)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var tables int
+	err = db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name IN ('books', 'authors', 'books_authors_link')`).Scan(&tables)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotCalibre, err)
+	}
+	if tables != 3 {
+		return nil, ErrNotCalibre
+	}
+
+	library := &calibreDatabase{db: db, byID: make(map[int64]*BookRecord)}
+	if err := library.readBooks(); err != nil {
+		return nil, err
+	}
+	if err := library.readDetails(); err != nil {
+		return nil, err
+	}
+	return library.books, nil
+}
+
+// calibreDatabase collects the books of a metadata.db while the tables holding their details are read
+type calibreDatabase struct {
+	db    *sql.DB
+	books []BookRecord
+	byID  map[int64]*BookRecord
+	// seriesIndex holds the index of every book, which Calibre keeps even for books outside a series
+	seriesIndex map[int64]float64
+}
+
+func (c *calibreDatabase) readBooks() error {
+	rows, err := c.db.Query(`SELECT id, title, series_index FROM books ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	c.seriesIndex = make(map[int64]float64)
+	for rows.Next() {
+		var id int64
+		var title sql.NullString
+		var index sql.NullFloat64
+		if err := rows.Scan(&id, &title, &index); err != nil {
+			return err
+		}
+		if len(ids) == maxRecords {
+			return fmt.Errorf("the library has more than %d books", maxRecords)
+		}
+		ids = append(ids, id)
+		c.books = append(c.books, BookRecord{Entry: len(ids), Title: strings.TrimSpace(title.String)})
+		if index.Valid {
+			c.seriesIndex[id] = index.Float64
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Pointers are taken once the slice has stopped growing
+	for i, id := range ids {
+		c.byID[id] = &c.books[i]
+	}
+	return nil
+}
+
+// readDetails reads authors, series, tags, ratings, comments and ISBNs. Older libraries lack some of
+// the tables, which leaves those details empty.
+func (c *calibreDatabase) readDetails() error {
+	details := []struct {
+		table string
+		query string
+		apply func(book *BookRecord, id int64, value string)
+	}{
+		{"authors", `SELECT l.book, a.name FROM books_authors_link l JOIN authors a ON a.id = l.author ORDER BY l.id`,
+			func(book *BookRecord, _ int64, name string) {
+				if name = strings.TrimSpace(name); name != "" {
+					book.Authors = append(book.Authors, name)
+				}
+			}},
+		{"series", `SELECT l.book, s.name FROM books_series_link l JOIN series s ON s.id = l.series`,
+			func(book *BookRecord, id int64, name string) {
+				book.Series = strings.TrimSpace(name)
+				if index, ok := c.seriesIndex[id]; ok && book.Series != "" {
+					book.SeriesIndex = &index
+				}
+			}},
+		{"tags", `SELECT l.book, t.name FROM books_tags_link l JOIN tags t ON t.id = l.tag ORDER BY t.name`,
+			func(book *BookRecord, _ int64, tag string) {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					book.Tags = append(book.Tags, tag)
+				}
+			}},
+		{"ratings", `SELECT l.book, r.rating FROM books_ratings_link l JOIN ratings r ON r.id = l.rating`,
+			func(book *BookRecord, _ int64, rating string) {
+				var value float64
+				if _, err := fmt.Sscan(rating, &value); err == nil {
+					book.RatingScore = calibreScore(value)
+				}
+			}},
+		{"comments", `SELECT book, text FROM comments`,
+			func(book *BookRecord, _ int64, text string) {
+				book.Comment = htmlToText(text)
+			}},
+		{"identifiers", `SELECT book, val FROM identifiers WHERE lower(type) = 'isbn' ORDER BY id`,
+			func(book *BookRecord, _ int64, isbn string) {
+				if book.ISBN == "" {
+					book.ISBN = strings.TrimSpace(isbn)
+				}
+			}},
+	}
+
+	for _, detail := range details {
+		var exists int
+		if err := c.db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, detail.table).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			continue
+		}
+		if err := c.readDetail(detail.query, detail.apply); err != nil {
+			return fmt.Errorf("reading %s: %w", detail.table, err)
+		}
+	}
+	return nil
+}
+
+func (c *calibreDatabase) readDetail(query string, apply func(book *BookRecord, id int64, value string)) error {
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var value sql.NullString
+		if err := rows.Scan(&id, &value); err != nil {
+			return err
+		}
+		if book, ok := c.byID[id]; ok && value.Valid {
+			apply(book, id, value.String)
+		}
+	}
+	return rows.Err()
+}
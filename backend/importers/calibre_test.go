@@ -0,0 +1,156 @@
+package importers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleOPF = `<?xml version='1.0' encoding='utf-8'?>
+<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="uuid_id" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:identifier opf:scheme="calibre" id="calibre_id">12</dc:identifier>
+    <dc:identifier opf:scheme="ISBN">978-0-441-17271-9</dc:identifier>
+    <dc:title>Dune</dc:title>
+    <dc:creator opf:file-as="Herbert, Frank" opf:role="aut">Frank Herbert</dc:creator>
+    <dc:creator opf:role="edt">Some Editor</dc:creator>
+    <dc:description>&lt;div&gt;&lt;p&gt;A desert planet &amp;amp; its &lt;b&gt;spice&lt;/b&gt;.&lt;/p&gt;&lt;p&gt;Second   paragraph&lt;/p&gt;&lt;/div&gt;</dc:description>
+    <dc:subject>Science Fiction</dc:subject>
+    <dc:subject>Classics</dc:subject>
+    <meta name="calibre:series" content="Dune Chronicles"/>
+    <meta name="calibre:series_index" content="1.0"/>
+    <meta name="calibre:rating" content="8"/>
+  </metadata>
+</package>`
+
+func TestParseOPF(t *testing.T) {
+	book, err := ParseOPF(strings.NewReader(sampleOPF))
+
+	index := 1.0
+	assert.NoError(t, err)
+	assert.Equal(t, BookRecord{
+		Title:       "Dune",
+		Authors:     []string{"Frank Herbert"},
+		Series:      "Dune Chronicles",
+		SeriesIndex: &index,
+		Tags:        []string{"Science Fiction", "Classics"},
+		RatingScore: 80,
+		ISBN:        "978-0-441-17271-9",
+		Comment:     "A desert planet & its spice.\n\nSecond paragraph",
+	}, book)
+}
+
+func TestParseOPF_ReadsISBNURNs(t *testing.T) {
+	opf := `<package><metadata><title>Emma</title><identifier>urn:isbn:9780141439587</identifier></metadata></package>`
+
+	book, err := ParseOPF(strings.NewReader(opf))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "9780141439587", book.ISBN)
+	assert.Nil(t, book.SeriesIndex)
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, contents := range files {
+		file, err := archive.Create(name)
+		assert.NoError(t, err)
+		_, _ = file.Write([]byte(contents))
+	}
+	assert.NoError(t, archive.Close())
+	return buffer.Bytes()
+}
+
+func TestParseCalibreOPFZip(t *testing.T) {
+	contents := zipFiles(t, map[string]string{
+		"Library/Jane Austen/Emma (2)/metadata.opf":   `<package><metadata><title>Emma</title></metadata></package>`,
+		"Library/Frank Herbert/Dune (1)/metadata.opf": sampleOPF,
+		"Library/Frank Herbert/Dune (1)/cover.jpg":    "not an opf",
+	})
+
+	books, err := ParseCalibreOPFZip(bytes.NewReader(contents), int64(len(contents)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(books))
+	assert.Equal(t, 1, books[0].Entry)
+	assert.Equal(t, "Dune", books[0].Title)
+	assert.Equal(t, 2, books[1].Entry)
+	assert.Equal(t, "Emma", books[1].Title)
+}
+
+func TestParseCalibreOPFZip_RejectsZipsWithoutMetadata(t *testing.T) {
+	contents := zipFiles(t, map[string]string{"notes.txt": "hello"})
+
+	_, err := ParseCalibreOPFZip(bytes.NewReader(contents), int64(len(contents)))
+
+	assert.ErrorIs(t, err, ErrNotCalibre)
+}
+
+// calibreSchema is the part of the schema of a Calibre metadata.db the importer reads
+const calibreSchema = `
+CREATE TABLE books (id INTEGER PRIMARY KEY, title TEXT NOT NULL DEFAULT 'Unknown', series_index REAL NOT NULL DEFAULT 1.0);
+CREATE TABLE authors (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE books_authors_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, author INTEGER NOT NULL);
+CREATE TABLE series (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE books_series_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, series INTEGER NOT NULL);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE books_tags_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, tag INTEGER NOT NULL);
+CREATE TABLE ratings (id INTEGER PRIMARY KEY, rating INTEGER);
+CREATE TABLE books_ratings_link (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, rating INTEGER NOT NULL);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, text TEXT NOT NULL);
+CREATE TABLE identifiers (id INTEGER PRIMARY KEY, book INTEGER NOT NULL, type TEXT NOT NULL DEFAULT 'isbn', val TEXT NOT NULL);
+
+INSERT INTO books VALUES (1, 'Good Omens', 1.0), (2, 'Dune Messiah', 2.0), (3, 'Untitled', 1.0);
+INSERT INTO authors VALUES (1, 'Terry Pratchett'), (2, 'Neil Gaiman'), (3, 'Frank Herbert');
+INSERT INTO books_authors_link VALUES (1, 1, 1), (2, 1, 2), (3, 2, 3);
+INSERT INTO series VALUES (1, 'Dune Chronicles');
+INSERT INTO books_series_link VALUES (1, 2, 1);
+INSERT INTO tags VALUES (1, 'fantasy'), (2, 'humour'), (3, 'sci-fi');
+INSERT INTO books_tags_link VALUES (1, 1, 2), (2, 1, 1), (3, 2, 3);
+INSERT INTO ratings VALUES (1, 10), (2, 7);
+INSERT INTO books_ratings_link VALUES (1, 1, 1), (2, 2, 2);
+INSERT INTO comments VALUES (1, 2, '<p>The sequel.</p>');
+INSERT INTO identifiers VALUES (1, 2, 'isbn', '9780441172696'), (2, 1, 'amazon', 'B000');
+`
+
+func TestParseCalibreDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+	_, err = db.Exec(calibreSchema)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	books, err := ParseCalibreFile(file)
+
+	volume := 2.0
+	assert.NoError(t, err)
+	assert.Equal(t, []BookRecord{
+		{Entry: 1, Title: "Good Omens", Authors: []string{"Terry Pratchett", "Neil Gaiman"}, Tags: []string{"fantasy", "humour"}, RatingScore: 100},
+		{Entry: 2, Title: "Dune Messiah", Authors: []string{"Frank Herbert"}, Series: "Dune Chronicles", SeriesIndex: &volume,
+			Tags: []string{"sci-fi"}, RatingScore: 70, ISBN: "9780441172696", Comment: "The sequel."},
+		{Entry: 3, Title: "Untitled"},
+	}, books)
+}
+
+func TestParseCalibreFile_RejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("title,author\nDune,Frank Herbert\n"), 0o600))
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	_, err = ParseCalibreFile(file)
+
+	assert.ErrorIs(t, err, ErrNotCalibre)
+}
@@ -0,0 +1,192 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"tranquil-pages/models"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxRecords bounds the number of books read from one import
+const maxRecords = 50000
+
+// BookRecord is a book read from an export of another app, mapped onto the fields of the library
+type BookRecord struct {
+	// Entry is the 1-based position of the book in the import
+	Entry   int
+	Title   string
+	Authors []string
+	// ISBN is as found in the export, and may not be valid
+	ISBN   string
+	Series string
+	// SeriesIndex is only set for books in a series
+	SeriesIndex *float64
+	Tags        []string
+	// RatingScore is the rating converted to the library's scores, or 0 for unrated books
+	RatingScore int
+	Status      models.ReadingStatus
+	Format      models.BookFormat
+	Publisher   string
+	Year        int
+	PageCount   int
+	Review      string
+	Comment     string
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	// Problem explains why the book can't be imported, for entries the export has in a form that
+	// can't be read
+	Problem string
+}
+
+// Importer reads the books of one export format. Adding a format takes an Importer and a route.
+type Importer interface {
+	Parse(r io.Reader) ([]BookRecord, error)
+}
+
+// ErrInvalidExport is returned for files that are not in the format of the importer
+var ErrInvalidExport = errors.New("invalid export")
+
+// column maps the value of a column of a CSV or TSV export onto a record. Empty values are not mapped.
+type column func(record *BookRecord, value string) error
+
+// parseTable reads an export with a header row, such as a CSV file, mapping each column named in
+// columns onto the records. Columns not in columns are ignored. A value that can't be mapped is
+// reported as the record's problem.
+func parseTable(r io.Reader, separator rune, columns map[string]column) ([]BookRecord, error) {
+	text, err := readText(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExport, err)
+	}
+	mappings := make([]column, len(header))
+	hasTitle := false
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		mappings[i] = columns[name]
+		hasTitle = hasTitle || name == "Title"
+	}
+	if !hasTitle {
+		return nil, fmt.Errorf("%w: there is no Title column", ErrInvalidExport)
+	}
+
+	var records []BookRecord
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidExport, err)
+		}
+		if len(records) == maxRecords {
+			return nil, fmt.Errorf("%w: the export has more than %d books", ErrInvalidExport, maxRecords)
+		}
+
+		record := BookRecord{Entry: len(records) + 1}
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if i >= len(mappings) || mappings[i] == nil || value == "" {
+				continue
+			}
+			if err := mappings[i](&record, value); err != nil && record.Problem == "" {
+				record.Problem = fmt.Sprintf("%s: %v", strings.TrimSpace(header[i]), err)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readText reads the whole export as UTF-8, dropping a byte order mark. Exports saved as UTF-16 with
+// a byte order mark, as some spreadsheet programs do, are converted.
+func readText(r io.Reader) (string, error) {
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	var order func([]byte) uint16
+	switch {
+	case bytes.HasPrefix(contents, []byte{0xff, 0xfe}):
+		order = func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 }
+	case bytes.HasPrefix(contents, []byte{0xfe, 0xff}):
+		order = func(b []byte) uint16 { return uint16(b[1]) | uint16(b[0])<<8 }
+	default:
+		contents = bytes.TrimPrefix(contents, []byte("\ufeff"))
+		if !utf8.Valid(contents) {
+			return "", fmt.Errorf("%w: the file is not UTF-8 text", ErrInvalidExport)
+		}
+		return string(contents), nil
+	}
+
+	units := make([]uint16, 0, len(contents)/2)
+	for i := 2; i+1 < len(contents); i += 2 {
+		units = append(units, order(contents[i:i+2]))
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// splitList splits a list such as "sci-fi, classics", dropping empty items
+func splitList(value, separator string) []string {
+	var items []string
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// starScore converts a rating of 0 to 5 stars, which may be fractional, to a score
+func starScore(value string) (int, error) {
+	stars, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(stars) || stars < 0 || stars > 5 {
+		return 0, fmt.Errorf("%q is not a rating from 0 to 5", value)
+	}
+	return int(math.Round(stars * models.MaxRatingScore / 5)), nil
+}
+
+// parseDay reads a date in one of the layouts
+func parseDay(value string, layouts ...string) (*time.Time, error) {
+	for _, layout := range layouts {
+		if day, err := time.Parse(layout, value); err == nil {
+			return &day, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a date", value)
+}
+
+// formatFromName recognises the names exports give book formats, such as "Mass Market Paperback"
+// or "Kindle Edition". Unknown names leave the format unset.
+func formatFromName(name string) models.BookFormat {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "audio"):
+		return models.FormatAudiobook
+	case strings.Contains(name, "ebook"), strings.Contains(name, "e-book"), strings.Contains(name, "kindle"),
+		strings.Contains(name, "digital"):
+		return models.FormatEbook
+	case strings.Contains(name, "hardcover"), strings.Contains(name, "hardback"):
+		return models.FormatHardcover
+	case strings.Contains(name, "paperback"), strings.Contains(name, "softcover"):
+		return models.FormatPaperback
+	}
+	return ""
+}
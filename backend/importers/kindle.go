@@ -0,0 +1,203 @@
+package importers
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ClippingKind string
+
+const (
+	ClippingHighlight ClippingKind = "highlight"
+	ClippingNote      ClippingKind = "note"
+	ClippingBookmark  ClippingKind = "bookmark"
+	// ClippingUnknown marks an entry that couldn't be parsed
+	ClippingUnknown ClippingKind = ""
+)
+
+// Clipping is one entry of a Kindle "My Clippings.txt" file
+type Clipping struct {
+	// Entry is the 1-based position of the clipping in the file
+	Entry    int
+	Title    string
+	Author   string
+	Kind     ClippingKind
+	Page     int
+	Location string
+	// AddedAt is zero if the date couldn't be parsed
+	AddedAt time.Time
+	Text    string
+}
+
+const clippingSeparator = "=========="
+
+// Date formats used by Kindles with English locales, after the "Added on " prefix
+var clippingDateLayouts = []string{
+	"Monday, 2 January 2006 15:04:05",
+	"Monday, January 2, 2006 3:04:05 PM",
+	"Monday, January 2, 2006 15:04:05",
+	"Monday, January 2, 2006, 03:04 PM",
+	"Monday, 2 January 06 15:04:05",
+}
+
+// ParseKindleClippings reads every entry of a "My Clippings.txt" file. An entry looks like
+//
+//	Title (Author)
+//	- Your Highlight on page 12 | Location 170-172 | Added on Sunday, 3 March 2019 10:15:30
+//
+//	Highlighted text
+//	==========
+//
+// Entries that don't follow this layout are returned with Kind ClippingUnknown.
+func ParseKindleClippings(r io.Reader) ([]Clipping, error) {
+	var clippings []Clipping
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.ReplaceAll(scanner.Text(), "\ufeff", ""), "\r")
+		if strings.TrimSpace(line) == clippingSeparator {
+			if clipping, ok := parseClipping(lines); ok {
+				clipping.Entry = len(clippings) + 1
+				clippings = append(clippings, clipping)
+			}
+			lines = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tolerate a missing separator after the last entry
+	if clipping, ok := parseClipping(lines); ok {
+		clipping.Entry = len(clippings) + 1
+		clippings = append(clippings, clipping)
+	}
+	return clippings, nil
+}
+
+// parseClipping parses the lines between two separators. It returns false for blank entries.
+func parseClipping(lines []string) (Clipping, bool) {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return Clipping{}, false
+	}
+
+	var clipping Clipping
+	clipping.Title, clipping.Author = splitTitleAndAuthor(strings.TrimSpace(lines[0]))
+	if len(lines) < 2 || !strings.HasPrefix(strings.TrimSpace(lines[1]), "-") {
+		return clipping, true
+	}
+
+	parseClippingMetadata(&clipping, strings.TrimSpace(lines[1]))
+	clipping.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+	return clipping, true
+}
+
+// splitTitleAndAuthor splits "Title (Author)" on the last balanced pair of parentheses,
+// so that titles which contain parentheses themselves are kept intact.
+func splitTitleAndAuthor(line string) (string, string) {
+	if !strings.HasSuffix(line, ")") {
+		return line, ""
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				title := strings.TrimSpace(line[:i])
+				if title == "" {
+					return line, ""
+				}
+				return title, strings.TrimSpace(line[i+1 : len(line)-1])
+			}
+		}
+	}
+	return line, ""
+}
+
+// parseClippingMetadata reads a line like "- Your Highlight on page 12 | Location 170-172 | Added on ..."
+func parseClippingMetadata(clipping *Clipping, line string) {
+	for i, part := range strings.Split(strings.TrimPrefix(line, "-"), "|") {
+		part = strings.TrimSpace(part)
+		lower := strings.ToLower(part)
+
+		if i == 0 {
+			switch {
+			case strings.Contains(lower, "highlight"):
+				clipping.Kind = ClippingHighlight
+			case strings.Contains(lower, "note"):
+				clipping.Kind = ClippingNote
+			case strings.Contains(lower, "bookmark"):
+				clipping.Kind = ClippingBookmark
+			}
+		}
+
+		if value, ok := valueAfter(part, lower, "location "); ok {
+			clipping.Location = value
+		} else if value, ok := valueAfter(part, lower, "loc. "); ok {
+			clipping.Location = value
+		}
+		if value, ok := valueAfter(part, lower, "page "); ok {
+			page, _ := strconv.Atoi(strings.SplitN(value, "-", 2)[0])
+			clipping.Page = page
+		}
+		if value, ok := valueAfter(part, lower, "added on "); ok {
+			for _, layout := range clippingDateLayouts {
+				if addedAt, err := time.Parse(layout, value); err == nil {
+					clipping.AddedAt = addedAt
+					break
+				}
+			}
+		}
+	}
+}
+
+// valueAfter returns the first word following prefix in part, or everything after it for dates
+func valueAfter(part, lower, prefix string) (string, bool) {
+	index := strings.Index(lower, prefix)
+	if index < 0 {
+		return "", false
+	}
+
+	value := strings.TrimSpace(part[index+len(prefix):])
+	if prefix != "added on " {
+		value = strings.Fields(value + " ")[0]
+	}
+	return value, value != ""
+}
+
+// LocationRange returns the first and last location of a clipping location such as "170-172".
+// Older Kindles abbreviate the end, as in "1234-56", which is expanded to 1256.
+func LocationRange(location string) (int, int, bool) {
+	startText, endText, isRange := strings.Cut(location, "-")
+
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return start, start, true
+	}
+
+	if len(endText) < len(startText) {
+		endText = startText[:len(startText)-len(endText)] + endText
+	}
+	end, err := strconv.Atoi(endText)
+	if err != nil || end < start {
+		return start, start, true
+	}
+	return start, end, true
+}
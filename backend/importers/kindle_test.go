@@ -0,0 +1,116 @@
+package importers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleClippings = "\ufeffThe Great Gatsby (F. Scott Fitzgerald)\r\n" +
+	"- Your Highlight on page 1 | Location 170-172 | Added on Sunday, 3 March 2019 10:15:30\r\n" +
+	"\r\n" +
+	"In my younger and more vulnerable years\r\n" +
+	"==========\r\n" +
+	"\ufeffThe Great Gatsby (F. Scott Fitzgerald)\r\n" +
+	"- Your Note on page 1 | Location 172 | Added on Sunday, 3 March 2019 10:16:00\r\n" +
+	"\r\n" +
+	"Father's advice\r\n" +
+	"==========\r\n" +
+	"Thinking, Fast and Slow (Kahneman, Daniel)\r\n" +
+	"- Your Bookmark on Location 900 | Added on Monday, March 4, 2019 9:00:00 PM\r\n" +
+	"\r\n" +
+	"\r\n" +
+	"==========\r\n" +
+	"Gödel, Escher, Bach (20th Anniversary Edition) (Douglas R. Hofstadter)\r\n" +
+	"- Highlight Loc. 1234-56 | Added on Thursday, March 12, 2015, 10:15 PM\r\n" +
+	"\r\n" +
+	"Meaning lies as much in the mind\r\n" +
+	"of the reader as in the Haiku\r\n" +
+	"==========\r\n" +
+	"garbage without metadata\r\n" +
+	"==========\r\n"
+
+func TestParseKindleClippings(t *testing.T) {
+	clippings, err := ParseKindleClippings(strings.NewReader(sampleClippings))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(clippings))
+
+	tests := []struct {
+		name     string
+		expected Clipping
+	}{
+		{
+			name: "highlight with page and location",
+			expected: Clipping{
+				Entry: 1, Title: "The Great Gatsby", Author: "F. Scott Fitzgerald", Kind: ClippingHighlight,
+				Page: 1, Location: "170-172", AddedAt: time.Date(2019, 3, 3, 10, 15, 30, 0, time.UTC),
+				Text: "In my younger and more vulnerable years",
+			},
+		},
+		{
+			name: "note",
+			expected: Clipping{
+				Entry: 2, Title: "The Great Gatsby", Author: "F. Scott Fitzgerald", Kind: ClippingNote,
+				Page: 1, Location: "172", AddedAt: time.Date(2019, 3, 3, 10, 16, 0, 0, time.UTC),
+				Text: "Father's advice",
+			},
+		},
+		{
+			name: "bookmark with US date",
+			expected: Clipping{
+				Entry: 3, Title: "Thinking, Fast and Slow", Author: "Kahneman, Daniel", Kind: ClippingBookmark,
+				Location: "900", AddedAt: time.Date(2019, 3, 4, 21, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "old format with parentheses in the title and multiline text",
+			expected: Clipping{
+				Entry: 4, Title: "Gödel, Escher, Bach (20th Anniversary Edition)", Author: "Douglas R. Hofstadter", Kind: ClippingHighlight,
+				Location: "1234-56", AddedAt: time.Date(2015, 3, 12, 22, 15, 0, 0, time.UTC),
+				Text: "Meaning lies as much in the mind\nof the reader as in the Haiku",
+			},
+		},
+		{
+			name:     "entry without metadata",
+			expected: Clipping{Entry: 5, Title: "garbage without metadata", Kind: ClippingUnknown},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, clippings[i])
+		})
+	}
+}
+
+func TestParseKindleClippings_EmptyFile(t *testing.T) {
+	clippings, err := ParseKindleClippings(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, clippings)
+}
+
+func TestLocationRange(t *testing.T) {
+	tests := []struct {
+		location   string
+		start, end int
+		ok         bool
+	}{
+		{"170-172", 170, 172, true},
+		{"1234-56", 1234, 1256, true},
+		{"900", 900, 900, true},
+		{"172-100", 172, 172, true},
+		{"", 0, 0, false},
+		{"xii", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			start, end, ok := LocationRange(tt.location)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+		})
+	}
+}
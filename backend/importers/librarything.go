@@ -0,0 +1,385 @@
+package importers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tranquil-pages/models"
+)
+
+// LibraryThingImporter reads the TSV or JSON export of LibraryThing, telling them apart by their first
+// character. Authors are listed there as "Last, First", and are imported as "First Last". The status
+// of a book comes from the Currently reading, To read and Wishlist collections, or from its read dates.
+type LibraryThingImporter struct{}
+
+func (LibraryThingImporter) Parse(r io.Reader) ([]BookRecord, error) {
+	text, err := readText(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return parseLibraryThingJSON([]byte(trimmed))
+	}
+	records, err := parseTable(strings.NewReader(text), '\t', libraryThingColumns)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		finishLibraryThingRecord(&records[i])
+	}
+	return records, nil
+}
+
+// libraryThingDateLayouts are the layouts of the export's dates. Dates may lack the day or month.
+var libraryThingDateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+var (
+	// libraryThingYear finds the year in a publication date such as "c1965" or "1990-09"
+	libraryThingYear = regexp.MustCompile(`(?:^|\D)(\d{4})(?:\D|$)`)
+	// libraryThingSeries reads a series with its position, as in "Dune Chronicles (1)"
+	libraryThingSeries = regexp.MustCompile(`^(.*?)\s*\(([\d.]+)\)$`)
+	// libraryThingPages finds the page count in a field such as "535 p."
+	libraryThingPages = regexp.MustCompile(`\d+`)
+)
+
+var libraryThingColumns = map[string]column{
+	"Title": func(record *BookRecord, value string) error {
+		record.Title = value
+		return nil
+	},
+	"Primary Author": func(record *BookRecord, value string) error {
+		record.Authors = []string{invertName(value)}
+		return nil
+	},
+	"ISBNs": func(record *BookRecord, value string) error {
+		record.ISBN = libraryThingISBN(value)
+		return nil
+	},
+	"ISBN": func(record *BookRecord, value string) error {
+		if record.ISBN == "" {
+			record.ISBN = libraryThingISBN(value)
+		}
+		return nil
+	},
+	"Series": func(record *BookRecord, value string) error {
+		setLibraryThingSeries(record, splitList(value, ";"))
+		return nil
+	},
+	"Publication": func(record *BookRecord, value string) error {
+		record.Publisher = libraryThingPublisher(value)
+		return nil
+	},
+	"Date": func(record *BookRecord, value string) error {
+		record.Year = libraryThingPublicationYear(value)
+		return nil
+	},
+	"Page Count": func(record *BookRecord, value string) error {
+		record.PageCount = libraryThingPageCount(value)
+		return nil
+	},
+	"Media": func(record *BookRecord, value string) error {
+		record.Format = formatFromName(value)
+		return nil
+	},
+	"Rating": func(record *BookRecord, value string) (err error) {
+		record.RatingScore, err = starScore(value)
+		return err
+	},
+	"Review": func(record *BookRecord, value string) error {
+		record.Review = value
+		return nil
+	},
+	"Comment": func(record *BookRecord, value string) error {
+		record.Comment = value
+		return nil
+	},
+	"Tags": func(record *BookRecord, value string) error {
+		record.Tags = splitList(value, ",")
+		return nil
+	},
+	"Collections": func(record *BookRecord, value string) error {
+		record.Status = libraryThingStatus(splitList(value, ","))
+		return nil
+	},
+	"Date Started": func(record *BookRecord, value string) (err error) {
+		record.StartedAt, err = parseDay(value, libraryThingDateLayouts...)
+		return err
+	},
+	"Date Read": func(record *BookRecord, value string) (err error) {
+		record.FinishedAt, err = parseDay(value, libraryThingDateLayouts...)
+		return err
+	},
+}
+
+// finishLibraryThingRecord fills in the status of books that aren't in a reading collection from
+// their read dates
+func finishLibraryThingRecord(record *BookRecord) {
+	if record.Status != "" {
+		return
+	}
+	switch {
+	case record.FinishedAt != nil:
+		record.Status = models.StatusFinished
+	case record.StartedAt != nil:
+		record.Status = models.StatusReading
+	}
+}
+
+func libraryThingStatus(collections []string) models.ReadingStatus {
+	for _, collection := range collections {
+		switch strings.ToLower(collection) {
+		case "currently reading":
+			return models.StatusReading
+		case "to read", "wishlist":
+			return models.StatusWantToRead
+		}
+	}
+	return ""
+}
+
+// invertName turns "Herbert, Frank" into "Frank Herbert". Names with more than one comma are kept.
+func invertName(name string) string {
+	if strings.Count(name, ",") != 1 {
+		return name
+	}
+	last, first, _ := strings.Cut(name, ",")
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
+
+// libraryThingISBN picks the first ISBN of a list such as "[0441172717, 9780441172719]"
+func libraryThingISBN(value string) string {
+	isbns := splitList(strings.Trim(value, "[]"), ",")
+	if len(isbns) == 0 {
+		return ""
+	}
+	return isbns[0]
+}
+
+// libraryThingPublisher reads the publisher from a publication such as "Ace (1990), Edition: Reissue"
+func libraryThingPublisher(publication string) string {
+	publisher, _, _ := strings.Cut(publication, ",")
+	if index := strings.Index(publisher, "("); index >= 0 {
+		publisher = publisher[:index]
+	}
+	return strings.TrimSpace(publisher)
+}
+
+func libraryThingPublicationYear(date string) int {
+	match := libraryThingYear.FindStringSubmatch(date)
+	if match == nil {
+		return 0
+	}
+	year, _ := strconv.Atoi(match[1])
+	return year
+}
+
+func libraryThingPageCount(value string) int {
+	pages, _ := strconv.Atoi(libraryThingPages.FindString(value))
+	return pages
+}
+
+// setLibraryThingSeries takes the first series the book is in
+func setLibraryThingSeries(record *BookRecord, series []string) {
+	if len(series) == 0 {
+		return
+	}
+	record.Series = series[0]
+	if match := libraryThingSeries.FindStringSubmatch(series[0]); match != nil {
+		if index, err := strconv.ParseFloat(match[2], 64); err == nil {
+			record.Series = match[1]
+			record.SeriesIndex = &index
+		}
+	}
+}
+
+// libraryThingBook is a book of the JSON export. Several fields hold either text or numbers, or either
+// a list or an object, depending on the book, and are read by the functions below.
+type libraryThingBook struct {
+	Title         string `json:"title"`
+	PrimaryAuthor string `json:"primaryauthor"`
+	Authors       []struct {
+		Name string `json:"fl"`
+		Role string `json:"role"`
+	} `json:"authors"`
+	ISBN         json.RawMessage `json:"isbn"`
+	OriginalISBN json.RawMessage `json:"originalisbn"`
+	Series       []string        `json:"series"`
+	Publication  string          `json:"publication"`
+	Date         json.RawMessage `json:"date"`
+	Pages        json.RawMessage `json:"pages"`
+	Format       []struct {
+		Text string `json:"text"`
+	} `json:"format"`
+	Rating       json.RawMessage `json:"rating"`
+	Review       string          `json:"review"`
+	Comment      string          `json:"comment"`
+	Tags         []string        `json:"tags"`
+	Collections  []string        `json:"collections"`
+	DateStarted  string          `json:"datestarted"`
+	DateFinished string          `json:"datefinished"`
+}
+
+// parseLibraryThingJSON reads the JSON export, an object of books keyed by their LibraryThing ID.
+// Books are numbered in the order of their IDs.
+func parseLibraryThingJSON(contents []byte) ([]BookRecord, error) {
+	var books map[string]json.RawMessage
+	if err := json.Unmarshal(contents, &books); err != nil {
+		var list []json.RawMessage
+		if listErr := json.Unmarshal(contents, &list); listErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidExport, err)
+		}
+		books = make(map[string]json.RawMessage, len(list))
+		for i, book := range list {
+			books[strconv.Itoa(i)] = book
+		}
+	}
+	if len(books) > maxRecords {
+		return nil, fmt.Errorf("%w: the export has more than %d books", ErrInvalidExport, maxRecords)
+	}
+
+	ids := make([]string, 0, len(books))
+	for id := range books {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+
+	records := make([]BookRecord, 0, len(ids))
+	for _, id := range ids {
+		record := BookRecord{Entry: len(records) + 1}
+		var book libraryThingBook
+		if err := json.Unmarshal(books[id], &book); err != nil {
+			record.Problem = fmt.Sprintf("book %s: %v", id, err)
+		} else {
+			mapLibraryThingBook(&record, &book)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func mapLibraryThingBook(record *BookRecord, book *libraryThingBook) {
+	record.Title = strings.TrimSpace(book.Title)
+	for _, author := range book.Authors {
+		if name := strings.TrimSpace(author.Name); name != "" && (author.Role == "" || strings.EqualFold(author.Role, "author")) {
+			record.Authors = append(record.Authors, name)
+		}
+	}
+	if len(record.Authors) == 0 && strings.TrimSpace(book.PrimaryAuthor) != "" {
+		record.Authors = []string{invertName(strings.TrimSpace(book.PrimaryAuthor))}
+	}
+
+	if isbns := jsonStrings(book.ISBN); len(isbns) > 0 {
+		record.ISBN = isbns[0]
+	} else if isbns := jsonStrings(book.OriginalISBN); len(isbns) > 0 {
+		record.ISBN = isbns[0]
+	}
+	setLibraryThingSeries(record, book.Series)
+	record.Publisher = libraryThingPublisher(book.Publication)
+	if date := jsonStrings(book.Date); len(date) > 0 {
+		record.Year = libraryThingPublicationYear(date[0])
+	}
+	if pages := jsonStrings(book.Pages); len(pages) > 0 {
+		record.PageCount = libraryThingPageCount(pages[0])
+	}
+	if len(book.Format) > 0 {
+		record.Format = formatFromName(book.Format[0].Text)
+	}
+
+	record.Review = strings.TrimSpace(book.Review)
+	record.Comment = strings.TrimSpace(book.Comment)
+	for _, tag := range book.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+	record.Status = libraryThingStatus(book.Collections)
+
+	var problems []string
+	if rating := jsonStrings(book.Rating); len(rating) > 0 && rating[0] != "" {
+		score, err := starScore(rating[0])
+		if err != nil {
+			problems = append(problems, "rating: "+err.Error())
+		}
+		record.RatingScore = score
+	}
+	for _, date := range []struct {
+		name  string
+		value string
+		into  **time.Time
+	}{
+		{"datestarted", book.DateStarted, &record.StartedAt},
+		{"datefinished", book.DateFinished, &record.FinishedAt},
+	} {
+		if value := strings.TrimSpace(date.value); value != "" {
+			day, err := parseDay(value, libraryThingDateLayouts...)
+			if err != nil {
+				problems = append(problems, date.name+": "+err.Error())
+			}
+			*date.into = day
+		}
+	}
+	if len(problems) > 0 {
+		record.Problem = problems[0]
+	}
+	finishLibraryThingRecord(record)
+}
+
+// jsonStrings reads a field that may hold text, a number, a list of them or an object of them, as
+// LibraryThing writes ISBNs as either a list or an object keyed by position
+func jsonStrings(raw json.RawMessage) []string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil
+		}
+		return []string{text}
+	}
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return []string{number.String()}
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		var values []string
+		for _, item := range list {
+			values = append(values, jsonStrings(item)...)
+		}
+		return values
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil {
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var values []string
+		for _, key := range keys {
+			values = append(values, jsonStrings(object[key])...)
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package importers
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tranquil-pages/models"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleLibraryThingTSV = "Book Id\tTitle\tPrimary Author\tPublication\tDate\tReview\tRating\tComment\tMedia\tPage Count\tDate Started\tDate Read\tTags\tCollections\tISBN\tISBNs\tSeries\n" +
+	"101\tDune\tHerbert, Frank\tAce (1990), Edition: Reissue, Paperback, 535 pages\tc1965\tA classic.\t4.5\tBorrowed\tPaperback Book\t535 p.\t2021-01-05\t2021-02-01\tsci-fi, classics\tYour library\t[0441172717]\t0441172717, 9780441172719\tDune Chronicles (1)\n" +
+	"102\tThe Hobbit\tTolkien, J. R. R.\t\t\t\t\t\tAudiobook\t\t\t\t\tCurrently reading\t\t\t\n" +
+	"103\tEmma\tAusten, Jane\t\t\t\t\t\t\t\t\t\t\tTo read, Your library\t\t\t\n"
+
+func TestLibraryThingImporter_ReadsTSV(t *testing.T) {
+	records, err := LibraryThingImporter{}.Parse(strings.NewReader(sampleLibraryThingTSV))
+
+	started := time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	volume := 1.0
+	assert.NoError(t, err)
+	assert.Equal(t, []BookRecord{
+		{
+			Entry: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, ISBN: "0441172717", Series: "Dune Chronicles", SeriesIndex: &volume,
+			Tags: []string{"sci-fi", "classics"}, RatingScore: 90, Status: models.StatusFinished, Format: models.FormatPaperback,
+			Publisher: "Ace", Year: 1965, PageCount: 535, Review: "A classic.", Comment: "Borrowed", StartedAt: &started, FinishedAt: &finished,
+		},
+		{Entry: 2, Title: "The Hobbit", Authors: []string{"J. R. R. Tolkien"}, Status: models.StatusReading, Format: models.FormatAudiobook},
+		{Entry: 3, Title: "Emma", Authors: []string{"Jane Austen"}, Status: models.StatusWantToRead},
+	}, records)
+}
+
+func TestLibraryThingImporter_ReadsUTF16(t *testing.T) {
+	text := "Title\tPrimary Author\nGödel, Escher, Bach\tHofstadter, Douglas R.\n"
+	units := utf16.Encode([]rune(text))
+	contents := []byte{0xff, 0xfe}
+	for _, unit := range units {
+		contents = append(contents, byte(unit), byte(unit>>8))
+	}
+
+	records, err := LibraryThingImporter{}.Parse(strings.NewReader(string(contents)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []BookRecord{{Entry: 1, Title: "Gödel, Escher, Bach", Authors: []string{"Douglas R. Hofstadter"}}}, records)
+}
+
+const sampleLibraryThingJSON = `{
+  "20": {
+    "books_id": "20", "title": "Emma", "primaryauthor": "Austen, Jane",
+    "rating": "bad", "collections": ["Your library"]
+  },
+  "3": {
+    "books_id": "3", "title": "Dune", "primaryauthor": "Herbert, Frank",
+    "authors": [{"lf": "Herbert, Frank", "fl": "Frank Herbert", "role": "Author"}, {"fl": "Someone Else", "role": "Translator"}],
+    "isbn": {"0": "0441172717", "2": "9780441172719"},
+    "series": ["Dune Chronicles (1)"], "publication": "Ace (1990)", "date": 1965, "pages": "535 ",
+    "format": [{"code": "", "text": "Paperback"}], "rating": 5, "review": "Still great",
+    "tags": ["sci-fi"], "collections": ["Your library"], "datefinished": "2021-02"
+  }
+}`
+
+func TestLibraryThingImporter_ReadsJSON(t *testing.T) {
+	records, err := LibraryThingImporter{}.Parse(strings.NewReader(sampleLibraryThingJSON))
+
+	finished := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	volume := 1.0
+	assert.NoError(t, err)
+	assert.Equal(t, []BookRecord{
+		{
+			Entry: 1, Title: "Dune", Authors: []string{"Frank Herbert"}, ISBN: "0441172717", Series: "Dune Chronicles", SeriesIndex: &volume,
+			Tags: []string{"sci-fi"}, RatingScore: 100, Status: models.StatusFinished, Format: models.FormatPaperback,
+			Publisher: "Ace", Year: 1965, PageCount: 535, Review: "Still great", FinishedAt: &finished,
+		},
+		{Entry: 2, Title: "Emma", Authors: []string{"Jane Austen"}, Problem: `rating: "bad" is not a rating from 0 to 5`},
+	}, records)
+}
+
+func TestLibraryThingImporter_RejectsInvalidJSON(t *testing.T) {
+	_, err := LibraryThingImporter{}.Parse(strings.NewReader(`{"1": `))
+
+	assert.ErrorIs(t, err, ErrInvalidExport)
+}
@@ -0,0 +1,85 @@
+package importers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"tranquil-pages/models"
+)
+
+// StoryGraphImporter reads the CSV export of The StoryGraph. Star ratings there go in quarter stars,
+// which the library's scores keep. Books the reader did not finish or paused are imported without a
+// status.
+type StoryGraphImporter struct{}
+
+func (StoryGraphImporter) Parse(r io.Reader) ([]BookRecord, error) {
+	return parseTable(r, ',', storyGraphColumns)
+}
+
+var storyGraphStatuses = map[string]models.ReadingStatus{
+	"read":              models.StatusFinished,
+	"currently-reading": models.StatusReading,
+	"to-read":           models.StatusWantToRead,
+	"did-not-finish":    "",
+	"paused":            "",
+}
+
+// storyGraphDateLayouts are the layouts of the export's dates, such as 2023/05/14
+var storyGraphDateLayouts = []string{"2006/01/02", "2006-01-02"}
+
+var storyGraphColumns = map[string]column{
+	"Title": func(record *BookRecord, value string) error {
+		record.Title = value
+		return nil
+	},
+	"Authors": func(record *BookRecord, value string) error {
+		record.Authors = splitList(value, ",")
+		return nil
+	},
+	"ISBN/UID": func(record *BookRecord, value string) error {
+		record.ISBN = value
+		return nil
+	},
+	"Format": func(record *BookRecord, value string) error {
+		record.Format = formatFromName(value)
+		return nil
+	},
+	"Read Status": func(record *BookRecord, value string) error {
+		status, ok := storyGraphStatuses[strings.ToLower(value)]
+		if !ok {
+			return fmt.Errorf("unknown status %q", value)
+		}
+		record.Status = status
+		return nil
+	},
+	"Last Date Read": func(record *BookRecord, value string) (err error) {
+		record.FinishedAt, err = parseDay(value, storyGraphDateLayouts...)
+		return err
+	},
+	"Dates Read": func(record *BookRecord, value string) error {
+		// Reads are listed as 2023/01/02-2023/01/20, separated by commas; the last one dates the book
+		reads := splitList(value, ",")
+		if len(reads) == 0 {
+			return nil
+		}
+		started, _, _ := strings.Cut(reads[len(reads)-1], "-")
+		day, err := parseDay(strings.TrimSpace(started), storyGraphDateLayouts...)
+		if err != nil {
+			return err
+		}
+		record.StartedAt = day
+		return nil
+	},
+	"Star Rating": func(record *BookRecord, value string) (err error) {
+		record.RatingScore, err = starScore(value)
+		return err
+	},
+	"Review": func(record *BookRecord, value string) error {
+		record.Review = value
+		return nil
+	},
+	"Tags": func(record *BookRecord, value string) error {
+		record.Tags = splitList(value, ",")
+		return nil
+	},
+}
@@ -0,0 +1,43 @@
+package importers
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tranquil-pages/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleStoryGraph = "Title,Authors,Contributors,ISBN/UID,Format,Read Status,Date Added,Last Date Read,Dates Read,Read Count,Moods,Pace,Star Rating,Review,Tags,Owned?\n" +
+	"Good Omens,\"Terry Pratchett, Neil Gaiman\",,9780060853983,paperback,read,2023/01/01,2023/02/10,\"2020/05/01-2020/05/20, 2023/02/01-2023/02/10\",2,funny,fast,4.25,\"Still funny, \"\"really\"\".\",\"fantasy, humour\",Yes\n" +
+	"Piranesi,Susanna Clarke,,9781635575637,digital,currently-reading,2024/03/01,,,0,,,,,,No\n" +
+	"Middlemarch,George Eliot,,B000FC1PJI,audio,did-not-finish,2024/03/01,,,0,,,,,,No\n" +
+	"Broken,Someone,,,hardcover,read,2024/03/01,,,1,,,seven,,,No\n"
+
+func TestStoryGraphImporter(t *testing.T) {
+	records, err := StoryGraphImporter{}.Parse(strings.NewReader(sampleStoryGraph))
+
+	started := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	finished := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, []BookRecord{
+		{
+			Entry: 1, Title: "Good Omens", Authors: []string{"Terry Pratchett", "Neil Gaiman"}, ISBN: "9780060853983",
+			Format: models.FormatPaperback, Status: models.StatusFinished, StartedAt: &started, FinishedAt: &finished,
+			RatingScore: 85, Review: `Still funny, "really".`, Tags: []string{"fantasy", "humour"},
+		},
+		{Entry: 2, Title: "Piranesi", Authors: []string{"Susanna Clarke"}, ISBN: "9781635575637", Format: models.FormatEbook, Status: models.StatusReading},
+		{Entry: 3, Title: "Middlemarch", Authors: []string{"George Eliot"}, ISBN: "B000FC1PJI", Format: models.FormatAudiobook},
+		{
+			Entry: 4, Title: "Broken", Authors: []string{"Someone"}, Format: models.FormatHardcover, Status: models.StatusFinished,
+			Problem: `Star Rating: "seven" is not a rating from 0 to 5`,
+		},
+	}, records)
+}
+
+func TestStoryGraphImporter_RejectsOtherFiles(t *testing.T) {
+	_, err := StoryGraphImporter{}.Parse(strings.NewReader("Name,Author\nDune,Frank Herbert\n"))
+
+	assert.ErrorIs(t, err, ErrInvalidExport)
+}
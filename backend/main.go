@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"tranquil-pages/auth"
+	"tranquil-pages/blobstore"
 	"tranquil-pages/controllers"
 	"tranquil-pages/database"
+	"tranquil-pages/metadata"
 	"tranquil-pages/repository"
 	"tranquil-pages/services"
 
@@ -13,24 +17,96 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func setupRoutes(db *database.Database) *gin.Engine {
+// inMemory runs the server without a database, which is handy for trying it out and for development
+var inMemory = flag.Bool("memory", false, "keep all data in memory instead of the database DB_BACKEND names; it is lost when the server stops")
+
+func setupRoutes(repos *repository.Repositories, authRepos *auth.Repositories) *gin.Engine {
 	// Initialize repositories
-	bookRepo := repository.NewBookRepository(db)
+	bookRepo := repos.Books
+	profileRepo := repos.Profiles
+	followRepo := repos.Follows
+	activityRepo := repos.Activities
+	groupRepo := repos.Groups
+	highlightRepo := repos.Highlights
+	metadataCacheRepo := repos.MetadataCache
+	seriesRepo := repos.Series
+	authorRepo := repos.Authors
+	journalRepo := repos.Journal
+	shelfRepo := repos.Shelves
+	jobRepo := repos.Jobs
+
+	blobs, err := blobstore.NewBlobStoreFromEnv()
+	if err != nil {
+		log.Fatal("Failed to initialize blob store:", err)
+	}
+
+	jobConfig, err := services.LoadJobConfig()
+	if err != nil {
+		log.Fatal("Failed to load job config:", err)
+	}
 
 	// Initialize services
-	bookService := services.NewBookService(bookRepo)
+	jobService := services.NewJobService(jobRepo, jobConfig)
+	permissions := services.NewPermissionEvaluator(groupRepo)
+	bookService := services.NewBookService(bookRepo, activityRepo, highlightRepo, permissions)
+	groupService := services.NewGroupService(groupRepo, permissions)
+	profileService := services.NewProfileService(profileRepo, bookRepo)
+	feedService := services.NewFeedService(followRepo, activityRepo, profileRepo, bookRepo)
+	highlightService := services.NewHighlightService(highlightRepo, bookService)
+	importService := services.NewImportService(bookService, highlightRepo, seriesRepo, jobService)
+	coverService := services.NewCoverService(bookService, blobs)
+	seriesService := services.NewSeriesService(seriesRepo, bookService)
+	authorService := services.NewAuthorService(authorRepo, bookService)
+	journalService := services.NewJournalService(journalRepo, bookService)
+	shelfService := services.NewShelfService(shelfRepo, bookService)
+	lookupService := services.NewLookupService(metadata.NewOpenLibraryProvider(metadata.LoadOpenLibraryURL()), metadataCacheRepo)
+
+	// Ratings saved before rating scales existed are converted before anything reads them
+	if err := bookService.MigrateRatings(context.Background()); err != nil {
+		log.Fatal("Failed to migrate ratings:", err)
+	}
+	// Jobs interrupted by the last restart resume once every service has registered its jobs
+	jobService.Start()
 
 	// Initialize controllers
 	bookController := controllers.NewBookController(bookService)
+	profileController := controllers.NewProfileController(profileService)
+	feedController := controllers.NewFeedController(feedService)
+	groupController := controllers.NewGroupController(groupService, bookService)
+	highlightController := controllers.NewHighlightController(highlightService)
+	importController := controllers.NewImportController(importService)
+	lookupController := controllers.NewLookupController(lookupService)
+	coverController := controllers.NewCoverController(coverService)
+	seriesController := controllers.NewSeriesController(seriesService)
+	authorController := controllers.NewAuthorController(authorService)
+	journalController := controllers.NewJournalController(journalService)
+	shelfController := controllers.NewShelfController(shelfService)
+	jobController := controllers.NewJobController(jobService)
 
 	// Initialize OAuth
 	if err := auth.InitOAuthConfig(); err != nil {
 		log.Fatal("Failed to initialize OAuth config:", err)
 	}
-	stateRepo := auth.NewOAuthStateRepository(db)
-	tokenRepo := auth.NewTokenRepository(db)
-	authService := auth.NewAuthService(auth.OAuthConfig, stateRepo, tokenRepo)
+	stateRepo := authRepos.States
+	tokenCacheConfig, err := auth.LoadTokenCacheConfig()
+	if err != nil {
+		log.Fatal("Failed to load token cache config:", err)
+	}
+	tokenRepo := auth.NewCachedTokenRepository(authRepos.Tokens, tokenCacheConfig)
+	tokenRepo.Start()
+	userCacheConfig, err := auth.LoadUserCacheConfig()
+	if err != nil {
+		log.Fatal("Failed to load user cache config:", err)
+	}
+	userRepo := auth.NewCachedUserRepository(authRepos.Users, userCacheConfig)
+	authService := auth.NewAuthService(auth.OAuthConfig, stateRepo, tokenRepo, userRepo)
 	authController := auth.NewAuthController(authService)
+	adminController := controllers.NewAdminController(authService, bookService, authorService)
+
+	requestTimeout, err := controllers.LoadRequestTimeout()
+	if err != nil {
+		log.Fatal("Failed to load request timeout:", err)
+	}
 
 	// Setup router
 	router := gin.Default()
@@ -45,28 +121,80 @@ func setupRoutes(db *database.Database) *gin.Engine {
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
 		AllowCredentials: true,
-	}))
+	}), controllers.RequestTimeoutMiddleware(requestTimeout))
 
 	// Setup public routes
 	authController.SetupAuthRoutes(router)
+	profileController.SetupPublicRoutes(router.Group("/public"))
 
 	// Setup user api routes
 	userApi := router.Group("/api")
-	userApi.Use(auth.AuthMiddleware(authService))
+	userApi.Use(auth.AuthMiddleware(authService), controllers.RatingScaleMiddleware(profileService))
 	bookController.SetupBookRoutes(userApi)
+	profileController.SetupProfileRoutes(userApi)
+	feedController.SetupFeedRoutes(userApi)
+	groupController.SetupGroupRoutes(userApi)
+	highlightController.SetupHighlightRoutes(userApi)
+	importController.SetupImportRoutes(userApi)
+	lookupController.SetupLookupRoutes(userApi)
+	coverController.SetupCoverRoutes(userApi)
+	seriesController.SetupSeriesRoutes(userApi)
+	authorController.SetupAuthorRoutes(userApi)
+	journalController.SetupJournalRoutes(userApi)
+	shelfController.SetupShelfRoutes(userApi)
+	jobController.SetupJobRoutes(userApi)
+
+	// Setup admin routes
+	adminApi := router.Group("/admin")
+	adminApi.Use(auth.AuthMiddleware(authService), auth.RequireRole(auth.RoleAdmin))
+	adminController.SetupAdminRoutes(adminApi)
 
 	return router
 }
 
 func main() {
-	// Initialize database
-	db, err := database.GetDatabase()
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	flag.Parse()
+
+	// -memory needs no database config at all, so it works without an .env file
+	config := database.Config{Backend: database.BackendMemory}
+	if !*inMemory {
+		var err error
+		if config, err = database.LoadConfig(); err != nil {
+			log.Fatal("Failed to load database config:", err)
+		}
+	}
+
+	var repos *repository.Repositories
+	var authRepos *auth.Repositories
+	switch config.Backend {
+	case database.BackendMemory:
+		log.Println("Keeping all data in memory; it is lost when the server stops")
+		repos, authRepos = repository.NewMemoryRepositories(), auth.NewMemoryRepositories()
+	case database.BackendPostgres:
+		db, err := database.OpenPostgres(config.URL)
+		if err != nil {
+			log.Fatal("Failed to connect to database:", err)
+		}
+		defer db.Close()
+		repos, authRepos = repository.NewSQLRepositories(db), auth.NewSQLRepositories(db)
+	case database.BackendSQLite:
+		db, err := database.OpenSQLite(config.Path)
+		if err != nil {
+			log.Fatal("Failed to open database:", err)
+		}
+		defer db.Close()
+		repos, authRepos = repository.NewSQLRepositories(db), auth.NewSQLRepositories(db)
+	default:
+		// Initialize database
+		db, err := database.GetDatabase()
+		if err != nil {
+			log.Fatal("Failed to connect to database:", err)
+		}
+		defer db.Close()
+		repos, authRepos = repository.NewMongoRepositories(db), auth.NewMongoRepositories(db)
 	}
-	defer db.Close()
 
-	router := setupRoutes(db)
+	router := setupRoutes(repos, authRepos)
 
 	// Start server
 	if err := router.Run(":8080"); err != nil {
@@ -5,21 +5,14 @@ import (
 	"net/http/httptest"
 	"testing"
 	"tranquil-pages/auth"
-	"tranquil-pages/database"
+	"tranquil-pages/repository"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestBookRoutesProtected(t *testing.T) {
-	// Initialize test database
-	db, err := database.GetDatabase()
-	if err != nil {
-		t.Fatal("Failed to connect to database:", err)
-	}
-	defer db.Close()
-
-	// Get the actual router setup from main
-	router := setupRoutes(db)
+	// Get the actual router setup from main, on the in-memory store so no database is needed
+	router := setupRoutes(repository.NewMemoryRepositories(), auth.NewMemoryRepositories())
 
 	// Test request without JWT
 	t.Run("without JWT", func(t *testing.T) {
@@ -45,3 +38,36 @@ func TestBookRoutesProtected(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
+
+func TestAdminRoutesRequireAdminRole(t *testing.T) {
+	authRepos := auth.NewMemoryRepositories()
+	router := setupRoutes(repository.NewMemoryRepositories(), authRepos)
+	testUser := &auth.GoogleUserInfo{
+		ID:            "123",
+		Email:         "test@example.com",
+		VerifiedEmail: true,
+	}
+	// Admin access follows the role stored with the account
+	_, err := authRepos.Users.RecordLogin(t.Context(), &auth.User{ID: testUser.ID, Email: testUser.Email}, true)
+	assert.NoError(t, err)
+
+	t.Run("as regular user", func(t *testing.T) {
+		token, _ := auth.GenerateToken(&auth.GoogleUserInfo{ID: "456", Email: "other@example.com", VerifiedEmail: true})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("as admin", func(t *testing.T) {
+		token, _ := auth.GenerateTokenWithRole(testUser, auth.RoleAdmin)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/stats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,144 @@
+// Package markdown renders the Markdown users write in reviews to HTML that is safe to show to others
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// Spoilers selects what happens to spoiler blocks when rendering
+type Spoilers int
+
+const (
+	// ShowSpoilers renders spoiler blocks as collapsed <details> elements
+	ShowSpoilers Spoilers = iota
+	// HideSpoilers replaces spoiler blocks with a note that a spoiler was left out
+	HideSpoilers
+)
+
+const (
+	spoilerOpen  = ":::spoiler"
+	spoilerClose = ":::"
+
+	defaultSpoilerTitle = "Spoiler"
+	hiddenSpoiler       = `<p class="spoiler-hidden">Spoiler hidden</p>`
+)
+
+// The renderer leaves out raw HTML and drops links with dangerous schemes such as javascript:.
+// The policy then sanitizes the result again, so a gap in either is covered by the other.
+var (
+	renderer = goldmark.New(goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify))
+	policy   = newPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowElements("details", "summary")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^spoiler$`)).OnElements("details")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^spoiler-hidden$`)).OnElements("p")
+	return policy
+}
+
+// Render converts Markdown to sanitized HTML. A spoiler block starts with a line ":::spoiler", optionally
+// followed by a title, and ends with a line ":::". Spoiler blocks may be nested; an unclosed one runs to
+// the end of the text.
+func Render(source string, spoilers Spoilers) string {
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+
+	var output, chunk bytes.Buffer
+	depth := 0
+	flush := func() {
+		if depth == 0 || spoilers == ShowSpoilers {
+			// Markdown the renderer can't handle is written out as it is, and sanitized with the rest
+			if err := renderer.Convert(chunk.Bytes(), &output); err != nil {
+				output.WriteString(html.EscapeString(chunk.String()))
+			}
+		}
+		chunk.Reset()
+	}
+
+	var fence string
+	for _, line := range strings.SplitAfter(source, "\n") {
+		title, isOpen, isClose := "", false, false
+		if fence == "" {
+			title, isOpen = spoilerStart(line)
+			isClose = !isOpen && depth > 0 && isMarker(line) && strings.TrimSpace(line) == spoilerClose
+		}
+
+		switch {
+		case isOpen:
+			flush()
+			if spoilers == ShowSpoilers {
+				output.WriteString(`<details class="spoiler"><summary>` + html.EscapeString(title) + "</summary>\n")
+			} else if depth == 0 {
+				output.WriteString(hiddenSpoiler + "\n")
+			}
+			depth++
+		case isClose:
+			flush()
+			depth--
+			if spoilers == ShowSpoilers {
+				output.WriteString("</details>\n")
+			}
+		default:
+			fence = nextFence(fence, line)
+			chunk.WriteString(line)
+		}
+	}
+	flush()
+	if spoilers == ShowSpoilers {
+		output.WriteString(strings.Repeat("</details>\n", depth))
+	}
+
+	return policy.Sanitize(output.String())
+}
+
+// isMarker reports whether the line may hold a block marker. Lines indented by four or more spaces are code.
+func isMarker(line string) bool {
+	return !strings.HasPrefix(strings.ReplaceAll(line, "\t", "    "), "    ")
+}
+
+// spoilerStart reports whether the line opens a spoiler block, and returns its title
+func spoilerStart(line string) (string, bool) {
+	if !isMarker(line) {
+		return "", false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), spoilerOpen)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	if title := strings.TrimSpace(rest); title != "" {
+		return title, true
+	}
+	return defaultSpoilerTitle, true
+}
+
+// nextFence tracks fenced code blocks, in which spoiler markers are plain text. It returns the fence
+// of the code block that is open after the line, or "" outside code blocks.
+func nextFence(fence, line string) string {
+	if !isMarker(line) {
+		return fence
+	}
+	trimmed := strings.TrimSpace(line)
+	if fence == "" {
+		for _, char := range []string{"`", "~"} {
+			if length := len(trimmed) - len(strings.TrimLeft(trimmed, char)); length >= 3 {
+				return strings.Repeat(char, length)
+			}
+		}
+		return ""
+	}
+	// A closing fence is at least as long as the opening one and has nothing after it
+	if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+		return ""
+	}
+	return fence
+}
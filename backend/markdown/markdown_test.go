@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_FormatsMarkdown(t *testing.T) {
+	rendered := Render("# Verdict\n\nA *slow* start, but:\n\n- great characters\n- a **huge** ending\n", ShowSpoilers)
+
+	assert.Contains(t, rendered, "<h1>Verdict</h1>")
+	assert.Contains(t, rendered, "<em>slow</em>")
+	assert.Contains(t, rendered, "<li>great characters</li>")
+	assert.Contains(t, rendered, "<strong>huge</strong>")
+}
+
+func TestRender_RemovesUnsafeContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		forbidden string
+	}{
+		{"script tag", "Nice <script>alert(1)</script> book", "<script"},
+		{"raw html block", "<div onclick=\"alert(1)\">click</div>", "onclick"},
+		{"inline event handler", "<img src=x onerror=alert(1)>", "onerror"},
+		{"javascript link", "[click](javascript:alert(1))", "javascript:"},
+		{"encoded javascript link", "[click](jAvAsCrIpT&#58;alert(1))", "alert(1)\""},
+		{"javascript autolink", "<javascript:alert(1)>", "href=\"javascript"},
+		{"data link", "[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)", "data:"},
+		{"spoiler title", ":::spoiler <script>alert(1)</script>\nhidden\n:::", "<script"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered := Render(tt.source, ShowSpoilers)
+			assert.NotContains(t, rendered, tt.forbidden)
+		})
+	}
+}
+
+func TestRender_KeepsSafeLinks(t *testing.T) {
+	rendered := Render("[site](https://example.com/review)", ShowSpoilers)
+
+	assert.Contains(t, rendered, `href="https://example.com/review"`)
+}
+
+func TestRender_SpoilersBecomeCollapsibleBlocks(t *testing.T) {
+	source := "Before\n\n:::spoiler Ending\nThe butler **did** it.\n:::\n\nAfter\n"
+
+	rendered := Render(source, ShowSpoilers)
+
+	assert.Contains(t, rendered, `<details class="spoiler"><summary>Ending</summary>`)
+	assert.Contains(t, rendered, "The butler <strong>did</strong> it.")
+	assert.Contains(t, rendered, "</details>")
+	assert.Contains(t, rendered, "<p>After</p>")
+}
+
+func TestRender_SpoilersWithoutTitleOrEnd(t *testing.T) {
+	rendered := Render(":::spoiler\nEveryone dies", ShowSpoilers)
+
+	assert.Contains(t, rendered, "<summary>Spoiler</summary>")
+	assert.Contains(t, rendered, "Everyone dies")
+	assert.Contains(t, rendered, "</details>")
+}
+
+func TestRender_HidesSpoilers(t *testing.T) {
+	source := "Before\n\n:::spoiler Ending\nThe butler did it.\n\n:::spoiler Epilogue\nHe got away.\n:::\n:::\n\nAfter\n"
+
+	rendered := Render(source, HideSpoilers)
+
+	assert.NotContains(t, rendered, "butler")
+	assert.NotContains(t, rendered, "got away")
+	assert.NotContains(t, rendered, "<details")
+	assert.Contains(t, rendered, `<p class="spoiler-hidden">Spoiler hidden</p>`)
+	assert.Contains(t, rendered, "<p>Before</p>")
+	assert.Contains(t, rendered, "<p>After</p>")
+}
+
+func TestRender_SpoilerMarkersInCodeAreText(t *testing.T) {
+	source := "```\n:::spoiler\nnot a spoiler\n:::\n```\n\n    :::spoiler\n"
+
+	rendered := Render(source, HideSpoilers)
+
+	assert.NotContains(t, rendered, "Spoiler hidden")
+	assert.Contains(t, rendered, "not a spoiler")
+}
+
+func TestRender_EmptySource(t *testing.T) {
+	assert.Equal(t, "", Render("  \n", ShowSpoilers))
+}
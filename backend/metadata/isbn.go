@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"strings"
+	appErrors "tranquil-pages/errors"
+)
+
+// ParseISBN accepts an ISBN-10 or ISBN-13, with or without hyphens and spaces, and returns both
+// forms after checking the check digit. isbn10 is empty for ISBN-13s in the 979 range, which have
+// no ISBN-10 equivalent.
+func ParseISBN(value string) (isbn10, isbn13 string, err error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(value)))
+
+	switch len(normalized) {
+	case 10:
+		if !validISBN10(normalized) {
+			return "", "", appErrors.ErrInvalidISBN
+		}
+		return normalized, isbn10To13(normalized), nil
+	case 13:
+		if !validISBN13(normalized) {
+			return "", "", appErrors.ErrInvalidISBN
+		}
+		return isbn13To10(normalized), normalized, nil
+	}
+	return "", "", appErrors.ErrInvalidISBN
+}
+
+func validISBN10(isbn string) bool {
+	sum := 0
+	for i, r := range isbn {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case r == 'X' && i == 9:
+			digit = 10
+		default:
+			return false
+		}
+		sum += digit * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func validISBN13(isbn string) bool {
+	if !strings.HasPrefix(isbn, "978") && !strings.HasPrefix(isbn, "979") {
+		return false
+	}
+	for _, r := range isbn {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return isbn13CheckDigit(isbn[:12]) == isbn[12]
+}
+
+func isbn13CheckDigit(first12 string) byte {
+	sum := 0
+	for i, r := range first12 {
+		digit := int(r - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isbn10CheckDigit(first9 string) byte {
+	sum := 0
+	for i, r := range first9 {
+		sum += int(r-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+func isbn10To13(isbn10 string) string {
+	first12 := "978" + isbn10[:9]
+	return first12 + string(isbn13CheckDigit(first12))
+}
+
+func isbn13To10(isbn13 string) string {
+	if !strings.HasPrefix(isbn13, "978") {
+		return ""
+	}
+	first9 := isbn13[3:12]
+	return first9 + string(isbn10CheckDigit(first9))
+}
@@ -0,0 +1,39 @@
+package metadata
+
+import (
+	"testing"
+	appErrors "tranquil-pages/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseISBN(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		expectedISBN10 string
+		expectedISBN13 string
+		expectedError  error
+	}{
+		{"ISBN-10", "0140328726", "0140328726", "9780140328721", nil},
+		{"ISBN-10 with hyphens and spaces", " 0-14-032872-6 ", "0140328726", "9780140328721", nil},
+		{"ISBN-10 with X check digit", "080442957x", "080442957X", "9780804429573", nil},
+		{"ISBN-13", "978-0-14-032872-1", "0140328726", "9780140328721", nil},
+		{"ISBN-13 in the 979 range", "9791032305690", "", "9791032305690", nil},
+		{"wrong ISBN-10 check digit", "0140328727", "", "", appErrors.ErrInvalidISBN},
+		{"wrong ISBN-13 check digit", "9780140328722", "", "", appErrors.ErrInvalidISBN},
+		{"ISBN-13 with an unknown prefix", "9770140328721", "", "", appErrors.ErrInvalidISBN},
+		{"X in the middle", "01403X8726", "", "", appErrors.ErrInvalidISBN},
+		{"wrong length", "014032872", "", "", appErrors.ErrInvalidISBN},
+		{"empty", "", "", "", appErrors.ErrInvalidISBN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isbn10, isbn13, err := ParseISBN(tt.value)
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedISBN10, isbn10)
+			assert.Equal(t, tt.expectedISBN13, isbn13)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+)
+
+const (
+	defaultOpenLibraryURL = "https://openlibrary.org"
+	openLibraryTimeout    = 10 * time.Second
+)
+
+// OpenLibraryProvider looks books up through the Open Library books API
+type OpenLibraryProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOpenLibraryProvider(baseURL string) *OpenLibraryProvider {
+	return &OpenLibraryProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: openLibraryTimeout},
+	}
+}
+
+// LoadOpenLibraryURL reads the Open Library base URL from OPEN_LIBRARY_URL, defaulting to the public instance
+func LoadOpenLibraryURL() string {
+	if value, ok := os.LookupEnv("OPEN_LIBRARY_URL"); ok && value != "" {
+		return value
+	}
+	return defaultOpenLibraryURL
+}
+
+type openLibraryNamed struct {
+	Name string `json:"name"`
+}
+
+type openLibraryBook struct {
+	Title         string             `json:"title"`
+	Subtitle      string             `json:"subtitle"`
+	Authors       []openLibraryNamed `json:"authors"`
+	Publishers    []openLibraryNamed `json:"publishers"`
+	PublishDate   string             `json:"publish_date"`
+	NumberOfPages int                `json:"number_of_pages"`
+	Cover         struct {
+		Large  string `json:"large"`
+		Medium string `json:"medium"`
+	} `json:"cover"`
+}
+
+func (p *OpenLibraryProvider) LookupISBN(ctx context.Context, isbn13 string) (*models.BookMetadata, error) {
+	key := "ISBN:" + isbn13
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		log.Printf("Open Library lookup of %s failed: %v", isbn13, err)
+		return nil, appErrors.ErrMetadataProvider
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		// The caller giving up isn't a problem with the provider
+		if ctxErr := appErrors.FromContext(ctx.Err()); ctxErr != nil {
+			return nil, ctxErr
+		}
+		log.Printf("Open Library lookup of %s failed: %v", isbn13, err)
+		return nil, appErrors.ErrMetadataProvider
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		log.Printf("Open Library lookup of %s failed: %s", isbn13, response.Status)
+		return nil, appErrors.ErrMetadataProvider
+	}
+
+	// Unknown ISBNs are left out of the response object
+	var books map[string]openLibraryBook
+	if err := json.NewDecoder(response.Body).Decode(&books); err != nil {
+		log.Printf("Open Library lookup of %s returned an invalid response: %v", isbn13, err)
+		return nil, appErrors.ErrMetadataProvider
+	}
+	book, ok := books[key]
+	if !ok {
+		return nil, appErrors.ErrNotFound
+	}
+
+	metadata := &models.BookMetadata{
+		ISBN13:      isbn13,
+		Title:       book.Title,
+		Subtitle:    book.Subtitle,
+		Authors:     []string{},
+		PublishDate: book.PublishDate,
+		PageCount:   book.NumberOfPages,
+		CoverURL:    book.Cover.Large,
+		Source:      "openlibrary",
+	}
+	if metadata.CoverURL == "" {
+		metadata.CoverURL = book.Cover.Medium
+	}
+	if isbn10, _, err := ParseISBN(isbn13); err == nil {
+		metadata.ISBN10 = isbn10
+	}
+	for _, author := range book.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(book.Publishers) > 0 {
+		metadata.Publisher = book.Publishers[0].Name
+	}
+	return metadata, nil
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const openLibraryResponse = `{
+	"ISBN:9780140328721": {
+		"title": "Fantastic Mr. Fox",
+		"authors": [{"url": "https://openlibrary.org/authors/OL34184A/Roald_Dahl", "name": "Roald Dahl"}],
+		"publishers": [{"name": "Puffin"}],
+		"publish_date": "October 1, 1988",
+		"number_of_pages": 96,
+		"cover": {"medium": "https://covers.openlibrary.org/b/id/8739161-M.jpg", "large": "https://covers.openlibrary.org/b/id/8739161-L.jpg"}
+	}
+}`
+
+func TestOpenLibraryProvider_LookupISBN(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		body             string
+		expectedMetadata *models.BookMetadata
+		expectedError    error
+	}{
+		{
+			name:   "known ISBN",
+			status: http.StatusOK,
+			body:   openLibraryResponse,
+			expectedMetadata: &models.BookMetadata{
+				ISBN10:      "0140328726",
+				ISBN13:      "9780140328721",
+				Title:       "Fantastic Mr. Fox",
+				Authors:     []string{"Roald Dahl"},
+				Publisher:   "Puffin",
+				PublishDate: "October 1, 1988",
+				PageCount:   96,
+				CoverURL:    "https://covers.openlibrary.org/b/id/8739161-L.jpg",
+				Source:      "openlibrary",
+			},
+		},
+		{
+			name:          "unknown ISBN",
+			status:        http.StatusOK,
+			body:          `{}`,
+			expectedError: appErrors.ErrNotFound,
+		},
+		{
+			name:          "server error",
+			status:        http.StatusInternalServerError,
+			body:          `oops`,
+			expectedError: appErrors.ErrMetadataProvider,
+		},
+		{
+			name:          "invalid response",
+			status:        http.StatusOK,
+			body:          `<html>`,
+			expectedError: appErrors.ErrMetadataProvider,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/books", r.URL.Path)
+				assert.Equal(t, "ISBN:9780140328721", r.URL.Query().Get("bibkeys"))
+				assert.Equal(t, "data", r.URL.Query().Get("jscmd"))
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			metadata, err := NewOpenLibraryProvider(server.URL+"/").LookupISBN(t.Context(), "9780140328721")
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedMetadata, metadata)
+		})
+	}
+}
+
+func TestOpenLibraryProvider_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	_, err := NewOpenLibraryProvider(server.URL).LookupISBN(t.Context(), "9780140328721")
+	assert.Equal(t, appErrors.ErrMetadataProvider, err)
+}
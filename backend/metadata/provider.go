@@ -0,0 +1,13 @@
+package metadata
+
+import (
+	"context"
+	"tranquil-pages/models"
+)
+
+// MetadataProvider looks up books in an external catalogue
+type MetadataProvider interface {
+	// LookupISBN returns the edition with the given ISBN-13. It returns appErrors.ErrNotFound if the
+	// provider doesn't know the ISBN and appErrors.ErrMetadataProvider if it couldn't be reached.
+	LookupISBN(ctx context.Context, isbn13 string) (*models.BookMetadata, error)
+}
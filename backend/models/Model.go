@@ -6,19 +6,91 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// BookVisibility lets a single book opt out of the owner's sharing settings
+type BookVisibility string
+
+const (
+	// BookVisibilityDefault follows the owner's profile visibility
+	BookVisibilityDefault BookVisibility = ""
+	// BookVisibilityPrivate is never shown to visitors
+	BookVisibilityPrivate BookVisibility = "private"
+)
+
+func (v BookVisibility) IsValid() bool {
+	return v == BookVisibilityDefault || v == BookVisibilityPrivate
+}
+
+// ReadingStatus tracks where the user is with a book
+type ReadingStatus string
+
+const (
+	StatusWantToRead ReadingStatus = "want_to_read"
+	StatusReading    ReadingStatus = "reading"
+	StatusFinished   ReadingStatus = "finished"
+)
+
+func (s ReadingStatus) IsValid() bool {
+	switch s {
+	case "", StatusWantToRead, StatusReading, StatusFinished:
+		return true
+	}
+	return false
+}
+
 type Book struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    string             `bson:"user_id" json:"user_id"`
-	Title     string             `bson:"title" json:"title"`
-	Author    string             `bson:"author" json:"author"`
-	Comment   string             `bson:"comment" json:"comment"`
-	Rating    int                `bson:"rating" json:"rating"`
-	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
-	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `bson:"user_id" json:"user_id"`
+	// GroupID is set for books in a shared group library. UserID then records who added the book.
+	GroupID *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	Title   string              `bson:"title" json:"title"`
+	// Author is the display string for the book's authors. For personal books it is kept in sync with Authors:
+	// a book saved with only an Author string gets linked to matching authors, created where needed, and
+	// a book saved with Authors gets its Author string rebuilt from their names.
+	Author  string       `bson:"author" json:"author"`
+	Authors []BookAuthor `bson:"authors,omitempty" json:"authors,omitempty"`
+	// ISBN10 and ISBN13 are stored without hyphens. Either is filled in from the other where possible.
+	ISBN10   string              `bson:"isbn10,omitempty" json:"isbn10,omitempty"`
+	ISBN13   string              `bson:"isbn13,omitempty" json:"isbn13,omitempty"`
+	SeriesID *primitive.ObjectID `bson:"series_id,omitempty" json:"series_id,omitempty"`
+	// Volume is the position in the series. It may be fractional, as for a novella numbered 2.5.
+	Volume   *float64         `bson:"volume,omitempty" json:"volume,omitempty"`
+	Format   BookFormat       `bson:"format,omitempty" json:"format,omitempty"`
+	Edition  *Edition         `bson:"edition,omitempty" json:"edition,omitempty"`
+	Progress *ReadingProgress `bson:"progress,omitempty" json:"progress,omitempty"`
+	Tags     []string         `bson:"tags,omitempty" json:"tags,omitempty"`
+	Comment  string           `bson:"comment" json:"comment"`
+	// Review is Markdown. ReviewHTML is its rendering, filled in when the book is returned to its readers.
+	Review     string `bson:"review,omitempty" json:"review,omitempty"`
+	ReviewHTML string `bson:"-" json:"review_html,omitempty"`
+	// Rating is shown and entered on the user's rating scale. RatingScore is what gets stored.
+	Rating      float64             `bson:"-" json:"rating"`
+	RatingScore int                 `bson:"rating_score" json:"-"`
+	Visibility  BookVisibility      `bson:"visibility,omitempty" json:"visibility,omitempty"`
+	Status      ReadingStatus       `bson:"status,omitempty" json:"status,omitempty"`
+	FinishedAt  *primitive.DateTime `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	// Reads is the book's reading history. Changing the status to reading starts a read and finishing the
+	// book closes it, so a re-read of a finished book gets a read of its own.
+	Reads []Read `bson:"reads,omitempty" json:"reads,omitempty"`
+	// CurrentLoan is set while the book is lent out. Returned loans move to LoanHistory.
+	CurrentLoan *Loan              `bson:"current_loan,omitempty" json:"current_loan,omitempty"`
+	LoanHistory []Loan             `bson:"loan_history,omitempty" json:"loan_history,omitempty"`
+	Cover       *BookCover         `bson:"cover,omitempty" json:"cover,omitempty"`
+	CreatedAt   primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt   primitive.DateTime `bson:"updated_at" json:"updated_at"`
 }
 
-var bookCompareOptions = cmpopts.IgnoreFields(Book{}, "ID", "CreatedAt", "UpdatedAt")
+var bookCompareOptions = cmpopts.IgnoreFields(Book{}, "ID", "CreatedAt", "UpdatedAt", "FinishedAt", "Reads", "RatingScore")
 
 func CompareBooks(expected, actual *Book) bool {
 	return cmp.Equal(expected, actual, bookCompareOptions)
 }
+
+// BookCover describes the uploaded cover image. The image files live in blob storage.
+type BookCover struct {
+	// ID changes with every upload, so it also serves as the version of the image files
+	ID          string             `bson:"id" json:"id"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Width       int                `bson:"width" json:"width"`
+	Height      int                `bson:"height" json:"height"`
+	UpdatedAt   primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
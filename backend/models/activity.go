@@ -0,0 +1,52 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ActivityType string
+
+const (
+	ActivityAdded    ActivityType = "added"
+	ActivityFinished ActivityType = "finished"
+	ActivityRated    ActivityType = "rated"
+)
+
+// Activity records something a user did with one of their books, for their followers' feeds
+type Activity struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"user_id" json:"-"`
+	BookID      primitive.ObjectID `bson:"book_id" json:"-"`
+	Type        ActivityType       `bson:"type" json:"type"`
+	RatingScore int                `bson:"rating_score,omitempty" json:"-"`
+	CreatedAt   primitive.DateTime `bson:"created_at" json:"created_at"`
+}
+
+type Follow struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	FollowerID string             `bson:"follower_id" json:"-"`
+	FolloweeID string             `bson:"followee_id" json:"-"`
+	CreatedAt  primitive.DateTime `bson:"created_at" json:"created_at"`
+}
+
+// Actor identifies the user behind a feed item without revealing their account
+type Actor struct {
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+}
+
+type FeedItem struct {
+	ID    primitive.ObjectID `json:"id"`
+	Type  ActivityType       `json:"type"`
+	Actor Actor              `json:"actor"`
+	Book  SharedBook         `json:"book"`
+	// Rating is on the viewer's rating scale
+	Rating    *float64           `json:"rating,omitempty"`
+	CreatedAt primitive.DateTime `json:"created_at"`
+}
+
+// Feed is one page of the activity feed. NextBefore is passed as the before parameter to fetch the next page.
+type Feed struct {
+	Items      []FeedItem `json:"items"`
+	NextBefore string     `json:"next_before,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthorRole is what a person contributed to a book
+type AuthorRole string
+
+const (
+	AuthorRoleAuthor     AuthorRole = "author"
+	AuthorRoleTranslator AuthorRole = "translator"
+	AuthorRoleEditor     AuthorRole = "editor"
+)
+
+func (r AuthorRole) IsValid() bool {
+	switch r {
+	case AuthorRoleAuthor, AuthorRoleTranslator, AuthorRoleEditor:
+		return true
+	}
+	return false
+}
+
+// Author is a person in a user's library. Aliases are other spellings of the name that resolve to the same author.
+type Author struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID  string             `bson:"user_id" json:"-"`
+	Name    string             `bson:"name" json:"name"`
+	Aliases []string           `bson:"aliases,omitempty" json:"aliases"`
+	// Keys holds the normalized name and aliases, used to look authors up by any of them
+	Keys      []string           `bson:"keys" json:"-"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// BookAuthor links a book to one of its authors
+type BookAuthor struct {
+	AuthorID primitive.ObjectID `bson:"author_id" json:"author_id"`
+	Role     AuthorRole         `bson:"role" json:"role"`
+}
+
+// AuthorMigrationSummary reports how many books got author links from their author strings
+type AuthorMigrationSummary struct {
+	BooksUpdated   int `json:"books_updated"`
+	AuthorsCreated int `json:"authors_created"`
+}
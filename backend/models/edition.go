@@ -0,0 +1,44 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookFormat is the physical or digital form of the copy the user reads
+type BookFormat string
+
+const (
+	FormatHardcover BookFormat = "hardcover"
+	FormatPaperback BookFormat = "paperback"
+	FormatEbook     BookFormat = "ebook"
+	FormatAudiobook BookFormat = "audiobook"
+)
+
+func (f BookFormat) IsValid() bool {
+	switch f {
+	case "", FormatHardcover, FormatPaperback, FormatEbook, FormatAudiobook:
+		return true
+	}
+	return false
+}
+
+// IsTimed reports whether the format is measured in listening time rather than pages
+func (f BookFormat) IsTimed() bool {
+	return f == FormatAudiobook
+}
+
+// Edition describes the particular edition of a book. Audiobooks have a duration instead of a page count.
+type Edition struct {
+	Publisher       string `bson:"publisher,omitempty" json:"publisher,omitempty"`
+	Year            int    `bson:"year,omitempty" json:"year,omitempty"`
+	PageCount       int    `bson:"page_count,omitempty" json:"page_count,omitempty"`
+	DurationMinutes int    `bson:"duration_minutes,omitempty" json:"duration_minutes,omitempty"`
+}
+
+// ReadingProgress is how far the user is into a book: a page for printed books and ebooks,
+// minutes of listening for audiobooks
+type ReadingProgress struct {
+	Page      int                `bson:"page,omitempty" json:"page,omitempty"`
+	Minutes   int                `bson:"minutes,omitempty" json:"minutes,omitempty"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GroupRole string
+
+const (
+	// GroupRoleOwner can manage members and invites in addition to editing
+	GroupRoleOwner GroupRole = "owner"
+	// GroupRoleEditor can add, change and remove the group's books
+	GroupRoleEditor GroupRole = "editor"
+	// GroupRoleViewer can only see the group's books
+	GroupRoleViewer GroupRole = "viewer"
+)
+
+func (r GroupRole) IsValid() bool {
+	switch r {
+	case GroupRoleOwner, GroupRoleEditor, GroupRoleViewer:
+		return true
+	}
+	return false
+}
+
+type GroupMember struct {
+	UserID   string             `bson:"user_id" json:"user_id"`
+	Role     GroupRole          `bson:"role" json:"role"`
+	JoinedAt primitive.DateTime `bson:"joined_at" json:"joined_at"`
+}
+
+// GroupInvite is a link that lets anyone holding it join the group until it expires
+type GroupInvite struct {
+	Token     string             `bson:"token" json:"token"`
+	Role      GroupRole          `bson:"role" json:"role"`
+	CreatedBy string             `bson:"created_by" json:"-"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	ExpiresAt primitive.DateTime `bson:"expires_at" json:"expires_at"`
+}
+
+// Group is a shared library, such as a household or book club, whose books belong to all members
+type Group struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Members   []GroupMember      `bson:"members" json:"members"`
+	Invites   []GroupInvite      `bson:"invites" json:"-"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+	// Version counts the updates of the group. An update only applies to the version it was made to,
+	// so that concurrent changes to members and invites can't overwrite each other.
+	Version int64 `bson:"version" json:"-"`
+}
+
+// MemberRole returns the user's role in the group, or false if they aren't a member
+func (g *Group) MemberRole(userID string) (GroupRole, bool) {
+	for _, member := range g.Members {
+		if member.UserID == userID {
+			return member.Role, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HighlightColor string
+
+const (
+	HighlightYellow HighlightColor = "yellow"
+	HighlightGreen  HighlightColor = "green"
+	HighlightBlue   HighlightColor = "blue"
+	HighlightPink   HighlightColor = "pink"
+	HighlightOrange HighlightColor = "orange"
+)
+
+func (c HighlightColor) IsValid() bool {
+	switch c {
+	case "", HighlightYellow, HighlightGreen, HighlightBlue, HighlightPink, HighlightOrange:
+		return true
+	}
+	return false
+}
+
+// Highlight is a passage marked in a book. Access follows the parent book, so UserID and
+// GroupID are copied from it to let highlights be searched without loading every book.
+type Highlight struct {
+	ID      primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	BookID  primitive.ObjectID  `bson:"book_id" json:"book_id"`
+	UserID  string              `bson:"user_id" json:"user_id"`
+	GroupID *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	Text    string              `bson:"text" json:"text"`
+	Page    int                 `bson:"page,omitempty" json:"page,omitempty"`
+	// Location is a position in books without page numbers, such as a Kindle location
+	Location  string             `bson:"location,omitempty" json:"location,omitempty"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	Color     HighlightColor     `bson:"color,omitempty" json:"color,omitempty"`
+	Tags      []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// HighlightFilter narrows down a highlight search. The zero value matches every highlight.
+type HighlightFilter struct {
+	// Query matches highlights whose text or note contains it, ignoring case
+	Query  string
+	BookID *primitive.ObjectID
+	Tag    string
+	Color  HighlightColor
+}
@@ -0,0 +1,56 @@
+package models
+
+// ImportSummary reports the outcome of an import
+type ImportSummary struct {
+	// DryRun is set for a preview, which reports what an import would do without storing anything
+	DryRun       bool `bson:"dry_run,omitempty" json:"dry_run,omitempty"`
+	BooksMatched int  `bson:"books_matched" json:"books_matched"`
+	BooksCreated int  `bson:"books_created" json:"books_created"`
+	BooksUpdated int  `bson:"books_updated" json:"books_updated"`
+	// Imported counts the entries stored, including notes attached to existing highlights
+	Imported int `bson:"imported" json:"imported"`
+	// Skipped lists up to MaxSkippedEntries of the entries that were not stored, and MoreSkipped
+	// counts the rest, so that the summary of a large export full of bad entries stays small
+	Skipped     []SkippedEntry `bson:"skipped" json:"skipped"`
+	MoreSkipped int            `bson:"more_skipped,omitempty" json:"more_skipped,omitempty"`
+	// Books lists the books a dry run would create or update, in the order of the file
+	Books []ImportedBook `bson:"-" json:"books,omitempty"`
+}
+
+// MaxSkippedEntries is the number of skipped entries an import summary lists
+const MaxSkippedEntries = 1000
+
+// Skip records that the entry was not stored
+func (s *ImportSummary) Skip(entry SkippedEntry) {
+	if len(s.Skipped) < MaxSkippedEntries {
+		s.Skipped = append(s.Skipped, entry)
+	} else {
+		s.MoreSkipped++
+	}
+}
+
+// SkippedEntry is an entry of an import file that was not stored
+type SkippedEntry struct {
+	// Entry is the 1-based position of the entry in the file
+	Entry  int    `bson:"entry" json:"entry"`
+	Title  string `bson:"title,omitempty" json:"title,omitempty"`
+	Reason string `bson:"reason" json:"reason"`
+}
+
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+)
+
+// ImportedBook is an entry of an import file that created or updated a book
+type ImportedBook struct {
+	Entry  int          `json:"entry"`
+	Action ImportAction `json:"action"`
+	// Fields lists the fields an update filled in
+	Fields []string `json:"fields,omitempty"`
+	// Series names the book's series, which a dry run may not have created yet
+	Series string `json:"series,omitempty"`
+	Book   *Book  `json:"book"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobType string
+
+const (
+	// JobImportBooks imports the books of an export, such as a StoryGraph CSV file
+	JobImportBooks JobType = "import_books"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is work done in the background, such as a large import. Its progress is stored as it goes, so a
+// job interrupted by a restart carries on from its last committed batch.
+type Job struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `bson:"user_id" json:"-"`
+	Type   JobType            `bson:"type" json:"type"`
+	// Format is the export format of an import, such as storygraph
+	Format string    `bson:"format,omitempty" json:"format,omitempty"`
+	Status JobStatus `bson:"status" json:"status"`
+	// Total is the number of entries the job works through, and Processed the number it has committed
+	Total     int `bson:"total" json:"total"`
+	Processed int `bson:"processed" json:"processed"`
+	// Summary is the outcome of an import so far, and its final summary once the job has completed
+	Summary *ImportSummary `bson:"summary,omitempty" json:"summary,omitempty"`
+	// Owner is the worker that claimed a running job. It holds the job until LeaseExpiresAt, and
+	// extends the lease each time it commits progress; a job whose lease ran out was left behind.
+	Owner          string              `bson:"owner,omitempty" json:"-"`
+	LeaseExpiresAt *primitive.DateTime `bson:"lease_expires_at,omitempty" json:"-"`
+	// Error explains why a failed job stopped
+	Error      string              `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  primitive.DateTime  `bson:"created_at" json:"created_at"`
+	UpdatedAt  primitive.DateTime  `bson:"updated_at" json:"updated_at"`
+	FinishedAt *primitive.DateTime `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
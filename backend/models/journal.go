@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JournalEntry is a dated note kept while reading a book. UserID and GroupID are copied from the book,
+// which access to the entry follows.
+type JournalEntry struct {
+	ID      primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	BookID  primitive.ObjectID  `bson:"book_id" json:"book_id"`
+	UserID  string              `bson:"user_id" json:"user_id"`
+	GroupID *primitive.ObjectID `bson:"group_id,omitempty" json:"group_id,omitempty"`
+	// Date is when the entry was written about, which defaults to when it was saved
+	Date    primitive.DateTime `bson:"date" json:"date"`
+	Page    int                `bson:"page,omitempty" json:"page,omitempty"`
+	Chapter string             `bson:"chapter,omitempty" json:"chapter,omitempty"`
+	// Text is Markdown. TextHTML is its rendering, filled in when the entry is returned.
+	Text      string             `bson:"text" json:"text"`
+	TextHTML  string             `bson:"-" json:"text_html,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// JournalFilter narrows down the journal. The zero value matches every entry.
+type JournalFilter struct {
+	// From and To bound the entry dates. From is inclusive and To exclusive.
+	From   *time.Time
+	To     *time.Time
+	BookID *primitive.ObjectID
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loan records a book being lent to someone, either a user of the app or anyone else by name
+type Loan struct {
+	ID             primitive.ObjectID  `bson:"_id" json:"id"`
+	BorrowerName   string              `bson:"borrower_name,omitempty" json:"borrower_name,omitempty"`
+	BorrowerUserID string              `bson:"borrower_user_id,omitempty" json:"borrower_user_id,omitempty"`
+	LentAt         primitive.DateTime  `bson:"lent_at" json:"lent_at"`
+	DueAt          primitive.DateTime  `bson:"due_at" json:"due_at"`
+	ReturnedAt     *primitive.DateTime `bson:"returned_at,omitempty" json:"returned_at,omitempty"`
+}
+
+// OutstandingLoan is a book that is currently lent out
+type OutstandingLoan struct {
+	BookID  primitive.ObjectID  `json:"book_id"`
+	GroupID *primitive.ObjectID `json:"group_id,omitempty"`
+	Title   string              `json:"title"`
+	Author  string              `json:"author"`
+	Loan    Loan                `json:"loan"`
+	Overdue bool                `json:"overdue"`
+}
+
+// BookFilter narrows down book listings. The zero value matches every book.
+type BookFilter struct {
+	// Overdue only matches books lent out past their due date
+	Overdue bool
+	// Formats only matches books in one of the given formats
+	Formats []BookFormat
+}
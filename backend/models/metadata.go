@@ -0,0 +1,28 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BookMetadata describes an edition of a book as published, as returned by a metadata provider
+type BookMetadata struct {
+	ISBN10      string   `bson:"isbn10,omitempty" json:"isbn10,omitempty"`
+	ISBN13      string   `bson:"isbn13" json:"isbn13"`
+	Title       string   `bson:"title" json:"title"`
+	Subtitle    string   `bson:"subtitle,omitempty" json:"subtitle,omitempty"`
+	Authors     []string `bson:"authors" json:"authors"`
+	Publisher   string   `bson:"publisher,omitempty" json:"publisher,omitempty"`
+	PublishDate string   `bson:"publish_date,omitempty" json:"publish_date,omitempty"`
+	PageCount   int      `bson:"page_count,omitempty" json:"page_count,omitempty"`
+	CoverURL    string   `bson:"cover_url,omitempty" json:"cover_url,omitempty"`
+	// Source names the provider the metadata came from
+	Source string `bson:"source" json:"source"`
+}
+
+// MetadataCacheEntry stores a provider response for an ISBN-13. Metadata is nil when the
+// provider didn't know the ISBN, so that misses are cached as well.
+type MetadataCacheEntry struct {
+	ISBN13    string             `bson:"_id"`
+	Metadata  *BookMetadata      `bson:"metadata"`
+	FetchedAt primitive.DateTime `bson:"fetched_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Visibility controls who can see a user's library without logging in
+type Visibility string
+
+const (
+	// VisibilityPrivate shares nothing
+	VisibilityPrivate Visibility = "private"
+	// VisibilityUnlisted shares the library with anyone who has the share link
+	VisibilityUnlisted Visibility = "unlisted"
+	// VisibilityPublic additionally shares the library under the user's handle
+	VisibilityPublic Visibility = "public"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// Profile holds a user's sharing settings and display preferences
+type Profile struct {
+	UserID        string             `bson:"_id" json:"-"`
+	Handle        string             `bson:"handle,omitempty" json:"handle"`
+	DisplayName   string             `bson:"display_name" json:"display_name"`
+	Visibility    Visibility         `bson:"visibility" json:"visibility"`
+	ShareToken    string             `bson:"share_token,omitempty" json:"share_token,omitempty"`
+	ShareRatings  bool               `bson:"share_ratings" json:"share_ratings"`
+	ShareComments bool               `bson:"share_comments" json:"share_comments"`
+	RatingScale   RatingScale        `bson:"rating_scale,omitempty" json:"rating_scale"`
+	UpdatedAt     primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// SharedBook is the view of a book shown to visitors. It only carries the fields the owner opted to share.
+type SharedBook struct {
+	Title   string   `json:"title"`
+	Author  string   `json:"author"`
+	Rating  *float64 `json:"rating,omitempty"`
+	Comment string   `json:"comment,omitempty"`
+	// ReviewHTML is the rendered review, without spoilers unless the visitor asked for them
+	ReviewHTML string             `json:"review_html,omitempty"`
+	AddedAt    primitive.DateTime `json:"added_at"`
+}
+
+// SharedLibrary is what a visitor sees when opening a public profile or share link
+type SharedLibrary struct {
+	Handle      string       `json:"handle,omitempty"`
+	DisplayName string       `json:"display_name"`
+	Books       []SharedBook `json:"books"`
+}
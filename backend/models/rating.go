@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxRatingScore is the stored score of a top rating. Scores are integers from 0 to MaxRatingScore,
+// fine enough to hold a rating on any of the scales exactly.
+const MaxRatingScore = 100
+
+// RatingScale is how a user enters and reads ratings. Scores are converted to and from the scale at the API.
+type RatingScale string
+
+const (
+	RatingScaleStars     RatingScale = "stars"
+	RatingScaleHalfStars RatingScale = "half_stars"
+	RatingScaleTen       RatingScale = "ten"
+
+	DefaultRatingScale = RatingScaleStars
+)
+
+func (s RatingScale) IsValid() bool {
+	switch s {
+	case RatingScaleStars, RatingScaleHalfStars, RatingScaleTen:
+		return true
+	}
+	return false
+}
+
+// bounds returns the top rating and the step between ratings. Unknown scales fall back to stars.
+func (s RatingScale) bounds() (max, step float64) {
+	switch s {
+	case RatingScaleHalfStars:
+		return 5, 0.5
+	case RatingScaleTen:
+		return 10, 1
+	}
+	return 5, 1
+}
+
+// ToScore converts a rating on the scale to its stored score. It reports false for ratings that are
+// out of range or fall between steps.
+func (s RatingScale) ToScore(rating float64) (int, bool) {
+	max, step := s.bounds()
+	steps := rating / step
+	if math.IsNaN(rating) || rating < 0 || rating > max || math.Abs(steps-math.Round(steps)) > 1e-9 {
+		return 0, false
+	}
+	return int(math.Round(rating / max * MaxRatingScore)), true
+}
+
+// FromScore converts a stored score to the scale, rounding to the nearest step. A score entered on
+// a finer scale may round, but the stored score itself is unchanged.
+func (s RatingScale) FromScore(score int) float64 {
+	max, step := s.bounds()
+	return math.Round(float64(score)/MaxRatingScore*max/step) * step
+}
+
+// KeepScore returns the stored score when the submitted score is what the stored one shows as on the
+// scale, so that saving a rating back unchanged doesn't round a score entered on a finer scale
+func (s RatingScale) KeepScore(stored, submitted int) int {
+	if shown, _ := s.ToScore(s.FromScore(stored)); shown == submitted {
+		return stored
+	}
+	return submitted
+}
+
+// Describe explains the valid ratings, for error messages
+func (s RatingScale) Describe() string {
+	max, step := s.bounds()
+	return fmt.Sprintf("from 0 to %g in steps of %g", max, step)
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Read is one time through a book. A read without FinishedAt is still in progress.
+type Read struct {
+	ID         primitive.ObjectID  `bson:"_id" json:"id"`
+	StartedAt  *primitive.DateTime `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	FinishedAt *primitive.DateTime `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	// Rating is on the user's rating scale like Book.Rating; RatingScore is what gets stored
+	Rating      float64 `bson:"-" json:"rating"`
+	RatingScore int     `bson:"rating_score" json:"-"`
+	Comment     string  `bson:"comment" json:"comment"`
+}
+
+// IsOpen reports whether the read has been started but not finished
+func (r *Read) IsOpen() bool {
+	return r.FinishedAt == nil
+}
+
+// YearlyReads is the number of reads finished in a calendar year
+type YearlyReads struct {
+	Year     int `json:"year"`
+	Finished int `json:"finished"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Series groups a user's books that belong together, ordered by Book.Volume
+type Series struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `bson:"user_id" json:"-"`
+	Name   string             `bson:"name" json:"name"`
+	// TotalVolumes is the number of volumes the series is expected to have, or 0 if unknown
+	TotalVolumes int                `bson:"total_volumes,omitempty" json:"total_volumes,omitempty"`
+	CreatedAt    primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt    primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
+
+// SeriesVolumes is a series with the user's books in it, ordered by volume. Books without a volume come last.
+type SeriesVolumes struct {
+	Series
+	Books    []Book `json:"books"`
+	Finished int    `json:"finished"`
+}
+
+// NextVolume suggests the next volume of a series to read. Book is nil if the user doesn't have it yet.
+type NextVolume struct {
+	Volume float64 `json:"volume"`
+	Book   *Book   `json:"book,omitempty"`
+}
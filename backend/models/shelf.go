@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Shelf is a saved query over the user's library. Its books are found by running the query, so they
+// change as the library does.
+type Shelf struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID string             `bson:"user_id" json:"-"`
+	Name   string             `bson:"name" json:"name"`
+	// Query is written in the language of the query package, such as tag:sci-fi rating:0 added:this-year
+	Query string `bson:"query" json:"query"`
+	// RatingScale is the scale ratings in the query are on: the owner's scale when the query was saved,
+	// so that changing scales later doesn't change what the shelf holds
+	RatingScale RatingScale        `bson:"rating_scale,omitempty" json:"rating_scale,omitempty"`
+	CreatedAt   primitive.DateTime `bson:"created_at" json:"created_at"`
+	UpdatedAt   primitive.DateTime `bson:"updated_at" json:"updated_at"`
+}
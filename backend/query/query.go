@@ -0,0 +1,367 @@
+// Package query parses the query language of smart shelves. A query is a list of conditions that all
+// have to match, such as
+//
+//	tag:sci-fi rating:0 added:this-year
+//
+// Conditions are a field, an operator and a value. Values with spaces are quoted, as in author:"Le Guin".
+// Conditions can be combined with AND, OR and parentheses, and negated with NOT or a leading dash.
+// A word or quoted string on its own matches books with it in their title or author.
+//
+// Parsing checks every field, operator and value, and produces a tree of typed conditions that the
+// repository translates into a database filter. User input only ever ends up as a value in that tree.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"tranquil-pages/models"
+)
+
+const (
+	// MaxLength bounds the length of a query
+	MaxLength = 1000
+	// maxConditions and maxDepth bound the size of the filter a query turns into
+	maxConditions = 50
+	maxDepth      = 10
+)
+
+// Field is a book field a query can filter on
+type Field string
+
+const (
+	FieldTitle    Field = "title"
+	FieldAuthor   Field = "author"
+	FieldRating   Field = "rating"
+	FieldStatus   Field = "status"
+	FieldFormat   Field = "format"
+	FieldTag      Field = "tag"
+	FieldAdded    Field = "added"
+	FieldFinished Field = "finished"
+)
+
+var fields = []Field{FieldTitle, FieldAuthor, FieldRating, FieldStatus, FieldFormat, FieldTag, FieldAdded, FieldFinished}
+
+// Operator compares a field with a condition's value
+type Operator string
+
+const (
+	// Contains matches text fields containing the value, ignoring case
+	Contains Operator = "contains"
+	// Matches matches text fields equal to the value, ignoring case
+	Matches        Operator = "matches"
+	Equals         Operator = "="
+	Greater        Operator = ">"
+	GreaterOrEqual Operator = ">="
+	Less           Operator = "<"
+	LessOrEqual    Operator = "<="
+)
+
+// Node is a parsed query: an And, Or, Not or Condition
+type Node interface {
+	isNode()
+}
+
+// And matches books that match all of its nodes
+type And []Node
+
+// Or matches books that match any of its nodes
+type Or []Node
+
+// Not matches books that don't match its node
+type Not struct {
+	Node Node
+}
+
+// Condition compares a field with a value. The value is a string for text fields, tags, statuses and
+// formats, a rating score for ratings, and a time for dates.
+type Condition struct {
+	Field    Field
+	Operator Operator
+	Value    any
+}
+
+func (And) isNode()       {}
+func (Or) isNode()        {}
+func (Not) isNode()       {}
+func (Condition) isNode() {}
+
+// Options are what values in a query are interpreted against
+type Options struct {
+	// Scale is the rating scale ratings in the query are on
+	Scale models.RatingScale
+	// Now is the time relative dates such as this-year are counted from
+	Now time.Time
+}
+
+// Error describes what is wrong with a query and where
+type Error struct {
+	Position int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position+1)
+}
+
+// Parse checks the query and turns it into a tree of conditions. An empty query matches every book.
+func Parse(source string, options Options) (Node, error) {
+	if len(source) > MaxLength {
+		return nil, &Error{Position: MaxLength, Message: fmt.Sprintf("the query is longer than %d characters", MaxLength)}
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, options: options}
+	node, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != tokenEnd {
+		return nil, &Error{Position: token.position, Message: fmt.Sprintf("unexpected %q", token.text)}
+	}
+	if node == nil {
+		return And{}, nil
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens     []token
+	next       int
+	options    Options
+	conditions int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	token := p.tokens[p.next]
+	if token.kind != tokenEnd {
+		p.next++
+	}
+	return token
+}
+
+// parseOr parses alternatives separated by OR. It returns nil for an empty query.
+func (p *parser) parseOr(depth int) (Node, error) {
+	if depth > maxDepth {
+		return nil, &Error{Position: p.peek().position, Message: fmt.Sprintf("the query nests deeper than %d levels", maxDepth)}
+	}
+
+	var alternatives Or
+	for {
+		node, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		if token := p.peek(); token.kind != tokenOr {
+			if node == nil && len(alternatives) > 0 {
+				return nil, &Error{Position: token.position, Message: "OR needs a condition after it"}
+			}
+			if len(alternatives) == 0 {
+				return node, nil
+			}
+			return append(alternatives, node), nil
+		}
+		or := p.take()
+		if node == nil {
+			return nil, &Error{Position: or.position, Message: "OR needs a condition before it"}
+		}
+		alternatives = append(alternatives, node)
+	}
+}
+
+// parseAnd parses conditions that all have to match, separated by AND or nothing at all
+func (p *parser) parseAnd(depth int) (Node, error) {
+	var all And
+	for {
+		token := p.peek()
+		switch token.kind {
+		case tokenEnd, tokenOr, tokenClose:
+			switch len(all) {
+			case 0:
+				return nil, nil
+			case 1:
+				return all[0], nil
+			}
+			return all, nil
+		case tokenAnd:
+			p.take()
+			if len(all) == 0 {
+				return nil, &Error{Position: token.position, Message: "AND needs a condition before it"}
+			}
+			if next := p.peek().kind; next == tokenEnd || next == tokenOr || next == tokenClose || next == tokenAnd {
+				return nil, &Error{Position: token.position, Message: "AND needs a condition after it"}
+			}
+		default:
+			node, err := p.parseUnary(depth)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, node)
+		}
+	}
+}
+
+// parseUnary parses a negation, a parenthesized query or a single condition
+func (p *parser) parseUnary(depth int) (Node, error) {
+	token := p.take()
+	switch token.kind {
+	case tokenNot:
+		if depth+1 > maxDepth {
+			return nil, &Error{Position: token.position, Message: fmt.Sprintf("the query nests deeper than %d levels", maxDepth)}
+		}
+		if next := p.peek().kind; next != tokenOpen && next != tokenNot && next != tokenCondition && next != tokenText {
+			return nil, &Error{Position: token.position, Message: "NOT needs a condition after it"}
+		}
+		node, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	case tokenOpen:
+		node, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != tokenClose {
+			return nil, &Error{Position: token.position, Message: "unclosed parenthesis"}
+		}
+		if node == nil {
+			return nil, &Error{Position: token.position, Message: "empty parentheses"}
+		}
+		return node, nil
+	case tokenCondition, tokenText:
+		p.conditions++
+		if p.conditions > maxConditions {
+			return nil, &Error{Position: token.position, Message: fmt.Sprintf("the query has more than %d conditions", maxConditions)}
+		}
+		if token.kind == tokenText {
+			return Or{
+				Condition{Field: FieldTitle, Operator: Contains, Value: token.value},
+				Condition{Field: FieldAuthor, Operator: Contains, Value: token.value},
+			}, nil
+		}
+		return p.condition(token)
+	case tokenClose:
+		return nil, &Error{Position: token.position, Message: "unexpected )"}
+	}
+	return nil, &Error{Position: token.position, Message: fmt.Sprintf("unexpected %q", token.text)}
+}
+
+// condition checks the field, operator and value of a condition and converts the value to its type
+func (p *parser) condition(token token) (Node, error) {
+	fail := func(format string, args ...any) (Node, error) {
+		return nil, &Error{Position: token.position, Message: fmt.Sprintf(format, args...)}
+	}
+	equality := token.operator == ":" || token.operator == "="
+
+	switch field := Field(strings.ToLower(token.field)); field {
+	case FieldTitle, FieldAuthor:
+		switch token.operator {
+		case ":":
+			return Condition{Field: field, Operator: Contains, Value: token.value}, nil
+		case "=":
+			return Condition{Field: field, Operator: Matches, Value: token.value}, nil
+		}
+		return fail("%s can only be compared with : or =", field)
+
+	case FieldTag:
+		tag := strings.ToLower(strings.TrimSpace(token.value))
+		if !equality || tag == "" {
+			return fail("tag needs a tag after : or =")
+		}
+		return Condition{Field: field, Operator: Equals, Value: tag}, nil
+
+	case FieldStatus:
+		status := models.ReadingStatus(strings.ToLower(token.value))
+		if !equality || status == "" || !status.IsValid() {
+			return fail("status must be one of want_to_read, reading or finished, after : or =")
+		}
+		return Condition{Field: field, Operator: Equals, Value: string(status)}, nil
+
+	case FieldFormat:
+		format := models.BookFormat(strings.ToLower(token.value))
+		if !equality || format == "" || !format.IsValid() {
+			return fail("format must be one of hardcover, paperback, ebook or audiobook, after : or =")
+		}
+		return Condition{Field: field, Operator: Equals, Value: string(format)}, nil
+
+	case FieldRating:
+		rating, err := strconv.ParseFloat(token.value, 64)
+		score, ok := p.options.Scale.ToScore(rating)
+		if err != nil || !ok {
+			return fail("ratings go %s", p.options.Scale.Describe())
+		}
+		operator := Operator(token.operator)
+		if equality {
+			operator = Equals
+		}
+		return Condition{Field: field, Operator: operator, Value: score}, nil
+
+	case FieldAdded, FieldFinished:
+		start, end, ok := period(token.value, p.options.Now)
+		if !ok {
+			return fail("%s needs a date like 2024-05-31, 2024-05 or 2024, or one of today, this-month or this-year", field)
+		}
+		// A period compares by its bounds: after 2024 means from the start of 2025
+		switch token.operator {
+		case ":", "=":
+			return And{
+				Condition{Field: field, Operator: GreaterOrEqual, Value: start},
+				Condition{Field: field, Operator: Less, Value: end},
+			}, nil
+		case ">":
+			return Condition{Field: field, Operator: GreaterOrEqual, Value: end}, nil
+		case ">=":
+			return Condition{Field: field, Operator: GreaterOrEqual, Value: start}, nil
+		case "<":
+			return Condition{Field: field, Operator: Less, Value: start}, nil
+		}
+		return Condition{Field: field, Operator: Less, Value: end}, nil
+	}
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = string(field)
+	}
+	return fail("unknown field %q, expected one of %s", token.field, strings.Join(names, ", "))
+}
+
+// period returns the start and end of the day, month or year a date value stands for, in UTC
+func period(value string, now time.Time) (time.Time, time.Time, bool) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch strings.ToLower(value) {
+	case "today":
+		return today, today.AddDate(0, 0, 1), true
+	case "this-month":
+		start := today.AddDate(0, 0, 1-today.Day())
+		return start, start.AddDate(0, 1, 0), true
+	case "this-year":
+		start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0), true
+	}
+
+	for _, layout := range []struct {
+		format        string
+		years, months int
+		days          int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	} {
+		if start, err := time.Parse(layout.format, value); err == nil {
+			return start, start.AddDate(layout.years, layout.months, layout.days), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tranquil-pages/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testOptions = Options{Scale: models.RatingScaleStars, Now: time.Date(2024, 5, 17, 15, 0, 0, 0, time.UTC)}
+
+func TestParse_ReadsConditions(t *testing.T) {
+	node, err := Parse(`tag:Sci-Fi rating>=4 status:reading author:"Le Guin"`, testOptions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, And{
+		Condition{Field: FieldTag, Operator: Equals, Value: "sci-fi"},
+		Condition{Field: FieldRating, Operator: GreaterOrEqual, Value: 80},
+		Condition{Field: FieldStatus, Operator: Equals, Value: "reading"},
+		Condition{Field: FieldAuthor, Operator: Contains, Value: "Le Guin"},
+	}, node)
+}
+
+func TestParse_CombinesConditions(t *testing.T) {
+	node, err := Parse(`(tag:fantasy OR tag:sci-fi) -status:finished NOT dune`, testOptions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, And{
+		Or{
+			Condition{Field: FieldTag, Operator: Equals, Value: "fantasy"},
+			Condition{Field: FieldTag, Operator: Equals, Value: "sci-fi"},
+		},
+		Not{Node: Condition{Field: FieldStatus, Operator: Equals, Value: "finished"}},
+		Not{Node: Or{
+			Condition{Field: FieldTitle, Operator: Contains, Value: "dune"},
+			Condition{Field: FieldAuthor, Operator: Contains, Value: "dune"},
+		}},
+	}, node)
+}
+
+func TestParse_EmptyQueryMatchesEverything(t *testing.T) {
+	node, err := Parse("   ", testOptions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, And{}, node)
+}
+
+func TestParse_ReadsRatingsOnTheScale(t *testing.T) {
+	node, err := Parse("rating:3.5", Options{Scale: models.RatingScaleHalfStars, Now: testOptions.Now})
+	assert.NoError(t, err)
+	assert.Equal(t, Condition{Field: FieldRating, Operator: Equals, Value: 70}, node)
+
+	_, err = Parse("rating:3.5", testOptions)
+	assert.Error(t, err)
+}
+
+func TestParse_ReadsDatePeriods(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		query    string
+		expected Node
+	}{
+		{"added:this-year", And{
+			Condition{Field: FieldAdded, Operator: GreaterOrEqual, Value: day(2024, 1, 1)},
+			Condition{Field: FieldAdded, Operator: Less, Value: day(2025, 1, 1)},
+		}},
+		{"added:this-month", And{
+			Condition{Field: FieldAdded, Operator: GreaterOrEqual, Value: day(2024, 5, 1)},
+			Condition{Field: FieldAdded, Operator: Less, Value: day(2024, 6, 1)},
+		}},
+		{"finished:today", And{
+			Condition{Field: FieldFinished, Operator: GreaterOrEqual, Value: day(2024, 5, 17)},
+			Condition{Field: FieldFinished, Operator: Less, Value: day(2024, 5, 18)},
+		}},
+		{"finished>2023", Condition{Field: FieldFinished, Operator: GreaterOrEqual, Value: day(2024, 1, 1)}},
+		{"finished<=2023-02", Condition{Field: FieldFinished, Operator: Less, Value: day(2023, 3, 1)}},
+		{"added<2023-02-28", Condition{Field: FieldAdded, Operator: Less, Value: day(2023, 2, 28)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := Parse(tt.query, testOptions)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, node)
+		})
+	}
+}
+
+func TestParse_KeepsOperatorsInValuesAsText(t *testing.T) {
+	node, err := Parse(`author:{"$ne":1} title="$where"`, testOptions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, And{
+		Condition{Field: FieldAuthor, Operator: Contains, Value: "{"},
+		Or{
+			Condition{Field: FieldTitle, Operator: Contains, Value: "$ne"},
+			Condition{Field: FieldAuthor, Operator: Contains, Value: "$ne"},
+		},
+		Or{
+			Condition{Field: FieldTitle, Operator: Contains, Value: ":1}"},
+			Condition{Field: FieldAuthor, Operator: Contains, Value: ":1}"},
+		},
+		Condition{Field: FieldTitle, Operator: Matches, Value: "$where"},
+	}, node)
+}
+
+func TestParse_RejectsInvalidQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown field", "isbn:1"},
+		{"unknown status", "status:abandoned"},
+		{"unknown format", "format:scroll"},
+		{"rating off the scale", "rating:6"},
+		{"rating that isn't a number", "rating:good"},
+		{"comparing text", "title>a"},
+		{"comparing tags", "tag<b"},
+		{"bad date", "added:yesterday"},
+		{"missing value", "tag:"},
+		{"unclosed quote", `author:"Le Guin`},
+		{"unclosed parenthesis", "(tag:a"},
+		{"stray parenthesis", "tag:a)"},
+		{"empty parentheses", "()"},
+		{"dangling OR", "tag:a OR"},
+		{"leading AND", "AND tag:a"},
+		{"dangling NOT", "tag:a NOT"},
+		{"too long", strings.Repeat("a", MaxLength+1)},
+		{"too many conditions", strings.Repeat("a ", maxConditions+1)},
+		{"too deep", strings.Repeat("(", maxDepth+1) + "a" + strings.Repeat(")", maxDepth+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query, testOptions)
+			var queryErr *Error
+			assert.ErrorAs(t, err, &queryErr)
+		})
+	}
+}
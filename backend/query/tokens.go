@@ -0,0 +1,149 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+	tokenNot
+	// tokenCondition is field, operator and value, as in rating>=4
+	tokenCondition
+	// tokenText is a word or quoted string on its own
+	tokenText
+)
+
+type token struct {
+	kind     tokenKind
+	position int
+	// text is the token as written, for error messages
+	text     string
+	field    string
+	operator string
+	value    string
+}
+
+// operators are the comparisons a condition can use, longest first so >= isn't read as >
+var operators = []string{">=", "<=", ":", "=", ">", "<"}
+
+// tokenize splits the query into tokens, ending with a tokenEnd
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		char := source[i]
+		switch {
+		case isSpace(char):
+			i++
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenOpen, position: i, text: "("})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenClose, position: i, text: ")"})
+			i++
+		case char == '-' && i+1 < len(source) && !isSpace(source[i+1]):
+			tokens = append(tokens, token{kind: tokenNot, position: i, text: "-"})
+			i++
+		case char == '"':
+			value, end, err := readQuoted(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenText, position: i, text: source[i:end], value: value})
+			i = end
+		default:
+			token, end, err := readWord(source, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, position: len(source), text: "end of query"}), nil
+}
+
+// readWord reads a keyword, a condition or a bare word starting at start, and returns it with the
+// position after it
+func readWord(source string, start int) (token, int, error) {
+	end := start
+	for end < len(source) && !isWordEnd(source[end]) {
+		end++
+	}
+	word := source[start:end]
+
+	switch word {
+	case "AND":
+		return token{kind: tokenAnd, position: start, text: word}, end, nil
+	case "OR":
+		return token{kind: tokenOr, position: start, text: word}, end, nil
+	case "NOT":
+		return token{kind: tokenNot, position: start, text: word}, end, nil
+	}
+
+	field := 0
+	for field < len(word) && (isLetter(word[field]) || word[field] == '_') {
+		field++
+	}
+	for _, operator := range operators {
+		if field == 0 || !strings.HasPrefix(word[field:], operator) {
+			continue
+		}
+
+		condition := token{kind: tokenCondition, position: start, field: word[:field], operator: operator}
+		condition.value = word[field+len(operator):]
+		if condition.value == "" && end < len(source) && source[end] == '"' {
+			value, quotedEnd, err := readQuoted(source, end)
+			if err != nil {
+				return token{}, 0, err
+			}
+			condition.value, end = value, quotedEnd
+		}
+		if condition.value == "" {
+			return token{}, 0, &Error{Position: start, Message: fmt.Sprintf("%s needs a value", word)}
+		}
+		condition.text = source[start:end]
+		return condition, end, nil
+	}
+
+	return token{kind: tokenText, position: start, text: word, value: word}, end, nil
+}
+
+// readQuoted reads the quoted string starting at start, where a backslash escapes the next character.
+// It returns the string without quotes and the position after the closing quote.
+func readQuoted(source string, start int) (string, int, error) {
+	var value strings.Builder
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			if i+1 < len(source) {
+				i++
+				value.WriteByte(source[i])
+			}
+		case '"':
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(source[i])
+		}
+	}
+	return "", 0, &Error{Position: start, Message: "unclosed quote"}
+}
+
+func isWordEnd(char byte) bool {
+	return char == '(' || char == ')' || char == '"' || isSpace(char)
+}
+
+// isSpace only looks at ASCII whitespace, so bytes within UTF-8 characters never split a word
+func isSpace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\f' || char == '\v'
+}
+
+func isLetter(char byte) bool {
+	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z'
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ActivityRepository interface {
+	Create(ctx context.Context, activity *models.Activity) error
+	// CreateMany records the activities in one bulk write
+	CreateMany(ctx context.Context, activities []*models.Activity) error
+	// FindByUserIDs returns the newest activities of the given users, older than before unless before is zero
+	FindByUserIDs(ctx context.Context, userIDs []string, before primitive.ObjectID, limit int) ([]models.Activity, error)
+	// MigrateRatings converts the star ratings of activities recorded before rating scores to scores
+	// and returns how many activities it converted. It is safe to run again.
+	MigrateRatings(ctx context.Context) (int, error)
+}
+
+type MongoActivityRepository struct {
+	db *database.Database
+}
+
+func NewActivityRepository(db *database.Database) ActivityRepository {
+	db.EnsureIndexes("activities", mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	return &MongoActivityRepository{db: db}
+}
+
+func (r *MongoActivityRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoActivityRepository) Create(ctx context.Context, activity *models.Activity) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	activity.ID = primitive.NewObjectID()
+	activity.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	_, err := r.db.GetCollection("activities").InsertOne(ctx, activity)
+	return r.handleDBError(err, "CreateActivity")
+}
+
+func (r *MongoActivityRepository) CreateMany(ctx context.Context, activities []*models.Activity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	documents := make([]interface{}, len(activities))
+	for i, activity := range activities {
+		activity.ID = primitive.NewObjectID()
+		activity.CreatedAt = now
+		documents[i] = activity
+	}
+
+	_, err := r.db.GetCollection("activities").InsertMany(ctx, documents)
+	return r.handleDBError(err, "CreateActivities")
+}
+
+func (r *MongoActivityRepository) FindByUserIDs(ctx context.Context, userIDs []string, before primitive.ObjectID, limit int) ([]models.Activity, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"user_id": bson.M{"$in": userIDs}}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := r.db.GetCollection("activities").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, "FindActivitiesByUserIDs"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var activities []models.Activity
+	if err := r.handleDBError(cursor.All(ctx, &activities), "FindActivitiesByUserIDs cursor.All"); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+func (r *MongoActivityRepository) MigrateRatings(ctx context.Context) (int, error) {
+	migrated, err := migrateLegacyRatings(ctx, r.db.GetCollection("activities"))
+	return migrated, r.handleDBError(err, "MigrateActivityRatings")
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuthorRepository interface {
+	Create(ctx context.Context, author *models.Author) error
+	FindByID(ctx context.Context, id string) (*models.Author, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Author, error)
+	// FindByKey returns the user's author whose normalized name or alias is key
+	FindByKey(ctx context.Context, userID, key string) (*models.Author, error)
+	// FindByUserID returns the user's authors ordered by name
+	FindByUserID(ctx context.Context, userID string) ([]models.Author, error)
+	Update(ctx context.Context, author *models.Author) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type MongoAuthorRepository struct {
+	db *database.Database
+}
+
+func NewAuthorRepository(db *database.Database) AuthorRepository {
+	db.EnsureIndexes("authors",
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "keys", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}},
+	)
+	return &MongoAuthorRepository{db: db}
+}
+
+func (r *MongoAuthorRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoAuthorRepository) Create(ctx context.Context, author *models.Author) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	author.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	author.UpdatedAt = author.CreatedAt
+
+	result, err := r.db.GetCollection("authors").InsertOne(ctx, author)
+	if err := r.handleDBError(err, "CreateAuthor"); err != nil {
+		return err
+	}
+
+	author.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoAuthorRepository) findOne(ctx context.Context, filter bson.M, operation string) (*models.Author, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var author models.Author
+	err := r.db.GetCollection("authors").FindOne(ctx, filter).Decode(&author)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, operation)
+	}
+	return &author, nil
+}
+
+func (r *MongoAuthorRepository) FindByID(ctx context.Context, id string) (*models.Author, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+	return r.findOne(ctx, bson.M{"_id": objectID}, "FindAuthorByID")
+}
+
+func (r *MongoAuthorRepository) FindByKey(ctx context.Context, userID, key string) (*models.Author, error) {
+	return r.findOne(ctx, bson.M{"user_id": userID, "keys": key}, "FindAuthorByKey")
+}
+
+func (r *MongoAuthorRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Author, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}}, "FindAuthorsByIDs")
+}
+
+func (r *MongoAuthorRepository) FindByUserID(ctx context.Context, userID string) ([]models.Author, error) {
+	return r.find(ctx, bson.M{"user_id": userID}, "FindAuthorsByUserID")
+}
+
+func (r *MongoAuthorRepository) find(ctx context.Context, filter bson.M, operation string) ([]models.Author, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.GetCollection("authors").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var authors []models.Author
+	if err := r.handleDBError(cursor.All(ctx, &authors), operation+" cursor.All"); err != nil {
+		return nil, err
+	}
+	return authors, nil
+}
+
+func (r *MongoAuthorRepository) Update(ctx context.Context, author *models.Author) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	author.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.GetCollection("authors").ReplaceOne(ctx, bson.M{"_id": author.ID}, author)
+	if err := r.handleDBError(err, "UpdateAuthor"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoAuthorRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("authors").DeleteOne(ctx, bson.M{"_id": id})
+	return r.handleDBError(err, "DeleteAuthor")
+}
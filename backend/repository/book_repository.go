@@ -1,23 +1,65 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 	"tranquil-pages/database"
 	appErrors "tranquil-pages/errors"
 	"tranquil-pages/models"
+	"tranquil-pages/query"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type BookRepository interface {
-	Create(book *models.Book) error
-	FindById(id string) (*models.Book, error)
-	Delete(id string) error
-	FindByUserID(userID string) ([]models.Book, error)
+	Create(ctx context.Context, book *models.Book) error
+	// CreateMany stores the books in one bulk write. Books without an ID are given one.
+	CreateMany(ctx context.Context, books []*models.Book) error
+	FindById(ctx context.Context, id string) (*models.Book, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Book, error)
+	Update(ctx context.Context, book *models.Book) error
+	// UpdateMany stores the books in one bulk write. Unlike Update, it stores their reads and progress
+	// too, so the books should have been loaded just before. Books that no longer exist are skipped.
+	UpdateMany(ctx context.Context, books []*models.Book) error
+	Delete(ctx context.Context, id string) error
+	// FindByUserID returns the user's personal books, excluding books they added to groups
+	FindByUserID(ctx context.Context, userID string, filter models.BookFilter) ([]models.Book, error)
+	FindByGroupID(ctx context.Context, groupID primitive.ObjectID, filter models.BookFilter) ([]models.Book, error)
+	FindBySeriesID(ctx context.Context, seriesID primitive.ObjectID) ([]models.Book, error)
+	FindByAuthorID(ctx context.Context, authorID primitive.ObjectID) ([]models.Book, error)
+	// FindWithoutAuthorLinks returns personal books that have an author string but no linked authors
+	FindWithoutAuthorLinks(ctx context.Context) ([]models.Book, error)
+	// SetAuthors replaces the book's linked authors and its author string
+	SetAuthors(ctx context.Context, bookID primitive.ObjectID, authors []models.BookAuthor, author string) error
+	// ClearSeries removes the series and volume from every book in the series
+	ClearSeries(ctx context.Context, seriesID primitive.ObjectID) error
+	// FindByQuery returns a page of the user's personal books that match the shelf query, newest first
+	FindByQuery(ctx context.Context, userID string, node query.Node, offset, limit int) ([]models.Book, error)
+	// FindOnLoan returns the lent out books among the user's personal books and the given groups' books
+	FindOnLoan(ctx context.Context, userID string, groupIDs []primitive.ObjectID) ([]models.Book, error)
+	Count(ctx context.Context) (int64, error)
+	// Lend records the loan unless the book already has one. Concurrent calls for the same book
+	// can't both succeed; the loser gets appErrors.ErrBookOnLoan.
+	Lend(ctx context.Context, bookID primitive.ObjectID, loan *models.Loan) error
+	// Return closes the book's current loan and moves it to the loan history
+	Return(ctx context.Context, bookID primitive.ObjectID, returnedAt time.Time) (*models.Loan, error)
+	// SetCover replaces the book's cover, or removes it if cover is nil
+	SetCover(ctx context.Context, bookID primitive.ObjectID, cover *models.BookCover) error
+	AddRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error
+	// UpdateRead replaces the read with the same ID
+	UpdateRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error
+	DeleteRead(ctx context.Context, bookID, readID primitive.ObjectID) error
+	// SetProgress replaces the book's reading progress, or removes it if progress is nil
+	SetProgress(ctx context.Context, bookID primitive.ObjectID, progress *models.ReadingProgress) error
+	// MigrateRatings converts the star ratings of books saved before rating scores to scores and
+	// returns how many books it converted. It is safe to run again.
+	MigrateRatings(ctx context.Context) (int, error)
 }
 
 type MongoBookRepository struct {
@@ -25,11 +67,18 @@ type MongoBookRepository struct {
 }
 
 func NewBookRepository(db *database.Database) BookRepository {
+	db.EnsureIndexes("books",
+		mongo.IndexModel{Keys: bson.D{{Key: "series_id", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "authors.author_id", Value: 1}}},
+	)
 	return &MongoBookRepository{db: db}
 }
 
 func (r *MongoBookRepository) handleDBError(err error, operation string) error {
 	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
 		if mongo.IsDuplicateKeyError(err) {
 			return appErrors.ErrDuplicateBook
 		}
@@ -39,8 +88,8 @@ func (r *MongoBookRepository) handleDBError(err error, operation string) error {
 	return nil
 }
 
-func (r *MongoBookRepository) Create(book *models.Book) error {
-	ctx, cancel := database.WithTimeout()
+func (r *MongoBookRepository) Create(ctx context.Context, book *models.Book) error {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	book.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
@@ -55,8 +104,30 @@ func (r *MongoBookRepository) Create(book *models.Book) error {
 	return nil
 }
 
-func (r *MongoBookRepository) FindById(id string) (*models.Book, error) {
-	ctx, cancel := database.WithTimeout()
+func (r *MongoBookRepository) CreateMany(ctx context.Context, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	documents := make([]interface{}, len(books))
+	for i, book := range books {
+		if book.ID.IsZero() {
+			book.ID = primitive.NewObjectID()
+		}
+		book.CreatedAt, book.UpdatedAt = now, now
+		documents[i] = book
+	}
+
+	_, err := r.db.GetCollection("books").InsertMany(ctx, documents)
+	return r.handleDBError(err, "CreateBooks")
+}
+
+func (r *MongoBookRepository) FindById(ctx context.Context, id string) (*models.Book, error) {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -75,8 +146,158 @@ func (r *MongoBookRepository) FindById(id string) (*models.Book, error) {
 	return &book, nil
 }
 
-func (r *MongoBookRepository) Delete(id string) error {
-	ctx, cancel := database.WithTimeout()
+func (r *MongoBookRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}}, "FindByIDs")
+}
+
+// Update stores the book's fields, except for loans, reads, the cover and progress which have their own methods
+func (r *MongoBookRepository) Update(ctx context.Context, book *models.Book) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	book.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	update, err := updateDocument(book, "_id", "current_loan", "loan_history", "reads", "cover", "progress")
+	if err != nil {
+		return r.handleDBError(err, "UpdateBook")
+	}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": book.ID}, update)
+	if err := r.handleDBError(err, "UpdateBook"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) UpdateMany(ctx context.Context, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	writes := make([]mongo.WriteModel, len(books))
+	for i, book := range books {
+		book.UpdatedAt = now
+		update, err := updateDocument(book, "_id", "current_loan", "loan_history", "cover")
+		if err != nil {
+			return r.handleDBError(err, "UpdateBooks")
+		}
+		writes[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": book.ID}).SetUpdate(update)
+	}
+
+	_, err := r.db.GetCollection("books").BulkWrite(ctx, writes)
+	return r.handleDBError(err, "UpdateBooks")
+}
+
+func (r *MongoBookRepository) SetCover(ctx context.Context, bookID primitive.ObjectID, cover *models.BookCover) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"cover": ""}}
+	if cover != nil {
+		update = bson.M{"$set": bson.M{"cover": cover}}
+	}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": bookID}, update)
+	if err := r.handleDBError(err, "SetCover"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) SetProgress(ctx context.Context, bookID primitive.ObjectID, progress *models.ReadingProgress) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"progress": ""}}
+	if progress != nil {
+		update = bson.M{"$set": bson.M{"progress": progress}}
+	}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": bookID}, update)
+	if err := r.handleDBError(err, "SetProgress"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) AddRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": bookID}, bson.M{"$push": bson.M{"reads": read}})
+	if err := r.handleDBError(err, "AddRead"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+// readPath finds the position of a read in the book's reads and returns its field path
+func (r *MongoBookRepository) readPath(ctx context.Context, bookID, readID primitive.ObjectID) (string, error) {
+	var book models.Book
+	opts := options.FindOne().SetProjection(bson.M{"reads._id": 1})
+	err := r.db.GetCollection("books").FindOne(ctx, bson.M{"_id": bookID}, opts).Decode(&book)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", appErrors.ErrNotFound
+	}
+	if err := r.handleDBError(err, "FindRead"); err != nil {
+		return "", err
+	}
+
+	for i := range book.Reads {
+		if book.Reads[i].ID == readID {
+			return fmt.Sprintf("reads.%d", i), nil
+		}
+	}
+	return "", appErrors.ErrNotFound
+}
+
+func (r *MongoBookRepository) UpdateRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	path, err := r.readPath(ctx, bookID, read.ID)
+	if err != nil {
+		return err
+	}
+
+	// Matching the ID at the position guards against reads added or removed since it was looked up
+	filter := bson.M{"_id": bookID, path + "._id": read.ID}
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, filter, bson.M{"$set": bson.M{path: read}})
+	if err := r.handleDBError(err, "UpdateRead"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) DeleteRead(ctx context.Context, bookID, readID primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": bookID}, bson.M{"$pull": bson.M{"reads": bson.M{"_id": readID}}})
+	return r.handleDBError(err, "DeleteRead")
+}
+
+func (r *MongoBookRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -88,19 +309,198 @@ func (r *MongoBookRepository) Delete(id string) error {
 	return r.handleDBError(err, "DeleteBook")
 }
 
-func (r *MongoBookRepository) FindByUserID(userID string) ([]models.Book, error) {
-	ctx, cancel := database.WithTimeout()
+// bookFilter translates a BookFilter into query conditions added to the given base query
+func bookFilter(base bson.M, filter models.BookFilter) bson.M {
+	if filter.Overdue {
+		base["current_loan.due_at"] = bson.M{"$lt": primitive.NewDateTimeFromTime(time.Now())}
+	}
+	if len(filter.Formats) > 0 {
+		base["format"] = bson.M{"$in": filter.Formats}
+	}
+	return base
+}
+
+func (r *MongoBookRepository) find(ctx context.Context, filter bson.M, operation string) ([]models.Book, error) {
+	ctx, cancel := database.WithTimeout(ctx)
 	defer cancel()
 
-	cursor, err := r.db.GetCollection("books").Find(ctx, bson.M{"user_id": userID})
-	if err := r.handleDBError(err, "FindByUserID"); err != nil {
+	cursor, err := r.db.GetCollection("books").Find(ctx, filter)
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var books []models.Book
+	if err := r.handleDBError(cursor.All(ctx, &books), operation+" cursor.All"); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *MongoBookRepository) FindByUserID(ctx context.Context, userID string, filter models.BookFilter) ([]models.Book, error) {
+	query := bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}}
+	return r.find(ctx, bookFilter(query, filter), "FindByUserID")
+}
+
+func (r *MongoBookRepository) FindByQuery(ctx context.Context, userID string, node query.Node, offset, limit int) ([]models.Book, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	shelf, err := queryFilter(node)
+	if err := r.handleDBError(err, "FindByQuery queryFilter"); err != nil {
+		return nil, err
+	}
+	filter := bson.M{"$and": bson.A{
+		bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}},
+		shelf,
+	}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	cursor, err := r.db.GetCollection("books").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, "FindByQuery"); err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var books []models.Book
-	if err := r.handleDBError(cursor.All(ctx, &books), "FindByUserID cursor.All"); err != nil {
+	if err := r.handleDBError(cursor.All(ctx, &books), "FindByQuery cursor.All"); err != nil {
 		return nil, err
 	}
 	return books, nil
 }
+
+func (r *MongoBookRepository) FindByGroupID(ctx context.Context, groupID primitive.ObjectID, filter models.BookFilter) ([]models.Book, error) {
+	return r.find(ctx, bookFilter(bson.M{"group_id": groupID}, filter), "FindByGroupID")
+}
+
+func (r *MongoBookRepository) FindBySeriesID(ctx context.Context, seriesID primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, bson.M{"series_id": seriesID}, "FindBySeriesID")
+}
+
+func (r *MongoBookRepository) ClearSeries(ctx context.Context, seriesID primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"series_id": "", "volume": ""}}
+	_, err := r.db.GetCollection("books").UpdateMany(ctx, bson.M{"series_id": seriesID}, update)
+	return r.handleDBError(err, "ClearSeries")
+}
+
+func (r *MongoBookRepository) FindByAuthorID(ctx context.Context, authorID primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, bson.M{"authors.author_id": authorID}, "FindByAuthorID")
+}
+
+func (r *MongoBookRepository) FindWithoutAuthorLinks(ctx context.Context) ([]models.Book, error) {
+	query := bson.M{
+		"group_id": bson.M{"$exists": false},
+		"authors":  bson.M{"$exists": false},
+		"author":   bson.M{"$nin": bson.A{"", nil}},
+	}
+	return r.find(ctx, query, "FindWithoutAuthorLinks")
+}
+
+func (r *MongoBookRepository) SetAuthors(ctx context.Context, bookID primitive.ObjectID, authors []models.BookAuthor, author string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"authors": authors, "author": author}}
+	if len(authors) == 0 {
+		update = bson.M{"$set": bson.M{"author": author}, "$unset": bson.M{"authors": ""}}
+	}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, bson.M{"_id": bookID}, update)
+	if err := r.handleDBError(err, "SetAuthors"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) FindOnLoan(ctx context.Context, userID string, groupIDs []primitive.ObjectID) ([]models.Book, error) {
+	owners := bson.A{bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}}}
+	if len(groupIDs) > 0 {
+		owners = append(owners, bson.M{"group_id": bson.M{"$in": groupIDs}})
+	}
+
+	query := bson.M{
+		"$or":          owners,
+		"current_loan": bson.M{"$exists": true},
+	}
+	return r.find(ctx, query, "FindOnLoan")
+}
+
+func (r *MongoBookRepository) Lend(ctx context.Context, bookID primitive.ObjectID, loan *models.Loan) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// The filter only matches while there is no open loan, which makes the check and the write a single atomic step
+	filter := bson.M{"_id": bookID, "current_loan": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"current_loan": loan}}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, filter, update)
+	if err := r.handleDBError(err, "Lend"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrBookOnLoan
+	}
+	return nil
+}
+
+func (r *MongoBookRepository) Return(ctx context.Context, bookID primitive.ObjectID, returnedAt time.Time) (*models.Loan, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var book models.Book
+	err := r.db.GetCollection("books").FindOne(ctx, bson.M{"_id": bookID}).Decode(&book)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, appErrors.ErrNotFound
+	}
+	if err := r.handleDBError(err, "Return"); err != nil {
+		return nil, err
+	}
+	if book.CurrentLoan == nil {
+		return nil, appErrors.ErrBookNotOnLoan
+	}
+
+	loan := *book.CurrentLoan
+	returned := primitive.NewDateTimeFromTime(returnedAt)
+	loan.ReturnedAt = &returned
+
+	// Only close the loan we read, in case it was returned and lent again in the meantime
+	filter := bson.M{"_id": bookID, "current_loan._id": loan.ID}
+	update := bson.M{
+		"$unset": bson.M{"current_loan": ""},
+		"$push":  bson.M{"loan_history": loan},
+	}
+
+	result, err := r.db.GetCollection("books").UpdateOne(ctx, filter, update)
+	if err := r.handleDBError(err, "Return"); err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, appErrors.ErrBookNotOnLoan
+	}
+	return &loan, nil
+}
+
+func (r *MongoBookRepository) Count(ctx context.Context) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	count, err := r.db.GetCollection("books").CountDocuments(ctx, bson.M{})
+	if err := r.handleDBError(err, "Count"); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *MongoBookRepository) MigrateRatings(ctx context.Context) (int, error) {
+	migrated, err := migrateLegacyRatings(ctx, r.db.GetCollection("books"))
+	return migrated, r.handleDBError(err, "MigrateRatings")
+}
@@ -0,0 +1,418 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/query"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func createBook(t *testing.T, repo BookRepository, book models.Book) *models.Book {
+	if err := repo.Create(t.Context(), &book); err != nil {
+		t.Fatal(err)
+	}
+	return &book
+}
+
+func titles(books []models.Book) []string {
+	titles := make([]string, len(books))
+	for i, book := range books {
+		titles[i] = book.Title
+	}
+	return titles
+}
+
+func TestBookRepository_CreateAndFind(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{
+			UserID:      "alice",
+			Title:       "The Dispossessed",
+			Author:      "Ursula K. Le Guin",
+			Tags:        []string{"sci-fi"},
+			Rating:      4,
+			RatingScore: 80,
+		})
+
+		// When
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+
+		// Then
+		assert.NoError(t, err)
+		assert.False(t, book.ID.IsZero())
+		assert.Equal(t, "The Dispossessed", found.Title)
+		assert.Equal(t, []string{"sci-fi"}, found.Tags)
+		assert.Equal(t, 80, found.RatingScore)
+		assert.Equal(t, 0.0, found.Rating, "fields that aren't stored come back empty")
+		assert.Equal(t, book.CreatedAt, found.CreatedAt)
+	})
+}
+
+func TestBookRepository_FindByIdErrors(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		_, err := repo.FindById(t.Context(), "not-an-id")
+		assert.ErrorIs(t, err, appErrors.ErrInvalidID)
+
+		_, err = repo.FindById(t.Context(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, appErrors.ErrNotFound)
+
+		assert.ErrorIs(t, repo.Delete(t.Context(), "not-an-id"), appErrors.ErrInvalidID)
+		assert.NoError(t, repo.Delete(t.Context(), primitive.NewObjectID().Hex()))
+	})
+}
+
+func TestBookRepository_CreateManyRejectsDuplicates(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+
+		// When
+		err := repo.CreateMany(t.Context(), []*models.Book{{ID: book.ID, UserID: "alice", Title: "Kindred again"}})
+
+		// Then
+		assert.ErrorIs(t, err, appErrors.ErrDuplicateBook)
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestBookRepository_UpdateKeepsLoansReadsCoverAndProgress(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Parable of the Sower", Tags: []string{"dystopia"}})
+		now := primitive.NewDateTimeFromTime(time.Now())
+		assert.NoError(t, repo.Lend(t.Context(), book.ID, &models.Loan{ID: primitive.NewObjectID(), BorrowerName: "Bob", LentAt: now, DueAt: now}))
+		assert.NoError(t, repo.AddRead(t.Context(), book.ID, &models.Read{ID: primitive.NewObjectID(), StartedAt: &now}))
+		assert.NoError(t, repo.SetCover(t.Context(), book.ID, &models.BookCover{ID: "cover", ContentType: "image/jpeg"}))
+		assert.NoError(t, repo.SetProgress(t.Context(), book.ID, &models.ReadingProgress{Page: 12, UpdatedAt: now}))
+
+		// When
+		book.Title = "Parable of the Talents"
+		book.Tags = nil
+		err := repo.Update(t.Context(), book)
+
+		// Then
+		assert.NoError(t, err)
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, "Parable of the Talents", found.Title)
+		assert.Nil(t, found.Tags, "clearing a field removes it")
+		assert.Equal(t, "Bob", found.CurrentLoan.BorrowerName)
+		assert.Len(t, found.Reads, 1)
+		assert.Equal(t, "cover", found.Cover.ID)
+		assert.Equal(t, 12, found.Progress.Page)
+	})
+}
+
+func TestBookRepository_UpdateMissingBook(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		missing := primitive.NewObjectID()
+
+		assert.ErrorIs(t, repo.Update(t.Context(), &models.Book{ID: missing}), appErrors.ErrNotFound)
+		assert.ErrorIs(t, repo.SetCover(t.Context(), missing, nil), appErrors.ErrNotFound)
+		assert.ErrorIs(t, repo.SetProgress(t.Context(), missing, nil), appErrors.ErrNotFound)
+		assert.ErrorIs(t, repo.AddRead(t.Context(), missing, &models.Read{ID: primitive.NewObjectID()}), appErrors.ErrNotFound)
+		assert.ErrorIs(t, repo.SetAuthors(t.Context(), missing, nil, "Anyone"), appErrors.ErrNotFound)
+	})
+}
+
+func TestBookRepository_UpdateManyStoresReadsAndSkipsMissingBooks(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+		book.Status = models.StatusReading
+		book.Reads = []models.Read{{ID: primitive.NewObjectID(), Comment: "again"}}
+
+		// When
+		err := repo.UpdateMany(t.Context(), []*models.Book{book, {ID: primitive.NewObjectID(), Title: "Gone"}})
+
+		// Then
+		assert.NoError(t, err)
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusReading, found.Status)
+		assert.Equal(t, "again", found.Reads[0].Comment)
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestBookRepository_Reads(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+		first := models.Read{ID: primitive.NewObjectID(), Comment: "first"}
+		second := models.Read{ID: primitive.NewObjectID(), Comment: "second"}
+		assert.NoError(t, repo.AddRead(t.Context(), book.ID, &first))
+		assert.NoError(t, repo.AddRead(t.Context(), book.ID, &second))
+
+		// When
+		second.Comment = "second, updated"
+		assert.NoError(t, repo.UpdateRead(t.Context(), book.ID, &second))
+
+		// Then
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Read{first, second}, found.Reads)
+		missing := models.Read{ID: primitive.NewObjectID()}
+		assert.ErrorIs(t, repo.UpdateRead(t.Context(), book.ID, &missing), appErrors.ErrNotFound)
+	})
+}
+
+func TestBookRepository_DeleteRead(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		if mongoRepo, ok := repo.(*MongoBookRepository); ok {
+			skipOnFerretDB(t, mongoRepo.db, "doesn't support conditions in $pull")
+		}
+
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+		first := models.Read{ID: primitive.NewObjectID(), Comment: "first"}
+		second := models.Read{ID: primitive.NewObjectID(), Comment: "second"}
+		assert.NoError(t, repo.AddRead(t.Context(), book.ID, &first))
+		assert.NoError(t, repo.AddRead(t.Context(), book.ID, &second))
+
+		// When
+		assert.NoError(t, repo.DeleteRead(t.Context(), book.ID, first.ID))
+
+		// Then
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Read{second}, found.Reads)
+		assert.ErrorIs(t, repo.UpdateRead(t.Context(), book.ID, &first), appErrors.ErrNotFound)
+		assert.NoError(t, repo.DeleteRead(t.Context(), book.ID, first.ID), "deleting a read that is gone is not an error")
+		assert.NoError(t, repo.DeleteRead(t.Context(), primitive.NewObjectID(), first.ID))
+	})
+}
+
+func TestBookRepository_LendAndReturn(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+		now := primitive.NewDateTimeFromTime(time.Now())
+		loan := &models.Loan{ID: primitive.NewObjectID(), BorrowerName: "Bob", LentAt: now, DueAt: now}
+
+		// When
+		lendErr := repo.Lend(t.Context(), book.ID, loan)
+		secondLendErr := repo.Lend(t.Context(), book.ID, &models.Loan{ID: primitive.NewObjectID(), BorrowerName: "Carol"})
+		returned, returnErr := repo.Return(t.Context(), book.ID, now.Time())
+		_, secondReturnErr := repo.Return(t.Context(), book.ID, now.Time())
+
+		// Then
+		assert.NoError(t, lendErr)
+		assert.ErrorIs(t, secondLendErr, appErrors.ErrBookOnLoan)
+		assert.NoError(t, returnErr)
+		assert.Equal(t, loan.ID, returned.ID)
+		assert.Equal(t, now, *returned.ReturnedAt)
+		assert.ErrorIs(t, secondReturnErr, appErrors.ErrBookNotOnLoan)
+
+		found, err := repo.FindById(t.Context(), book.ID.Hex())
+		assert.NoError(t, err)
+		assert.Nil(t, found.CurrentLoan)
+		assert.Equal(t, []models.Loan{*returned}, found.LoanHistory)
+
+		_, err = repo.Return(t.Context(), primitive.NewObjectID(), now.Time())
+		assert.ErrorIs(t, err, appErrors.ErrNotFound)
+		assert.ErrorIs(t, repo.Lend(t.Context(), primitive.NewObjectID(), loan), appErrors.ErrBookOnLoan)
+	})
+}
+
+func TestBookRepository_ConcurrentLendsOnlyOneSucceeds(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		if mongoRepo, ok := repo.(*MongoBookRepository); ok {
+			skipOnFerretDB(t, mongoRepo.db, "doesn't make concurrent conditional updates atomic")
+		}
+
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+
+		// When
+		errs := make(chan error, 8)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.Lend(t.Context(), book.ID, &models.Loan{ID: primitive.NewObjectID()})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		// Then
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, appErrors.ErrBookOnLoan)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+}
+
+func TestBookRepository_Listings(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		groupID := primitive.NewObjectID()
+		past := primitive.NewDateTimeFromTime(time.Now().Add(-time.Hour))
+		future := primitive.NewDateTimeFromTime(time.Now().Add(time.Hour))
+		ebook := createBook(t, repo, models.Book{UserID: "alice", Title: "Ebook", Format: models.FormatEbook})
+		overdue := createBook(t, repo, models.Book{UserID: "alice", Title: "Overdue"})
+		lent := createBook(t, repo, models.Book{UserID: "alice", Title: "Lent"})
+		createBook(t, repo, models.Book{UserID: "alice", GroupID: &groupID, Title: "Group book"})
+		createBook(t, repo, models.Book{UserID: "bob", Title: "Bob's book"})
+		assert.NoError(t, repo.Lend(t.Context(), overdue.ID, &models.Loan{ID: primitive.NewObjectID(), DueAt: past}))
+		assert.NoError(t, repo.Lend(t.Context(), lent.ID, &models.Loan{ID: primitive.NewObjectID(), DueAt: future}))
+
+		// When
+		personal, personalErr := repo.FindByUserID(t.Context(), "alice", models.BookFilter{})
+		ebooks, ebooksErr := repo.FindByUserID(t.Context(), "alice", models.BookFilter{Formats: []models.BookFormat{models.FormatEbook}})
+		overdueBooks, overdueErr := repo.FindByUserID(t.Context(), "alice", models.BookFilter{Overdue: true})
+		group, groupErr := repo.FindByGroupID(t.Context(), groupID, models.BookFilter{})
+		onLoan, onLoanErr := repo.FindOnLoan(t.Context(), "alice", []primitive.ObjectID{groupID})
+		byIDs, byIDsErr := repo.FindByIDs(t.Context(), []primitive.ObjectID{ebook.ID, lent.ID, primitive.NewObjectID()})
+
+		// Then
+		assert.NoError(t, personalErr)
+		assert.Equal(t, []string{"Ebook", "Overdue", "Lent"}, titles(personal))
+		assert.NoError(t, ebooksErr)
+		assert.Equal(t, []string{"Ebook"}, titles(ebooks))
+		assert.NoError(t, overdueErr)
+		assert.Equal(t, []string{"Overdue"}, titles(overdueBooks))
+		assert.NoError(t, groupErr)
+		assert.Equal(t, []string{"Group book"}, titles(group))
+		assert.NoError(t, onLoanErr)
+		assert.Equal(t, []string{"Overdue", "Lent"}, titles(onLoan))
+		assert.NoError(t, byIDsErr)
+		assert.Equal(t, []string{"Ebook", "Lent"}, titles(byIDs))
+	})
+}
+
+func TestBookRepository_SeriesAndAuthors(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		seriesID, authorID := primitive.NewObjectID(), primitive.NewObjectID()
+		volume := 1.0
+		inSeries := createBook(t, repo, models.Book{UserID: "alice", Title: "Wild Seed", SeriesID: &seriesID, Volume: &volume})
+		unlinked := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred", Author: "Octavia E. Butler"})
+		createBook(t, repo, models.Book{UserID: "alice", Title: "No author"})
+
+		// When
+		bySeries, bySeriesErr := repo.FindBySeriesID(t.Context(), seriesID)
+		withoutLinks, withoutLinksErr := repo.FindWithoutAuthorLinks(t.Context())
+		setErr := repo.SetAuthors(t.Context(), unlinked.ID, []models.BookAuthor{{AuthorID: authorID, Role: models.AuthorRoleAuthor}}, "Octavia Butler")
+		byAuthor, byAuthorErr := repo.FindByAuthorID(t.Context(), authorID)
+		clearErr := repo.ClearSeries(t.Context(), seriesID)
+
+		// Then
+		assert.NoError(t, bySeriesErr)
+		assert.Equal(t, []string{"Wild Seed"}, titles(bySeries))
+		assert.NoError(t, withoutLinksErr)
+		assert.Equal(t, []string{"Kindred"}, titles(withoutLinks))
+		assert.NoError(t, setErr)
+		assert.NoError(t, byAuthorErr)
+		assert.Equal(t, []string{"Kindred"}, titles(byAuthor))
+		assert.Equal(t, "Octavia Butler", byAuthor[0].Author)
+		assert.NoError(t, clearErr)
+		cleared, err := repo.FindById(t.Context(), inSeries.ID.Hex())
+		assert.NoError(t, err)
+		assert.Nil(t, cleared.SeriesID)
+		assert.Nil(t, cleared.Volume)
+	})
+}
+
+func TestBookRepository_FindByQuery(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		finished := primitive.NewDateTimeFromTime(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC))
+		createBook(t, repo, models.Book{UserID: "alice", Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", Tags: []string{"sci-fi"}, RatingScore: 100, Status: models.StatusFinished, FinishedAt: &finished})
+		createBook(t, repo, models.Book{UserID: "alice", Title: "Dune", Author: "Frank Herbert", Tags: []string{"sci-fi", "classic"}, RatingScore: 60, Status: models.StatusReading})
+		createBook(t, repo, models.Book{UserID: "alice", Title: "A Wizard of Earthsea", Author: "Ursula K. Le Guin", Tags: []string{"fantasy"}, Format: models.FormatPaperback})
+		createBook(t, repo, models.Book{UserID: "bob", Title: "Bob's Dune", Author: "Frank Herbert", Tags: []string{"sci-fi"}})
+		options := query.Options{Scale: models.RatingScaleStars, Now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+		tests := []struct {
+			query    string
+			expected []string
+		}{
+			{"", []string{"A Wizard of Earthsea", "Dune", "The Left Hand of Darkness"}},
+			{`author:"le guin"`, []string{"A Wizard of Earthsea", "The Left Hand of Darkness"}},
+			{`title="dune"`, []string{"Dune"}},
+			{"tag:sci-fi rating>=4", []string{"The Left Hand of Darkness"}},
+			{"tag:fantasy OR status:reading", []string{"A Wizard of Earthsea", "Dune"}},
+			{"-status:finished", []string{"A Wizard of Earthsea", "Dune"}},
+			{"NOT format:paperback", []string{"Dune", "The Left Hand of Darkness"}},
+			{"finished:2023", []string{"The Left Hand of Darkness"}},
+			{"finished<2023", []string{}},
+			{"-finished<2024", []string{"A Wizard of Earthsea", "Dune"}},
+			{"NOT tag:sci-fi", []string{"A Wizard of Earthsea"}},
+			{"rating<=3", []string{"A Wizard of Earthsea", "Dune"}},
+			{"-(tag:sci-fi title:dune)", []string{"A Wizard of Earthsea", "The Left Hand of Darkness"}},
+			{"status:finished OR -author:herbert", []string{"A Wizard of Earthsea", "The Left Hand of Darkness"}},
+			{"earthsea", []string{"A Wizard of Earthsea"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				node, err := query.Parse(tt.query, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// When
+				books, err := repo.FindByQuery(t.Context(), "alice", node, 0, 10)
+
+				// Then
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, titles(books))
+			})
+		}
+	})
+}
+
+func TestBookRepository_FindByQueryPages(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		for _, title := range []string{"One", "Two", "Three", "Four"} {
+			createBook(t, repo, models.Book{UserID: "alice", Title: title})
+		}
+
+		// When
+		first, firstErr := repo.FindByQuery(t.Context(), "alice", query.And{}, 0, 3)
+		second, secondErr := repo.FindByQuery(t.Context(), "alice", query.And{}, 3, 3)
+
+		// Then
+		assert.NoError(t, firstErr)
+		assert.Equal(t, []string{"Four", "Three", "Two"}, titles(first))
+		assert.NoError(t, secondErr)
+		assert.Equal(t, []string{"One"}, titles(second))
+	})
+}
+
+func TestBookRepository_CancelledContext(t *testing.T) {
+	bookRepositoryConformance(t, func(t *testing.T, repo BookRepository) {
+		// Given
+		book := createBook(t, repo, models.Book{UserID: "alice", Title: "Kindred"})
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		// When
+		_, findErr := repo.FindById(ctx, book.ID.Hex())
+		updateErr := repo.Update(ctx, book)
+		_, listErr := repo.FindByUserID(ctx, "alice", models.BookFilter{})
+
+		// Then
+		assert.ErrorIs(t, findErr, appErrors.ErrCanceled)
+		assert.ErrorIs(t, updateErr, appErrors.ErrCanceled)
+		assert.ErrorIs(t, listErr, appErrors.ErrCanceled)
+	})
+}
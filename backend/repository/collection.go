@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	appErrors "tranquil-pages/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errDuplicateKey is returned by a collection asked to store a document whose key or unique field
+// another document already has
+var errDuplicateKey = errors.New("duplicate key")
+
+// collection is a set of documents of one type, kept in memory or in a SQL database by the document
+// repositories. Documents are copied on the way in and out, so callers never share them with the
+// collection, and what comes back is exactly what Mongo would have stored: fields tagged bson:"-" are
+// dropped, empty omitempty slices come back nil and times are cut to milliseconds.
+//
+// Lookups select documents with a where on the fields of the collection's schema, which a SQL
+// collection answers from the columns of its table, and with an optional match that has the final say
+// on each document the where selected. Documents come back in the order they were inserted, which is
+// the order Mongo returns them in when a query doesn't sort. Each update is atomic.
+type collection[T any] interface {
+	// insert stores the documents. Either all of them are stored, or none is and errDuplicateKey is
+	// returned.
+	insert(ctx context.Context, docs ...*T) error
+	// find returns the documents that match
+	find(ctx context.Context, by where, match func(*T) bool) ([]T, error)
+	// findOne returns the first document that matches, or appErrors.ErrNotFound
+	findOne(ctx context.Context, by where, match func(*T) bool) (*T, error)
+	// count returns the number of documents that match
+	count(ctx context.Context, by where, match func(*T) bool) (int, error)
+	// update replaces each document that matches with what change makes of it, and returns how many
+	// documents matched. Documents are only replaced once change has succeeded on every one of them.
+	update(ctx context.Context, by where, match func(*T) bool, change func(*T) (*T, error)) (int, error)
+	// updateFirst is update for only the first document that matches in the given order. It returns
+	// the updated document, or appErrors.ErrNotFound if no document matches.
+	updateFirst(ctx context.Context, by where, match func(*T) bool, order func(a, b *T) int, change func(*T) (*T, error)) (*T, error)
+	// upsert replaces the documents with the same keys as docs, and inserts the others
+	upsert(ctx context.Context, docs ...*T) error
+	// remove removes the documents that match and returns how many it removed
+	remove(ctx context.Context, by where, match func(*T) bool) (int, error)
+}
+
+// schema describes the documents of a collection: how they are told apart, and the fields lookups can
+// select them by. A SQL collection stores them in table, with a column for each of columns and a table
+// of their own for each of lists, as the migrations lay them out.
+type schema[T any] struct {
+	table string
+	// key tells documents apart. It is the primary key of the table, and selected by as "id".
+	key func(*T) string
+	// columns are the fields documents are selected by. Their values are strings or int64s, or nil for
+	// a document without one.
+	columns map[string]func(*T) interface{}
+	// lists are fields with several values. A document is selected by a list if one of its values is.
+	lists map[string]func(*T) []string
+	// unique names the columns no two documents may share a value of. Like Mongo's sparse unique
+	// indexes, they leave out documents without one.
+	unique []string
+}
+
+// where selects the documents whose fields have the given values. A value can also be an anyOf, a
+// greaterThan or a lessThan, and nil selects the documents without a value, or without any value of a
+// list. Like Mongo's operators, the fields $and, $or and $nor hold a []where, and select the documents
+// that all, any or none of those wheres select.
+type where map[string]interface{}
+
+// anyOf selects documents whose field has one of the values
+type anyOf []string
+
+// greaterThan selects documents whose field is a greater number
+type greaterThan int64
+
+// lessThan selects documents whose field is a smaller number
+type lessThan int64
+
+// all selects every document
+var all = where{}
+
+// none selects no document
+var none = where{"id": anyOf{}}
+
+// values returns the values a document has for a field of the schema, for selecting it in memory
+func (s schema[T]) values(doc *T, field string) []interface{} {
+	if field == "id" {
+		return []interface{}{s.key(doc)}
+	}
+	if column, ok := s.columns[field]; ok {
+		return []interface{}{column(doc)}
+	}
+	if list, ok := s.lists[field]; ok {
+		var values []interface{}
+		for _, value := range list(doc) {
+			values = append(values, value)
+		}
+		return values
+	}
+	panic(fmt.Sprintf("%s have no field %s", s.table, field))
+}
+
+// selects reports whether a document is selected by a where
+func (s schema[T]) selects(doc *T, by where) bool {
+	selectedBy := func(by where) bool { return s.selects(doc, by) }
+	for field, want := range by {
+		var selected bool
+		switch field {
+		case "$and":
+			selected = !slices.ContainsFunc(want.([]where), func(by where) bool { return !s.selects(doc, by) })
+		case "$or":
+			selected = slices.ContainsFunc(want.([]where), selectedBy)
+		case "$nor":
+			selected = !slices.ContainsFunc(want.([]where), selectedBy)
+		default:
+			values := s.values(doc, field)
+			selected = (want == nil && len(values) == 0) ||
+				slices.ContainsFunc(values, func(value interface{}) bool { return selectsValue(want, value) })
+		}
+		if !selected {
+			return false
+		}
+	}
+	return true
+}
+
+func selectsValue(want, value interface{}) bool {
+	switch want := want.(type) {
+	case anyOf:
+		text, ok := value.(string)
+		return ok && slices.Contains(want, text)
+	case greaterThan:
+		number, ok := value.(int64)
+		return ok && number > int64(want)
+	case lessThan:
+		number, ok := value.(int64)
+		return ok && number < int64(want)
+	}
+	return value == want
+}
+
+// cloneDocument copies a document the way storing it in Mongo and reading it back would
+func cloneDocument[T any](doc *T) (*T, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var clone T
+	if err := bson.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// applyUpdate applies an update built by updateDocument to a copy of the document
+func applyUpdate[T any](doc *T, update bson.M) (*T, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	if set, ok := update["$set"].(bson.M); ok {
+		for field, value := range set {
+			fields[field] = value
+		}
+	}
+	if unset, ok := update["$unset"].(bson.M); ok {
+		for field := range unset {
+			delete(fields, field)
+		}
+	}
+
+	if data, err = bson.Marshal(fields); err != nil {
+		return nil, err
+	}
+	var updated T
+	if err := bson.Unmarshal(data, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// collectionError reports why a collection operation failed, the same way the Mongo repositories do
+func collectionError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+// page returns the documents from offset on, at most limit of them. Like Mongo, a limit of zero means
+// no limit.
+func page[T any](docs []T, offset, limit int) []T {
+	if offset >= len(docs) {
+		return nil
+	}
+	docs = docs[offset:]
+	if limit > 0 && limit < len(docs) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+// compareIDs orders object IDs the way Mongo does, which is also the order they were generated in
+func compareIDs(a, b primitive.ObjectID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// hexIDs selects documents by any of the object IDs
+func hexIDs(ids []primitive.ObjectID) anyOf {
+	hexes := make(anyOf, len(ids))
+	for i, id := range ids {
+		hexes[i] = id.Hex()
+	}
+	return hexes
+}
+
+// optionalID is the column value of an object ID a document may not have
+func optionalID(id *primitive.ObjectID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.Hex()
+}
+
+// ownerOf is the owner column of documents that are in a user's personal library or in a group. It
+// lets a single lookup select a user's own documents together with their groups'.
+func ownerOf(userID string, groupID *primitive.ObjectID) string {
+	if groupID != nil {
+		return "group:" + groupID.Hex()
+	}
+	return "user:" + userID
+}
+
+// ownedBy selects the documents in the user's personal library and in the groups
+func ownedBy(userID string, groupIDs []primitive.ObjectID) anyOf {
+	owners := anyOf{ownerOf(userID, nil)}
+	for _, groupID := range groupIDs {
+		owners = append(owners, ownerOf("", &groupID))
+	}
+	return owners
+}
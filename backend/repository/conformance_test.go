@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"tranquil-pages/database"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// conformance runs a test against every implementation of a repository, each starting out empty, so
+// that the stores are held to the same behaviour. Stores the tests have no server for are skipped.
+func conformance[R any](t *testing.T, newMongo func(*database.Database) R, newMemory func() R, newSQL func(*database.SQLDatabase) R, test func(t *testing.T, repo R)) {
+	t.Run("mongo", func(t *testing.T) {
+		testDB, err := database.NewTestDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, newMongo(testDB.Database))
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, newMemory())
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		testDB, err := database.NewTestPostgresDatabase()
+		if errors.Is(err, database.ErrNoTestDatabase) {
+			t.Skip(err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, newSQL(testDB.SQLDatabase))
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		testDB, err := database.NewTestSQLiteDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer testDB.Close()
+
+		test(t, newSQL(testDB.SQLDatabase))
+	})
+}
+
+// bookRepositoryConformance runs a test against every implementation of BookRepository
+func bookRepositoryConformance(t *testing.T, test func(t *testing.T, repo BookRepository)) {
+	conformance(t, NewBookRepository, NewMemoryBookRepository, NewSQLBookRepository, test)
+}
+
+// jobRepositoryConformance runs a test against every implementation of JobRepository
+func jobRepositoryConformance(t *testing.T, test func(t *testing.T, repo JobRepository)) {
+	conformance(t, NewJobRepository, NewMemoryJobRepository, NewSQLJobRepository, test)
+}
+
+// skipOnFerretDB skips a test when the Mongo test database is FerretDB, which can stand in for MongoDB
+// on a development machine but doesn't make conditional updates atomic or support conditions in $pull
+func skipOnFerretDB(t *testing.T, db *database.Database, reason string) {
+	var info bson.M
+	command := bson.D{{Key: "buildInfo", Value: 1}}
+	if err := db.GetCollection("books").Database().Client().Database("admin").RunCommand(t.Context(), command).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if _, isFerretDB := info["ferretdbVersion"]; isFerretDB {
+		t.Skip("FerretDB " + reason)
+	}
+}
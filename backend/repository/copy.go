@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CopyToSQL copies every document of the Mongo database into the tables of the SQL database, each
+// collection into the table of the same name. Documents already in the SQL database are replaced by
+// their Mongo version, so a copy that was interrupted can simply be run again.
+//
+// The Mongo database is only read. Legacy star ratings are converted to scores as they are copied,
+// since the SQL repositories only ever hold scores.
+func CopyToSQL(ctx context.Context, from *database.Database, to *database.SQLDatabase) error {
+	copies := []func() error{
+		func() error { return copyCollection(ctx, from, to, bookSchema, convertLegacyBook) },
+		func() error { return copyCollection(ctx, from, to, profileSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, followSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, activitySchema, convertLegacyActivity) },
+		func() error { return copyCollection(ctx, from, to, groupSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, highlightSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, metadataCacheSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, seriesSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, authorSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, journalSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, shelfSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, jobSchema, nil) },
+		func() error { return copyCollection(ctx, from, to, jobRecordSchema, nil) },
+	}
+	for _, copy := range copies {
+		if err := copy(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertLegacyBook gives the copy of a book the scores of its legacy ratings, and of its reads'
+func convertLegacyBook(raw bson.Raw, book *models.Book) error {
+	var legacy legacyRatingDocument
+	if err := bson.Unmarshal(raw, &legacy); err != nil {
+		return err
+	}
+	if legacy.Rating != nil {
+		book.RatingScore = legacyScore(*legacy.Rating)
+	}
+	for i, read := range legacy.Reads {
+		if read.Rating != nil && i < len(book.Reads) {
+			book.Reads[i].RatingScore = legacyScore(*read.Rating)
+		}
+	}
+	return nil
+}
+
+// convertLegacyActivity gives the copy of an activity the score of its legacy rating
+func convertLegacyActivity(raw bson.Raw, activity *models.Activity) error {
+	var legacy legacyRatingDocument
+	if err := bson.Unmarshal(raw, &legacy); err != nil {
+		return err
+	}
+	if legacy.Rating != nil {
+		activity.RatingScore = legacyScore(*legacy.Rating)
+	}
+	return nil
+}
+
+// copyCollection copies the documents of a Mongo collection into the table of the schema, in batches
+// and in the order Mongo returns them. convert, if given, fixes up each copy from the Mongo document.
+func copyCollection[T any](ctx context.Context, from *database.Database, to *database.SQLDatabase, schema schema[T], convert func(bson.Raw, *T) error) error {
+	cursor, err := from.GetCollection(schema.table).Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", schema.table, err)
+	}
+	defer cursor.Close(ctx)
+
+	target := newSQLCollection(to, schema)
+	copied := 0
+	batch := make([]*T, 0, sqlChunk)
+	flush := func() error {
+		if err := target.upsert(ctx, batch...); err != nil {
+			return fmt.Errorf("failed to write %s: %w", schema.table, err)
+		}
+		copied += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc T
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", schema.table, err)
+		}
+		if convert != nil {
+			if err := convert(cursor.Current, &doc); err != nil {
+				return fmt.Errorf("failed to convert %s: %w", schema.table, err)
+			}
+		}
+		batch = append(batch, &doc)
+		if len(batch) == sqlChunk {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", schema.table, err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Copied %d %s", copied, schema.table)
+	return nil
+}
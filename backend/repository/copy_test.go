@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCopyToSQL(t *testing.T) {
+	t.Run("postgres", func(t *testing.T) {
+		sqlDB, err := database.NewTestPostgresDatabase()
+		if errors.Is(err, database.ErrNoTestDatabase) {
+			t.Skip(err)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sqlDB.Close()
+
+		testCopyToSQL(t, sqlDB.SQLDatabase)
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		sqlDB, err := database.NewTestSQLiteDatabase()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sqlDB.Close()
+
+		testCopyToSQL(t, sqlDB.SQLDatabase)
+	})
+}
+
+func testCopyToSQL(t *testing.T, sqlDB *database.SQLDatabase) {
+	// Given
+	mongoDB, err := database.NewTestDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mongoDB.Close()
+
+	from := NewMongoRepositories(mongoDB.Database)
+	book := createBook(t, from.Books, models.Book{UserID: "alice", Title: "The Dispossessed", Tags: []string{"sci-fi"}})
+	assert.NoError(t, from.Profiles.Save(t.Context(), &models.Profile{UserID: "alice", Handle: "alice"}))
+	assert.NoError(t, from.Follows.Follow(t.Context(), "bob", "alice"))
+
+	legacyID, legacyReadID := primitive.NewObjectID(), primitive.NewObjectID()
+	legacyBook := bson.M{"_id": legacyID, "user_id": "alice", "title": "Legacy", "rating": 4, "reads": bson.A{bson.M{"_id": legacyReadID, "rating": 3}}}
+	_, err = mongoDB.GetCollection("books").InsertOne(t.Context(), legacyBook)
+	assert.NoError(t, err)
+	activityID := primitive.NewObjectID()
+	_, err = mongoDB.GetCollection("activities").InsertOne(t.Context(), bson.M{"_id": activityID, "user_id": "alice", "book_id": legacyID, "type": models.ActivityRated, "rating": 5})
+	assert.NoError(t, err)
+
+	// When
+	err = CopyToSQL(t.Context(), mongoDB.Database, sqlDB)
+	assert.NoError(t, err)
+	// Copying again replaces what was copied instead of failing on it
+	assert.NoError(t, CopyToSQL(t.Context(), mongoDB.Database, sqlDB))
+
+	// Then
+	to := NewSQLRepositories(sqlDB)
+	books, err := to.Books.FindByUserID(t.Context(), "alice", models.BookFilter{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"The Dispossessed", "Legacy"}, titles(books))
+
+	copied, err := to.Books.FindById(t.Context(), book.ID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sci-fi"}, copied.Tags)
+	assert.Equal(t, book.CreatedAt, copied.CreatedAt)
+
+	legacy, err := to.Books.FindById(t.Context(), legacyID.Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, 80, legacy.RatingScore, "legacy star ratings are converted as they are copied")
+	assert.Equal(t, 60, legacy.Reads[0].RatingScore)
+	activities, err := to.Activities.FindByUserIDs(t.Context(), []string{"alice"}, primitive.NilObjectID, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, activities[0].RatingScore)
+
+	var source bson.M
+	assert.NoError(t, mongoDB.GetCollection("books").FindOne(t.Context(), bson.M{"_id": legacyID}).Decode(&source))
+	assert.EqualValues(t, 4, source["rating"], "the Mongo database is left as it was")
+	assert.NotContains(t, source, "rating_score")
+
+	profile, err := to.Profiles.FindByHandle(t.Context(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", profile.UserID)
+
+	followees, err := to.Follows.FindFolloweeIDs(t.Context(), "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, followees)
+}
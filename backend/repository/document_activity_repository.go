@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentActivityRepository struct {
+	activities collection[models.Activity]
+}
+
+var activitySchema = schema[models.Activity]{
+	table: "activities",
+	key:   func(activity *models.Activity) string { return activity.ID.Hex() },
+	columns: map[string]func(*models.Activity) interface{}{
+		"user_id": func(activity *models.Activity) interface{} { return activity.UserID },
+	},
+}
+
+func NewMemoryActivityRepository() ActivityRepository {
+	return &DocumentActivityRepository{activities: newMemoryCollection(activitySchema)}
+}
+
+func NewSQLActivityRepository(db *database.SQLDatabase) ActivityRepository {
+	return &DocumentActivityRepository{activities: newSQLCollection(db, activitySchema)}
+}
+
+func (r *DocumentActivityRepository) Create(ctx context.Context, activity *models.Activity) error {
+	activity.ID = primitive.NewObjectID()
+	activity.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return collectionError(r.activities.insert(ctx, activity), "CreateActivity")
+}
+
+func (r *DocumentActivityRepository) CreateMany(ctx context.Context, activities []*models.Activity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, activity := range activities {
+		activity.ID = primitive.NewObjectID()
+		activity.CreatedAt = now
+	}
+	return collectionError(r.activities.insert(ctx, activities...), "CreateActivities")
+}
+
+func (r *DocumentActivityRepository) FindByUserIDs(ctx context.Context, userIDs []string, before primitive.ObjectID, limit int) ([]models.Activity, error) {
+	activities, err := r.activities.find(ctx, where{"user_id": anyOf(userIDs)}, func(activity *models.Activity) bool {
+		return before.IsZero() || compareIDs(activity.ID, before) < 0
+	})
+	if err := collectionError(err, "FindActivitiesByUserIDs"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(activities, func(a, b models.Activity) int { return -compareIDs(a.ID, b.ID) })
+	return page(activities, 0, limit), nil
+}
+
+// MigrateRatings has nothing to do. The SQL and in-memory stores came after rating scores, and
+// CopyToSQL converts legacy ratings as it copies activities from Mongo, so no activity stored here has one.
+func (r *DocumentActivityRepository) MigrateRatings(ctx context.Context) (int, error) {
+	return 0, collectionError(ctx.Err(), "MigrateActivityRatings")
+}
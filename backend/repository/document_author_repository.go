@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentAuthorRepository struct {
+	authors collection[models.Author]
+}
+
+var authorSchema = schema[models.Author]{
+	table: "authors",
+	key:   func(author *models.Author) string { return author.ID.Hex() },
+	columns: map[string]func(*models.Author) interface{}{
+		"user_id": func(author *models.Author) interface{} { return author.UserID },
+	},
+}
+
+func NewMemoryAuthorRepository() AuthorRepository {
+	return &DocumentAuthorRepository{authors: newMemoryCollection(authorSchema)}
+}
+
+func NewSQLAuthorRepository(db *database.SQLDatabase) AuthorRepository {
+	return &DocumentAuthorRepository{authors: newSQLCollection(db, authorSchema)}
+}
+
+func (r *DocumentAuthorRepository) Create(ctx context.Context, author *models.Author) error {
+	author.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	author.UpdatedAt = author.CreatedAt
+	if author.ID.IsZero() {
+		author.ID = primitive.NewObjectID()
+	}
+
+	return collectionError(r.authors.insert(ctx, author), "CreateAuthor")
+}
+
+func (r *DocumentAuthorRepository) findOne(ctx context.Context, by where, match func(*models.Author) bool, operation string) (*models.Author, error) {
+	author, err := r.authors.findOne(ctx, by, match)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, operation); err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
+func (r *DocumentAuthorRepository) FindByID(ctx context.Context, id string) (*models.Author, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+	return r.findOne(ctx, where{"id": objectID.Hex()}, nil, "FindAuthorByID")
+}
+
+func (r *DocumentAuthorRepository) FindByKey(ctx context.Context, userID, key string) (*models.Author, error) {
+	return r.findOne(ctx, where{"user_id": userID}, func(author *models.Author) bool {
+		return slices.Contains(author.Keys, key)
+	}, "FindAuthorByKey")
+}
+
+func (r *DocumentAuthorRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Author, error) {
+	return r.find(ctx, where{"id": hexIDs(ids)}, "FindAuthorsByIDs")
+}
+
+func (r *DocumentAuthorRepository) FindByUserID(ctx context.Context, userID string) ([]models.Author, error) {
+	return r.find(ctx, where{"user_id": userID}, "FindAuthorsByUserID")
+}
+
+func (r *DocumentAuthorRepository) find(ctx context.Context, by where, operation string) ([]models.Author, error) {
+	authors, err := r.authors.find(ctx, by, nil)
+	if err := collectionError(err, operation); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(authors, func(a, b models.Author) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), compareIDs(a.ID, b.ID))
+	})
+	return authors, nil
+}
+
+func (r *DocumentAuthorRepository) Update(ctx context.Context, author *models.Author) error {
+	author.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	matched, err := r.authors.update(ctx, where{"id": author.ID.Hex()}, nil,
+		func(*models.Author) (*models.Author, error) { return author, nil },
+	)
+	if err := collectionError(err, "UpdateAuthor"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DocumentAuthorRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.authors.remove(ctx, where{"id": id.Hex()}, nil)
+	return collectionError(err, "DeleteAuthor")
+}
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DocumentBookRepository keeps books in a collection, in memory or in a SQL database. It behaves like
+// MongoBookRepository, down to the errors it returns.
+type DocumentBookRepository struct {
+	books collection[models.Book]
+}
+
+var bookSchema = schema[models.Book]{
+	table: "books",
+	key:   func(book *models.Book) string { return book.ID.Hex() },
+	columns: map[string]func(*models.Book) interface{}{
+		"owner":     func(book *models.Book) interface{} { return ownerOf(book.UserID, book.GroupID) },
+		"series_id": func(book *models.Book) interface{} { return optionalID(book.SeriesID) },
+		// The fields shelf queries compare, see queryWhere
+		"status":       func(book *models.Book) interface{} { return sparse(string(book.Status)) },
+		"format":       func(book *models.Book) interface{} { return sparse(string(book.Format)) },
+		"rating_score": func(book *models.Book) interface{} { return int64(book.RatingScore) },
+		"created_at":   func(book *models.Book) interface{} { return int64(book.CreatedAt) },
+		"finished_at": func(book *models.Book) interface{} {
+			if book.FinishedAt == nil {
+				return nil
+			}
+			return int64(*book.FinishedAt)
+		},
+	},
+	lists: map[string]func(*models.Book) []string{
+		"tag": func(book *models.Book) []string { return book.Tags },
+		"author_id": func(book *models.Book) []string {
+			authorIDs := make([]string, len(book.Authors))
+			for i, author := range book.Authors {
+				authorIDs[i] = author.AuthorID.Hex()
+			}
+			return authorIDs
+		},
+	},
+}
+
+// NewMemoryBookRepository returns an empty repository that keeps books in memory, for development and tests
+func NewMemoryBookRepository() BookRepository {
+	return &DocumentBookRepository{books: newMemoryCollection(bookSchema)}
+}
+
+// NewSQLBookRepository returns a repository that keeps books in the database
+func NewSQLBookRepository(db *database.SQLDatabase) BookRepository {
+	return &DocumentBookRepository{books: newSQLCollection(db, bookSchema)}
+}
+
+func (r *DocumentBookRepository) handleDBError(err error, operation string) error {
+	if errors.Is(err, errDuplicateKey) {
+		return appErrors.ErrDuplicateBook
+	}
+	return collectionError(err, operation)
+}
+
+// matchesBookFilter is the in-memory equivalent of bookFilter
+func matchesBookFilter(book *models.Book, filter models.BookFilter) bool {
+	if filter.Overdue && (book.CurrentLoan == nil || book.CurrentLoan.DueAt >= primitive.NewDateTimeFromTime(time.Now())) {
+		return false
+	}
+	if len(filter.Formats) > 0 && !slices.Contains(filter.Formats, book.Format) {
+		return false
+	}
+	return true
+}
+
+func (r *DocumentBookRepository) Create(ctx context.Context, book *models.Book) error {
+	book.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	book.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	if book.ID.IsZero() {
+		book.ID = primitive.NewObjectID()
+	}
+	return r.handleDBError(r.books.insert(ctx, book), "CreateBook")
+}
+
+func (r *DocumentBookRepository) CreateMany(ctx context.Context, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, book := range books {
+		if book.ID.IsZero() {
+			book.ID = primitive.NewObjectID()
+		}
+		book.CreatedAt, book.UpdatedAt = now, now
+	}
+	return r.handleDBError(r.books.insert(ctx, books...), "CreateBooks")
+}
+
+func (r *DocumentBookRepository) findOne(ctx context.Context, bookID primitive.ObjectID, operation string) (*models.Book, error) {
+	book, err := r.books.findOne(ctx, where{"id": bookID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (r *DocumentBookRepository) FindById(ctx context.Context, id string) (*models.Book, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+	return r.findOne(ctx, objectID, "GetBookById")
+}
+
+func (r *DocumentBookRepository) find(ctx context.Context, by where, match func(*models.Book) bool, operation string) ([]models.Book, error) {
+	books, err := r.books.find(ctx, by, match)
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+func (r *DocumentBookRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, where{"id": hexIDs(ids)}, nil, "FindByIDs")
+}
+
+// updateOne applies change to the book and reports appErrors.ErrNotFound if there is no such book
+func (r *DocumentBookRepository) updateOne(ctx context.Context, bookID primitive.ObjectID, operation string, change func(*models.Book) (*models.Book, error)) error {
+	matched, err := r.books.update(ctx, where{"id": bookID.Hex()}, nil, change)
+	if err := r.handleDBError(err, operation); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+// Update stores the book's fields, except for loans, reads, the cover and progress which have their own methods
+func (r *DocumentBookRepository) Update(ctx context.Context, book *models.Book) error {
+	book.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	update, err := updateDocument(book, "_id", "current_loan", "loan_history", "reads", "cover", "progress")
+	if err != nil {
+		return r.handleDBError(err, "UpdateBook")
+	}
+	return r.updateOne(ctx, book.ID, "UpdateBook", func(stored *models.Book) (*models.Book, error) {
+		return applyUpdate(stored, update)
+	})
+}
+
+func (r *DocumentBookRepository) UpdateMany(ctx context.Context, books []*models.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	updates := make(map[primitive.ObjectID]bson.M, len(books))
+	ids := make([]primitive.ObjectID, 0, len(books))
+	for _, book := range books {
+		book.UpdatedAt = now
+		update, err := updateDocument(book, "_id", "current_loan", "loan_history", "cover")
+		if err != nil {
+			return r.handleDBError(err, "UpdateBooks")
+		}
+		updates[book.ID] = update
+		ids = append(ids, book.ID)
+	}
+
+	_, err := r.books.update(ctx, where{"id": hexIDs(ids)}, nil,
+		func(book *models.Book) (*models.Book, error) { return applyUpdate(book, updates[book.ID]) },
+	)
+	return r.handleDBError(err, "UpdateBooks")
+}
+
+func (r *DocumentBookRepository) SetCover(ctx context.Context, bookID primitive.ObjectID, cover *models.BookCover) error {
+	return r.updateOne(ctx, bookID, "SetCover", func(book *models.Book) (*models.Book, error) {
+		book.Cover = cover
+		return book, nil
+	})
+}
+
+func (r *DocumentBookRepository) SetProgress(ctx context.Context, bookID primitive.ObjectID, progress *models.ReadingProgress) error {
+	return r.updateOne(ctx, bookID, "SetProgress", func(book *models.Book) (*models.Book, error) {
+		book.Progress = progress
+		return book, nil
+	})
+}
+
+func (r *DocumentBookRepository) AddRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error {
+	return r.updateOne(ctx, bookID, "AddRead", func(book *models.Book) (*models.Book, error) {
+		book.Reads = append(book.Reads, *read)
+		return book, nil
+	})
+}
+
+// readIndex returns the position of the read in the book's reads, or -1
+func readIndex(book *models.Book, readID primitive.ObjectID) int {
+	return slices.IndexFunc(book.Reads, func(read models.Read) bool { return read.ID == readID })
+}
+
+func (r *DocumentBookRepository) UpdateRead(ctx context.Context, bookID primitive.ObjectID, read *models.Read) error {
+	found := false
+	err := r.updateOne(ctx, bookID, "UpdateRead", func(book *models.Book) (*models.Book, error) {
+		if i := readIndex(book, read.ID); i >= 0 {
+			book.Reads[i] = *read
+			found = true
+		}
+		return book, nil
+	})
+	if err == nil && !found {
+		return appErrors.ErrNotFound
+	}
+	return err
+}
+
+func (r *DocumentBookRepository) DeleteRead(ctx context.Context, bookID, readID primitive.ObjectID) error {
+	err := r.updateOne(ctx, bookID, "DeleteRead", func(book *models.Book) (*models.Book, error) {
+		if i := readIndex(book, readID); i >= 0 {
+			book.Reads = slices.Delete(book.Reads, i, i+1)
+		}
+		return book, nil
+	})
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (r *DocumentBookRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return appErrors.ErrInvalidID
+	}
+
+	_, err = r.books.remove(ctx, where{"id": objectID.Hex()}, nil)
+	return r.handleDBError(err, "DeleteBook")
+}
+
+func (r *DocumentBookRepository) FindByUserID(ctx context.Context, userID string, filter models.BookFilter) ([]models.Book, error) {
+	return r.find(ctx, where{"owner": ownerOf(userID, nil)}, func(book *models.Book) bool {
+		return matchesBookFilter(book, filter)
+	}, "FindByUserID")
+}
+
+func (r *DocumentBookRepository) FindByQuery(ctx context.Context, userID string, node query.Node, offset, limit int) ([]models.Book, error) {
+	// A query the evaluator can't handle fails before any book is looked at, as it does on Mongo
+	if _, err := queryFilter(node); err != nil {
+		return nil, r.handleDBError(err, "FindByQuery queryFilter")
+	}
+
+	// The where leaves out the books the typed conditions rule out. Conditions on the title and author
+	// are only checked on the books it reads, so a query of nothing else reads all of the user's books.
+	by := where{"owner": ownerOf(userID, nil), "$and": []where{queryWhere(node, false)}}
+	books, err := r.find(ctx, by, func(book *models.Book) bool {
+		return matchesQuery(book, node)
+	}, "FindByQuery")
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(books, func(a, b models.Book) int {
+		if a.CreatedAt != b.CreatedAt {
+			return -cmp.Compare(a.CreatedAt, b.CreatedAt)
+		}
+		return -compareIDs(a.ID, b.ID)
+	})
+	return page(books, offset, limit), nil
+}
+
+func (r *DocumentBookRepository) FindByGroupID(ctx context.Context, groupID primitive.ObjectID, filter models.BookFilter) ([]models.Book, error) {
+	return r.find(ctx, where{"owner": ownerOf("", &groupID)}, func(book *models.Book) bool {
+		return matchesBookFilter(book, filter)
+	}, "FindByGroupID")
+}
+
+func (r *DocumentBookRepository) FindBySeriesID(ctx context.Context, seriesID primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, where{"series_id": seriesID.Hex()}, nil, "FindBySeriesID")
+}
+
+func (r *DocumentBookRepository) ClearSeries(ctx context.Context, seriesID primitive.ObjectID) error {
+	_, err := r.books.update(ctx, where{"series_id": seriesID.Hex()}, nil,
+		func(book *models.Book) (*models.Book, error) {
+			book.SeriesID, book.Volume = nil, nil
+			return book, nil
+		},
+	)
+	return r.handleDBError(err, "ClearSeries")
+}
+
+func (r *DocumentBookRepository) FindByAuthorID(ctx context.Context, authorID primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, where{"author_id": authorID.Hex()}, nil, "FindByAuthorID")
+}
+
+func (r *DocumentBookRepository) FindWithoutAuthorLinks(ctx context.Context) ([]models.Book, error) {
+	return r.find(ctx, where{"author_id": nil}, func(book *models.Book) bool {
+		return book.GroupID == nil && book.Author != ""
+	}, "FindWithoutAuthorLinks")
+}
+
+func (r *DocumentBookRepository) SetAuthors(ctx context.Context, bookID primitive.ObjectID, authors []models.BookAuthor, author string) error {
+	return r.updateOne(ctx, bookID, "SetAuthors", func(book *models.Book) (*models.Book, error) {
+		book.Authors, book.Author = authors, author
+		return book, nil
+	})
+}
+
+func (r *DocumentBookRepository) FindOnLoan(ctx context.Context, userID string, groupIDs []primitive.ObjectID) ([]models.Book, error) {
+	return r.find(ctx, where{"owner": ownedBy(userID, groupIDs)}, func(book *models.Book) bool {
+		return book.CurrentLoan != nil
+	}, "FindOnLoan")
+}
+
+func (r *DocumentBookRepository) Lend(ctx context.Context, bookID primitive.ObjectID, loan *models.Loan) error {
+	// Checking for an open loan inside the update makes the check and the write a single atomic step
+	matched, err := r.books.update(ctx, where{"id": bookID.Hex()},
+		func(book *models.Book) bool { return book.CurrentLoan == nil },
+		func(book *models.Book) (*models.Book, error) {
+			book.CurrentLoan = loan
+			return book, nil
+		},
+	)
+	if err := r.handleDBError(err, "Lend"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrBookOnLoan
+	}
+	return nil
+}
+
+func (r *DocumentBookRepository) Return(ctx context.Context, bookID primitive.ObjectID, returnedAt time.Time) (*models.Loan, error) {
+	var loan *models.Loan
+	err := r.updateOne(ctx, bookID, "Return", func(book *models.Book) (*models.Book, error) {
+		if book.CurrentLoan == nil {
+			return book, nil
+		}
+		loan = book.CurrentLoan
+		returned := primitive.NewDateTimeFromTime(returnedAt)
+		loan.ReturnedAt = &returned
+
+		book.LoanHistory = append(book.LoanHistory, *loan)
+		book.CurrentLoan = nil
+		return book, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, appErrors.ErrBookNotOnLoan
+	}
+	return loan, nil
+}
+
+func (r *DocumentBookRepository) Count(ctx context.Context) (int64, error) {
+	count, err := r.books.count(ctx, all, nil)
+	if err := r.handleDBError(err, "Count"); err != nil {
+		return 0, err
+	}
+	return int64(count), nil
+}
+
+// MigrateRatings has nothing to do. The SQL and in-memory stores came after rating scores, and CopyToSQL
+// converts legacy ratings as it copies books from Mongo, so no book stored here has one.
+func (r *DocumentBookRepository) MigrateRatings(ctx context.Context) (int, error) {
+	return 0, r.handleDBError(ctx.Err(), "MigrateRatings")
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentFollowRepository struct {
+	follows collection[models.Follow]
+}
+
+var followSchema = schema[models.Follow]{
+	table: "follows",
+	// Follows are told apart the way the unique index on follows does. Quoting the IDs keeps any
+	// two pairs of them apart.
+	key: func(follow *models.Follow) string {
+		return strconv.Quote(follow.FollowerID) + strconv.Quote(follow.FolloweeID)
+	},
+	columns: map[string]func(*models.Follow) interface{}{
+		"follower_id": func(follow *models.Follow) interface{} { return follow.FollowerID },
+		"followee_id": func(follow *models.Follow) interface{} { return follow.FolloweeID },
+	},
+}
+
+func NewMemoryFollowRepository() FollowRepository {
+	return &DocumentFollowRepository{follows: newMemoryCollection(followSchema)}
+}
+
+func NewSQLFollowRepository(db *database.SQLDatabase) FollowRepository {
+	return &DocumentFollowRepository{follows: newSQLCollection(db, followSchema)}
+}
+
+func (r *DocumentFollowRepository) Follow(ctx context.Context, followerID, followeeID string) error {
+	follow := &models.Follow{
+		ID:         primitive.NewObjectID(),
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	err := r.follows.insert(ctx, follow)
+	if errors.Is(err, errDuplicateKey) {
+		return nil
+	}
+	return collectionError(err, "Follow")
+}
+
+func (r *DocumentFollowRepository) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	_, err := r.follows.remove(ctx, where{"follower_id": followerID, "followee_id": followeeID}, nil)
+	return collectionError(err, "Unfollow")
+}
+
+func (r *DocumentFollowRepository) FindFolloweeIDs(ctx context.Context, followerID string) ([]string, error) {
+	follows, err := r.follows.find(ctx, where{"follower_id": followerID}, nil)
+	if err := collectionError(err, "FindFolloweeIDs"); err != nil {
+		return nil, err
+	}
+
+	followeeIDs := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		followeeIDs = append(followeeIDs, follow.FolloweeID)
+	}
+	return followeeIDs, nil
+}
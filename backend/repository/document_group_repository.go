@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentGroupRepository struct {
+	groups collection[models.Group]
+}
+
+var groupSchema = schema[models.Group]{
+	table: "groups",
+	key:   func(group *models.Group) string { return group.ID.Hex() },
+	lists: map[string]func(*models.Group) []string{
+		"member_id": func(group *models.Group) []string {
+			memberIDs := make([]string, len(group.Members))
+			for i, member := range group.Members {
+				memberIDs[i] = member.UserID
+			}
+			return memberIDs
+		},
+		"invite_token": func(group *models.Group) []string {
+			tokens := make([]string, len(group.Invites))
+			for i, invite := range group.Invites {
+				tokens[i] = invite.Token
+			}
+			return tokens
+		},
+	},
+}
+
+func NewMemoryGroupRepository() GroupRepository {
+	return &DocumentGroupRepository{groups: newMemoryCollection(groupSchema)}
+}
+
+func NewSQLGroupRepository(db *database.SQLDatabase) GroupRepository {
+	return &DocumentGroupRepository{groups: newSQLCollection(db, groupSchema)}
+}
+
+func (r *DocumentGroupRepository) Create(ctx context.Context, group *models.Group) error {
+	group.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	group.UpdatedAt = group.CreatedAt
+	if group.ID.IsZero() {
+		group.ID = primitive.NewObjectID()
+	}
+
+	return collectionError(r.groups.insert(ctx, group), "CreateGroup")
+}
+
+func (r *DocumentGroupRepository) findOne(ctx context.Context, by where, operation string) (*models.Group, error) {
+	group, err := r.groups.findOne(ctx, by, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, operation); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (r *DocumentGroupRepository) FindByID(ctx context.Context, id string) (*models.Group, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+	return r.findOne(ctx, where{"id": objectID.Hex()}, "FindGroupByID")
+}
+
+func (r *DocumentGroupRepository) FindByInviteToken(ctx context.Context, token string) (*models.Group, error) {
+	return r.findOne(ctx, where{"invite_token": token}, "FindGroupByInviteToken")
+}
+
+func (r *DocumentGroupRepository) FindByMember(ctx context.Context, userID string) ([]models.Group, error) {
+	groups, err := r.groups.find(ctx, where{"member_id": userID}, nil)
+	if err := collectionError(err, "FindGroupsByMember"); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(groups, func(a, b models.Group) int { return strings.Compare(a.Name, b.Name) })
+	return groups, nil
+}
+
+func (r *DocumentGroupRepository) Update(ctx context.Context, group *models.Group) error {
+	updated := *group
+	updated.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	updated.Version = group.Version + 1
+
+	matched, err := r.groups.update(ctx, where{"id": group.ID.Hex()},
+		func(stored *models.Group) bool { return stored.Version == group.Version },
+		func(*models.Group) (*models.Group, error) { return &updated, nil },
+	)
+	if err := collectionError(err, "UpdateGroup"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		if _, err := r.FindByID(ctx, group.ID.Hex()); err != nil {
+			return err
+		}
+		return appErrors.ErrGroupChanged
+	}
+
+	*group = updated
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentHighlightRepository struct {
+	highlights collection[models.Highlight]
+}
+
+var highlightSchema = schema[models.Highlight]{
+	table: "highlights",
+	key:   func(highlight *models.Highlight) string { return highlight.ID.Hex() },
+	columns: map[string]func(*models.Highlight) interface{}{
+		"owner":   func(highlight *models.Highlight) interface{} { return ownerOf(highlight.UserID, highlight.GroupID) },
+		"book_id": func(highlight *models.Highlight) interface{} { return highlight.BookID.Hex() },
+	},
+}
+
+func NewMemoryHighlightRepository() HighlightRepository {
+	return &DocumentHighlightRepository{highlights: newMemoryCollection(highlightSchema)}
+}
+
+func NewSQLHighlightRepository(db *database.SQLDatabase) HighlightRepository {
+	return &DocumentHighlightRepository{highlights: newSQLCollection(db, highlightSchema)}
+}
+
+func (r *DocumentHighlightRepository) Create(ctx context.Context, highlight *models.Highlight) error {
+	// Imported highlights keep the time they were made on the reader
+	highlight.ID = primitive.NewObjectID()
+	highlight.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	if highlight.CreatedAt == 0 {
+		highlight.CreatedAt = highlight.UpdatedAt
+	}
+
+	return collectionError(r.highlights.insert(ctx, highlight), "CreateHighlight")
+}
+
+func (r *DocumentHighlightRepository) FindByID(ctx context.Context, id string) (*models.Highlight, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	highlight, err := r.highlights.findOne(ctx, where{"id": objectID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindHighlightByID"); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+func (r *DocumentHighlightRepository) FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.Highlight, error) {
+	highlights, err := r.highlights.find(ctx, where{"book_id": bookID.Hex()}, nil)
+	if err := collectionError(err, "FindHighlightsByBookID"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(highlights, func(a, b models.Highlight) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), compareIDs(a.ID, b.ID))
+	})
+	return highlights, nil
+}
+
+func (r *DocumentHighlightRepository) Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.HighlightFilter, offset, limit int) ([]models.Highlight, error) {
+	by := where{"owner": ownedBy(userID, groupIDs)}
+	if filter.BookID != nil {
+		by["book_id"] = filter.BookID.Hex()
+	}
+
+	pattern := regexp.MustCompile("(?i)" + regexp.QuoteMeta(filter.Query))
+	highlights, err := r.highlights.find(ctx, by, func(highlight *models.Highlight) bool {
+		if filter.Query != "" && !pattern.MatchString(highlight.Text) && !pattern.MatchString(highlight.Note) {
+			return false
+		}
+		if filter.Tag != "" && !slices.Contains(highlight.Tags, filter.Tag) {
+			return false
+		}
+		return filter.Color == "" || highlight.Color == filter.Color
+	})
+	if err := collectionError(err, "SearchHighlights"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(highlights, func(a, b models.Highlight) int {
+		return -cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), compareIDs(a.ID, b.ID))
+	})
+	return page(highlights, offset, limit), nil
+}
+
+func (r *DocumentHighlightRepository) Update(ctx context.Context, highlight *models.Highlight) error {
+	highlight.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	matched, err := r.highlights.update(ctx, where{"id": highlight.ID.Hex()}, nil,
+		func(*models.Highlight) (*models.Highlight, error) { return highlight, nil },
+	)
+	if err := collectionError(err, "UpdateHighlight"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DocumentHighlightRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.highlights.remove(ctx, where{"id": id.Hex()}, nil)
+	return collectionError(err, "DeleteHighlight")
+}
+
+func (r *DocumentHighlightRepository) DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error {
+	_, err := r.highlights.remove(ctx, where{"book_id": bookID.Hex()}, nil)
+	return collectionError(err, "DeleteHighlightsByBookID")
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/importers"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// jobRecordDocument is a jobRecord with the ID Mongo would have given it
+type jobRecordDocument struct {
+	ID     primitive.ObjectID   `bson:"_id"`
+	JobID  primitive.ObjectID   `bson:"job_id"`
+	Record importers.BookRecord `bson:"record"`
+}
+
+type DocumentJobRepository struct {
+	jobs    collection[models.Job]
+	records collection[jobRecordDocument]
+}
+
+var jobSchema = schema[models.Job]{
+	table: "jobs",
+	key:   func(job *models.Job) string { return job.ID.Hex() },
+	columns: map[string]func(*models.Job) interface{}{
+		"status": func(job *models.Job) interface{} { return string(job.Status) },
+	},
+}
+
+var jobRecordSchema = schema[jobRecordDocument]{
+	table: "job_records",
+	key:   func(document *jobRecordDocument) string { return document.ID.Hex() },
+	columns: map[string]func(*jobRecordDocument) interface{}{
+		"job_id": func(document *jobRecordDocument) interface{} { return document.JobID.Hex() },
+		"entry":  func(document *jobRecordDocument) interface{} { return int64(document.Record.Entry) },
+	},
+}
+
+func NewMemoryJobRepository() JobRepository {
+	return &DocumentJobRepository{
+		jobs:    newMemoryCollection(jobSchema),
+		records: newMemoryCollection(jobRecordSchema),
+	}
+}
+
+func NewSQLJobRepository(db *database.SQLDatabase) JobRepository {
+	return &DocumentJobRepository{
+		jobs:    newSQLCollection(db, jobSchema),
+		records: newSQLCollection(db, jobRecordSchema),
+	}
+}
+
+func (r *DocumentJobRepository) Create(ctx context.Context, job *models.Job) error {
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	job.UpdatedAt = job.CreatedAt
+
+	return collectionError(r.jobs.insert(ctx, job), "CreateJob")
+}
+
+func (r *DocumentJobRepository) FindByID(ctx context.Context, id string) (*models.Job, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	job, err := r.jobs.findOne(ctx, where{"id": objectID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindJobByID"); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *DocumentJobRepository) Claim(ctx context.Context, owner string, leaseExpiresAt time.Time) (*models.Job, error) {
+	// Finding and updating in one step is what keeps two workers from claiming the same job
+	job, err := r.jobs.updateFirst(ctx, where{"status": string(models.JobQueued)}, nil,
+		func(a, b *models.Job) int {
+			return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), compareIDs(a.ID, b.ID))
+		},
+		func(job *models.Job) (*models.Job, error) {
+			lease := primitive.NewDateTimeFromTime(leaseExpiresAt)
+			job.Status = models.JobRunning
+			job.Owner = owner
+			job.LeaseExpiresAt = &lease
+			job.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+			return job, nil
+		},
+	)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "ClaimJob"); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *DocumentJobRepository) Requeue(ctx context.Context, owner string) (int, error) {
+	now := primitive.NewDateTimeFromTime(time.Now())
+	// Jobs claimed before leases were recorded have none, and are requeued like expired ones
+	abandoned := func(job *models.Job) bool {
+		return (owner != "" && job.Owner == owner) || job.LeaseExpiresAt == nil || *job.LeaseExpiresAt <= now
+	}
+	requeued, err := r.jobs.update(ctx, where{"status": string(models.JobRunning)}, abandoned,
+		func(job *models.Job) (*models.Job, error) {
+			job.Status = models.JobQueued
+			job.Owner = ""
+			job.LeaseExpiresAt = nil
+			job.UpdatedAt = now
+			return job, nil
+		},
+	)
+	if err := collectionError(err, "RequeueJobs"); err != nil {
+		return 0, err
+	}
+	return requeued, nil
+}
+
+func (r *DocumentJobRepository) Update(ctx context.Context, job *models.Job) error {
+	job.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	update, err := updateDocument(job, "_id", "user_id", "type", "format", "created_at")
+	if err != nil {
+		return collectionError(err, "UpdateJob")
+	}
+
+	held := func(stored *models.Job) bool { return stored.Owner == job.Owner }
+	matched, err := r.jobs.update(ctx, where{"id": job.ID.Hex(), "status": string(models.JobRunning)}, held,
+		func(stored *models.Job) (*models.Job, error) { return applyUpdate(stored, update) },
+	)
+	if err := collectionError(err, "UpdateJob"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *DocumentJobRepository) AddRecords(ctx context.Context, jobID primitive.ObjectID, records []importers.BookRecord) error {
+	documents := make([]*jobRecordDocument, len(records))
+	for i, record := range records {
+		documents[i] = &jobRecordDocument{ID: primitive.NewObjectID(), JobID: jobID, Record: record}
+	}
+	return collectionError(r.records.insert(ctx, documents...), "AddJobRecords")
+}
+
+func (r *DocumentJobRepository) FindRecords(ctx context.Context, jobID primitive.ObjectID, after, limit int) ([]importers.BookRecord, error) {
+	documents, err := r.records.find(ctx, where{"job_id": jobID.Hex(), "entry": greaterThan(after)}, nil)
+	if err := collectionError(err, "FindJobRecords"); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(documents, func(a, b jobRecordDocument) int { return cmp.Compare(a.Record.Entry, b.Record.Entry) })
+	documents = page(documents, 0, limit)
+
+	records := make([]importers.BookRecord, len(documents))
+	for i, document := range documents {
+		records[i] = document.Record
+	}
+	return records, nil
+}
+
+func (r *DocumentJobRepository) DeleteRecords(ctx context.Context, jobID primitive.ObjectID) error {
+	_, err := r.records.remove(ctx, where{"job_id": jobID.Hex()}, nil)
+	return collectionError(err, "DeleteJobRecords")
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentJournalRepository struct {
+	entries collection[models.JournalEntry]
+}
+
+var journalSchema = schema[models.JournalEntry]{
+	table: "journal",
+	key:   func(entry *models.JournalEntry) string { return entry.ID.Hex() },
+	columns: map[string]func(*models.JournalEntry) interface{}{
+		"owner":   func(entry *models.JournalEntry) interface{} { return ownerOf(entry.UserID, entry.GroupID) },
+		"book_id": func(entry *models.JournalEntry) interface{} { return entry.BookID.Hex() },
+	},
+}
+
+func NewMemoryJournalRepository() JournalRepository {
+	return &DocumentJournalRepository{entries: newMemoryCollection(journalSchema)}
+}
+
+func NewSQLJournalRepository(db *database.SQLDatabase) JournalRepository {
+	return &DocumentJournalRepository{entries: newSQLCollection(db, journalSchema)}
+}
+
+func (r *DocumentJournalRepository) Create(ctx context.Context, entry *models.JournalEntry) error {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	entry.UpdatedAt = entry.CreatedAt
+
+	return collectionError(r.entries.insert(ctx, entry), "CreateJournalEntry")
+}
+
+func (r *DocumentJournalRepository) FindByID(ctx context.Context, id string) (*models.JournalEntry, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	entry, err := r.entries.findOne(ctx, where{"id": objectID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindJournalEntryByID"); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// compareJournalEntries is journalOrder: by date, keeping entries with the same date in the order they were written
+func compareJournalEntries(a, b models.JournalEntry) int {
+	return cmp.Or(cmp.Compare(a.Date, b.Date), compareIDs(a.ID, b.ID))
+}
+
+func (r *DocumentJournalRepository) FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.JournalEntry, error) {
+	entries, err := r.entries.find(ctx, where{"book_id": bookID.Hex()}, nil)
+	if err := collectionError(err, "FindJournalEntriesByBookID"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(entries, compareJournalEntries)
+	return entries, nil
+}
+
+func (r *DocumentJournalRepository) Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, error) {
+	by := where{"owner": ownedBy(userID, groupIDs)}
+	if filter.BookID != nil {
+		by["book_id"] = filter.BookID.Hex()
+	}
+
+	entries, err := r.entries.find(ctx, by, func(entry *models.JournalEntry) bool {
+		if filter.From != nil && entry.Date < primitive.NewDateTimeFromTime(*filter.From) {
+			return false
+		}
+		return filter.To == nil || entry.Date < primitive.NewDateTimeFromTime(*filter.To)
+	})
+	if err := collectionError(err, "SearchJournal"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(entries, compareJournalEntries)
+	return page(entries, offset, limit), nil
+}
+
+func (r *DocumentJournalRepository) Update(ctx context.Context, entry *models.JournalEntry) error {
+	entry.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	matched, err := r.entries.update(ctx, where{"id": entry.ID.Hex()}, nil,
+		func(*models.JournalEntry) (*models.JournalEntry, error) { return entry, nil },
+	)
+	if err := collectionError(err, "UpdateJournalEntry"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DocumentJournalRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.entries.remove(ctx, where{"id": id.Hex()}, nil)
+	return collectionError(err, "DeleteJournalEntry")
+}
+
+func (r *DocumentJournalRepository) DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error {
+	_, err := r.entries.remove(ctx, where{"book_id": bookID.Hex()}, nil)
+	return collectionError(err, "DeleteJournalEntriesByBookID")
+}
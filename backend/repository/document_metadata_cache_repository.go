@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+)
+
+type DocumentMetadataCacheRepository struct {
+	entries collection[models.MetadataCacheEntry]
+}
+
+var metadataCacheSchema = schema[models.MetadataCacheEntry]{
+	table: "metadata_cache",
+	key:   func(entry *models.MetadataCacheEntry) string { return entry.ISBN13 },
+}
+
+func NewMemoryMetadataCacheRepository() MetadataCacheRepository {
+	return &DocumentMetadataCacheRepository{entries: newMemoryCollection(metadataCacheSchema)}
+}
+
+func NewSQLMetadataCacheRepository(db *database.SQLDatabase) MetadataCacheRepository {
+	return &DocumentMetadataCacheRepository{entries: newSQLCollection(db, metadataCacheSchema)}
+}
+
+func (r *DocumentMetadataCacheRepository) FindByISBN(ctx context.Context, isbn13 string) (*models.MetadataCacheEntry, error) {
+	// Entries past the retention are treated as gone, like the ones Mongo's TTL index removes
+	expired := time.Now().Add(-metadataCacheRetention)
+	entry, err := r.entries.findOne(ctx, where{"id": isbn13}, func(entry *models.MetadataCacheEntry) bool {
+		return entry.FetchedAt.Time().After(expired)
+	})
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindMetadataByISBN"); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *DocumentMetadataCacheRepository) Save(ctx context.Context, entry *models.MetadataCacheEntry) error {
+	return collectionError(r.entries.upsert(ctx, entry), "SaveMetadata")
+}
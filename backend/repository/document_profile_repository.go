@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentProfileRepository struct {
+	profiles collection[models.Profile]
+}
+
+var profileSchema = schema[models.Profile]{
+	table: "profiles",
+	key:   func(profile *models.Profile) string { return profile.UserID },
+	columns: map[string]func(*models.Profile) interface{}{
+		"handle":      func(profile *models.Profile) interface{} { return sparse(profile.Handle) },
+		"share_token": func(profile *models.Profile) interface{} { return sparse(profile.ShareToken) },
+	},
+	unique: []string{"handle", "share_token"},
+}
+
+func NewMemoryProfileRepository() ProfileRepository {
+	return &DocumentProfileRepository{profiles: newMemoryCollection(profileSchema)}
+}
+
+func NewSQLProfileRepository(db *database.SQLDatabase) ProfileRepository {
+	return &DocumentProfileRepository{profiles: newSQLCollection(db, profileSchema)}
+}
+
+// sparse is the column value of a field omitted when empty. It leaves the field out of a unique index,
+// as Mongo does.
+func sparse(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func (r *DocumentProfileRepository) handleDBError(err error, operation string) error {
+	if errors.Is(err, errDuplicateKey) {
+		return appErrors.ErrHandleTaken
+	}
+	return collectionError(err, operation)
+}
+
+func (r *DocumentProfileRepository) findOne(ctx context.Context, by where, operation string) (*models.Profile, error) {
+	profile, err := r.profiles.findOne(ctx, by, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (r *DocumentProfileRepository) FindByUserID(ctx context.Context, userID string) (*models.Profile, error) {
+	return r.findOne(ctx, where{"id": userID}, "FindProfileByUserID")
+}
+
+// storedValue selects the profiles with a value of a sparse field. Profiles without a handle or share
+// token don't store one, so an empty one selects no profile.
+func storedValue(value string) interface{} {
+	if value == "" {
+		return anyOf{}
+	}
+	return value
+}
+
+func (r *DocumentProfileRepository) FindByHandle(ctx context.Context, handle string) (*models.Profile, error) {
+	return r.findOne(ctx, where{"handle": storedValue(handle)}, "FindProfileByHandle")
+}
+
+func (r *DocumentProfileRepository) FindByShareToken(ctx context.Context, token string) (*models.Profile, error) {
+	return r.findOne(ctx, where{"share_token": storedValue(token)}, "FindProfileByShareToken")
+}
+
+func (r *DocumentProfileRepository) FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Profile, error) {
+	profiles, err := r.profiles.find(ctx, where{"id": anyOf(userIDs)}, nil)
+	if err := r.handleDBError(err, "FindProfilesByUserIDs"); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (r *DocumentProfileRepository) Save(ctx context.Context, profile *models.Profile) error {
+	profile.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return r.handleDBError(r.profiles.upsert(ctx, profile), "SaveProfile")
+}
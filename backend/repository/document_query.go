@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"tranquil-pages/models"
+	"tranquil-pages/query"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// matchesQuery is the in-memory equivalent of queryFilter. It follows Mongo's rules: a condition on a
+// list matches if any element does, a condition on a missing field never matches, and values of
+// different types never compare. The query should have been checked with queryFilter first, as anything
+// queryFilter rejects doesn't match here.
+func matchesQuery(book *models.Book, node query.Node) bool {
+	switch node := node.(type) {
+	case query.And:
+		for _, child := range node {
+			if !matchesQuery(book, child) {
+				return false
+			}
+		}
+		return true
+	case query.Or:
+		for _, child := range node {
+			if matchesQuery(book, child) {
+				return true
+			}
+		}
+		return false
+	case query.Not:
+		return !matchesQuery(book, node.Node)
+	case query.Condition:
+		for _, value := range bookQueryValues(book, node.Field) {
+			if matchesCondition(value, node) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryColumns are the book fields queryWhere selects by, for the query fields stored as numbers
+var queryColumns = map[query.Field]string{
+	query.FieldRating:   "rating_score",
+	query.FieldAdded:    "created_at",
+	query.FieldFinished: "finished_at",
+}
+
+// queryWhere selects the books that can match a query, for matchesQuery to have the final say on.
+// Conditions on the status, format, tags, rating and dates are decided by the fields of bookSchema.
+// Conditions on the title and author aren't: they select every book, or none if negated is set because
+// they are under a NOT. Either way the where selects at least the books that match.
+func queryWhere(node query.Node, negated bool) where {
+	switch node := node.(type) {
+	case query.And:
+		wheres := make([]where, len(node))
+		for i, child := range node {
+			wheres[i] = queryWhere(child, negated)
+		}
+		return where{"$and": wheres}
+	case query.Or:
+		wheres := make([]where, len(node))
+		for i, child := range node {
+			wheres[i] = queryWhere(child, negated)
+		}
+		return where{"$or": wheres}
+	case query.Not:
+		return where{"$nor": []where{queryWhere(node.Node, !negated)}}
+	case query.Condition:
+		if by := conditionWhere(node); by != nil {
+			return by
+		}
+	}
+	if negated {
+		return none
+	}
+	return all
+}
+
+// conditionWhere selects the books that match a condition, or returns nil if the fields can't tell
+func conditionWhere(condition query.Condition) where {
+	switch condition.Field {
+	case query.FieldStatus, query.FieldFormat, query.FieldTag:
+		value, ok := condition.Value.(string)
+		if !ok || condition.Operator != query.Equals {
+			return nil
+		}
+		return where{string(condition.Field): value}
+	}
+
+	column, ok := queryColumns[condition.Field]
+	if !ok {
+		return nil
+	}
+	var number int64
+	switch value := condition.Value.(type) {
+	case int:
+		number = int64(value)
+	case time.Time:
+		number = int64(primitive.NewDateTimeFromTime(value))
+	default:
+		return nil
+	}
+
+	switch condition.Operator {
+	case query.Equals:
+		return where{column: number}
+	case query.Greater:
+		return where{column: greaterThan(number)}
+	case query.GreaterOrEqual:
+		return where{column: greaterThan(number - 1)}
+	case query.Less:
+		return where{column: lessThan(number)}
+	case query.LessOrEqual:
+		return where{column: lessThan(number + 1)}
+	}
+	return nil
+}
+
+// bookQueryValues returns the stored values of a query field: none if the field isn't stored, and
+// several for a list
+func bookQueryValues(book *models.Book, field query.Field) []interface{} {
+	switch field {
+	case query.FieldTitle:
+		return []interface{}{book.Title}
+	case query.FieldAuthor:
+		return []interface{}{book.Author}
+	case query.FieldRating:
+		return []interface{}{book.RatingScore}
+	case query.FieldStatus:
+		if book.Status != "" {
+			return []interface{}{string(book.Status)}
+		}
+	case query.FieldFormat:
+		if book.Format != "" {
+			return []interface{}{string(book.Format)}
+		}
+	case query.FieldTag:
+		values := make([]interface{}, len(book.Tags))
+		for i, tag := range book.Tags {
+			values[i] = tag
+		}
+		return values
+	case query.FieldAdded:
+		return []interface{}{book.CreatedAt}
+	case query.FieldFinished:
+		if book.FinishedAt != nil {
+			return []interface{}{*book.FinishedAt}
+		}
+	}
+	return nil
+}
+
+func matchesCondition(stored interface{}, condition query.Condition) bool {
+	value := condition.Value
+	if t, ok := value.(time.Time); ok {
+		value = primitive.NewDateTimeFromTime(t)
+	}
+
+	switch condition.Operator {
+	case query.Contains, query.Matches:
+		text, ok := stored.(string)
+		if !ok {
+			return false
+		}
+		pattern := "(?i)" + regexp.QuoteMeta(fmt.Sprint(value))
+		if condition.Operator == query.Matches {
+			pattern = "(?i)^" + regexp.QuoteMeta(fmt.Sprint(value)) + "$"
+		}
+		return regexp.MustCompile(pattern).MatchString(text)
+	}
+
+	order, ok := compareQueryValues(stored, value)
+	if !ok {
+		return false
+	}
+	switch condition.Operator {
+	case query.Equals:
+		return order == 0
+	case query.Greater:
+		return order > 0
+	case query.GreaterOrEqual:
+		return order >= 0
+	case query.Less:
+		return order < 0
+	case query.LessOrEqual:
+		return order <= 0
+	}
+	return false
+}
+
+// compareQueryValues orders two values of the same type, and reports false for values of different types
+func compareQueryValues(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), true
+		}
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b), true
+		}
+	case primitive.DateTime:
+		if b, ok := b.(primitive.DateTime); ok {
+			return cmp.Compare(a, b), true
+		}
+	}
+	return 0, false
+}
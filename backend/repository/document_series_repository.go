@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentSeriesRepository struct {
+	series collection[models.Series]
+}
+
+var seriesSchema = schema[models.Series]{
+	table: "series",
+	key:   func(series *models.Series) string { return series.ID.Hex() },
+	columns: map[string]func(*models.Series) interface{}{
+		"user_id": func(series *models.Series) interface{} { return series.UserID },
+	},
+}
+
+func NewMemorySeriesRepository() SeriesRepository {
+	return &DocumentSeriesRepository{series: newMemoryCollection(seriesSchema)}
+}
+
+func NewSQLSeriesRepository(db *database.SQLDatabase) SeriesRepository {
+	return &DocumentSeriesRepository{series: newSQLCollection(db, seriesSchema)}
+}
+
+func (r *DocumentSeriesRepository) Create(ctx context.Context, series *models.Series) error {
+	series.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	series.UpdatedAt = series.CreatedAt
+	if series.ID.IsZero() {
+		series.ID = primitive.NewObjectID()
+	}
+
+	return collectionError(r.series.insert(ctx, series), "CreateSeries")
+}
+
+func (r *DocumentSeriesRepository) FindByID(ctx context.Context, id string) (*models.Series, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	series, err := r.series.findOne(ctx, where{"id": objectID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindSeriesByID"); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *DocumentSeriesRepository) FindByUserID(ctx context.Context, userID string) ([]models.Series, error) {
+	series, err := r.series.find(ctx, where{"user_id": userID}, nil)
+	if err := collectionError(err, "FindSeriesByUserID"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(series, func(a, b models.Series) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), compareIDs(a.ID, b.ID))
+	})
+	return series, nil
+}
+
+func (r *DocumentSeriesRepository) Update(ctx context.Context, series *models.Series) error {
+	series.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	matched, err := r.series.update(ctx, where{"id": series.ID.Hex()}, nil,
+		func(*models.Series) (*models.Series, error) { return series, nil },
+	)
+	if err := collectionError(err, "UpdateSeries"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DocumentSeriesRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.series.remove(ctx, where{"id": id.Hex()}, nil)
+	return collectionError(err, "DeleteSeries")
+}
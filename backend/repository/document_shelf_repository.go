@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DocumentShelfRepository struct {
+	shelves collection[models.Shelf]
+}
+
+var shelfSchema = schema[models.Shelf]{
+	table: "shelves",
+	key:   func(shelf *models.Shelf) string { return shelf.ID.Hex() },
+	columns: map[string]func(*models.Shelf) interface{}{
+		"user_id": func(shelf *models.Shelf) interface{} { return shelf.UserID },
+	},
+}
+
+func NewMemoryShelfRepository() ShelfRepository {
+	return &DocumentShelfRepository{shelves: newMemoryCollection(shelfSchema)}
+}
+
+func NewSQLShelfRepository(db *database.SQLDatabase) ShelfRepository {
+	return &DocumentShelfRepository{shelves: newSQLCollection(db, shelfSchema)}
+}
+
+func (r *DocumentShelfRepository) Create(ctx context.Context, shelf *models.Shelf) error {
+	shelf.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	shelf.UpdatedAt = shelf.CreatedAt
+	if shelf.ID.IsZero() {
+		shelf.ID = primitive.NewObjectID()
+	}
+
+	return collectionError(r.shelves.insert(ctx, shelf), "CreateShelf")
+}
+
+func (r *DocumentShelfRepository) FindByID(ctx context.Context, id string) (*models.Shelf, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	shelf, err := r.shelves.findOne(ctx, where{"id": objectID.Hex()}, nil)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+	if err := collectionError(err, "FindShelfByID"); err != nil {
+		return nil, err
+	}
+	return shelf, nil
+}
+
+func (r *DocumentShelfRepository) FindByUserID(ctx context.Context, userID string) ([]models.Shelf, error) {
+	shelves, err := r.shelves.find(ctx, where{"user_id": userID}, nil)
+	if err := collectionError(err, "FindShelvesByUserID"); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(shelves, func(a, b models.Shelf) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), compareIDs(a.ID, b.ID))
+	})
+	return shelves, nil
+}
+
+func (r *DocumentShelfRepository) Update(ctx context.Context, shelf *models.Shelf) error {
+	shelf.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	matched, err := r.shelves.update(ctx, where{"id": shelf.ID.Hex()}, nil,
+		func(*models.Shelf) (*models.Shelf, error) { return shelf, nil },
+	)
+	if err := collectionError(err, "UpdateShelf"); err != nil {
+		return err
+	}
+	if matched == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *DocumentShelfRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.shelves.remove(ctx, where{"id": id.Hex()}, nil)
+	return collectionError(err, "DeleteShelf")
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// updateDocument builds an update that stores every field of value except the excluded ones.
+// Fields tagged omitempty that are currently empty are unset, so clearing a field removes the stored value.
+func updateDocument(value interface{}, exclude ...string) (bson.M, error) {
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var set bson.M
+	if err := bson.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	unset := bson.M{}
+	for _, field := range omitEmptyFields(reflect.TypeOf(value)) {
+		if _, ok := set[field]; !ok {
+			unset[field] = ""
+		}
+	}
+
+	for _, field := range exclude {
+		delete(set, field)
+		delete(unset, field)
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// omitEmptyFields lists the bson names of the struct's fields tagged omitempty
+func omitEmptyFields(t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, options, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+		if name != "" && name != "-" && strings.Contains(options, "omitempty") {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
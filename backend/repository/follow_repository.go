@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FollowRepository interface {
+	// Follow is idempotent: following someone twice is not an error
+	Follow(ctx context.Context, followerID, followeeID string) error
+	Unfollow(ctx context.Context, followerID, followeeID string) error
+	FindFolloweeIDs(ctx context.Context, followerID string) ([]string, error)
+}
+
+type MongoFollowRepository struct {
+	db *database.Database
+}
+
+func NewFollowRepository(db *database.Database) FollowRepository {
+	db.EnsureIndexes("follows", mongo.IndexModel{
+		Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return &MongoFollowRepository{db: db}
+}
+
+func (r *MongoFollowRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoFollowRepository) Follow(ctx context.Context, followerID, followeeID string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"follower_id": followerID, "followee_id": followeeID}
+	update := bson.M{"$setOnInsert": bson.M{"created_at": primitive.NewDateTimeFromTime(time.Now())}}
+
+	_, err := r.db.GetCollection("follows").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return r.handleDBError(err, "Follow")
+}
+
+func (r *MongoFollowRepository) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("follows").DeleteOne(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID})
+	return r.handleDBError(err, "Unfollow")
+}
+
+func (r *MongoFollowRepository) FindFolloweeIDs(ctx context.Context, followerID string) ([]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	cursor, err := r.db.GetCollection("follows").Find(ctx, bson.M{"follower_id": followerID})
+	if err := r.handleDBError(err, "FindFolloweeIDs"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var follows []models.Follow
+	if err := r.handleDBError(cursor.All(ctx, &follows), "FindFolloweeIDs cursor.All"); err != nil {
+		return nil, err
+	}
+
+	followeeIDs := make([]string, 0, len(follows))
+	for _, follow := range follows {
+		followeeIDs = append(followeeIDs, follow.FolloweeID)
+	}
+	return followeeIDs, nil
+}
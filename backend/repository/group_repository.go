@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GroupRepository interface {
+	Create(ctx context.Context, group *models.Group) error
+	FindByID(ctx context.Context, id string) (*models.Group, error)
+	FindByMember(ctx context.Context, userID string) ([]models.Group, error)
+	FindByInviteToken(ctx context.Context, token string) (*models.Group, error)
+	// Update replaces the group if it is still at the version it was read at, and moves it to the next
+	// version. It returns appErrors.ErrGroupChanged if the group was updated in the meantime.
+	Update(ctx context.Context, group *models.Group) error
+}
+
+type MongoGroupRepository struct {
+	db *database.Database
+}
+
+func NewGroupRepository(db *database.Database) GroupRepository {
+	db.EnsureIndexes("groups",
+		mongo.IndexModel{Keys: bson.D{{Key: "members.user_id", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "invites.token", Value: 1}}},
+	)
+	return &MongoGroupRepository{db: db}
+}
+
+func (r *MongoGroupRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoGroupRepository) Create(ctx context.Context, group *models.Group) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	group.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	group.UpdatedAt = group.CreatedAt
+
+	result, err := r.db.GetCollection("groups").InsertOne(ctx, group)
+	if err := r.handleDBError(err, "CreateGroup"); err != nil {
+		return err
+	}
+
+	group.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoGroupRepository) findOne(ctx context.Context, filter bson.M, operation string) (*models.Group, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var group models.Group
+	err := r.db.GetCollection("groups").FindOne(ctx, filter).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, operation)
+	}
+	return &group, nil
+}
+
+func (r *MongoGroupRepository) FindByID(ctx context.Context, id string) (*models.Group, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+	return r.findOne(ctx, bson.M{"_id": objectID}, "FindGroupByID")
+}
+
+func (r *MongoGroupRepository) FindByInviteToken(ctx context.Context, token string) (*models.Group, error) {
+	return r.findOne(ctx, bson.M{"invites.token": token}, "FindGroupByInviteToken")
+}
+
+func (r *MongoGroupRepository) FindByMember(ctx context.Context, userID string) ([]models.Group, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.db.GetCollection("groups").Find(ctx, bson.M{"members.user_id": userID}, opts)
+	if err := r.handleDBError(err, "FindGroupsByMember"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []models.Group
+	if err := r.handleDBError(cursor.All(ctx, &groups), "FindGroupsByMember cursor.All"); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *MongoGroupRepository) Update(ctx context.Context, group *models.Group) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	updated := *group
+	updated.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	updated.Version = group.Version + 1
+
+	// Groups saved before versions existed have none, which is version 0
+	version := interface{}(group.Version)
+	if group.Version == 0 {
+		version = bson.M{"$in": bson.A{0, nil}}
+	}
+	filter := bson.M{"_id": group.ID, "version": version}
+
+	result, err := r.db.GetCollection("groups").ReplaceOne(ctx, filter, &updated)
+	if err := r.handleDBError(err, "UpdateGroup"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.findOne(ctx, bson.M{"_id": group.ID}, "UpdateGroup"); err != nil {
+			return err
+		}
+		return appErrors.ErrGroupChanged
+	}
+
+	*group = updated
+	return nil
+}
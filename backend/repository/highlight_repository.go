@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type HighlightRepository interface {
+	Create(ctx context.Context, highlight *models.Highlight) error
+	FindByID(ctx context.Context, id string) (*models.Highlight, error)
+	FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.Highlight, error)
+	// Search returns the highlights on the user's personal books and on the books of the given groups, newest first
+	Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.HighlightFilter, offset, limit int) ([]models.Highlight, error)
+	Update(ctx context.Context, highlight *models.Highlight) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error
+}
+
+type MongoHighlightRepository struct {
+	db *database.Database
+}
+
+func NewHighlightRepository(db *database.Database) HighlightRepository {
+	db.EnsureIndexes("highlights",
+		mongo.IndexModel{Keys: bson.D{{Key: "book_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	)
+	return &MongoHighlightRepository{db: db}
+}
+
+func (r *MongoHighlightRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoHighlightRepository) Create(ctx context.Context, highlight *models.Highlight) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// Imported highlights keep the time they were made on the reader
+	highlight.ID = primitive.NewObjectID()
+	highlight.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	if highlight.CreatedAt == 0 {
+		highlight.CreatedAt = highlight.UpdatedAt
+	}
+
+	_, err := r.db.GetCollection("highlights").InsertOne(ctx, highlight)
+	return r.handleDBError(err, "CreateHighlight")
+}
+
+func (r *MongoHighlightRepository) FindByID(ctx context.Context, id string) (*models.Highlight, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	var highlight models.Highlight
+	err = r.db.GetCollection("highlights").FindOne(ctx, bson.M{"_id": objectID}).Decode(&highlight)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindHighlightByID")
+	}
+	return &highlight, nil
+}
+
+func (r *MongoHighlightRepository) FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.Highlight, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	return r.find(ctx, bson.M{"book_id": bookID}, opts, "FindHighlightsByBookID")
+}
+
+func (r *MongoHighlightRepository) Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.HighlightFilter, offset, limit int) ([]models.Highlight, error) {
+	owners := bson.A{bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}}}
+	if len(groupIDs) > 0 {
+		owners = append(owners, bson.M{"group_id": bson.M{"$in": groupIDs}})
+	}
+
+	conditions := bson.A{bson.M{"$or": owners}}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"text": pattern},
+			bson.M{"note": pattern},
+		}})
+	}
+	if filter.BookID != nil {
+		conditions = append(conditions, bson.M{"book_id": *filter.BookID})
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, bson.M{"tags": filter.Tag})
+	}
+	if filter.Color != "" {
+		conditions = append(conditions, bson.M{"color": filter.Color})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	return r.find(ctx, bson.M{"$and": conditions}, opts, "SearchHighlights")
+}
+
+func (r *MongoHighlightRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions, operation string) ([]models.Highlight, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	cursor, err := r.db.GetCollection("highlights").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var highlights []models.Highlight
+	if err := r.handleDBError(cursor.All(ctx, &highlights), operation+" cursor.All"); err != nil {
+		return nil, err
+	}
+	return highlights, nil
+}
+
+func (r *MongoHighlightRepository) Update(ctx context.Context, highlight *models.Highlight) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	highlight.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.GetCollection("highlights").ReplaceOne(ctx, bson.M{"_id": highlight.ID}, highlight)
+	if err := r.handleDBError(err, "UpdateHighlight"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoHighlightRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("highlights").DeleteOne(ctx, bson.M{"_id": id})
+	return r.handleDBError(err, "DeleteHighlight")
+}
+
+func (r *MongoHighlightRepository) DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("highlights").DeleteMany(ctx, bson.M{"book_id": bookID})
+	return r.handleDBError(err, "DeleteHighlightsByBookID")
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/importers"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recordChunk bounds the number of import entries written in one insert
+const recordChunk = 1000
+
+type JobRepository interface {
+	Create(ctx context.Context, job *models.Job) error
+	FindByID(ctx context.Context, id string) (*models.Job, error)
+	// Claim marks the oldest queued job as running, held by owner until leaseExpiresAt, and returns
+	// it, or appErrors.ErrNotFound if no job is queued. Concurrent calls never claim the same job.
+	Claim(ctx context.Context, owner string, leaseExpiresAt time.Time) (*models.Job, error)
+	// Requeue puts the running jobs whose lease has expired back in the queue, along with those of owner
+	// unless owner is empty, and returns how many it requeued. Jobs other workers still hold are left
+	// alone.
+	Requeue(ctx context.Context, owner string) (int, error)
+	// Update stores the job's status, progress and lease. It returns appErrors.ErrLeaseLost unless the
+	// job is still running and held by job.Owner, so that a worker whose job was taken over can't
+	// overwrite what the new owner stored.
+	Update(ctx context.Context, job *models.Job) error
+	// AddRecords stores the entries of an import for its job to work through
+	AddRecords(ctx context.Context, jobID primitive.ObjectID, records []importers.BookRecord) error
+	// FindRecords returns up to limit of the job's entries that come after the given entry, in order
+	FindRecords(ctx context.Context, jobID primitive.ObjectID, after, limit int) ([]importers.BookRecord, error)
+	DeleteRecords(ctx context.Context, jobID primitive.ObjectID) error
+}
+
+type MongoJobRepository struct {
+	db *database.Database
+}
+
+// jobRecord is an entry of an import, kept until its job is done with it
+type jobRecord struct {
+	JobID  primitive.ObjectID   `bson:"job_id"`
+	Record importers.BookRecord `bson:"record"`
+}
+
+func NewJobRepository(db *database.Database) JobRepository {
+	db.EnsureIndexes("jobs",
+		mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	)
+	db.EnsureIndexes("job_records",
+		mongo.IndexModel{Keys: bson.D{{Key: "job_id", Value: 1}, {Key: "record.entry", Value: 1}}},
+	)
+	return &MongoJobRepository{db: db}
+}
+
+func (r *MongoJobRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoJobRepository) Create(ctx context.Context, job *models.Job) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	if job.ID.IsZero() {
+		job.ID = primitive.NewObjectID()
+	}
+	job.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	job.UpdatedAt = job.CreatedAt
+
+	_, err := r.db.GetCollection("jobs").InsertOne(ctx, job)
+	return r.handleDBError(err, "CreateJob")
+}
+
+func (r *MongoJobRepository) FindByID(ctx context.Context, id string) (*models.Job, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	var job models.Job
+	err = r.db.GetCollection("jobs").FindOne(ctx, bson.M{"_id": objectID}).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindJobByID")
+	}
+	return &job, nil
+}
+
+func (r *MongoJobRepository) Claim(ctx context.Context, owner string, leaseExpiresAt time.Time) (*models.Job, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// Finding and updating in one step is what keeps two workers from claiming the same job
+	update := bson.M{"$set": bson.M{
+		"status":           models.JobRunning,
+		"owner":            owner,
+		"lease_expires_at": primitive.NewDateTimeFromTime(leaseExpiresAt),
+		"updated_at":       primitive.NewDateTimeFromTime(time.Now()),
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := r.db.GetCollection("jobs").FindOneAndUpdate(ctx, bson.M{"status": models.JobQueued}, update, opts).Decode(&job)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "ClaimJob")
+	}
+	return &job, nil
+}
+
+func (r *MongoJobRepository) Requeue(ctx context.Context, owner string) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	// Jobs claimed before leases were recorded have none, and are requeued like expired ones
+	abandoned := bson.A{
+		bson.M{"lease_expires_at": bson.M{"$lte": now}},
+		bson.M{"lease_expires_at": nil},
+	}
+	if owner != "" {
+		abandoned = append(abandoned, bson.M{"owner": owner})
+	}
+	filter := bson.M{"status": models.JobRunning, "$or": abandoned}
+	update := bson.M{
+		"$set":   bson.M{"status": models.JobQueued, "updated_at": now},
+		"$unset": bson.M{"owner": "", "lease_expires_at": ""},
+	}
+	result, err := r.db.GetCollection("jobs").UpdateMany(ctx, filter, update)
+	if err := r.handleDBError(err, "RequeueJobs"); err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
+
+func (r *MongoJobRepository) Update(ctx context.Context, job *models.Job) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	job.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	update, err := updateDocument(job, "_id", "user_id", "type", "format", "created_at")
+	if err != nil {
+		return r.handleDBError(err, "UpdateJob")
+	}
+
+	filter := bson.M{"_id": job.ID, "owner": job.Owner, "status": models.JobRunning}
+	result, err := r.db.GetCollection("jobs").UpdateOne(ctx, filter, update)
+	if err := r.handleDBError(err, "UpdateJob"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *MongoJobRepository) AddRecords(ctx context.Context, jobID primitive.ObjectID, records []importers.BookRecord) error {
+	for start := 0; start < len(records); start += recordChunk {
+		documents := make([]interface{}, 0, recordChunk)
+		for _, record := range records[start:min(start+recordChunk, len(records))] {
+			documents = append(documents, jobRecord{JobID: jobID, Record: record})
+		}
+
+		chunkCtx, cancel := database.WithTimeout(ctx)
+		_, err := r.db.GetCollection("job_records").InsertMany(chunkCtx, documents)
+		cancel()
+		if err := r.handleDBError(err, "AddJobRecords"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MongoJobRepository) FindRecords(ctx context.Context, jobID primitive.ObjectID, after, limit int) ([]importers.BookRecord, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	filter := bson.M{"job_id": jobID, "record.entry": bson.M{"$gt": after}}
+	opts := options.Find().SetSort(bson.D{{Key: "record.entry", Value: 1}}).SetLimit(int64(limit))
+
+	cursor, err := r.db.GetCollection("job_records").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, "FindJobRecords"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []jobRecord
+	if err := r.handleDBError(cursor.All(ctx, &documents), "FindJobRecords cursor.All"); err != nil {
+		return nil, err
+	}
+
+	records := make([]importers.BookRecord, len(documents))
+	for i, document := range documents {
+		records[i] = document.Record
+	}
+	return records, nil
+}
+
+func (r *MongoJobRepository) DeleteRecords(ctx context.Context, jobID primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("job_records").DeleteMany(ctx, bson.M{"job_id": jobID})
+	return r.handleDBError(err, "DeleteJobRecords")
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"testing"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRepository_OnlyTheOwnerUpdatesAJob(t *testing.T) {
+	jobRepositoryConformance(t, func(t *testing.T, repo JobRepository) {
+		// Given a job whose first worker let its lease expire, and which a second worker took over
+		assert.NoError(t, repo.Create(t.Context(), &models.Job{UserID: "alice", Type: models.JobImportBooks, Status: models.JobQueued, Total: 10}))
+		first, err := repo.Claim(t.Context(), "first", time.Now().Add(-time.Second))
+		assert.NoError(t, err)
+		requeued, err := repo.Requeue(t.Context(), "")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, requeued)
+		second, err := repo.Claim(t.Context(), "second", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+
+		// When both commit their progress
+		first.Processed = 5
+		second.Processed = 2
+		firstErr := repo.Update(t.Context(), first)
+		secondErr := repo.Update(t.Context(), second)
+
+		// Then only the second worker's is stored
+		assert.ErrorIs(t, firstErr, appErrors.ErrLeaseLost)
+		assert.NoError(t, secondErr)
+		stored, err := repo.FindByID(t.Context(), first.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, "second", stored.Owner)
+		assert.Equal(t, 2, stored.Processed)
+	})
+}
+
+func TestJobRepository_RequeueLeavesHeldJobsAlone(t *testing.T) {
+	jobRepositoryConformance(t, func(t *testing.T, repo JobRepository) {
+		// Given a job another worker holds
+		assert.NoError(t, repo.Create(t.Context(), &models.Job{UserID: "alice", Type: models.JobImportBooks, Status: models.JobQueued}))
+		_, err := repo.Claim(t.Context(), "other", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+
+		// When expired jobs, and those of this worker, are requeued
+		expired, expiredErr := repo.Requeue(t.Context(), "")
+		own, ownErr := repo.Requeue(t.Context(), "this")
+
+		// Then the job is left running
+		assert.NoError(t, expiredErr)
+		assert.NoError(t, ownErr)
+		assert.Equal(t, 0, expired)
+		assert.Equal(t, 0, own)
+		requeued, err := repo.Requeue(t.Context(), "other")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, requeued)
+	})
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JournalRepository interface {
+	Create(ctx context.Context, entry *models.JournalEntry) error
+	FindByID(ctx context.Context, id string) (*models.JournalEntry, error)
+	// FindByBookID returns the book's entries, oldest first
+	FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.JournalEntry, error)
+	// Search returns the entries on the user's personal books and on the books of the given groups, oldest first
+	Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, error)
+	Update(ctx context.Context, entry *models.JournalEntry) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error
+}
+
+type MongoJournalRepository struct {
+	db *database.Database
+}
+
+func NewJournalRepository(db *database.Database) JournalRepository {
+	db.EnsureIndexes("journal",
+		mongo.IndexModel{Keys: bson.D{{Key: "book_id", Value: 1}, {Key: "date", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}}},
+		mongo.IndexModel{Keys: bson.D{{Key: "group_id", Value: 1}, {Key: "date", Value: 1}}},
+	)
+	return &MongoJournalRepository{db: db}
+}
+
+func (r *MongoJournalRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoJournalRepository) Create(ctx context.Context, entry *models.JournalEntry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	entry.UpdatedAt = entry.CreatedAt
+
+	_, err := r.db.GetCollection("journal").InsertOne(ctx, entry)
+	return r.handleDBError(err, "CreateJournalEntry")
+}
+
+func (r *MongoJournalRepository) FindByID(ctx context.Context, id string) (*models.JournalEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	var entry models.JournalEntry
+	err = r.db.GetCollection("journal").FindOne(ctx, bson.M{"_id": objectID}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindJournalEntryByID")
+	}
+	return &entry, nil
+}
+
+// journalOrder sorts entries by date, keeping entries with the same date in the order they were written
+var journalOrder = bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}
+
+func (r *MongoJournalRepository) FindByBookID(ctx context.Context, bookID primitive.ObjectID) ([]models.JournalEntry, error) {
+	return r.find(ctx, bson.M{"book_id": bookID}, options.Find().SetSort(journalOrder), "FindJournalEntriesByBookID")
+}
+
+func (r *MongoJournalRepository) Search(ctx context.Context, userID string, groupIDs []primitive.ObjectID, filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, error) {
+	owners := bson.A{bson.M{"user_id": userID, "group_id": bson.M{"$exists": false}}}
+	if len(groupIDs) > 0 {
+		owners = append(owners, bson.M{"group_id": bson.M{"$in": groupIDs}})
+	}
+
+	conditions := bson.A{bson.M{"$or": owners}}
+	if filter.From != nil {
+		conditions = append(conditions, bson.M{"date": bson.M{"$gte": primitive.NewDateTimeFromTime(*filter.From)}})
+	}
+	if filter.To != nil {
+		conditions = append(conditions, bson.M{"date": bson.M{"$lt": primitive.NewDateTimeFromTime(*filter.To)}})
+	}
+	if filter.BookID != nil {
+		conditions = append(conditions, bson.M{"book_id": *filter.BookID})
+	}
+
+	opts := options.Find().
+		SetSort(journalOrder).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	return r.find(ctx, bson.M{"$and": conditions}, opts, "SearchJournal")
+}
+
+func (r *MongoJournalRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions, operation string) ([]models.JournalEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	cursor, err := r.db.GetCollection("journal").Find(ctx, filter, opts)
+	if err := r.handleDBError(err, operation); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.JournalEntry
+	if err := r.handleDBError(cursor.All(ctx, &entries), operation+" cursor.All"); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *MongoJournalRepository) Update(ctx context.Context, entry *models.JournalEntry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	entry.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.GetCollection("journal").ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry)
+	if err := r.handleDBError(err, "UpdateJournalEntry"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoJournalRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("journal").DeleteOne(ctx, bson.M{"_id": id})
+	return r.handleDBError(err, "DeleteJournalEntry")
+}
+
+func (r *MongoJournalRepository) DeleteByBookID(ctx context.Context, bookID primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("journal").DeleteMany(ctx, bson.M{"book_id": bookID})
+	return r.handleDBError(err, "DeleteJournalEntriesByBookID")
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sync"
+	appErrors "tranquil-pages/errors"
+)
+
+// memoryCollection is a collection kept in memory, for running the server or its tests without a
+// database. Documents are copied through BSON on the way in and out. The collection is safe for
+// concurrent use.
+type memoryCollection[T any] struct {
+	mu     sync.RWMutex
+	schema schema[T]
+	docs   []*T
+}
+
+// newMemoryCollection returns an empty collection of documents with the schema
+func newMemoryCollection[T any](schema schema[T]) *memoryCollection[T] {
+	return &memoryCollection[T]{schema: schema}
+}
+
+// matches reports whether a document is selected by the where and the match
+func (c *memoryCollection[T]) matches(doc *T, by where, match func(*T) bool) bool {
+	return c.schema.selects(doc, by) && (match == nil || match(doc))
+}
+
+// indexOf returns the position of the document with the key, or -1. The caller holds the lock.
+func (c *memoryCollection[T]) indexOf(key string) int {
+	for i, doc := range c.docs {
+		if c.schema.key(doc) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// conflicts reports whether doc shares a unique value with a stored document other than the one at
+// position skip. The caller holds the lock.
+func (c *memoryCollection[T]) conflicts(doc *T, skip int) bool {
+	for _, name := range c.schema.unique {
+		column := c.schema.columns[name]
+		value := column(doc)
+		if value == nil {
+			continue
+		}
+		for i, stored := range c.docs {
+			if i != skip && column(stored) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *memoryCollection[T]) insert(ctx context.Context, docs ...*T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	clones := make([]*T, len(docs))
+	for i, doc := range docs {
+		clone, err := cloneDocument(doc)
+		if err != nil {
+			return err
+		}
+		clones[i] = clone
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := len(c.docs)
+	for _, clone := range clones {
+		if c.indexOf(c.schema.key(clone)) >= 0 || c.conflicts(clone, -1) {
+			clear(c.docs[stored:])
+			c.docs = c.docs[:stored]
+			return errDuplicateKey
+		}
+		c.docs = append(c.docs, clone)
+	}
+	return nil
+}
+
+func (c *memoryCollection[T]) find(ctx context.Context, by where, match func(*T) bool) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var found []T
+	for _, doc := range c.docs {
+		if !c.matches(doc, by, match) {
+			continue
+		}
+		clone, err := cloneDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, *clone)
+	}
+	return found, nil
+}
+
+func (c *memoryCollection[T]) findOne(ctx context.Context, by where, match func(*T) bool) (*T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, doc := range c.docs {
+		if c.matches(doc, by, match) {
+			return cloneDocument(doc)
+		}
+	}
+	return nil, appErrors.ErrNotFound
+}
+
+func (c *memoryCollection[T]) count(ctx context.Context, by where, match func(*T) bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	count := 0
+	for _, doc := range c.docs {
+		if c.matches(doc, by, match) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (c *memoryCollection[T]) update(ctx context.Context, by where, match func(*T) bool, change func(*T) (*T, error)) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := make(map[int]*T)
+	for i, doc := range c.docs {
+		if !c.matches(doc, by, match) {
+			continue
+		}
+		clone, err := cloneDocument(doc)
+		if err != nil {
+			return 0, err
+		}
+		updated, err := change(clone)
+		if err != nil {
+			return 0, err
+		}
+		if updated, err = cloneDocument(updated); err != nil {
+			return 0, err
+		}
+		changed[i] = updated
+	}
+
+	for i, updated := range changed {
+		c.docs[i] = updated
+	}
+	return len(changed), nil
+}
+
+func (c *memoryCollection[T]) updateFirst(ctx context.Context, by where, match func(*T) bool, order func(a, b *T) int, change func(*T) (*T, error)) (*T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first := -1
+	for i, doc := range c.docs {
+		if c.matches(doc, by, match) && (first < 0 || order(doc, c.docs[first]) < 0) {
+			first = i
+		}
+	}
+	if first < 0 {
+		return nil, appErrors.ErrNotFound
+	}
+
+	clone, err := cloneDocument(c.docs[first])
+	if err != nil {
+		return nil, err
+	}
+	updated, err := change(clone)
+	if err != nil {
+		return nil, err
+	}
+	if updated, err = cloneDocument(updated); err != nil {
+		return nil, err
+	}
+	c.docs[first] = updated
+	return cloneDocument(updated)
+}
+
+func (c *memoryCollection[T]) upsert(ctx context.Context, docs ...*T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	clones := make([]*T, len(docs))
+	for i, doc := range docs {
+		clone, err := cloneDocument(doc)
+		if err != nil {
+			return err
+		}
+		clones[i] = clone
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := slices.Clone(c.docs)
+	for _, clone := range clones {
+		i := c.indexOf(c.schema.key(clone))
+		if c.conflicts(clone, i) {
+			c.docs = previous
+			return errDuplicateKey
+		}
+		if i >= 0 {
+			c.docs[i] = clone
+		} else {
+			c.docs = append(c.docs, clone)
+		}
+	}
+	return nil
+}
+
+func (c *memoryCollection[T]) remove(ctx context.Context, by where, match func(*T) bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	kept := c.docs[:0]
+	for _, doc := range c.docs {
+		if !c.matches(doc, by, match) {
+			kept = append(kept, doc)
+		}
+	}
+	deleted := len(c.docs) - len(kept)
+	clear(c.docs[len(kept):])
+	c.docs = kept
+	return deleted, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// metadataCacheRetention is how long cached provider responses are kept before Mongo removes them
+const metadataCacheRetention = 90 * 24 * time.Hour
+
+type MetadataCacheRepository interface {
+	FindByISBN(ctx context.Context, isbn13 string) (*models.MetadataCacheEntry, error)
+	Save(ctx context.Context, entry *models.MetadataCacheEntry) error
+}
+
+type MongoMetadataCacheRepository struct {
+	db *database.Database
+}
+
+func NewMetadataCacheRepository(db *database.Database) MetadataCacheRepository {
+	db.EnsureIndexes("metadata_cache", mongo.IndexModel{
+		Keys:    bson.D{{Key: "fetched_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(metadataCacheRetention.Seconds())),
+	})
+	return &MongoMetadataCacheRepository{db: db}
+}
+
+func (r *MongoMetadataCacheRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoMetadataCacheRepository) FindByISBN(ctx context.Context, isbn13 string) (*models.MetadataCacheEntry, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var entry models.MetadataCacheEntry
+	err := r.db.GetCollection("metadata_cache").FindOne(ctx, bson.M{"_id": isbn13}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindMetadataByISBN")
+	}
+	return &entry, nil
+}
+
+func (r *MongoMetadataCacheRepository) Save(ctx context.Context, entry *models.MetadataCacheEntry) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.GetCollection("metadata_cache").ReplaceOne(ctx, bson.M{"_id": entry.ISBN13}, entry, opts)
+	return r.handleDBError(err, "SaveMetadata")
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProfileRepository interface {
+	// FindByUserID returns appErrors.ErrNotFound if the user never saved a profile
+	FindByUserID(ctx context.Context, userID string) (*models.Profile, error)
+	FindByHandle(ctx context.Context, handle string) (*models.Profile, error)
+	FindByShareToken(ctx context.Context, token string) (*models.Profile, error)
+	FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Profile, error)
+	Save(ctx context.Context, profile *models.Profile) error
+}
+
+type MongoProfileRepository struct {
+	db *database.Database
+}
+
+func NewProfileRepository(db *database.Database) ProfileRepository {
+	db.EnsureIndexes("profiles",
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "handle", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "share_token", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	)
+	return &MongoProfileRepository{db: db}
+}
+
+func (r *MongoProfileRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return appErrors.ErrHandleTaken
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoProfileRepository) findOne(ctx context.Context, filter bson.M, operation string) (*models.Profile, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var profile models.Profile
+	err := r.db.GetCollection("profiles").FindOne(ctx, filter).Decode(&profile)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, operation)
+	}
+	return &profile, nil
+}
+
+func (r *MongoProfileRepository) FindByUserID(ctx context.Context, userID string) (*models.Profile, error) {
+	return r.findOne(ctx, bson.M{"_id": userID}, "FindProfileByUserID")
+}
+
+func (r *MongoProfileRepository) FindByHandle(ctx context.Context, handle string) (*models.Profile, error) {
+	return r.findOne(ctx, bson.M{"handle": handle}, "FindProfileByHandle")
+}
+
+func (r *MongoProfileRepository) FindByShareToken(ctx context.Context, token string) (*models.Profile, error) {
+	return r.findOne(ctx, bson.M{"share_token": token}, "FindProfileByShareToken")
+}
+
+func (r *MongoProfileRepository) FindByUserIDs(ctx context.Context, userIDs []string) ([]models.Profile, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	cursor, err := r.db.GetCollection("profiles").Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	if err := r.handleDBError(err, "FindProfilesByUserIDs"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var profiles []models.Profile
+	if err := r.handleDBError(cursor.All(ctx, &profiles), "FindProfilesByUserIDs cursor.All"); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (r *MongoProfileRepository) Save(ctx context.Context, profile *models.Profile) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	profile.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.db.GetCollection("profiles").ReplaceOne(ctx, bson.M{"_id": profile.UserID}, profile, opts)
+	return r.handleDBError(err, "SaveProfile")
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"tranquil-pages/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// queryFields maps the fields of shelf queries to the fields of stored books
+var queryFields = map[query.Field]string{
+	query.FieldTitle:    "title",
+	query.FieldAuthor:   "author",
+	query.FieldRating:   "rating_score",
+	query.FieldStatus:   "status",
+	query.FieldFormat:   "format",
+	query.FieldTag:      "tags",
+	query.FieldAdded:    "created_at",
+	query.FieldFinished: "finished_at",
+}
+
+var queryOperators = map[query.Operator]string{
+	query.Greater:        "$gt",
+	query.GreaterOrEqual: "$gte",
+	query.Less:           "$lt",
+	query.LessOrEqual:    "$lte",
+}
+
+// queryFilter translates a parsed shelf query into a filter on books. Field names and operators come
+// from the fixed tables above, and values from the query only ever appear as values: text is quoted
+// before it goes into a regular expression, and nothing from the query can become an operator.
+func queryFilter(node query.Node) (bson.M, error) {
+	switch node := node.(type) {
+	case query.And:
+		if len(node) == 0 {
+			return bson.M{}, nil
+		}
+		filters, err := queryFilters(node)
+		return bson.M{"$and": filters}, err
+	case query.Or:
+		filters, err := queryFilters(node)
+		return bson.M{"$or": filters}, err
+	case query.Not:
+		filter, err := queryFilter(node.Node)
+		return bson.M{"$nor": bson.A{filter}}, err
+	case query.Condition:
+		return conditionFilter(node)
+	}
+	return nil, fmt.Errorf("unknown query node %T", node)
+}
+
+func queryFilters(nodes []query.Node) (bson.A, error) {
+	filters := make(bson.A, len(nodes))
+	for i, node := range nodes {
+		filter, err := queryFilter(node)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = filter
+	}
+	return filters, nil
+}
+
+func conditionFilter(condition query.Condition) (bson.M, error) {
+	field, ok := queryFields[condition.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown query field %q", condition.Field)
+	}
+
+	var value interface{}
+	switch v := condition.Value.(type) {
+	case string:
+		value = v
+	case int:
+		value = v
+	case time.Time:
+		value = primitive.NewDateTimeFromTime(v)
+	default:
+		return nil, fmt.Errorf("unexpected query value %T", v)
+	}
+
+	switch condition.Operator {
+	case query.Contains:
+		return bson.M{field: primitive.Regex{Pattern: regexp.QuoteMeta(fmt.Sprint(value)), Options: "i"}}, nil
+	case query.Matches:
+		return bson.M{field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(fmt.Sprint(value)) + "$", Options: "i"}}, nil
+	case query.Equals:
+		// Comparing with $eq keeps even a value that looks like a document from being read as a filter
+		return bson.M{field: bson.M{"$eq": value}}, nil
+	}
+	operator, ok := queryOperators[condition.Operator]
+	if !ok {
+		return nil, fmt.Errorf("unknown query operator %q", condition.Operator)
+	}
+	return bson.M{field: bson.M{operator: value}}, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tranquil-pages/database"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// legacyRatingDocument holds the ratings of a document saved before rating scores, when a rating was
+// a whole number of stars stored in the rating field
+type legacyRatingDocument struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Rating *float64           `bson:"rating"`
+	Reads  []struct {
+		Rating *float64 `bson:"rating"`
+	} `bson:"reads"`
+}
+
+// legacyScore converts a legacy star rating to a score. Ratings that were never valid become unrated.
+func legacyScore(rating float64) int {
+	score, _ := models.RatingScaleStars.ToScore(rating)
+	return score
+}
+
+// migrateLegacyRatings replaces the legacy rating fields of the collection's documents, and of their
+// reads, with scores. Each migrated document loses its legacy fields, so running it again only picks
+// up documents it hasn't converted yet. It returns the number of documents converted.
+func migrateLegacyRatings(ctx context.Context, collection *mongo.Collection) (int, error) {
+	legacy := bson.M{"$or": bson.A{
+		bson.M{"rating": bson.M{"$exists": true}},
+		bson.M{"reads.rating": bson.M{"$exists": true}},
+	}}
+
+	migrated := 0
+	for {
+		converted, err := migrateLegacyRating(ctx, collection, legacy)
+		if err != nil {
+			return migrated, err
+		}
+		if !converted {
+			return migrated, nil
+		}
+		migrated++
+	}
+}
+
+// migrateLegacyRating converts one document matching the filter, reporting false once none are left
+func migrateLegacyRating(ctx context.Context, collection *mongo.Collection, filter bson.M) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var document legacyRatingDocument
+	err := collection.FindOne(ctx, filter).Decode(&document)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// A null rating decodes like a missing one, and is dropped without a score: unsetting a field that
+	// isn't there does nothing
+	set, unset := bson.M{}, bson.M{"rating": ""}
+	if document.Rating != nil {
+		set["rating_score"] = legacyScore(*document.Rating)
+	}
+	for i, read := range document.Reads {
+		unset[fmt.Sprintf("reads.%d.rating", i)] = ""
+		if read.Rating != nil {
+			set[fmt.Sprintf("reads.%d.rating_score", i)] = legacyScore(*read.Rating)
+		}
+	}
+
+	update := bson.M{"$unset": unset}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": document.ID}, update)
+	return err == nil, err
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"tranquil-pages/database"
+)
+
+// Repositories holds the repositories the services work with, all backed by the same store
+type Repositories struct {
+	Books         BookRepository
+	Profiles      ProfileRepository
+	Follows       FollowRepository
+	Activities    ActivityRepository
+	Groups        GroupRepository
+	Highlights    HighlightRepository
+	MetadataCache MetadataCacheRepository
+	Series        SeriesRepository
+	Authors       AuthorRepository
+	Journal       JournalRepository
+	Shelves       ShelfRepository
+	Jobs          JobRepository
+}
+
+// NewMongoRepositories returns repositories that store everything in the database
+func NewMongoRepositories(db *database.Database) *Repositories {
+	return &Repositories{
+		Books:         NewBookRepository(db),
+		Profiles:      NewProfileRepository(db),
+		Follows:       NewFollowRepository(db),
+		Activities:    NewActivityRepository(db),
+		Groups:        NewGroupRepository(db),
+		Highlights:    NewHighlightRepository(db),
+		MetadataCache: NewMetadataCacheRepository(db),
+		Series:        NewSeriesRepository(db),
+		Authors:       NewAuthorRepository(db),
+		Journal:       NewJournalRepository(db),
+		Shelves:       NewShelfRepository(db),
+		Jobs:          NewJobRepository(db),
+	}
+}
+
+// NewMemoryRepositories returns empty repositories that keep everything in memory, which is lost when
+// the process exits
+func NewMemoryRepositories() *Repositories {
+	return &Repositories{
+		Books:         NewMemoryBookRepository(),
+		Profiles:      NewMemoryProfileRepository(),
+		Follows:       NewMemoryFollowRepository(),
+		Activities:    NewMemoryActivityRepository(),
+		Groups:        NewMemoryGroupRepository(),
+		Highlights:    NewMemoryHighlightRepository(),
+		MetadataCache: NewMemoryMetadataCacheRepository(),
+		Series:        NewMemorySeriesRepository(),
+		Authors:       NewMemoryAuthorRepository(),
+		Journal:       NewMemoryJournalRepository(),
+		Shelves:       NewMemoryShelfRepository(),
+		Jobs:          NewMemoryJobRepository(),
+	}
+}
+
+// NewSQLRepositories returns repositories that store everything in the SQL database
+func NewSQLRepositories(db *database.SQLDatabase) *Repositories {
+	return &Repositories{
+		Books:         NewSQLBookRepository(db),
+		Profiles:      NewSQLProfileRepository(db),
+		Follows:       NewSQLFollowRepository(db),
+		Activities:    NewSQLActivityRepository(db),
+		Groups:        NewSQLGroupRepository(db),
+		Highlights:    NewSQLHighlightRepository(db),
+		MetadataCache: NewSQLMetadataCacheRepository(db),
+		Series:        NewSQLSeriesRepository(db),
+		Authors:       NewSQLAuthorRepository(db),
+		Journal:       NewSQLJournalRepository(db),
+		Shelves:       NewSQLShelfRepository(db),
+		Jobs:          NewSQLJobRepository(db),
+	}
+}
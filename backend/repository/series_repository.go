@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SeriesRepository interface {
+	Create(ctx context.Context, series *models.Series) error
+	FindByID(ctx context.Context, id string) (*models.Series, error)
+	// FindByUserID returns the user's series ordered by name
+	FindByUserID(ctx context.Context, userID string) ([]models.Series, error)
+	Update(ctx context.Context, series *models.Series) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type MongoSeriesRepository struct {
+	db *database.Database
+}
+
+func NewSeriesRepository(db *database.Database) SeriesRepository {
+	db.EnsureIndexes("series",
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}},
+	)
+	return &MongoSeriesRepository{db: db}
+}
+
+func (r *MongoSeriesRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoSeriesRepository) Create(ctx context.Context, series *models.Series) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	series.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	series.UpdatedAt = series.CreatedAt
+
+	result, err := r.db.GetCollection("series").InsertOne(ctx, series)
+	if err := r.handleDBError(err, "CreateSeries"); err != nil {
+		return err
+	}
+
+	series.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoSeriesRepository) FindByID(ctx context.Context, id string) (*models.Series, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	var series models.Series
+	err = r.db.GetCollection("series").FindOne(ctx, bson.M{"_id": objectID}).Decode(&series)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindSeriesByID")
+	}
+	return &series, nil
+}
+
+func (r *MongoSeriesRepository) FindByUserID(ctx context.Context, userID string) ([]models.Series, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.GetCollection("series").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err := r.handleDBError(err, "FindSeriesByUserID"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var series []models.Series
+	if err := r.handleDBError(cursor.All(ctx, &series), "FindSeriesByUserID cursor.All"); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *MongoSeriesRepository) Update(ctx context.Context, series *models.Series) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	series.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.GetCollection("series").ReplaceOne(ctx, bson.M{"_id": series.ID}, series)
+	if err := r.handleDBError(err, "UpdateSeries"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoSeriesRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("series").DeleteOne(ctx, bson.M{"_id": id})
+	return r.handleDBError(err, "DeleteSeries")
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ShelfRepository interface {
+	Create(ctx context.Context, shelf *models.Shelf) error
+	FindByID(ctx context.Context, id string) (*models.Shelf, error)
+	// FindByUserID returns the user's shelves ordered by name
+	FindByUserID(ctx context.Context, userID string) ([]models.Shelf, error)
+	Update(ctx context.Context, shelf *models.Shelf) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type MongoShelfRepository struct {
+	db *database.Database
+}
+
+func NewShelfRepository(db *database.Database) ShelfRepository {
+	db.EnsureIndexes("shelves",
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}}},
+	)
+	return &MongoShelfRepository{db: db}
+}
+
+func (r *MongoShelfRepository) handleDBError(err error, operation string) error {
+	if err != nil {
+		if ctxErr := appErrors.FromContext(err); ctxErr != nil {
+			return ctxErr
+		}
+		log.Printf("Database error in %s: %v", operation, err)
+		return appErrors.ErrDatabase
+	}
+	return nil
+}
+
+func (r *MongoShelfRepository) Create(ctx context.Context, shelf *models.Shelf) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	shelf.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	shelf.UpdatedAt = shelf.CreatedAt
+
+	result, err := r.db.GetCollection("shelves").InsertOne(ctx, shelf)
+	if err := r.handleDBError(err, "CreateShelf"); err != nil {
+		return err
+	}
+
+	shelf.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *MongoShelfRepository) FindByID(ctx context.Context, id string) (*models.Shelf, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, appErrors.ErrInvalidID
+	}
+
+	var shelf models.Shelf
+	err = r.db.GetCollection("shelves").FindOne(ctx, bson.M{"_id": objectID}).Decode(&shelf)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, r.handleDBError(err, "FindShelfByID")
+	}
+	return &shelf, nil
+}
+
+func (r *MongoShelfRepository) FindByUserID(ctx context.Context, userID string) ([]models.Shelf, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.GetCollection("shelves").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err := r.handleDBError(err, "FindShelvesByUserID"); err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var shelves []models.Shelf
+	if err := r.handleDBError(cursor.All(ctx, &shelves), "FindShelvesByUserID cursor.All"); err != nil {
+		return nil, err
+	}
+	return shelves, nil
+}
+
+func (r *MongoShelfRepository) Update(ctx context.Context, shelf *models.Shelf) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	shelf.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.db.GetCollection("shelves").ReplaceOne(ctx, bson.M{"_id": shelf.ID}, shelf)
+	if err := r.handleDBError(err, "UpdateShelf"); err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoShelfRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.GetCollection("shelves").DeleteOne(ctx, bson.M{"_id": id})
+	return r.handleDBError(err, "DeleteShelf")
+}
@@ -0,0 +1,457 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"tranquil-pages/database"
+	appErrors "tranquil-pages/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// sqlChunk bounds the number of keys a statement names, which keeps it under the parameter limits
+// of the databases
+const sqlChunk = 500
+
+// sqlCollection is a collection kept in a table of a SQL database. Each row holds a document as
+// canonical extended JSON, which keeps the BSON types of its fields, next to the columns of the schema.
+// The values of the schema's lists are kept in a table per list, named after the table and the list.
+type sqlCollection[T any] struct {
+	db     *database.SQLDatabase
+	schema schema[T]
+	// columns are the names of the schema's columns, in the order statements list them
+	columns []string
+	// lists are the names of the schema's lists, in the order they are written
+	lists []string
+}
+
+// newSQLCollection returns the collection of documents with the schema stored in the database
+func newSQLCollection[T any](db *database.SQLDatabase, schema schema[T]) *sqlCollection[T] {
+	return &sqlCollection[T]{
+		db:      db,
+		schema:  schema,
+		columns: slices.Sorted(maps.Keys(schema.columns)),
+		lists:   slices.Sorted(maps.Keys(schema.lists)),
+	}
+}
+
+// sqlRow is a document as it is stored
+type sqlRow struct {
+	key      string
+	columns  []interface{}
+	lists    map[string][]string
+	document string
+}
+
+func (c *sqlCollection[T]) encode(doc *T) (*sqlRow, error) {
+	document, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	row := &sqlRow{key: c.schema.key(doc), lists: make(map[string][]string), document: string(document)}
+	for _, name := range c.columns {
+		row.columns = append(row.columns, c.schema.columns[name](doc))
+	}
+	for _, name := range c.lists {
+		values := slices.Clone(c.schema.lists[name](doc))
+		slices.Sort(values)
+		row.lists[name] = slices.Compact(values)
+	}
+	return row, nil
+}
+
+func decode[T any](document []byte) (*T, error) {
+	var doc T
+	if err := bson.UnmarshalExtJSON(document, true, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// condition turns a where into a condition on the table, with its parameters
+func (c *sqlCollection[T]) condition(by where) (string, []interface{}) {
+	if len(by) == 0 {
+		return "1 = 1", nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, field := range slices.Sorted(maps.Keys(by)) {
+		var condition string
+		var fieldArgs []interface{}
+		switch {
+		case field == "$and":
+			condition, fieldArgs = c.conditions(by[field].([]where), " AND ", "1 = 1")
+		case field == "$or":
+			condition, fieldArgs = c.conditions(by[field].([]where), " OR ", "1 = 0")
+		case field == "$nor":
+			// A comparison with a missing value is NULL rather than false, and so would be its negation
+			condition, fieldArgs = c.conditions(by[field].([]where), " OR ", "1 = 0")
+			condition = "NOT COALESCE(" + condition + ", 1 = 0)"
+		case field == "id" || c.schema.columns[field] != nil:
+			condition, fieldArgs = columnCondition(field, by[field])
+		case c.schema.lists[field] != nil && by[field] == nil:
+			condition = fmt.Sprintf("id NOT IN (SELECT id FROM %s_%s)", c.schema.table, field)
+		case c.schema.lists[field] != nil:
+			condition, fieldArgs = columnCondition("value", by[field])
+			condition = fmt.Sprintf("id IN (SELECT id FROM %s_%s WHERE %s)", c.schema.table, field, condition)
+		default:
+			panic(fmt.Sprintf("%s have no field %s", c.schema.table, field))
+		}
+		conditions = append(conditions, condition)
+		args = append(args, fieldArgs...)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// conditions joins the conditions of several wheres with an operator, or returns empty if there are none
+func (c *sqlCollection[T]) conditions(wheres []where, operator, empty string) (string, []interface{}) {
+	if len(wheres) == 0 {
+		return empty, nil
+	}
+
+	conditions := make([]string, len(wheres))
+	var args []interface{}
+	for i, by := range wheres {
+		condition, whereArgs := c.condition(by)
+		conditions[i] = "(" + condition + ")"
+		args = append(args, whereArgs...)
+	}
+	return "(" + strings.Join(conditions, operator) + ")", args
+}
+
+func columnCondition(column string, want interface{}) (string, []interface{}) {
+	switch want := want.(type) {
+	case nil:
+		return column + " IS NULL", nil
+	case anyOf:
+		if len(want) == 0 {
+			return "1 = 0", nil
+		}
+		args := make([]interface{}, len(want))
+		for i, value := range want {
+			args[i] = value
+		}
+		return column + " IN (" + placeholders(len(want)) + ")", args
+	case greaterThan:
+		return column + " > ?", []interface{}{int64(want)}
+	case lessThan:
+		return column + " < ?", []interface{}{int64(want)}
+	}
+	return column + " = ?", []interface{}{want}
+}
+
+// placeholders returns n parameters separated by commas
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// storedDocument is a document as it was read, under the key of its row
+type storedDocument[T any] struct {
+	key string
+	doc *T
+}
+
+// querier is what selecting runs on, either the database or a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// selectMatching returns the documents that match, in the order they were inserted. lock keeps other
+// transactions from updating them until the end of the transaction.
+func (c *sqlCollection[T]) selectMatching(ctx context.Context, q querier, by where, match func(*T) bool, lock bool) ([]storedDocument[T], error) {
+	condition, args := c.condition(by)
+	statement := fmt.Sprintf("SELECT id, document FROM %s WHERE %s ORDER BY seq", c.schema.table, condition)
+	if lock {
+		statement += c.db.LockRows()
+	}
+
+	rows, err := q.QueryContext(ctx, c.db.Rebind(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []storedDocument[T]
+	for rows.Next() {
+		var key string
+		var document []byte
+		if err := rows.Scan(&key, &document); err != nil {
+			return nil, err
+		}
+		doc, err := decode[T](document)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil || match(doc) {
+			found = append(found, storedDocument[T]{key: key, doc: doc})
+		}
+	}
+	return found, rows.Err()
+}
+
+func (c *sqlCollection[T]) insertRow(ctx context.Context, tx *sql.Tx, row *sqlRow, upsert bool) error {
+	names := append([]string{"id"}, c.columns...)
+	names = append(names, "document")
+	args := append([]interface{}{row.key}, row.columns...)
+	args = append(args, row.document)
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.schema.table, strings.Join(names, ", "), placeholders(len(names)))
+	if upsert {
+		var updates []string
+		for _, name := range names[1:] {
+			updates = append(updates, name+" = excluded."+name)
+		}
+		statement += " ON CONFLICT (id) DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	if _, err := tx.ExecContext(ctx, c.db.Rebind(statement), args...); err != nil {
+		return err
+	}
+	return c.writeLists(ctx, tx, row, upsert)
+}
+
+func (c *sqlCollection[T]) updateRow(ctx context.Context, tx *sql.Tx, row *sqlRow) error {
+	var updates []string
+	for _, name := range c.columns {
+		updates = append(updates, name+" = ?")
+	}
+	updates = append(updates, "document = ?")
+	args := slices.Concat(row.columns, []interface{}{row.document, row.key})
+
+	statement := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", c.schema.table, strings.Join(updates, ", "))
+	if _, err := tx.ExecContext(ctx, c.db.Rebind(statement), args...); err != nil {
+		return err
+	}
+	return c.writeLists(ctx, tx, row, true)
+}
+
+// writeLists stores the values of the row's lists, replacing the ones stored before if replace is set
+func (c *sqlCollection[T]) writeLists(ctx context.Context, tx *sql.Tx, row *sqlRow, replace bool) error {
+	for _, name := range c.lists {
+		table := c.schema.table + "_" + name
+		if replace {
+			if _, err := tx.ExecContext(ctx, c.db.Rebind("DELETE FROM "+table+" WHERE id = ?"), row.key); err != nil {
+				return err
+			}
+		}
+		for _, value := range row.lists[name] {
+			if _, err := tx.ExecContext(ctx, c.db.Rebind("INSERT INTO "+table+" (id, value) VALUES (?, ?)"), row.key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write runs fn in a transaction, and reports a unique column violated by it as errDuplicateKey
+func (c *sqlCollection[T]) write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	err := c.db.InTransaction(ctx, fn)
+	if err != nil && c.db.IsUniqueViolation(err) {
+		return errDuplicateKey
+	}
+	return err
+}
+
+func (c *sqlCollection[T]) insert(ctx context.Context, docs ...*T) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows := make([]*sqlRow, len(docs))
+	for i, doc := range docs {
+		row, err := c.encode(doc)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+
+	return c.write(ctx, func(tx *sql.Tx) error {
+		for _, row := range rows {
+			if err := c.insertRow(ctx, tx, row, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *sqlCollection[T]) find(ctx context.Context, by where, match func(*T) bool) ([]T, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	found, err := c.selectMatching(ctx, c.db, by, match, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []T
+	for _, stored := range found {
+		docs = append(docs, *stored.doc)
+	}
+	return docs, nil
+}
+
+func (c *sqlCollection[T]) findOne(ctx context.Context, by where, match func(*T) bool) (*T, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	found, err := c.selectMatching(ctx, c.db, by, match, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, appErrors.ErrNotFound
+	}
+	return found[0].doc, nil
+}
+
+func (c *sqlCollection[T]) count(ctx context.Context, by where, match func(*T) bool) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	if match != nil {
+		found, err := c.selectMatching(ctx, c.db, by, match, false)
+		return len(found), err
+	}
+
+	condition, args := c.condition(by)
+	var count int
+	statement := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", c.schema.table, condition)
+	err := c.db.QueryRowContext(ctx, c.db.Rebind(statement), args...).Scan(&count)
+	return count, err
+}
+
+// changeRow applies change to a document that was read, and returns the row to store
+func (c *sqlCollection[T]) changeRow(stored storedDocument[T], change func(*T) (*T, error)) (*sqlRow, error) {
+	updated, err := change(stored.doc)
+	if err != nil {
+		return nil, err
+	}
+	row, err := c.encode(updated)
+	if err != nil {
+		return nil, err
+	}
+	// The row keeps its key even if the document was given another
+	row.key = stored.key
+	return row, nil
+}
+
+func (c *sqlCollection[T]) update(ctx context.Context, by where, match func(*T) bool, change func(*T) (*T, error)) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	matched := 0
+	err := c.write(ctx, func(tx *sql.Tx) error {
+		found, err := c.selectMatching(ctx, tx, by, match, true)
+		if err != nil {
+			return err
+		}
+
+		rows := make([]*sqlRow, len(found))
+		for i, stored := range found {
+			if rows[i], err = c.changeRow(stored, change); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			if err := c.updateRow(ctx, tx, row); err != nil {
+				return err
+			}
+		}
+		matched = len(rows)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return matched, nil
+}
+
+func (c *sqlCollection[T]) updateFirst(ctx context.Context, by where, match func(*T) bool, order func(a, b *T) int, change func(*T) (*T, error)) (*T, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var updated *sqlRow
+	err := c.write(ctx, func(tx *sql.Tx) error {
+		found, err := c.selectMatching(ctx, tx, by, match, true)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return appErrors.ErrNotFound
+		}
+
+		first := slices.MinFunc(found, func(a, b storedDocument[T]) int { return order(a.doc, b.doc) })
+		if updated, err = c.changeRow(first, change); err != nil {
+			return err
+		}
+		return c.updateRow(ctx, tx, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decode[T]([]byte(updated.document))
+}
+
+func (c *sqlCollection[T]) upsert(ctx context.Context, docs ...*T) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	rows := make([]*sqlRow, len(docs))
+	for i, doc := range docs {
+		row, err := c.encode(doc)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+
+	return c.write(ctx, func(tx *sql.Tx) error {
+		for _, row := range rows {
+			if err := c.insertRow(ctx, tx, row, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *sqlCollection[T]) remove(ctx context.Context, by where, match func(*T) bool) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	removed := 0
+	err := c.write(ctx, func(tx *sql.Tx) error {
+		found, err := c.selectMatching(ctx, tx, by, match, true)
+		if err != nil {
+			return err
+		}
+
+		keys := make(anyOf, len(found))
+		for i, stored := range found {
+			keys[i] = stored.key
+		}
+		for chunk := range slices.Chunk(keys, sqlChunk) {
+			condition, args := columnCondition("id", chunk)
+			for _, name := range c.lists {
+				if _, err := tx.ExecContext(ctx, c.db.Rebind("DELETE FROM "+c.schema.table+"_"+name+" WHERE "+condition), args...); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, c.db.Rebind("DELETE FROM "+c.schema.table+" WHERE "+condition), args...); err != nil {
+				return err
+			}
+		}
+		removed = len(found)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
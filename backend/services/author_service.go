@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuthorService manages the authors of a user's library. Like series, authors are personal and
+// only the owner's personal books link to them; group books keep a plain author string.
+type AuthorService struct {
+	repo  repository.AuthorRepository
+	books *BookService
+}
+
+func NewAuthorService(repo repository.AuthorRepository, books *BookService) *AuthorService {
+	s := &AuthorService{repo: repo, books: books}
+	books.onValidate(s.checkBookAuthors)
+	books.onStore(s.linkAuthorNames)
+	return s
+}
+
+// authorKey normalizes a name for matching, ignoring case, dots and spacing
+func authorKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(name, ".", " ")), " "))
+}
+
+// splitAuthorNames splits an author string naming several people, such as "Terry Pratchett & Neil Gaiman"
+func splitAuthorNames(value string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '&' }) {
+		if name = strings.Join(strings.Fields(name), " "); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateAuthor tidies the name and aliases, drops aliases that normalize to a name already listed and fills in the keys
+func validateAuthor(author *models.Author) error {
+	author.Name = strings.Join(strings.Fields(author.Name), " ")
+	if authorKey(author.Name) == "" {
+		return appErrors.ErrInvalidAuthor
+	}
+
+	author.Keys = []string{authorKey(author.Name)}
+	aliases := author.Aliases
+	author.Aliases = nil
+	for _, alias := range aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		key := authorKey(alias)
+		if key == "" || slices.Contains(author.Keys, key) {
+			continue
+		}
+		author.Aliases = append(author.Aliases, alias)
+		author.Keys = append(author.Keys, key)
+	}
+	return nil
+}
+
+// checkKeysAvailable fails if another of the user's authors already goes by one of the author's names
+func (s *AuthorService) checkKeysAvailable(ctx context.Context, author *models.Author) error {
+	for _, key := range author.Keys {
+		existing, err := s.repo.FindByKey(ctx, author.UserID, key)
+		if errors.Is(err, appErrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if existing.ID != author.ID {
+			return appErrors.ErrDuplicateAuthor
+		}
+	}
+	return nil
+}
+
+func (s *AuthorService) CreateAuthor(ctx context.Context, author *models.Author) error {
+	if err := validateAuthor(author); err != nil {
+		return err
+	}
+	if err := s.checkKeysAvailable(ctx, author); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, author)
+}
+
+func (s *AuthorService) GetAuthorsByUserID(ctx context.Context, userID string) ([]models.Author, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// getAuthor loads the user's author. Other users' authors are reported as not found.
+func (s *AuthorService) getAuthor(ctx context.Context, id, userID string) (*models.Author, error) {
+	author, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if author.UserID != userID {
+		return nil, appErrors.ErrNotFound
+	}
+	return author, nil
+}
+
+func (s *AuthorService) GetAuthor(ctx context.Context, id, userID string) (*models.Author, error) {
+	return s.getAuthor(ctx, id, userID)
+}
+
+func (s *AuthorService) GetAuthorBooks(ctx context.Context, id, userID string) ([]models.Book, error) {
+	author, err := s.getAuthor(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.books.repo.FindByAuthorID(ctx, author.ID)
+}
+
+// UpdateAuthor renames the author or changes its aliases. The author strings of its books follow the new name.
+func (s *AuthorService) UpdateAuthor(ctx context.Context, id, userID string, update *models.Author) (*models.Author, error) {
+	author, err := s.getAuthor(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	author.Name = update.Name
+	author.Aliases = update.Aliases
+	if err := validateAuthor(author); err != nil {
+		return nil, err
+	}
+	if err := s.checkKeysAvailable(ctx, author); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, author); err != nil {
+		return nil, err
+	}
+
+	books, err := s.books.repo.FindByAuthorID(ctx, author.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		if err := s.setBookAuthors(ctx, &book, book.Authors); err != nil {
+			return nil, err
+		}
+	}
+	return author, nil
+}
+
+// DeleteAuthor removes an author no book links to anymore
+func (s *AuthorService) DeleteAuthor(ctx context.Context, id, userID string) error {
+	author, err := s.getAuthor(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	books, err := s.books.repo.FindByAuthorID(ctx, author.ID)
+	if err != nil {
+		return err
+	}
+	if len(books) > 0 {
+		return appErrors.ErrAuthorInUse
+	}
+	return s.repo.Delete(ctx, author.ID)
+}
+
+// MergeAuthor re-points the books of one author to another and deletes it. Its name and aliases
+// become aliases of the remaining author, so later books under those names link to it too.
+func (s *AuthorService) MergeAuthor(ctx context.Context, id, intoID, userID string) (*models.Author, error) {
+	if id == intoID {
+		return nil, appErrors.ErrInvalidMerge
+	}
+	source, err := s.getAuthor(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getAuthor(ctx, intoID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	target.Aliases = append(append(target.Aliases, source.Name), source.Aliases...)
+	if err := validateAuthor(target); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, target); err != nil {
+		return nil, err
+	}
+
+	// The source is deleted last, so a merge that fails halfway can simply be retried
+	books, err := s.books.repo.FindByAuthorID(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		authors := make([]models.BookAuthor, 0, len(book.Authors))
+		for _, link := range book.Authors {
+			if link.AuthorID == source.ID {
+				link.AuthorID = target.ID
+			}
+			if !slices.Contains(authors, link) {
+				authors = append(authors, link)
+			}
+		}
+		if err := s.setBookAuthors(ctx, &book, authors); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Delete(ctx, source.ID); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// setBookAuthors stores the book's links along with an author string built from the current names
+func (s *AuthorService) setBookAuthors(ctx context.Context, book *models.Book, links []models.BookAuthor) error {
+	authors, err := s.loadAuthors(ctx, book.UserID, links)
+	if err != nil {
+		return err
+	}
+	return s.books.repo.SetAuthors(ctx, book.ID, links, authorString(links, authors, book.Author))
+}
+
+// loadAuthors fetches the linked authors by ID, failing if any isn't one of the user's
+func (s *AuthorService) loadAuthors(ctx context.Context, userID string, links []models.BookAuthor) (map[primitive.ObjectID]models.Author, error) {
+	ids := make([]primitive.ObjectID, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.AuthorID)
+	}
+
+	found, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[primitive.ObjectID]models.Author, len(found))
+	for _, author := range found {
+		if author.UserID == userID {
+			authors[author.ID] = author
+		}
+	}
+	for _, id := range ids {
+		if _, ok := authors[id]; !ok {
+			return nil, appErrors.ErrUnknownAuthor
+		}
+	}
+	return authors, nil
+}
+
+// authorString joins the names of the people credited as author. Books with only translators or
+// editors linked keep their current string.
+func authorString(links []models.BookAuthor, authors map[primitive.ObjectID]models.Author, current string) string {
+	var names []string
+	for _, link := range links {
+		if link.Role == models.AuthorRoleAuthor {
+			names = append(names, authors[link.AuthorID].Name)
+		}
+	}
+	if len(names) == 0 {
+		return current
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkBookAuthors is run before a book is stored. Linked authors are checked and the author string
+// rebuilt from them. A book with only an author string is linked by linkAuthorNames once it is stored,
+// so here its names are only checked.
+func (s *AuthorService) checkBookAuthors(ctx context.Context, book *models.Book) error {
+	if book.GroupID != nil {
+		if len(book.Authors) > 0 {
+			return appErrors.ErrUnknownAuthor
+		}
+		return nil
+	}
+
+	if len(book.Authors) == 0 {
+		for _, name := range splitAuthorNames(book.Author) {
+			if authorKey(name) == "" {
+				return appErrors.ErrInvalidAuthor
+			}
+		}
+		return nil
+	}
+
+	var links []models.BookAuthor
+	for _, link := range book.Authors {
+		if link.Role == "" {
+			link.Role = models.AuthorRoleAuthor
+		}
+		if !link.Role.IsValid() {
+			return appErrors.ErrInvalidAuthor
+		}
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+
+	authors, err := s.loadAuthors(ctx, book.UserID, links)
+	if err != nil {
+		return err
+	}
+	book.Authors = links
+	book.Author = authorString(links, authors, book.Author)
+	return nil
+}
+
+// linkAuthorNames is run after a book is stored. A personal book with only an author string is linked
+// to the authors it names, which are created if needed. Books it fails to link are picked up by
+// MigrateAuthorStrings.
+func (s *AuthorService) linkAuthorNames(ctx context.Context, book *models.Book) error {
+	if book.GroupID != nil || len(book.Authors) > 0 {
+		return nil
+	}
+
+	links, _, err := s.resolveAuthorNames(ctx, book.UserID, book.Author)
+	if err != nil || len(links) == 0 {
+		return err
+	}
+	if err := s.books.repo.SetAuthors(ctx, book.ID, links, book.Author); err != nil {
+		return err
+	}
+	book.Authors = links
+	return nil
+}
+
+// resolveAuthorNames links each name in an author string to the user's author going by that name
+// or alias, creating authors for names not seen before. It also returns how many were created.
+func (s *AuthorService) resolveAuthorNames(ctx context.Context, userID, value string) ([]models.BookAuthor, int, error) {
+	var links []models.BookAuthor
+	created := 0
+	for _, name := range splitAuthorNames(value) {
+		author, err := s.repo.FindByKey(ctx, userID, authorKey(name))
+		if errors.Is(err, appErrors.ErrNotFound) {
+			author = &models.Author{UserID: userID, Name: name}
+			if err = validateAuthor(author); err == nil {
+				err = s.repo.Create(ctx, author)
+			}
+			created++
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		link := models.BookAuthor{AuthorID: author.ID, Role: models.AuthorRoleAuthor}
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	return links, created, nil
+}
+
+// MigrateAuthorStrings links the personal books saved before authors existed to authors created
+// from their author strings. Books that already have links are left alone, so it is safe to run again.
+func (s *AuthorService) MigrateAuthorStrings(ctx context.Context) (*models.AuthorMigrationSummary, error) {
+	books, err := s.books.repo.FindWithoutAuthorLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.AuthorMigrationSummary{}
+	for _, book := range books {
+		links, created, err := s.resolveAuthorNames(ctx, book.UserID, book.Author)
+		if err != nil {
+			return nil, err
+		}
+		summary.AuthorsCreated += created
+		if len(links) == 0 {
+			continue
+		}
+		if err := s.books.repo.SetAuthors(ctx, book.ID, links, book.Author); err != nil {
+			return nil, err
+		}
+		summary.BooksUpdated++
+	}
+	return summary, nil
+}
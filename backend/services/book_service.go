@@ -1,53 +1,725 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
 	appErrors "tranquil-pages/errors"
+	"tranquil-pages/markdown"
+	"tranquil-pages/metadata"
 	"tranquil-pages/models"
 	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// maxVolume is the highest volume number a book in a series can have
+	maxVolume = 10000
+	// maxReviewLength bounds the size of a review's Markdown, in bytes
+	maxReviewLength = 50000
 )
 
 type BookService struct {
-	repo repository.BookRepository
+	repo          repository.BookRepository
+	activityRepo  repository.ActivityRepository
+	highlightRepo repository.HighlightRepository
+	permissions   *PermissionEvaluator
+	// validateHooks check references to data other services keep before a book is stored. They must
+	// not change anything, as the book may still be rejected.
+	validateHooks []func(ctx context.Context, book *models.Book) error
+	// storeHooks keep data other services derive from a book up to date once it has been stored
+	storeHooks []func(ctx context.Context, book *models.Book) error
+	// deleteHooks clean up data other services keep for a book once it has been deleted
+	deleteHooks []func(ctx context.Context, book *models.Book) error
 }
 
-func NewBookService(repo repository.BookRepository) *BookService {
-	return &BookService{repo: repo}
+func NewBookService(repo repository.BookRepository, activityRepo repository.ActivityRepository, highlightRepo repository.HighlightRepository, permissions *PermissionEvaluator) *BookService {
+	return &BookService{repo: repo, activityRepo: activityRepo, highlightRepo: highlightRepo, permissions: permissions}
 }
 
-func (s *BookService) CreateBook(book *models.Book) error {
-	if book.Rating < 0 || book.Rating > 5 {
+func validateBook(book *models.Book) error {
+	book.Tags = normalizeTags(book.Tags)
+	if book.RatingScore < 0 || book.RatingScore > models.MaxRatingScore {
 		return appErrors.ErrInvalidRating
 	}
+	if !book.Visibility.IsValid() {
+		return appErrors.ErrInvalidVisibility
+	}
+	if len(book.Review) > maxReviewLength {
+		return appErrors.ErrInvalidReview
+	}
+	if !book.Status.IsValid() {
+		return appErrors.ErrInvalidStatus
+	}
+	if !book.Format.IsValid() {
+		return appErrors.ErrInvalidFormat
+	}
+	if err := validateEdition(book); err != nil {
+		return err
+	}
+	if book.Volume != nil {
+		volume := *book.Volume
+		if book.SeriesID == nil || math.IsNaN(volume) || volume < 0 || volume > maxVolume {
+			return appErrors.ErrInvalidVolume
+		}
+	}
+	return normalizeISBNs(book)
+}
+
+// validateEdition checks the edition details fit the book's format. An edition without any details is dropped.
+func validateEdition(book *models.Book) error {
+	edition := book.Edition
+	if edition == nil {
+		return nil
+	}
+
+	edition.Publisher = strings.TrimSpace(edition.Publisher)
+	if *edition == (models.Edition{}) {
+		book.Edition = nil
+		return nil
+	}
+	if edition.Year < 0 || edition.Year > 9999 || edition.PageCount < 0 || edition.DurationMinutes < 0 {
+		return appErrors.ErrInvalidEdition
+	}
+	if (book.Format.IsTimed() && edition.PageCount > 0) || (!book.Format.IsTimed() && edition.DurationMinutes > 0) {
+		return appErrors.ErrInvalidEdition
+	}
+	return nil
+}
 
-	return s.repo.Create(book)
+// validate runs the book's own checks and then the ones registered by other services
+func (s *BookService) validate(ctx context.Context, book *models.Book) error {
+	if err := validateBook(book); err != nil {
+		return err
+	}
+	for _, hook := range s.validateHooks {
+		if err := hook(ctx, book); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *BookService) GetBooksByUserID(userID string) ([]models.Book, error) {
-	return s.repo.FindByUserID(userID)
+// normalizeISBNs validates the book's ISBNs and fills in whichever form is missing. Either field
+// may hold either form; when both are given they must refer to the same edition.
+func normalizeISBNs(book *models.Book) error {
+	var isbn10, isbn13 string
+	for _, value := range []string{book.ISBN13, book.ISBN10} {
+		if value == "" {
+			continue
+		}
+		parsed10, parsed13, err := metadata.ParseISBN(value)
+		if err != nil {
+			return err
+		}
+		if isbn13 != "" && parsed13 != isbn13 {
+			return appErrors.ErrInvalidISBN
+		}
+		isbn10, isbn13 = parsed10, parsed13
+	}
+
+	book.ISBN10, book.ISBN13 = isbn10, isbn13
+	return nil
+}
+
+func newActivity(book *models.Book, activityType models.ActivityType) *models.Activity {
+	activity := &models.Activity{
+		UserID: book.UserID,
+		BookID: book.ID,
+		Type:   activityType,
+	}
+	if activityType == models.ActivityRated {
+		activity.RatingScore = book.RatingScore
+	}
+	return activity
 }
 
-func (s *BookService) GetBook(id, userID string) (*models.Book, error) {
-	book, err := s.repo.FindById(id)
+// recordActivities adds entries to the owners' activity logs. The book changes have already been
+// stored at this point, so the activities are recorded even if the client stops waiting, and a
+// failure is logged instead of failing the request.
+func (s *BookService) recordActivities(ctx context.Context, activities []*models.Activity) {
+	if err := s.activityRepo.CreateMany(context.WithoutCancel(ctx), activities); err != nil {
+		log.Printf("Failed to record %d activities: %v", len(activities), err)
+	}
+}
+
+// addedActivities lists the activities adding the book records
+func addedActivities(book *models.Book) []*models.Activity {
+	activities := []*models.Activity{newActivity(book, models.ActivityAdded)}
+	if book.Status == models.StatusFinished {
+		activities = append(activities, newActivity(book, models.ActivityFinished))
+	}
+	if book.RatingScore > 0 {
+		activities = append(activities, newActivity(book, models.ActivityRated))
+	}
+	return activities
+}
+
+func (s *BookService) CreateBook(ctx context.Context, book *models.Book) error {
+	if err := s.prepareBook(ctx, book); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, book); err != nil {
+		return err
+	}
+
+	s.stored(ctx, book)
+	s.recordActivities(ctx, addedActivities(book))
+	renderReview(book)
+	return nil
+}
+
+// prepareBook checks a new book and sets it up as it is first stored, with a read for the status it
+// starts with
+func (s *BookService) prepareBook(ctx context.Context, book *models.Book) error {
+	if err := s.validate(ctx, book); err != nil {
+		return err
+	}
+
+	if book.GroupID != nil {
+		if _, err := s.permissions.CheckGroup(ctx, book.GroupID.Hex(), book.UserID, PermissionEdit); err != nil {
+			return err
+		}
+	}
+
+	book.CurrentLoan = nil
+	book.LoanHistory = nil
+	book.Cover = nil
+	book.Progress = nil
+	book.FinishedAt = nil
+	book.Reads = nil
+	now := primitive.NewDateTimeFromTime(time.Now())
+	switch book.Status {
+	case models.StatusReading:
+		book.Reads = []models.Read{{ID: primitive.NewObjectID(), StartedAt: &now}}
+	case models.StatusFinished:
+		book.FinishedAt = &now
+		book.Reads = []models.Read{{ID: primitive.NewObjectID(), FinishedAt: &now, RatingScore: book.RatingScore, Comment: book.Comment}}
+	}
+	return nil
+}
+
+// createBooks stores books prepareBook has checked in one bulk write
+func (s *BookService) createBooks(ctx context.Context, books []*models.Book) error {
+	if err := s.repo.CreateMany(ctx, books); err != nil {
+		return err
+	}
+
+	s.stored(ctx, books...)
+	var activities []*models.Activity
+	for _, book := range books {
+		activities = append(activities, addedActivities(book)...)
+	}
+	s.recordActivities(ctx, activities)
+	return nil
+}
+
+func (s *BookService) GetBooksByUserID(ctx context.Context, userID string, filter models.BookFilter) ([]models.Book, error) {
+	return s.repo.FindByUserID(ctx, userID, filter)
+}
+
+// GetBooksByGroupID lists the books of a group library the user is a member of
+func (s *BookService) GetBooksByGroupID(ctx context.Context, groupID, userID string, filter models.BookFilter) ([]models.Book, error) {
+	group, err := s.permissions.CheckGroup(ctx, groupID, userID, PermissionView)
 	if err != nil {
 		return nil, err
 	}
-	if book.UserID != userID {
-		return nil, appErrors.ErrNotFound
+	return s.repo.FindByGroupID(ctx, group.ID, filter)
+}
+
+func (s *BookService) GetBook(ctx context.Context, id, userID string) (*models.Book, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionView)
+	if err != nil {
+		return nil, err
 	}
+	renderReview(book)
 	return book, nil
 }
 
-func (s *BookService) DeleteBook(id, userID string) error {
-	book, err := s.repo.FindById(id)
+// renderReview fills in the book's rendered review. Whoever may view the book sees its spoilers.
+func renderReview(book *models.Book) {
+	book.ReviewHTML = markdown.Render(book.Review, markdown.ShowSpoilers)
+}
+
+func (s *BookService) getBookWithPermission(ctx context.Context, id, userID string, permission Permission) (*models.Book, error) {
+	book, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.permissions.CheckBook(ctx, book, userID, permission); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// UpdateBook applies the editable fields of update to the user's book and returns the result. The
+// update's rating was entered on the given scale.
+func (s *BookService) UpdateBook(ctx context.Context, id, userID string, update *models.Book, scale models.RatingScale) (*models.Book, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	update.RatingScore = scale.KeepScore(book.RatingScore, update.RatingScore)
+
+	change, err := s.applyUpdate(ctx, book, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, book); err != nil {
+		return nil, err
+	}
+	if change.resetProgress {
+		if err := s.repo.SetProgress(ctx, book.ID, nil); err != nil {
+			return nil, err
+		}
+	}
+	if change.read != nil {
+		if change.added {
+			err = s.repo.AddRead(ctx, book.ID, change.read)
+		} else {
+			err = s.repo.UpdateRead(ctx, book.ID, change.read)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.stored(ctx, book)
+	s.recordActivities(ctx, change.activities)
+	renderReview(book)
+	return book, nil
+}
+
+// bookChange is what an update does to a book besides changing its fields
+type bookChange struct {
+	resetProgress bool
+	// read is the read a new status opened or closed, and added tells whether it is new
+	read       *models.Read
+	added      bool
+	activities []*models.Activity
+}
+
+// applyUpdate copies the editable fields of update onto the book and checks the result. It keeps the
+// book's reads in step with a change of status, but stores nothing.
+func (s *BookService) applyUpdate(ctx context.Context, book, update *models.Book) (*bookChange, error) {
+	started := update.Status == models.StatusReading && book.Status != models.StatusReading
+	finished := update.Status == models.StatusFinished && book.Status != models.StatusFinished
+	// Progress in pages means nothing for an audiobook and the other way round, so it's reset when that changes
+	resetProgress := book.Progress != nil && update.Format.IsTimed() != book.Format.IsTimed()
+	rated := update.RatingScore > 0 && update.RatingScore != book.RatingScore
+
+	book.Title = update.Title
+	book.Author = update.Author
+	book.Authors = update.Authors
+	book.ISBN10 = update.ISBN10
+	book.ISBN13 = update.ISBN13
+	book.SeriesID = update.SeriesID
+	book.Volume = update.Volume
+	book.Format = update.Format
+	book.Edition = update.Edition
+	book.Tags = update.Tags
+	book.Comment = update.Comment
+	book.Review = update.Review
+	book.RatingScore = update.RatingScore
+	book.Visibility = update.Visibility
+	book.Status = update.Status
+
+	if err := s.validate(ctx, book); err != nil {
+		return nil, err
+	}
+
+	change := &bookChange{resetProgress: resetProgress}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	if finished {
+		book.FinishedAt = &now
+	} else if book.Status != models.StatusFinished {
+		book.FinishedAt = nil
+	}
+	if resetProgress {
+		book.Progress = nil
+	}
+	if started || finished {
+		change.read, change.added = trackRead(book, now)
+	}
+
+	if finished {
+		change.activities = append(change.activities, newActivity(book, models.ActivityFinished))
+	}
+	if rated {
+		change.activities = append(change.activities, newActivity(book, models.ActivityRated))
+	}
+	return change, nil
+}
+
+func (s *BookService) DeleteBook(ctx context.Context, id, userID string) error {
+	book, err := s.repo.FindById(ctx, id)
 	if err != nil {
 		if errors.Is(err, appErrors.ErrNotFound) {
 			return nil
 		}
 		return err
 	}
-	if book.UserID != userID {
-		return appErrors.ErrNotFound
+	if err := s.permissions.CheckBook(ctx, book, userID, PermissionDelete); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	// The book is gone at this point, so its data is cleaned up even if the client stops waiting
+	cleanup := context.WithoutCancel(ctx)
+	if err := s.highlightRepo.DeleteByBookID(cleanup, book.ID); err != nil {
+		return err
+	}
+
+	// Leftovers are logged rather than failing the request
+	for _, hook := range s.deleteHooks {
+		if err := hook(cleanup, book); err != nil {
+			log.Printf("Failed to clean up after deleting book %s: %v", book.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// onValidate registers a function that can reject a book before it is created or updated
+func (s *BookService) onValidate(hook func(ctx context.Context, book *models.Book) error) {
+	s.validateHooks = append(s.validateHooks, hook)
+}
+
+// onStore registers a function to run after a book has been created or updated
+func (s *BookService) onStore(hook func(ctx context.Context, book *models.Book) error) {
+	s.storeHooks = append(s.storeHooks, hook)
+}
+
+// stored runs the store hooks for books that have just been stored. Like the activities, their work
+// is done even if the client stops waiting, and a failure is logged instead of failing the request.
+func (s *BookService) stored(ctx context.Context, books ...*models.Book) {
+	for _, book := range books {
+		for _, hook := range s.storeHooks {
+			if err := hook(context.WithoutCancel(ctx), book); err != nil {
+				log.Printf("Failed to update data derived from book %s: %v", book.ID.Hex(), err)
+			}
+		}
+	}
+}
+
+// onDelete registers a function to run after a book has been deleted
+func (s *BookService) onDelete(hook func(ctx context.Context, book *models.Book) error) {
+	s.deleteHooks = append(s.deleteHooks, hook)
+}
+
+// CountBooks returns the number of books across all users
+func (s *BookService) CountBooks(ctx context.Context) (int64, error) {
+	return s.repo.Count(ctx)
+}
+
+// openRead returns the book's read in progress, if any
+func openRead(book *models.Book) *models.Read {
+	for i := len(book.Reads) - 1; i >= 0; i-- {
+		if book.Reads[i].IsOpen() {
+			return &book.Reads[i]
+		}
+	}
+	return nil
+}
+
+// trackRead keeps the reading history in step with a change of status. Starting to read opens a read
+// unless one is open already. Finishing closes the open read, or records a finished read if there is
+// none; the read keeps the book's rating and comment unless it has its own. It returns the read it
+// added or closed, and whether it added it, or nil if the reads didn't change.
+func trackRead(book *models.Book, at primitive.DateTime) (*models.Read, bool) {
+	open := openRead(book)
+	if book.Status == models.StatusReading && open != nil {
+		return nil, false
+	}
+	if book.Status == models.StatusReading || open == nil {
+		read := models.Read{ID: primitive.NewObjectID()}
+		if book.Status == models.StatusReading {
+			read.StartedAt = &at
+		} else {
+			read.FinishedAt, read.RatingScore, read.Comment = &at, book.RatingScore, book.Comment
+		}
+		book.Reads = append(book.Reads, read)
+		return &book.Reads[len(book.Reads)-1], true
+	}
+
+	open.FinishedAt = &at
+	if open.RatingScore == 0 {
+		open.RatingScore = book.RatingScore
+	}
+	if open.Comment == "" {
+		open.Comment = book.Comment
+	}
+	return open, false
+}
+
+func validateRead(read *models.Read) error {
+	read.Comment = strings.TrimSpace(read.Comment)
+	if read.RatingScore < 0 || read.RatingScore > models.MaxRatingScore {
+		return appErrors.ErrInvalidRating
+	}
+	if read.StartedAt == nil && read.FinishedAt == nil {
+		return appErrors.ErrInvalidRead
+	}
+	if read.StartedAt != nil && read.FinishedAt != nil && *read.FinishedAt < *read.StartedAt {
+		return appErrors.ErrInvalidRead
+	}
+	return nil
+}
+
+// readTime orders reads by when they started, or finished for reads without a start date
+func readTime(read models.Read) primitive.DateTime {
+	if read.StartedAt != nil {
+		return *read.StartedAt
+	}
+	return *read.FinishedAt
+}
+
+// GetReads returns the book's reading history, oldest first
+func (s *BookService) GetReads(ctx context.Context, id, userID string) ([]models.Read, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+
+	reads := append([]models.Read{}, book.Reads...)
+	sort.SliceStable(reads, func(i, j int) bool {
+		return readTime(reads[i]) < readTime(reads[j])
+	})
+	return reads, nil
+}
+
+// AddRead records a read, such as one from before the book was added. A book has at most one read in progress.
+func (s *BookService) AddRead(ctx context.Context, id, userID string, read *models.Read) error {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return err
+	}
+	if err := validateRead(read); err != nil {
+		return err
+	}
+	if read.IsOpen() && openRead(book) != nil {
+		return appErrors.ErrReadInProgress
+	}
+
+	read.ID = primitive.NewObjectID()
+	return s.repo.AddRead(ctx, book.ID, read)
+}
+
+// UpdateRead changes a read of the user's book. The update's rating was entered on the given scale.
+func (s *BookService) UpdateRead(ctx context.Context, id, readID, userID string, update *models.Read, scale models.RatingScale) (*models.Read, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	read := findRead(book, readID)
+	if read == nil {
+		return nil, appErrors.ErrNotFound
+	}
+
+	read.StartedAt = update.StartedAt
+	read.FinishedAt = update.FinishedAt
+	read.RatingScore = scale.KeepScore(read.RatingScore, update.RatingScore)
+	read.Comment = update.Comment
+	if err := validateRead(read); err != nil {
+		return nil, err
+	}
+	for i := range book.Reads {
+		if read.IsOpen() && &book.Reads[i] != read && book.Reads[i].IsOpen() {
+			return nil, appErrors.ErrReadInProgress
+		}
+	}
+
+	if err := s.repo.UpdateRead(ctx, book.ID, read); err != nil {
+		return nil, err
+	}
+	return read, nil
+}
+
+func (s *BookService) DeleteRead(ctx context.Context, id, readID, userID string) error {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return err
+	}
+	read := findRead(book, readID)
+	if read == nil {
+		return nil
+	}
+	return s.repo.DeleteRead(ctx, book.ID, read.ID)
+}
+
+func findRead(book *models.Book, readID string) *models.Read {
+	for i := range book.Reads {
+		if book.Reads[i].ID.Hex() == readID {
+			return &book.Reads[i]
+		}
+	}
+	return nil
+}
+
+// GetYearlyReads counts the reads of the user's personal books finished in each year, latest year first.
+// Books finished before reads were tracked count once, in the year they were finished.
+func (s *BookService) GetYearlyReads(ctx context.Context, userID string) ([]models.YearlyReads, error) {
+	books, err := s.repo.FindByUserID(ctx, userID, models.BookFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int)
+	for _, book := range books {
+		if len(book.Reads) == 0 && book.FinishedAt != nil {
+			counts[book.FinishedAt.Time().UTC().Year()]++
+		}
+		for _, read := range book.Reads {
+			if read.FinishedAt != nil {
+				counts[read.FinishedAt.Time().UTC().Year()]++
+			}
+		}
+	}
+
+	yearly := []models.YearlyReads{}
+	for year, finished := range counts {
+		yearly = append(yearly, models.YearlyReads{Year: year, Finished: finished})
+	}
+	sort.Slice(yearly, func(i, j int) bool {
+		return yearly[i].Year > yearly[j].Year
+	})
+	return yearly, nil
+}
+
+// UpdateProgress records how far the user is into the book, in minutes for audiobooks and pages for
+// other formats. Progress of zero clears it.
+func (s *BookService) UpdateProgress(ctx context.Context, id, userID string, progress *models.ReadingProgress) (*models.Book, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	position, other, total := progress.Page, progress.Minutes, 0
+	if book.Format.IsTimed() {
+		position, other = progress.Minutes, progress.Page
+	}
+	if book.Edition != nil {
+		total = book.Edition.PageCount
+		if book.Format.IsTimed() {
+			total = book.Edition.DurationMinutes
+		}
+	}
+	if position < 0 || other != 0 || (total > 0 && position > total) {
+		return nil, appErrors.ErrInvalidProgress
+	}
+
+	if position == 0 {
+		progress = nil
+	} else {
+		progress.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+	if err := s.repo.SetProgress(ctx, book.ID, progress); err != nil {
+		return nil, err
+	}
+
+	book.Progress = progress
+	return book, nil
+}
+
+// LendBook records that the book was lent out. It fails with appErrors.ErrBookOnLoan while another loan is open.
+func (s *BookService) LendBook(ctx context.Context, id, userID string, loan *models.Loan) (*models.Book, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	loan.BorrowerName = strings.TrimSpace(loan.BorrowerName)
+	if loan.LentAt == 0 {
+		loan.LentAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+	if loan.BorrowerName == "" && loan.BorrowerUserID == "" {
+		return nil, appErrors.ErrInvalidLoan
+	}
+	if loan.DueAt <= loan.LentAt {
+		return nil, appErrors.ErrInvalidLoan
+	}
+	loan.ID = primitive.NewObjectID()
+	loan.ReturnedAt = nil
+
+	if err := s.repo.Lend(ctx, book.ID, loan); err != nil {
+		return nil, err
+	}
+
+	book.CurrentLoan = loan
+	return book, nil
+}
+
+// ReturnBook closes the book's open loan
+func (s *BookService) ReturnBook(ctx context.Context, id, userID string) (*models.Book, error) {
+	book, err := s.getBookWithPermission(ctx, id, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	loan, err := s.repo.Return(ctx, book.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	book.CurrentLoan = nil
+	book.LoanHistory = append(book.LoanHistory, *loan)
+	return book, nil
+}
+
+// GetOutstandingLoans lists the open loans on the user's books and on the books of their groups
+func (s *BookService) GetOutstandingLoans(ctx context.Context, userID string, overdueOnly bool) ([]models.OutstandingLoan, error) {
+	groupIDs, err := s.permissions.GroupIDsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	books, err := s.repo.FindOnLoan(ctx, userID, groupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	loans := []models.OutstandingLoan{}
+	for _, book := range books {
+		overdue := book.CurrentLoan.DueAt < now
+		if overdueOnly && !overdue {
+			continue
+		}
+		loans = append(loans, models.OutstandingLoan{
+			BookID:  book.ID,
+			GroupID: book.GroupID,
+			Title:   book.Title,
+			Author:  book.Author,
+			Loan:    *book.CurrentLoan,
+			Overdue: overdue,
+		})
+	}
+
+	sort.Slice(loans, func(i, j int) bool {
+		return loans[i].Loan.DueAt < loans[j].Loan.DueAt
+	})
+	return loans, nil
+}
+
+// MigrateRatings converts the star ratings saved before rating scales existed to scores
+func (s *BookService) MigrateRatings(ctx context.Context) error {
+	books, err := s.repo.MigrateRatings(ctx)
+	if err != nil {
+		return err
+	}
+	activities, err := s.activityRepo.MigrateRatings(ctx)
+	if err != nil {
+		return err
+	}
+	if books > 0 || activities > 0 {
+		log.Printf("Migrated the ratings of %d books and %d activities to rating scores", books, activities)
 	}
-	return s.repo.Delete(id)
+	return nil
 }
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"tranquil-pages/blobstore"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxCoverSize bounds the size of an uploaded cover image
+	MaxCoverSize = 5 << 20
+	// maxCoverPixels rejects images that are small files but would take a lot of memory to decode
+	maxCoverPixels = 40_000_000
+
+	CoverOriginal = "original"
+)
+
+// coverThumbnailWidths are the thumbnail sizes generated for every cover. Thumbnails are JPEGs.
+var coverThumbnailWidths = map[string]int{
+	"small":  160,
+	"medium": 480,
+}
+
+var coverContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// CoverService stores cover images for books. Anyone who may edit a book may change its cover,
+// and anyone who may view it may fetch it.
+type CoverService struct {
+	books *BookService
+	blobs blobstore.BlobStore
+}
+
+func NewCoverService(books *BookService, blobs blobstore.BlobStore) *CoverService {
+	s := &CoverService{books: books, blobs: blobs}
+	books.onDelete(s.deleteCoverFiles)
+	return s
+}
+
+func coverKey(bookID primitive.ObjectID, coverID, size string) string {
+	return fmt.Sprintf("covers/%s/%s/%s", bookID.Hex(), coverID, size)
+}
+
+// UploadCover validates the image by its content, stores it together with its thumbnails and
+// makes it the book's cover
+func (s *CoverService) UploadCover(ctx context.Context, bookID, userID string, data []byte) (*models.Book, error) {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(data)
+	if !coverContentTypes[contentType] {
+		return nil, appErrors.ErrInvalidCover
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width*config.Height > maxCoverPixels {
+		return nil, appErrors.ErrInvalidCover
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, appErrors.ErrInvalidCover
+	}
+
+	cover := &models.BookCover{
+		ID:          primitive.NewObjectID().Hex(),
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		UpdatedAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	files := map[string][]byte{CoverOriginal: data}
+	for size, width := range coverThumbnailWidths {
+		if files[size], err = thumbnail(img, width); err != nil {
+			return nil, err
+		}
+	}
+	for size, file := range files {
+		fileType := "image/jpeg"
+		if size == CoverOriginal {
+			fileType = contentType
+		}
+		if err := s.blobs.Put(coverKey(book.ID, cover.ID, size), file, fileType); err != nil {
+			s.logCleanup(s.deleteFiles(book.ID, cover))
+			return nil, err
+		}
+	}
+
+	if err := s.books.repo.SetCover(ctx, book.ID, cover); err != nil {
+		s.logCleanup(s.deleteFiles(book.ID, cover))
+		return nil, err
+	}
+	if book.Cover != nil {
+		s.logCleanup(s.deleteFiles(book.ID, book.Cover))
+	}
+
+	book.Cover = cover
+	return book, nil
+}
+
+// thumbnail scales the image down to the given width, keeping its aspect ratio. Transparent
+// areas become white, since thumbnails are JPEGs.
+func thumbnail(img image.Image, width int) ([]byte, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() < width {
+		width = bounds.Dx()
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, scaled, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (s *CoverService) DeleteCover(ctx context.Context, bookID, userID string) error {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, PermissionEdit)
+	if err != nil {
+		return err
+	}
+	if book.Cover == nil {
+		return nil
+	}
+
+	if err := s.books.repo.SetCover(ctx, book.ID, nil); err != nil {
+		return err
+	}
+	s.logCleanup(s.deleteFiles(book.ID, book.Cover))
+	return nil
+}
+
+// GetCover opens the cover image in the given size, one of CoverOriginal or a thumbnail size, and
+// returns it with its description and content type
+func (s *CoverService) GetCover(ctx context.Context, bookID, userID, size string) (io.ReadCloser, *models.BookCover, string, error) {
+	contentType := "image/jpeg"
+	if _, ok := coverThumbnailWidths[size]; !ok && size != CoverOriginal {
+		return nil, nil, "", appErrors.ErrInvalidCoverSize
+	}
+
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, PermissionView)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if book.Cover == nil {
+		return nil, nil, "", appErrors.ErrNotFound
+	}
+	if size == CoverOriginal {
+		contentType = book.Cover.ContentType
+	}
+
+	file, err := s.blobs.Get(coverKey(book.ID, book.Cover.ID, size))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return file, book.Cover, contentType, nil
+}
+
+func (s *CoverService) deleteCoverFiles(ctx context.Context, book *models.Book) error {
+	if book.Cover == nil {
+		return nil
+	}
+	return s.deleteFiles(book.ID, book.Cover)
+}
+
+func (s *CoverService) deleteFiles(bookID primitive.ObjectID, cover *models.BookCover) error {
+	var firstErr error
+	for size := range coverThumbnailWidths {
+		if err := s.blobs.Delete(coverKey(bookID, cover.ID, size)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := s.blobs.Delete(coverKey(bookID, cover.ID, CoverOriginal)); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// logCleanup logs a failure to remove files that are no longer used. Leftover files only cost storage.
+func (s *CoverService) logCleanup(err error) {
+	if err != nil {
+		log.Printf("Failed to delete unused cover files: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/markdown"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedService manages who follows whom and assembles the activity feed. The feed is built
+// on read from the followed users' activity logs, so no per-follower copies are stored.
+type FeedService struct {
+	followRepo   repository.FollowRepository
+	activityRepo repository.ActivityRepository
+	profileRepo  repository.ProfileRepository
+	bookRepo     repository.BookRepository
+}
+
+func NewFeedService(
+	followRepo repository.FollowRepository,
+	activityRepo repository.ActivityRepository,
+	profileRepo repository.ProfileRepository,
+	bookRepo repository.BookRepository,
+) *FeedService {
+	return &FeedService{
+		followRepo:   followRepo,
+		activityRepo: activityRepo,
+		profileRepo:  profileRepo,
+		bookRepo:     bookRepo,
+	}
+}
+
+// Follow subscribes the user to the activity of the public profile with the given handle
+func (s *FeedService) Follow(ctx context.Context, followerID, handle string) error {
+	profile, err := s.profileRepo.FindByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	if profile.Visibility != models.VisibilityPublic {
+		return appErrors.ErrNotFound
+	}
+	if profile.UserID == followerID {
+		return appErrors.ErrFollowSelf
+	}
+	return s.followRepo.Follow(ctx, followerID, profile.UserID)
+}
+
+func (s *FeedService) Unfollow(ctx context.Context, followerID, handle string) error {
+	profile, err := s.profileRepo.FindByHandle(ctx, handle)
+	if err != nil {
+		return err
+	}
+	return s.followRepo.Unfollow(ctx, followerID, profile.UserID)
+}
+
+// GetFollowing lists the followed users whose profiles are currently public
+func (s *FeedService) GetFollowing(ctx context.Context, followerID string) ([]models.Actor, error) {
+	profiles, err := s.visibleFollowees(ctx, followerID)
+	if err != nil {
+		return nil, err
+	}
+
+	actors := make([]models.Actor, 0, len(profiles))
+	for _, profile := range profiles {
+		actors = append(actors, models.Actor{Handle: profile.Handle, DisplayName: profile.DisplayName})
+	}
+	return actors, nil
+}
+
+func (s *FeedService) visibleFollowees(ctx context.Context, followerID string) (map[string]*models.Profile, error) {
+	followeeIDs, err := s.followRepo.FindFolloweeIDs(ctx, followerID)
+	if err != nil || len(followeeIDs) == 0 {
+		return nil, err
+	}
+
+	profiles, err := s.profileRepo.FindByUserIDs(ctx, followeeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(map[string]*models.Profile)
+	for i := range profiles {
+		if profiles[i].Visibility == models.VisibilityPublic {
+			visible[profiles[i].UserID] = &profiles[i]
+		}
+	}
+	return visible, nil
+}
+
+// GetFeed returns up to limit activities of followed users, newest first, starting below the before cursor.
+// Activities on books that are private or have been deleted are skipped.
+func (s *FeedService) GetFeed(ctx context.Context, userID, before string, limit int, spoilers markdown.Spoilers) (*models.Feed, error) {
+	feed := &models.Feed{Items: []models.FeedItem{}}
+
+	var cursor primitive.ObjectID
+	if before != "" {
+		var err error
+		if cursor, err = primitive.ObjectIDFromHex(before); err != nil {
+			return nil, appErrors.ErrInvalidID
+		}
+	}
+
+	profiles, err := s.visibleFollowees(ctx, userID)
+	if err != nil || len(profiles) == 0 {
+		return feed, err
+	}
+
+	// Ratings are shown on the reader's scale, whatever scale their authors rated on
+	viewer, err := findProfile(ctx, s.profileRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(profiles))
+	for id := range profiles {
+		userIDs = append(userIDs, id)
+	}
+
+	// Filtering can drop activities, so keep fetching until the page is full or the logs run out
+	for len(feed.Items) < limit {
+		activities, err := s.activityRepo.FindByUserIDs(ctx, userIDs, cursor, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(activities) == 0 {
+			break
+		}
+
+		books, err := s.booksForActivities(ctx, activities)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, activity := range activities {
+			cursor = activity.ID
+			if item, ok := feedItem(&activity, books[activity.BookID], profiles[activity.UserID], viewer.RatingScale, spoilers); ok {
+				feed.Items = append(feed.Items, item)
+				if len(feed.Items) == limit {
+					break
+				}
+			}
+		}
+
+		if len(activities) < limit {
+			break
+		}
+	}
+
+	if len(feed.Items) == limit {
+		feed.NextBefore = feed.Items[limit-1].ID.Hex()
+	}
+	return feed, nil
+}
+
+func (s *FeedService) booksForActivities(ctx context.Context, activities []models.Activity) (map[primitive.ObjectID]*models.Book, error) {
+	bookIDs := make([]primitive.ObjectID, 0, len(activities))
+	for _, activity := range activities {
+		bookIDs = append(bookIDs, activity.BookID)
+	}
+
+	books, err := s.bookRepo.FindByIDs(ctx, bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.Book, len(books))
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+	return byID, nil
+}
+
+// feedItem renders an activity for a follower, or reports false if the owner doesn't share it
+func feedItem(activity *models.Activity, book *models.Book, profile *models.Profile, scale models.RatingScale, spoilers markdown.Spoilers) (models.FeedItem, bool) {
+	if book == nil || book.UserID != activity.UserID || book.GroupID != nil || book.Visibility == models.BookVisibilityPrivate {
+		return models.FeedItem{}, false
+	}
+	if activity.Type == models.ActivityRated && !profile.ShareRatings {
+		return models.FeedItem{}, false
+	}
+
+	item := models.FeedItem{
+		ID:        activity.ID,
+		Type:      activity.Type,
+		Actor:     models.Actor{Handle: profile.Handle, DisplayName: profile.DisplayName},
+		Book:      shareBook(book, profile, scale, spoilers),
+		CreatedAt: activity.CreatedAt,
+	}
+	if activity.Type == models.ActivityRated {
+		rating := scale.FromScore(activity.RatingScore)
+		item.Rating = &rating
+	}
+	return item, true
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultInviteValidity = 7 * 24 * time.Hour
+	maxInviteValidity     = 30 * 24 * time.Hour
+	// groupUpdateAttempts bounds how often a change is made again to a group that another request
+	// changed first
+	groupUpdateAttempts = 5
+)
+
+// errUnchanged is returned by a change to a group that leaves nothing to update
+var errUnchanged = errors.New("group unchanged")
+
+type GroupService struct {
+	groupRepo   repository.GroupRepository
+	permissions *PermissionEvaluator
+}
+
+func NewGroupService(groupRepo repository.GroupRepository, permissions *PermissionEvaluator) *GroupService {
+	return &GroupService{groupRepo: groupRepo, permissions: permissions}
+}
+
+// CreateGroup creates a group library with the user as its only owner
+func (s *GroupService) CreateGroup(ctx context.Context, userID, name string) (*models.Group, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, appErrors.ErrInvalidGroupName
+	}
+
+	group := &models.Group{
+		Name: name,
+		Members: []models.GroupMember{{
+			UserID:   userID,
+			Role:     models.GroupRoleOwner,
+			JoinedAt: primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		Invites: []models.GroupInvite{},
+	}
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *GroupService) GetGroupsForUser(ctx context.Context, userID string) ([]models.Group, error) {
+	return s.groupRepo.FindByMember(ctx, userID)
+}
+
+func (s *GroupService) GetGroup(ctx context.Context, groupID, userID string) (*models.Group, error) {
+	return s.permissions.CheckGroup(ctx, groupID, userID, PermissionView)
+}
+
+// SetMemberRole changes the role of an existing member
+func (s *GroupService) SetMemberRole(ctx context.Context, groupID, actorID, memberID string, role models.GroupRole) (*models.Group, error) {
+	if !role.IsValid() {
+		return nil, appErrors.ErrInvalidGroupRole
+	}
+
+	return s.updateGroup(ctx, s.checkGroup(groupID, actorID, PermissionManage), func(group *models.Group) error {
+		index := memberIndex(group, memberID)
+		if index < 0 {
+			return appErrors.ErrNotFound
+		}
+		group.Members[index].Role = role
+		if !hasOwner(group) {
+			return appErrors.ErrLastOwner
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes a member from the group. Members may always remove themselves; removing others requires managing the group.
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, actorID, memberID string) error {
+	permission := PermissionManage
+	if actorID == memberID {
+		permission = PermissionView
+	}
+
+	_, err := s.updateGroup(ctx, s.checkGroup(groupID, actorID, permission), func(group *models.Group) error {
+		index := memberIndex(group, memberID)
+		if index < 0 {
+			return errUnchanged
+		}
+		group.Members = append(group.Members[:index], group.Members[index+1:]...)
+		if !hasOwner(group) {
+			return appErrors.ErrLastOwner
+		}
+		return nil
+	})
+	return err
+}
+
+// CreateInvite issues an invite link granting the role. A zero validFor uses the default validity.
+func (s *GroupService) CreateInvite(ctx context.Context, groupID, actorID string, role models.GroupRole, validFor time.Duration) (*models.GroupInvite, error) {
+	if role != models.GroupRoleEditor && role != models.GroupRoleViewer {
+		return nil, appErrors.ErrInvalidGroupRole
+	}
+	if validFor <= 0 {
+		validFor = defaultInviteValidity
+	}
+	if validFor > maxInviteValidity {
+		validFor = maxInviteValidity
+	}
+
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite := models.GroupInvite{
+		Token:     token,
+		Role:      role,
+		CreatedBy: actorID,
+		CreatedAt: primitive.NewDateTimeFromTime(now),
+		ExpiresAt: primitive.NewDateTimeFromTime(now.Add(validFor)),
+	}
+	_, err = s.updateGroup(ctx, s.checkGroup(groupID, actorID, PermissionManage), func(group *models.Group) error {
+		group.Invites = append(activeInvites(group.Invites, now), invite)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ListInvites returns the group's invites that haven't expired yet
+func (s *GroupService) ListInvites(ctx context.Context, groupID, actorID string) ([]models.GroupInvite, error) {
+	group, err := s.permissions.CheckGroup(ctx, groupID, actorID, PermissionManage)
+	if err != nil {
+		return nil, err
+	}
+	return activeInvites(group.Invites, time.Now()), nil
+}
+
+func (s *GroupService) RevokeInvite(ctx context.Context, groupID, actorID, token string) error {
+	_, err := s.updateGroup(ctx, s.checkGroup(groupID, actorID, PermissionManage), func(group *models.Group) error {
+		invites := make([]models.GroupInvite, 0, len(group.Invites))
+		for _, invite := range group.Invites {
+			if invite.Token != token {
+				invites = append(invites, invite)
+			}
+		}
+		group.Invites = invites
+		return nil
+	})
+	return err
+}
+
+// AcceptInvite adds the user to the invite's group. Accepting an invite to a group the user already belongs to changes nothing.
+func (s *GroupService) AcceptInvite(ctx context.Context, token, userID string) (*models.Group, error) {
+	load := func(ctx context.Context) (*models.Group, error) {
+		return s.groupRepo.FindByInviteToken(ctx, token)
+	}
+	return s.updateGroup(ctx, load, func(group *models.Group) error {
+		var invite *models.GroupInvite
+		for i := range group.Invites {
+			if group.Invites[i].Token == token {
+				invite = &group.Invites[i]
+			}
+		}
+		if invite == nil {
+			return appErrors.ErrNotFound
+		}
+		if invite.ExpiresAt.Time().Before(time.Now()) {
+			return appErrors.ErrInviteExpired
+		}
+
+		if _, isMember := group.MemberRole(userID); isMember {
+			return errUnchanged
+		}
+
+		group.Members = append(group.Members, models.GroupMember{
+			UserID:   userID,
+			Role:     invite.Role,
+			JoinedAt: primitive.NewDateTimeFromTime(time.Now()),
+		})
+		return nil
+	})
+}
+
+// checkGroup loads a group the actor needs the permission on
+func (s *GroupService) checkGroup(groupID, actorID string, permission Permission) func(ctx context.Context) (*models.Group, error) {
+	return func(ctx context.Context) (*models.Group, error) {
+		return s.permissions.CheckGroup(ctx, groupID, actorID, permission)
+	}
+}
+
+// updateGroup loads a group, applies change to it and stores it. If another request updated the group
+// in the meantime, the change is made again to the group as that request left it, so that neither
+// change is lost. A change returning errUnchanged leaves the group as it is.
+func (s *GroupService) updateGroup(ctx context.Context, load func(ctx context.Context) (*models.Group, error), change func(group *models.Group) error) (*models.Group, error) {
+	for attempt := 1; ; attempt++ {
+		group, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = change(group)
+		if errors.Is(err, errUnchanged) {
+			return group, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.groupRepo.Update(ctx, group)
+		if errors.Is(err, appErrors.ErrGroupChanged) && attempt < groupUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return group, nil
+	}
+}
+
+func memberIndex(group *models.Group, userID string) int {
+	for i, member := range group.Members {
+		if member.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasOwner(group *models.Group) bool {
+	for _, member := range group.Members {
+		if member.Role == models.GroupRoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+func activeInvites(invites []models.GroupInvite, now time.Time) []models.GroupInvite {
+	active := make([]models.GroupInvite, 0, len(invites))
+	for _, invite := range invites {
+		if invite.ExpiresAt.Time().After(now) {
+			active = append(active, invite)
+		}
+	}
+	return active
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+)
+
+// HighlightService manages highlights. Whoever may view a book may read its highlights,
+// and whoever may edit it may change them.
+type HighlightService struct {
+	repo  repository.HighlightRepository
+	books *BookService
+}
+
+func NewHighlightService(repo repository.HighlightRepository, books *BookService) *HighlightService {
+	return &HighlightService{repo: repo, books: books}
+}
+
+func validateHighlight(highlight *models.Highlight) error {
+	highlight.Text = strings.TrimSpace(highlight.Text)
+	highlight.Location = strings.TrimSpace(highlight.Location)
+	highlight.Tags = normalizeTags(highlight.Tags)
+
+	if highlight.Text == "" || highlight.Page < 0 {
+		return appErrors.ErrInvalidHighlight
+	}
+	if !highlight.Color.IsValid() {
+		return appErrors.ErrInvalidColor
+	}
+	return nil
+}
+
+// normalizeTags lowercases the tags and drops empty and repeated ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func (s *HighlightService) CreateHighlight(ctx context.Context, bookID, userID string, highlight *models.Highlight) error {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, PermissionEdit)
+	if err != nil {
+		return err
+	}
+	if err := validateHighlight(highlight); err != nil {
+		return err
+	}
+
+	highlight.BookID = book.ID
+	highlight.UserID = book.UserID
+	highlight.GroupID = book.GroupID
+	highlight.CreatedAt = 0
+	return s.repo.Create(ctx, highlight)
+}
+
+func (s *HighlightService) GetHighlights(ctx context.Context, bookID, userID string) ([]models.Highlight, error) {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByBookID(ctx, book.ID)
+}
+
+// getHighlight loads a highlight of the book, checking the user holds the permission on the book
+func (s *HighlightService) getHighlight(ctx context.Context, bookID, highlightID, userID string, permission Permission) (*models.Highlight, error) {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	highlight, err := s.repo.FindByID(ctx, highlightID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrInvalidID) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, err
+	}
+	if highlight.BookID != book.ID {
+		return nil, appErrors.ErrNotFound
+	}
+	return highlight, nil
+}
+
+// UpdateHighlight applies the editable fields of update to the highlight and returns the result
+func (s *HighlightService) UpdateHighlight(ctx context.Context, bookID, highlightID, userID string, update *models.Highlight) (*models.Highlight, error) {
+	highlight, err := s.getHighlight(ctx, bookID, highlightID, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	highlight.Text = update.Text
+	highlight.Page = update.Page
+	highlight.Location = update.Location
+	highlight.Note = update.Note
+	highlight.Color = update.Color
+	highlight.Tags = update.Tags
+
+	if err := validateHighlight(highlight); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, highlight); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+func (s *HighlightService) DeleteHighlight(ctx context.Context, bookID, highlightID, userID string) error {
+	highlight, err := s.getHighlight(ctx, bookID, highlightID, userID, PermissionEdit)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.repo.Delete(ctx, highlight.ID)
+}
+
+// SearchHighlights searches the highlights on every book the user can view, newest first
+func (s *HighlightService) SearchHighlights(ctx context.Context, userID string, filter models.HighlightFilter, offset, limit int) ([]models.Highlight, error) {
+	if !filter.Color.IsValid() {
+		return nil, appErrors.ErrInvalidColor
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+
+	groupIDs, err := s.books.permissions.GroupIDsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Search(ctx, userID, groupIDs, filter, offset, limit)
+}
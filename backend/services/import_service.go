@@ -0,0 +1,765 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/importers"
+	"tranquil-pages/metadata"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportService brings books and highlights from other apps and devices into a user's library
+type ImportService struct {
+	books      *BookService
+	highlights repository.HighlightRepository
+	series     repository.SeriesRepository
+	jobs       *JobService
+}
+
+// importBatchSize is the number of entries an import job stores at a time
+const importBatchSize = 500
+
+func NewImportService(books *BookService, highlights repository.HighlightRepository, series repository.SeriesRepository, jobs *JobService) *ImportService {
+	s := &ImportService{books: books, highlights: highlights, series: series, jobs: jobs}
+	jobs.handle(models.JobImportBooks, s.runImportJob)
+	return s
+}
+
+// bookMatcher finds books in a user's personal library by ISBN or by normalised title and author,
+// creating the ones that are missing
+type bookMatcher struct {
+	books   *BookService
+	userID  string
+	byTitle map[string][]*models.Book
+	byISBN  map[string]*models.Book
+	byID    map[primitive.ObjectID]*models.Book
+	seen    map[primitive.ObjectID]bool
+	summary *models.ImportSummary
+}
+
+func (s *ImportService) newBookMatcher(ctx context.Context, userID string, summary *models.ImportSummary) (*bookMatcher, error) {
+	books, err := s.books.GetBooksByUserID(ctx, userID, models.BookFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &bookMatcher{
+		books:   s.books,
+		userID:  userID,
+		byTitle: make(map[string][]*models.Book),
+		byISBN:  make(map[string]*models.Book),
+		byID:    make(map[primitive.ObjectID]*models.Book),
+		seen:    make(map[primitive.ObjectID]bool),
+		summary: summary,
+	}
+	for i := range books {
+		matcher.add(&books[i])
+	}
+	return matcher, nil
+}
+
+// add indexes the book under its ID, its ISBN, its full title and its title without the subtitle
+func (m *bookMatcher) add(book *models.Book) {
+	m.byID[book.ID] = book
+	if book.ISBN13 != "" {
+		m.byISBN[book.ISBN13] = book
+	}
+	full := normalizeTitle(book.Title)
+	m.byTitle[full] = append(m.byTitle[full], book)
+	if short := normalizeTitle(mainTitle(book.Title)); short != full {
+		m.byTitle[short] = append(m.byTitle[short], book)
+	}
+}
+
+func (m *bookMatcher) find(title, author string) *models.Book {
+	for _, key := range []string{normalizeTitle(title), normalizeTitle(mainTitle(title))} {
+		for _, book := range m.byTitle[key] {
+			if authorsMatch(book.Author, author) {
+				return book
+			}
+		}
+	}
+	return nil
+}
+
+// match records a book of the library the import refers to
+func (m *bookMatcher) match(book *models.Book) {
+	if !m.seen[book.ID] {
+		m.seen[book.ID] = true
+		m.summary.BooksMatched++
+	}
+}
+
+// findOrCreate returns the matching book, creating it if the user doesn't have it yet
+func (m *bookMatcher) findOrCreate(ctx context.Context, title, author string) (*models.Book, error) {
+	if book := m.find(title, author); book != nil {
+		m.match(book)
+		return book, nil
+	}
+
+	book := &models.Book{UserID: m.userID, Title: title, Author: author}
+	if err := m.books.CreateBook(ctx, book); err != nil {
+		return nil, err
+	}
+	m.add(book)
+	m.seen[book.ID] = true
+	m.summary.BooksCreated++
+	return book, nil
+}
+
+// normalizeTitle lowercases the title and reduces punctuation and spacing to single spaces
+func normalizeTitle(title string) string {
+	return strings.Join(words(title), " ")
+}
+
+// mainTitle drops a subtitle, as in "Sapiens: A Brief History of Humankind"
+func mainTitle(title string) string {
+	if index := strings.Index(title, ":"); index > 0 {
+		return title[:index]
+	}
+	return title
+}
+
+// authorsMatch compares author names ignoring case, punctuation and word order, so that
+// "Fitzgerald, F. Scott" matches "F. Scott Fitzgerald". A missing author matches anyone.
+func authorsMatch(a, b string) bool {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return true
+	}
+	if len(wordsA) != len(wordsB) {
+		return false
+	}
+
+	counts := make(map[string]int)
+	for _, word := range wordsA {
+		counts[word]++
+	}
+	for _, word := range wordsB {
+		if counts[word] == 0 {
+			return false
+		}
+		counts[word]--
+	}
+	return true
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// clippingBook holds the highlights of one book while its clippings are imported
+type clippingBook struct {
+	book       *models.Book
+	highlights []*models.Highlight
+}
+
+func highlightKey(location, text string) string {
+	return location + "|" + strings.Join(strings.Fields(text), " ")
+}
+
+func (b *clippingBook) find(location, text string) *models.Highlight {
+	key := highlightKey(location, text)
+	for _, highlight := range b.highlights {
+		if highlightKey(highlight.Location, highlight.Text) == key {
+			return highlight
+		}
+	}
+	return nil
+}
+
+// noteTarget returns the latest highlight whose location range contains the note's location.
+// Kindles store a note separately from the highlight it was written on.
+func (b *clippingBook) noteTarget(location string) *models.Highlight {
+	noteLocation, _, ok := importers.LocationRange(location)
+	if !ok {
+		return nil
+	}
+
+	for i := len(b.highlights) - 1; i >= 0; i-- {
+		start, end, ok := importers.LocationRange(b.highlights[i].Location)
+		if ok && start <= noteLocation && noteLocation <= end {
+			return b.highlights[i]
+		}
+	}
+	return nil
+}
+
+// ImportKindleClippings imports a Kindle "My Clippings.txt" file into the user's personal library.
+// Highlights already in the library are skipped, so the same file can be imported again as it grows.
+func (s *ImportService) ImportKindleClippings(ctx context.Context, userID string, r io.Reader) (*models.ImportSummary, error) {
+	clippings, err := importers.ParseKindleClippings(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", appErrors.ErrInvalidImportFile, err)
+	}
+
+	summary := &models.ImportSummary{Skipped: []models.SkippedEntry{}}
+	skip := func(clipping importers.Clipping, reason string) {
+		summary.Skip(models.SkippedEntry{Entry: clipping.Entry, Title: clipping.Title, Reason: reason})
+	}
+
+	matcher, err := s.newBookMatcher(ctx, userID, summary)
+	if err != nil {
+		return nil, err
+	}
+	books := make(map[primitive.ObjectID]*clippingBook)
+
+	for _, clipping := range clippings {
+		switch {
+		case clipping.Kind == importers.ClippingUnknown:
+			skip(clipping, "Unrecognised entry")
+			continue
+		case clipping.Kind == importers.ClippingBookmark:
+			skip(clipping, "Bookmarks have no text")
+			continue
+		case clipping.Text == "":
+			skip(clipping, "Empty clipping")
+			continue
+		}
+
+		book, err := matcher.findOrCreate(ctx, clipping.Title, clipping.Author)
+		if err != nil {
+			return nil, err
+		}
+		target, ok := books[book.ID]
+		if !ok {
+			existing, err := s.highlights.FindByBookID(ctx, book.ID)
+			if err != nil {
+				return nil, err
+			}
+			target = &clippingBook{book: book}
+			for i := range existing {
+				target.highlights = append(target.highlights, &existing[i])
+			}
+			books[book.ID] = target
+		}
+
+		if clipping.Kind == importers.ClippingNote {
+			if highlight := target.noteTarget(clipping.Location); highlight != nil {
+				if strings.Contains(highlight.Note, clipping.Text) {
+					skip(clipping, "Duplicate note")
+					continue
+				}
+				highlight.Note = strings.TrimSpace(highlight.Note + "\n\n" + clipping.Text)
+				if err := s.highlights.Update(ctx, highlight); err != nil {
+					return nil, err
+				}
+				summary.Imported++
+				continue
+			}
+		}
+
+		if target.find(clipping.Location, clipping.Text) != nil {
+			skip(clipping, "Duplicate highlight")
+			continue
+		}
+
+		highlight := &models.Highlight{
+			BookID:   book.ID,
+			UserID:   book.UserID,
+			Text:     clipping.Text,
+			Page:     clipping.Page,
+			Location: clipping.Location,
+		}
+		// A note that doesn't belong to any highlight is kept on its own
+		if clipping.Kind == importers.ClippingNote {
+			highlight.Tags = []string{"note"}
+		}
+		if !clipping.AddedAt.IsZero() {
+			highlight.CreatedAt = primitive.NewDateTimeFromTime(clipping.AddedAt)
+		}
+		if err := s.highlights.Create(ctx, highlight); err != nil {
+			return nil, err
+		}
+		target.highlights = append(target.highlights, highlight)
+		summary.Imported++
+	}
+
+	return summary, nil
+}
+
+// seriesMatcher finds the user's series by name, creating the ones that are missing. A dry run gives
+// missing series an ID without storing them.
+type seriesMatcher struct {
+	repo   repository.SeriesRepository
+	userID string
+	dryRun bool
+	byName map[string]*models.Series
+}
+
+func (s *ImportService) newSeriesMatcher(ctx context.Context, userID string, dryRun bool) (*seriesMatcher, error) {
+	existing, err := s.series.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &seriesMatcher{repo: s.series, userID: userID, dryRun: dryRun, byName: make(map[string]*models.Series)}
+	for i := range existing {
+		matcher.byName[normalizeTitle(existing[i].Name)] = &existing[i]
+	}
+	return matcher, nil
+}
+
+func (m *seriesMatcher) findOrCreate(ctx context.Context, name string) (*models.Series, error) {
+	if series, ok := m.byName[normalizeTitle(name)]; ok {
+		return series, nil
+	}
+
+	series := &models.Series{UserID: m.userID, Name: name}
+	if err := validateSeries(series); err != nil {
+		return nil, err
+	}
+	if m.dryRun {
+		series.ID = primitive.NewObjectID()
+	} else if err := m.repo.Create(ctx, series); err != nil {
+		return nil, err
+	}
+	m.byName[normalizeTitle(name)] = series
+	return series, nil
+}
+
+// assign puts the book in the record's series, if it has one
+func (m *seriesMatcher) assign(ctx context.Context, book *models.Book, record importers.BookRecord) error {
+	if record.Series == "" {
+		return nil
+	}
+	series, err := m.findOrCreate(ctx, record.Series)
+	if err != nil {
+		return err
+	}
+	book.SeriesID = &series.ID
+	if record.SeriesIndex != nil && *record.SeriesIndex >= 0 && *record.SeriesIndex <= maxVolume {
+		book.Volume = record.SeriesIndex
+	}
+	return nil
+}
+
+// recordBook maps an imported record onto a book of the user's personal library. ISBNs that aren't
+// valid are left out.
+func recordBook(userID string, record importers.BookRecord) *models.Book {
+	book := &models.Book{
+		UserID:      userID,
+		Title:       record.Title,
+		Author:      strings.Join(record.Authors, " & "),
+		Tags:        record.Tags,
+		RatingScore: record.RatingScore,
+		Status:      record.Status,
+		Format:      record.Format,
+		Review:      record.Review,
+		Comment:     record.Comment,
+	}
+	if _, isbn13, err := metadata.ParseISBN(record.ISBN); err == nil {
+		book.ISBN13 = isbn13
+	}
+
+	edition := models.Edition{Publisher: record.Publisher, Year: record.Year}
+	if !record.Format.IsTimed() {
+		edition.PageCount = record.PageCount
+	}
+	if edition != (models.Edition{}) {
+		book.Edition = &edition
+	}
+	return book
+}
+
+// fillBook fills in the fields of the book that are missing and the imported book has, and returns the
+// names of the fields it filled in. Tags the book lacks are added.
+func fillBook(book, imported *models.Book) []string {
+	var fields []string
+	fill := func(field string, missing bool, apply func()) {
+		if missing {
+			apply()
+			fields = append(fields, field)
+		}
+	}
+
+	fill("isbn", book.ISBN13 == "" && imported.ISBN13 != "", func() {
+		book.ISBN10, book.ISBN13 = imported.ISBN10, imported.ISBN13
+	})
+	fill("series", book.SeriesID == nil && imported.SeriesID != nil, func() {
+		book.SeriesID, book.Volume = imported.SeriesID, imported.Volume
+	})
+	fill("format", book.Format == "" && imported.Format != "", func() { book.Format = imported.Format })
+	if book.Edition == nil && imported.Edition != nil {
+		edition := *imported.Edition
+		if book.Format.IsTimed() {
+			edition.PageCount = 0
+		}
+		fill("edition", edition != (models.Edition{}), func() { book.Edition = &edition })
+	}
+	fill("rating", book.RatingScore == 0 && imported.RatingScore > 0, func() { book.RatingScore = imported.RatingScore })
+	fill("status", book.Status == "" && imported.Status != "", func() { book.Status = imported.Status })
+	fill("review", book.Review == "" && imported.Review != "", func() { book.Review = imported.Review })
+	fill("comment", book.Comment == "" && imported.Comment != "", func() { book.Comment = imported.Comment })
+
+	tags := slices.Clone(book.Tags)
+	for _, tag := range normalizeTags(imported.Tags) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	fill("tags", len(tags) > len(book.Tags), func() { book.Tags = tags })
+	return fields
+}
+
+// dateRead moves a read that a new status opened or finished, which the book service dates to now, to
+// the dates the import gives. Dates that don't make a valid read are ignored.
+func dateRead(book *models.Book, read *models.Read, record importers.BookRecord) {
+	if read == nil || (record.StartedAt == nil && record.FinishedAt == nil) {
+		return
+	}
+
+	dated := *read
+	if record.StartedAt != nil {
+		started := primitive.NewDateTimeFromTime(*record.StartedAt)
+		dated.StartedAt = &started
+	}
+	if record.FinishedAt != nil && dated.FinishedAt != nil {
+		finished := primitive.NewDateTimeFromTime(*record.FinishedAt)
+		dated.FinishedAt = &finished
+	}
+	if validateRead(&dated) != nil {
+		return
+	}
+
+	*read = dated
+	if book.Status == models.StatusFinished && dated.FinishedAt != nil {
+		book.FinishedAt = dated.FinishedAt
+	}
+}
+
+// parseExport reads the entries of an export, reporting exports in the wrong format as invalid import files
+func parseExport(importer importers.Importer, r io.Reader) ([]importers.BookRecord, error) {
+	records, err := importer.Parse(r)
+	if errors.Is(err, importers.ErrInvalidExport) {
+		return nil, fmt.Errorf("%w: %w", appErrors.ErrInvalidImportFile, err)
+	}
+	return records, err
+}
+
+// PreviewImport reports what importing the export would do, without storing anything. It lists the
+// books the import would create and update as they would be stored.
+func (s *ImportService) PreviewImport(ctx context.Context, userID string, importer importers.Importer, r io.Reader) (*models.ImportSummary, error) {
+	records, err := parseExport(importer, r)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.ImportSummary{DryRun: true, Skipped: []models.SkippedEntry{}}
+	run, err := s.newBookImport(ctx, userID, summary, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := run.importBatch(ctx, records); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// StartImport reads the export and queues a job importing its books, which GetJob reports on. Entries
+// of the export are numbered from 1 in order, and the job commits them in batches.
+func (s *ImportService) StartImport(ctx context.Context, userID, format string, importer importers.Importer, r io.Reader) (*models.Job, error) {
+	records, err := parseExport(importer, r)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.Job{
+		ID:      primitive.NewObjectID(),
+		UserID:  userID,
+		Type:    models.JobImportBooks,
+		Format:  format,
+		Total:   len(records),
+		Summary: &models.ImportSummary{Skipped: []models.SkippedEntry{}},
+	}
+	// The entries are stored before the job is queued, so that no worker can pick up a job without them
+	if err := s.jobs.repo.AddRecords(ctx, job.ID, records); err != nil {
+		s.deleteRecords(ctx, job)
+		return nil, err
+	}
+	if err := s.jobs.submit(ctx, job); err != nil {
+		s.deleteRecords(ctx, job)
+		return nil, err
+	}
+	return job, nil
+}
+
+// deleteRecords cleans up the job's entries once they are no longer needed, even if the request or
+// job that needed them was cancelled
+func (s *ImportService) deleteRecords(ctx context.Context, job *models.Job) {
+	if err := s.jobs.repo.DeleteRecords(context.WithoutCancel(ctx), job.ID); err != nil {
+		log.Printf("Failed to delete the entries of import job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+// runImportJob imports the job's entries a batch at a time, committing the job's progress after each
+// batch. A job resumed after a restart carries on after its last committed batch. A batch that was
+// stored but not committed is imported again, and as an import never adds a book the library already
+// has, its books are matched rather than duplicated.
+func (s *ImportService) runImportJob(ctx context.Context, job *models.Job) (err error) {
+	// The entries are kept only while the job can be resumed, however else it ends
+	defer func() {
+		if !errors.Is(err, errJobStopped) {
+			s.deleteRecords(ctx, job)
+		}
+	}()
+
+	// The job only stops between batches, so that a stopping service still commits the batch in progress
+	batchCtx := context.WithoutCancel(ctx)
+
+	if job.Summary == nil {
+		job.Summary = &models.ImportSummary{Skipped: []models.SkippedEntry{}}
+	}
+	run, err := s.newBookImport(batchCtx, job.UserID, job.Summary, false)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return errJobStopped
+		}
+
+		records, err := s.jobs.repo.FindRecords(batchCtx, job.ID, job.Processed, importBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			break
+		}
+		if err := run.importBatch(batchCtx, records); err != nil {
+			// A batch the database was too slow for is tried again once the job's lease has expired
+			if appErrors.FromContext(err) != nil {
+				return errJobStopped
+			}
+			return err
+		}
+
+		job.Processed = records[len(records)-1].Entry
+		if err := s.jobs.save(batchCtx, job); errors.Is(err, appErrors.ErrLeaseLost) {
+			// Another worker runs the job now, from the progress this one last committed
+			return errJobStopped
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookImport carries an import of books from one batch of entries to the next
+type bookImport struct {
+	s       *ImportService
+	userID  string
+	dryRun  bool
+	summary *models.ImportSummary
+	books   *bookMatcher
+	series  *seriesMatcher
+}
+
+func (s *ImportService) newBookImport(ctx context.Context, userID string, summary *models.ImportSummary, dryRun bool) (*bookImport, error) {
+	books, err := s.newBookMatcher(ctx, userID, summary)
+	if err != nil {
+		return nil, err
+	}
+	series, err := s.newSeriesMatcher(ctx, userID, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &bookImport{s: s, userID: userID, dryRun: dryRun, summary: summary, books: books, series: series}, nil
+}
+
+func (b *bookImport) skip(record importers.BookRecord, reason string) {
+	b.summary.Skip(models.SkippedEntry{Entry: record.Entry, Title: record.Title, Reason: reason})
+}
+
+// existing returns the book of the library the imported book matches, by ISBN or by title and author
+func (b *bookImport) existing(imported *models.Book) *models.Book {
+	if book := b.books.byISBN[imported.ISBN13]; book != nil && imported.ISBN13 != "" {
+		return book
+	}
+	return b.books.find(imported.Title, imported.Author)
+}
+
+// reload loads the books the entries match again, so that the batch doesn't undo changes made to
+// them since the import started
+func (b *bookImport) reload(ctx context.Context, records []importers.BookRecord) error {
+	var ids []primitive.ObjectID
+	for _, record := range records {
+		if record.Problem != "" || record.Title == "" {
+			continue
+		}
+		if book := b.existing(recordBook(b.userID, record)); book != nil {
+			ids = append(ids, book.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	books, err := b.s.books.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, book := range books {
+		if current := b.books.byID[book.ID]; current != nil {
+			*current = book
+		}
+	}
+	return nil
+}
+
+// importBatch imports a batch of entries. The books a batch creates and updates are stored together
+// at the end, in one bulk write each.
+func (b *bookImport) importBatch(ctx context.Context, records []importers.BookRecord) error {
+	if !b.dryRun {
+		if err := b.reload(ctx, records); err != nil {
+			return err
+		}
+	}
+
+	var created, updated []*models.Book
+	var activities []*models.Activity
+	pending := make(map[primitive.ObjectID]bool)
+
+	for _, record := range records {
+		switch {
+		case record.Problem != "":
+			b.skip(record, record.Problem)
+			continue
+		case record.Title == "":
+			b.skip(record, "Missing title")
+			continue
+		}
+
+		imported := recordBook(b.userID, record)
+		existing := b.existing(imported)
+		// Series are only looked up, and created, for books that will be put in them
+		if existing == nil || existing.SeriesID == nil {
+			if err := b.series.assign(ctx, imported, record); err != nil {
+				if errors.Is(err, appErrors.ErrInvalidSeries) {
+					b.skip(record, err.Error())
+					continue
+				}
+				return err
+			}
+		}
+
+		var result *models.ImportedBook
+		var err error
+		if existing != nil {
+			b.books.match(existing)
+			result, err = b.updateBook(ctx, existing, imported, record, &activities)
+		} else {
+			result, err = b.createBook(ctx, imported, record)
+		}
+		if errors.Is(err, appErrors.ErrDatabase) || appErrors.FromContext(err) != nil {
+			return err
+		}
+		if err != nil {
+			b.skip(record, err.Error())
+			continue
+		}
+		if result == nil {
+			b.skip(record, "Already in library")
+			continue
+		}
+
+		if existing == nil {
+			b.books.add(result.Book)
+			b.books.seen[result.Book.ID] = true
+			created = append(created, result.Book)
+			pending[result.Book.ID] = true
+			b.summary.BooksCreated++
+		} else {
+			// A book created earlier in the batch is stored with what the update filled in
+			*existing = *result.Book
+			if !pending[existing.ID] {
+				updated = append(updated, existing)
+				pending[existing.ID] = true
+			}
+			b.summary.BooksUpdated++
+		}
+		b.summary.Imported++
+		if b.dryRun {
+			b.summary.Books = append(b.summary.Books, *result)
+		}
+	}
+
+	if b.dryRun {
+		return nil
+	}
+	if err := b.s.books.createBooks(ctx, created); err != nil {
+		return err
+	}
+	if err := b.s.books.repo.UpdateMany(ctx, updated); err != nil {
+		return err
+	}
+	b.s.books.stored(ctx, updated...)
+	b.s.books.recordActivities(ctx, activities)
+	return nil
+}
+
+// createBook checks the new book and sets it up to be stored. It is given its ID straight away, so
+// that later entries of the batch can match it.
+func (b *bookImport) createBook(ctx context.Context, book *models.Book, record importers.BookRecord) (*models.ImportedBook, error) {
+	if b.dryRun {
+		if err := validateBook(book); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := b.s.books.prepareBook(ctx, book); err != nil {
+			return nil, err
+		}
+		if len(book.Reads) > 0 {
+			dateRead(book, &book.Reads[0], record)
+		}
+	}
+	book.ID = primitive.NewObjectID()
+	return &models.ImportedBook{Entry: record.Entry, Action: models.ImportCreated, Series: record.Series, Book: book}, nil
+}
+
+// updateBook fills in what the book is missing, adding the activities the update records. It returns
+// nil if the book has everything.
+func (b *bookImport) updateBook(ctx context.Context, existing, imported *models.Book, record importers.BookRecord, activities *[]*models.Activity) (*models.ImportedBook, error) {
+	filled := *existing
+	fields := fillBook(&filled, imported)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	book := filled
+	if b.dryRun {
+		if err := validateBook(&book); err != nil {
+			return nil, err
+		}
+	} else {
+		book = *existing
+		book.Reads = slices.Clone(existing.Reads)
+		change, err := b.s.books.applyUpdate(ctx, &book, &filled)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(fields, "status") {
+			dateRead(&book, change.read, record)
+		}
+		*activities = append(*activities, change.activities...)
+	}
+
+	result := &models.ImportedBook{Entry: record.Entry, Action: models.ImportUpdated, Fields: fields, Book: &book}
+	if slices.Contains(fields, "series") {
+		result.Series = record.Series
+	}
+	return result, nil
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errJobStopped is returned by handlers that stopped early, because the service is stopping, the job
+// was taken over by another worker, or the database was too slow to finish a batch. The job is left as
+// it is, and is requeued when the service starts again, or by any replica once its lease has expired.
+var errJobStopped = errors.New("job stopped")
+
+// JobConfig controls the pool of workers running background jobs
+type JobConfig struct {
+	// Workers is the number of jobs run at the same time
+	Workers int
+	// PollInterval bounds how long a job queued by another replica waits for an idle worker
+	PollInterval time.Duration
+	// WorkerID tells the jobs of this server apart from those of other replicas. It should stay the
+	// same across restarts, so that a restarted server resumes its own jobs straight away.
+	WorkerID string
+	// Lease is how long a running job is held by its worker without committing progress. Any replica
+	// takes the job over after that, so it must be longer than a batch of the job.
+	Lease time.Duration
+}
+
+func LoadJobConfig() (JobConfig, error) {
+	config := JobConfig{
+		Workers:      2,
+		PollInterval: 5 * time.Second,
+		WorkerID:     os.Getenv("JOB_WORKER_ID"),
+		Lease:        10 * time.Minute,
+	}
+
+	if value, ok := os.LookupEnv("JOB_WORKERS"); ok {
+		workers, err := strconv.Atoi(value)
+		if err != nil || workers < 1 {
+			return config, fmt.Errorf("invalid JOB_WORKERS %q", value)
+		}
+		config.Workers = workers
+	}
+
+	if value, ok := os.LookupEnv("JOB_POLL_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return config, fmt.Errorf("invalid JOB_POLL_INTERVAL %q", value)
+		}
+		config.PollInterval = interval
+	}
+
+	if value, ok := os.LookupEnv("JOB_LEASE"); ok {
+		lease, err := time.ParseDuration(value)
+		if err != nil || lease <= 0 {
+			return config, fmt.Errorf("invalid JOB_LEASE %q", value)
+		}
+		config.Lease = lease
+	}
+
+	return config, nil
+}
+
+// jobHandler does the work of a job. It commits its progress with save as it goes, so that a job
+// interrupted by a restart carries on from there, and returns errJobStopped if ctx is cancelled first.
+type jobHandler func(ctx context.Context, job *models.Job) error
+
+// JobService runs background jobs on a pool of workers. The queue is kept in the database, so jobs
+// queued or running when the server stopped are run once it starts again.
+type JobService struct {
+	repo     repository.JobRepository
+	config   JobConfig
+	handlers map[models.JobType]jobHandler
+
+	// wake tells an idle worker that a job was queued
+	wake chan struct{}
+	// cancel stops the workers started by Start
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+func NewJobService(repo repository.JobRepository, config JobConfig) *JobService {
+	if config.WorkerID == "" {
+		config.WorkerID = defaultWorkerID()
+	}
+	if config.Lease <= 0 {
+		config.Lease = 10 * time.Minute
+	}
+	return &JobService{
+		repo:     repo,
+		config:   config,
+		handlers: make(map[models.JobType]jobHandler),
+		wake:     make(chan struct{}, config.Workers),
+	}
+}
+
+// defaultWorkerID is the host name, which stays the same across restarts of a server, or a random id
+// if it can't be read
+func defaultWorkerID() string {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return primitive.NewObjectID().Hex()
+}
+
+// handle registers the function that runs jobs of the type
+func (s *JobService) handle(jobType models.JobType, handler jobHandler) {
+	s.handlers[jobType] = handler
+}
+
+// Start requeues the jobs a previous run of the server left running, along with jobs whose worker
+// stopped renewing their lease, and starts the workers
+func (s *JobService) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.requeue(ctx, s.config.WorkerID)
+
+	s.cancel = cancel
+	for i := 0; i < s.config.Workers; i++ {
+		s.done.Add(1)
+		go s.work(ctx)
+	}
+	s.done.Add(1)
+	go s.watchLeases(ctx)
+}
+
+// requeue puts the jobs owner left running, if any, and the jobs whose lease has expired back in the
+// queue and wakes the workers for them
+func (s *JobService) requeue(ctx context.Context, owner string) {
+	requeued, err := s.repo.Requeue(ctx, owner)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to requeue interrupted jobs: %v", err)
+		}
+		return
+	}
+	if requeued > 0 {
+		log.Printf("Resuming %d interrupted jobs", requeued)
+	}
+	for i := 0; i < requeued; i++ {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// watchLeases requeues the jobs of replicas that stopped without their jobs being resumed, once their
+// lease has expired
+func (s *JobService) watchLeases(ctx context.Context) {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.requeue(ctx, "")
+		}
+	}
+}
+
+// Stop waits for the workers to commit their current batch and stops them. Jobs they were running are
+// resumed by the next Start. It is safe to call Stop without Start.
+func (s *JobService) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.done.Wait()
+	s.cancel = nil
+}
+
+// submit queues the job
+func (s *JobService) submit(ctx context.Context, job *models.Job) error {
+	job.Status = models.JobQueued
+	if err := s.repo.Create(ctx, job); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// save commits the progress of a running job and renews its lease. It returns appErrors.ErrLeaseLost
+// if the job was taken over by another worker.
+func (s *JobService) save(ctx context.Context, job *models.Job) error {
+	lease := primitive.NewDateTimeFromTime(time.Now().Add(s.config.Lease))
+	job.LeaseExpiresAt = &lease
+	return s.repo.Update(ctx, job)
+}
+
+// GetJob returns the user's job. Other users' jobs are reported as not found.
+func (s *JobService) GetJob(ctx context.Context, id, userID string) (*models.Job, error) {
+	job, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != userID {
+		return nil, appErrors.ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *JobService) work(ctx context.Context) {
+	defer s.done.Done()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := s.repo.Claim(ctx, s.config.WorkerID, time.Now().Add(s.config.Lease))
+		if err == nil {
+			s.run(ctx, job)
+			continue
+		}
+		if !errors.Is(err, appErrors.ErrNotFound) && ctx.Err() == nil {
+			log.Printf("Failed to claim a job: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs the job's handler and stores how the job ended
+func (s *JobService) run(ctx context.Context, job *models.Job) {
+	err := s.runHandler(ctx, job)
+	if errors.Is(err, errJobStopped) {
+		return
+	}
+
+	finished := primitive.NewDateTimeFromTime(time.Now())
+	job.FinishedAt = &finished
+	job.Status = models.JobCompleted
+	if err != nil {
+		log.Printf("Job %s failed: %v", job.ID.Hex(), err)
+		job.Status = models.JobFailed
+		job.Error = err.Error()
+	}
+	// The job is over, so its outcome is stored even if the service is stopping
+	if err := s.save(context.WithoutCancel(ctx), job); err != nil {
+		log.Printf("Failed to store the outcome of job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+// runHandler turns a panicking handler into a failed job, so that one bad job can't take the server down
+func (s *JobService) runHandler(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := s.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("unknown job type %q", job.Type)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("Job %s panicked: %v", job.ID.Hex(), recovered)
+			err = fmt.Errorf("the job stopped unexpectedly")
+		}
+	}()
+	return handler(ctx, job)
+}
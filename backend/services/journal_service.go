@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/markdown"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxEntryLength bounds the size of a journal entry's Markdown, in bytes
+const maxEntryLength = 50000
+
+// JournalService manages the reading journal. Like highlights, whoever may view a book may read its
+// journal, and whoever may edit it may change it.
+type JournalService struct {
+	repo  repository.JournalRepository
+	books *BookService
+}
+
+func NewJournalService(repo repository.JournalRepository, books *BookService) *JournalService {
+	s := &JournalService{repo: repo, books: books}
+	books.onDelete(func(ctx context.Context, book *models.Book) error {
+		return s.repo.DeleteByBookID(ctx, book.ID)
+	})
+	return s
+}
+
+func validateEntry(entry *models.JournalEntry) error {
+	entry.Text = strings.TrimSpace(entry.Text)
+	entry.Chapter = strings.TrimSpace(entry.Chapter)
+
+	if entry.Text == "" || len(entry.Text) > maxEntryLength || entry.Page < 0 {
+		return appErrors.ErrInvalidEntry
+	}
+	if entry.Date == 0 {
+		entry.Date = primitive.NewDateTimeFromTime(time.Now())
+	}
+	return nil
+}
+
+// renderEntries fills in the rendered text of the entries
+func renderEntries(entries []models.JournalEntry) {
+	for i := range entries {
+		entries[i].TextHTML = markdown.Render(entries[i].Text, markdown.ShowSpoilers)
+	}
+}
+
+// getBook loads the book of the journal, reporting a book the user can't see as ErrBookNotFound
+func (s *JournalService) getBook(ctx context.Context, bookID, userID string, permission Permission) (*models.Book, error) {
+	book, err := s.books.getBookWithPermission(ctx, bookID, userID, permission)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return nil, appErrors.ErrBookNotFound
+	}
+	return book, err
+}
+
+func (s *JournalService) CreateEntry(ctx context.Context, bookID, userID string, entry *models.JournalEntry) error {
+	book, err := s.getBook(ctx, bookID, userID, PermissionEdit)
+	if err != nil {
+		return err
+	}
+	if err := validateEntry(entry); err != nil {
+		return err
+	}
+
+	entry.BookID = book.ID
+	entry.UserID = book.UserID
+	entry.GroupID = book.GroupID
+	if err := s.repo.Create(ctx, entry); err != nil {
+		return err
+	}
+	entry.TextHTML = markdown.Render(entry.Text, markdown.ShowSpoilers)
+	return nil
+}
+
+func (s *JournalService) GetEntries(ctx context.Context, bookID, userID string) ([]models.JournalEntry, error) {
+	book, err := s.getBook(ctx, bookID, userID, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.FindByBookID(ctx, book.ID)
+	if err != nil {
+		return nil, err
+	}
+	renderEntries(entries)
+	return entries, nil
+}
+
+// getEntry loads a journal entry of the book, checking the user holds the permission on the book
+func (s *JournalService) getEntry(ctx context.Context, bookID, entryID, userID string, permission Permission) (*models.JournalEntry, error) {
+	book, err := s.getBook(ctx, bookID, userID, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.repo.FindByID(ctx, entryID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrInvalidID) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, err
+	}
+	if entry.BookID != book.ID {
+		return nil, appErrors.ErrNotFound
+	}
+	return entry, nil
+}
+
+// UpdateEntry applies the editable fields of update to the entry and returns the result
+func (s *JournalService) UpdateEntry(ctx context.Context, bookID, entryID, userID string, update *models.JournalEntry) (*models.JournalEntry, error) {
+	entry, err := s.getEntry(ctx, bookID, entryID, userID, PermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Date = update.Date
+	entry.Page = update.Page
+	entry.Chapter = update.Chapter
+	entry.Text = update.Text
+
+	if err := validateEntry(entry); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, entry); err != nil {
+		return nil, err
+	}
+	entry.TextHTML = markdown.Render(entry.Text, markdown.ShowSpoilers)
+	return entry, nil
+}
+
+func (s *JournalService) DeleteEntry(ctx context.Context, bookID, entryID, userID string) error {
+	entry, err := s.getEntry(ctx, bookID, entryID, userID, PermissionEdit)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.repo.Delete(ctx, entry.ID)
+}
+
+// GetJournal returns the entries on every book the user can view, oldest first
+func (s *JournalService) GetJournal(ctx context.Context, userID string, filter models.JournalFilter, offset, limit int) ([]models.JournalEntry, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, appErrors.ErrInvalidDateRange
+	}
+
+	groupIDs, err := s.books.permissions.GroupIDsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.Search(ctx, userID, groupIDs, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	renderEntries(entries)
+	return entries, nil
+}
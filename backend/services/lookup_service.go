@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/metadata"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// metadataCacheTTL is how long a provider response is reused
+	metadataCacheTTL = 30 * 24 * time.Hour
+	// metadataMissTTL is shorter, since providers regularly add books
+	metadataMissTTL = 24 * time.Hour
+)
+
+// LookupService fills in book details from a metadata provider, caching its responses
+type LookupService struct {
+	provider metadata.MetadataProvider
+	cache    repository.MetadataCacheRepository
+	now      func() time.Time
+}
+
+func NewLookupService(provider metadata.MetadataProvider, cache repository.MetadataCacheRepository) *LookupService {
+	return &LookupService{provider: provider, cache: cache, now: time.Now}
+}
+
+// LookupISBN returns the metadata of the edition with the given ISBN-10 or ISBN-13
+func (s *LookupService) LookupISBN(ctx context.Context, isbn string) (*models.BookMetadata, error) {
+	_, isbn13, err := metadata.ParseISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry := s.cached(ctx, isbn13); entry != nil {
+		if entry.Metadata == nil {
+			return nil, appErrors.ErrNotFound
+		}
+		return entry.Metadata, nil
+	}
+
+	found, err := s.provider.LookupISBN(ctx, isbn13)
+	if err != nil && !errors.Is(err, appErrors.ErrNotFound) {
+		return nil, err
+	}
+
+	// A failure to cache only costs another upstream request later
+	entry := &models.MetadataCacheEntry{ISBN13: isbn13, Metadata: found, FetchedAt: primitive.NewDateTimeFromTime(s.now())}
+	if err := s.cache.Save(ctx, entry); err != nil {
+		log.Printf("Failed to cache metadata for %s: %v", isbn13, err)
+	}
+
+	if found == nil {
+		return nil, appErrors.ErrNotFound
+	}
+	return found, nil
+}
+
+// cached returns the cache entry for the ISBN if there is one that hasn't expired
+func (s *LookupService) cached(ctx context.Context, isbn13 string) *models.MetadataCacheEntry {
+	entry, err := s.cache.FindByISBN(ctx, isbn13)
+	if err != nil {
+		if !errors.Is(err, appErrors.ErrNotFound) {
+			log.Printf("Failed to read cached metadata for %s: %v", isbn13, err)
+		}
+		return nil
+	}
+
+	ttl := metadataCacheTTL
+	if entry.Metadata == nil {
+		ttl = metadataMissTTL
+	}
+	if s.now().Sub(entry.FetchedAt.Time()) > ttl {
+		return nil
+	}
+	return entry
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission is an action a user may want to take on a book or group library
+type Permission int
+
+const (
+	PermissionView Permission = iota
+	PermissionEdit
+	PermissionDelete
+	// PermissionManage covers a group's members and invites
+	PermissionManage
+)
+
+// groupRolePermissions lists what each group role may do with the group and its books
+var groupRolePermissions = map[models.GroupRole][]Permission{
+	models.GroupRoleOwner:  {PermissionView, PermissionEdit, PermissionDelete, PermissionManage},
+	models.GroupRoleEditor: {PermissionView, PermissionEdit, PermissionDelete},
+	models.GroupRoleViewer: {PermissionView},
+}
+
+// PermissionEvaluator decides whether a user may act on a book. Personal books are only
+// accessible to their owner; group books to group members according to their role.
+type PermissionEvaluator struct {
+	groupRepo repository.GroupRepository
+}
+
+func NewPermissionEvaluator(groupRepo repository.GroupRepository) *PermissionEvaluator {
+	return &PermissionEvaluator{groupRepo: groupRepo}
+}
+
+// CheckBook returns nil if the user holds the permission on the book. Users without any access
+// get appErrors.ErrNotFound, so they can't learn whether the book exists.
+func (e *PermissionEvaluator) CheckBook(ctx context.Context, book *models.Book, userID string, permission Permission) error {
+	if book.GroupID == nil {
+		if book.UserID != userID {
+			return appErrors.ErrNotFound
+		}
+		return nil
+	}
+
+	_, err := e.CheckGroup(ctx, book.GroupID.Hex(), userID, permission)
+	return err
+}
+
+// CheckGroup loads the group and returns it if the user holds the permission in it
+func (e *PermissionEvaluator) CheckGroup(ctx context.Context, groupID, userID string, permission Permission) (*models.Group, error) {
+	group, err := e.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrInvalidID) {
+			return nil, appErrors.ErrNotFound
+		}
+		return nil, err
+	}
+
+	role, isMember := group.MemberRole(userID)
+	if !isMember {
+		return nil, appErrors.ErrNotFound
+	}
+	if !roleAllows(role, permission) {
+		return nil, appErrors.ErrForbidden
+	}
+	return group, nil
+}
+
+// GroupIDsForUser lists the groups the user is a member of
+func (e *PermissionEvaluator) GroupIDsForUser(ctx context.Context, userID string) ([]primitive.ObjectID, error) {
+	groups, err := e.groupRepo.FindByMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupIDs := make([]primitive.ObjectID, 0, len(groups))
+	for _, group := range groups {
+		groupIDs = append(groupIDs, group.ID)
+	}
+	return groupIDs, nil
+}
+
+func roleAllows(role models.GroupRole, permission Permission) bool {
+	for _, granted := range groupRolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/markdown"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_-]{3,30}$`)
+
+type ProfileService struct {
+	profileRepo repository.ProfileRepository
+	bookRepo    repository.BookRepository
+}
+
+func NewProfileService(profileRepo repository.ProfileRepository, bookRepo repository.BookRepository) *ProfileService {
+	return &ProfileService{profileRepo: profileRepo, bookRepo: bookRepo}
+}
+
+func generateShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GetProfile returns the user's profile, or the private default if they never saved one
+func (s *ProfileService) GetProfile(ctx context.Context, userID string) (*models.Profile, error) {
+	return findProfile(ctx, s.profileRepo, userID)
+}
+
+// findProfile loads the user's profile with defaults filled in for anything they never chose
+func findProfile(ctx context.Context, profileRepo repository.ProfileRepository, userID string) (*models.Profile, error) {
+	profile, err := profileRepo.FindByUserID(ctx, userID)
+	if errors.Is(err, appErrors.ErrNotFound) {
+		profile, err = &models.Profile{UserID: userID, Visibility: models.VisibilityPrivate}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if profile.RatingScale == "" {
+		profile.RatingScale = models.DefaultRatingScale
+	}
+	return profile, nil
+}
+
+// GetRatingScale returns the scale the user enters and reads ratings on
+func (s *ProfileService) GetRatingScale(ctx context.Context, userID string) (models.RatingScale, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return profile.RatingScale, nil
+}
+
+// UpdateProfile replaces the user's sharing settings. A share token is issued the first time the library is shared.
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID string, update *models.Profile) (*models.Profile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Handle != "" && !handlePattern.MatchString(update.Handle) {
+		return nil, appErrors.ErrInvalidHandle
+	}
+	if !update.Visibility.IsValid() {
+		return nil, appErrors.ErrInvalidVisibility
+	}
+	if update.Visibility == models.VisibilityPublic && update.Handle == "" {
+		return nil, appErrors.ErrHandleMissing
+	}
+	if update.RatingScale != "" && !update.RatingScale.IsValid() {
+		return nil, appErrors.ErrInvalidScale
+	}
+
+	profile.Handle = update.Handle
+	profile.DisplayName = update.DisplayName
+	profile.Visibility = update.Visibility
+	profile.ShareRatings = update.ShareRatings
+	profile.ShareComments = update.ShareComments
+	// Clients that don't know about rating scales leave the chosen scale alone
+	if update.RatingScale != "" {
+		profile.RatingScale = update.RatingScale
+	}
+
+	if profile.Visibility != models.VisibilityPrivate && profile.ShareToken == "" {
+		if profile.ShareToken, err = generateShareToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.profileRepo.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// RotateShareToken invalidates the user's current share link and issues a new one
+func (s *ProfileService) RotateShareToken(ctx context.Context, userID string) (*models.Profile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.ShareToken, err = generateShareToken(); err != nil {
+		return nil, err
+	}
+
+	if err := s.profileRepo.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetLibraryByHandle returns the shared library of a public profile
+func (s *ProfileService) GetLibraryByHandle(ctx context.Context, handle string, spoilers markdown.Spoilers) (*models.SharedLibrary, error) {
+	profile, err := s.profileRepo.FindByHandle(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Visibility != models.VisibilityPublic {
+		return nil, appErrors.ErrNotFound
+	}
+	return s.sharedLibrary(ctx, profile, spoilers)
+}
+
+// GetLibraryByShareToken returns the shared library behind a share link
+func (s *ProfileService) GetLibraryByShareToken(ctx context.Context, token string, spoilers markdown.Spoilers) (*models.SharedLibrary, error) {
+	profile, err := s.profileRepo.FindByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Visibility == models.VisibilityPrivate {
+		return nil, appErrors.ErrNotFound
+	}
+	return s.sharedLibrary(ctx, profile, spoilers)
+}
+
+func (s *ProfileService) sharedLibrary(ctx context.Context, profile *models.Profile, spoilers markdown.Spoilers) (*models.SharedLibrary, error) {
+	books, err := s.bookRepo.FindByUserID(ctx, profile.UserID, models.BookFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	library := &models.SharedLibrary{
+		DisplayName: profile.DisplayName,
+		Books:       []models.SharedBook{},
+	}
+	if profile.Visibility == models.VisibilityPublic {
+		library.Handle = profile.Handle
+	}
+
+	for _, book := range books {
+		if book.Visibility == models.BookVisibilityPrivate {
+			continue
+		}
+		library.Books = append(library.Books, shareBook(&book, profile, profile.RatingScale, spoilers))
+	}
+	return library, nil
+}
+
+// shareBook copies only the fields the owner opted to share, with the rating on the given scale
+func shareBook(book *models.Book, profile *models.Profile, scale models.RatingScale, spoilers markdown.Spoilers) models.SharedBook {
+	shared := models.SharedBook{
+		Title:   book.Title,
+		Author:  book.Author,
+		AddedAt: book.CreatedAt,
+	}
+	if profile.ShareRatings {
+		rating := scale.FromScore(book.RatingScore)
+		shared.Rating = &rating
+	}
+	if profile.ShareComments {
+		shared.Comment = book.Comment
+		shared.ReviewHTML = markdown.Render(book.Review, spoilers)
+	}
+	return shared
+}
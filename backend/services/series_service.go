@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/repository"
+)
+
+// SeriesService manages a user's series. Series are personal: only their owner sees them,
+// and only the owner's personal books can be put in one.
+type SeriesService struct {
+	repo  repository.SeriesRepository
+	books *BookService
+}
+
+func NewSeriesService(repo repository.SeriesRepository, books *BookService) *SeriesService {
+	s := &SeriesService{repo: repo, books: books}
+	books.onValidate(s.checkBookSeries)
+	return s
+}
+
+func validateSeries(series *models.Series) error {
+	series.Name = strings.TrimSpace(series.Name)
+	if series.Name == "" || series.TotalVolumes < 0 || series.TotalVolumes > maxVolume {
+		return appErrors.ErrInvalidSeries
+	}
+	return nil
+}
+
+// checkBookSeries rejects books that refer to a series their owner doesn't have
+func (s *SeriesService) checkBookSeries(ctx context.Context, book *models.Book) error {
+	if book.SeriesID == nil {
+		return nil
+	}
+	if book.GroupID != nil {
+		return appErrors.ErrUnknownSeries
+	}
+
+	series, err := s.repo.FindByID(ctx, book.SeriesID.Hex())
+	if errors.Is(err, appErrors.ErrNotFound) {
+		return appErrors.ErrUnknownSeries
+	}
+	if err != nil {
+		return err
+	}
+	if series.UserID != book.UserID {
+		return appErrors.ErrUnknownSeries
+	}
+	return nil
+}
+
+func (s *SeriesService) CreateSeries(ctx context.Context, series *models.Series) error {
+	if err := validateSeries(series); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, series)
+}
+
+func (s *SeriesService) GetSeriesByUserID(ctx context.Context, userID string) ([]models.Series, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// getSeries loads the user's series. Other users' series are reported as not found.
+func (s *SeriesService) getSeries(ctx context.Context, id, userID string) (*models.Series, error) {
+	series, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if series.UserID != userID {
+		return nil, appErrors.ErrNotFound
+	}
+	return series, nil
+}
+
+// GetSeries returns the series with its books in reading order
+func (s *SeriesService) GetSeries(ctx context.Context, id, userID string) (*models.SeriesVolumes, error) {
+	series, err := s.getSeries(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	books, err := s.seriesBooks(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := &models.SeriesVolumes{Series: *series, Books: books}
+	for _, book := range books {
+		if book.Status == models.StatusFinished {
+			volumes.Finished++
+		}
+	}
+	return volumes, nil
+}
+
+// seriesBooks returns the books of the series ordered by volume, then title. Books without a volume come last.
+func (s *SeriesService) seriesBooks(ctx context.Context, series *models.Series) ([]models.Book, error) {
+	books, err := s.books.repo.FindBySeriesID(ctx, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	if books == nil {
+		books = []models.Book{}
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i].Volume, books[j].Volume
+		if (a == nil) != (b == nil) {
+			return b == nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return books[i].Title < books[j].Title
+	})
+	return books, nil
+}
+
+func (s *SeriesService) UpdateSeries(ctx context.Context, id, userID string, update *models.Series) (*models.Series, error) {
+	series, err := s.getSeries(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	series.Name = update.Name
+	series.TotalVolumes = update.TotalVolumes
+	if err := validateSeries(series); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, series); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// DeleteSeries removes the series. Its books are kept, without a series.
+func (s *SeriesService) DeleteSeries(ctx context.Context, id, userID string) error {
+	series, err := s.getSeries(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := s.books.repo.ClearSeries(ctx, series.ID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, series.ID)
+}
+
+// GetMissingVolumes lists the whole-numbered volumes the user has no book for. Up to the expected
+// total if the series has one, otherwise up to the highest volume the user has.
+func (s *SeriesService) GetMissingVolumes(ctx context.Context, id, userID string) ([]int, error) {
+	series, err := s.getSeries(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	books, err := s.seriesBooks(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+	return missingVolumes(series, books), nil
+}
+
+func missingVolumes(series *models.Series, books []models.Book) []int {
+	owned := make(map[float64]bool)
+	last := series.TotalVolumes
+	for _, book := range books {
+		if book.Volume == nil {
+			continue
+		}
+		owned[*book.Volume] = true
+		if series.TotalVolumes == 0 {
+			last = max(last, int(math.Floor(*book.Volume)))
+		}
+	}
+
+	missing := []int{}
+	for volume := 1; volume <= last; volume++ {
+		if !owned[float64(volume)] {
+			missing = append(missing, volume)
+		}
+	}
+	return missing
+}
+
+// GetNextVolume suggests the first volume in reading order that the user hasn't finished, whether
+// or not they have a book for it. It fails with appErrors.ErrNoUnreadVolume once everything is read.
+func (s *SeriesService) GetNextVolume(ctx context.Context, id, userID string) (*models.NextVolume, error) {
+	series, err := s.getSeries(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	books, err := s.seriesBooks(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	// Several books can share a volume, such as two editions; the volume counts as read if any of them is
+	finished := make(map[float64]bool)
+	for _, book := range books {
+		if book.Volume != nil && book.Status == models.StatusFinished {
+			finished[*book.Volume] = true
+		}
+	}
+
+	var candidates []models.NextVolume
+	for i := range books {
+		if books[i].Volume != nil && !finished[*books[i].Volume] {
+			candidates = append(candidates, models.NextVolume{Volume: *books[i].Volume, Book: &books[i]})
+		}
+	}
+	for _, volume := range missingVolumes(series, books) {
+		candidates = append(candidates, models.NextVolume{Volume: float64(volume)})
+	}
+	if len(candidates) == 0 {
+		return nil, appErrors.ErrNoUnreadVolume
+	}
+
+	// Prefer a book the user is already reading over another copy of the same volume
+	next := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.Volume < next.Volume ||
+			(candidate.Volume == next.Volume && candidate.Book.Status == models.StatusReading && next.Book.Status != models.StatusReading) {
+			next = candidate
+		}
+	}
+	return &next, nil
+}
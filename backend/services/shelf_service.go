@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	appErrors "tranquil-pages/errors"
+	"tranquil-pages/models"
+	"tranquil-pages/query"
+	"tranquil-pages/repository"
+)
+
+// ShelfService manages smart shelves. Shelves are personal: only their owner sees them, and they
+// only cover the owner's personal books.
+type ShelfService struct {
+	repo  repository.ShelfRepository
+	books *BookService
+}
+
+func NewShelfService(repo repository.ShelfRepository, books *BookService) *ShelfService {
+	return &ShelfService{repo: repo, books: books}
+}
+
+// parseShelfQuery parses the shelf's query with ratings on the given scale
+func parseShelfQuery(source string, scale models.RatingScale) (query.Node, error) {
+	node, err := query.Parse(source, query.Options{Scale: scale, Now: time.Now()})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", appErrors.ErrInvalidQuery, err)
+	}
+	return node, nil
+}
+
+// validateShelf checks the shelf's query with ratings on the given scale, and keeps the scale with it
+func validateShelf(shelf *models.Shelf, scale models.RatingScale) error {
+	shelf.Name = strings.TrimSpace(shelf.Name)
+	shelf.Query = strings.TrimSpace(shelf.Query)
+	shelf.RatingScale = scale
+	if shelf.Name == "" {
+		return appErrors.ErrInvalidShelf
+	}
+	_, err := parseShelfQuery(shelf.Query, scale)
+	return err
+}
+
+// CreateShelf saves the shelf after checking its query, reading ratings in it on the given scale
+func (s *ShelfService) CreateShelf(ctx context.Context, shelf *models.Shelf, scale models.RatingScale) error {
+	if err := validateShelf(shelf, scale); err != nil {
+		return err
+	}
+	return s.repo.Create(ctx, shelf)
+}
+
+func (s *ShelfService) GetShelvesByUserID(ctx context.Context, userID string) ([]models.Shelf, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// GetShelf loads the user's shelf. Other users' shelves are reported as not found.
+func (s *ShelfService) GetShelf(ctx context.Context, id, userID string) (*models.Shelf, error) {
+	shelf, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shelf.UserID != userID {
+		return nil, appErrors.ErrNotFound
+	}
+	return shelf, nil
+}
+
+func (s *ShelfService) UpdateShelf(ctx context.Context, id, userID string, update *models.Shelf, scale models.RatingScale) (*models.Shelf, error) {
+	shelf, err := s.GetShelf(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf.Name = update.Name
+	shelf.Query = update.Query
+	if err := validateShelf(shelf, scale); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, shelf); err != nil {
+		return nil, err
+	}
+	return shelf, nil
+}
+
+func (s *ShelfService) DeleteShelf(ctx context.Context, id, userID string) error {
+	shelf, err := s.GetShelf(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.repo.Delete(ctx, shelf.ID)
+}
+
+// GetShelfBooks runs the shelf's query and returns a page of the matching books, newest first. Ratings
+// in the query are read on the scale the shelf was saved with, or on the given scale for shelves saved
+// before scales were kept, and relative dates as of now.
+func (s *ShelfService) GetShelfBooks(ctx context.Context, id, userID string, scale models.RatingScale, offset, limit int) ([]models.Book, error) {
+	shelf, err := s.GetShelf(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if shelf.RatingScale != "" {
+		scale = shelf.RatingScale
+	}
+
+	node, err := parseShelfQuery(shelf.Query, scale)
+	if err != nil {
+		return nil, err
+	}
+	return s.books.repo.FindByQuery(ctx, userID, node, offset, limit)
+}
@@ -44,6 +44,21 @@ resource "azurerm_container_app" "this" {
         secret_name = azurerm_key_vault_secret.jwt_secret.name
       }
 
+      env {
+        name  = "BLOB_STORE"
+        value = "azure"
+      }
+
+      env {
+        name        = "AZURE_STORAGE_CONNECTION_STRING"
+        secret_name = azurerm_key_vault_secret.blob_storage_connection_string.name
+      }
+
+      env {
+        name  = "AZURE_STORAGE_CONTAINER"
+        value = azurerm_storage_container.user-files.name
+      }
+
       env {
         name  = "FRONTEND_URL"
         value = azurerm_storage_account.storage.primary_web_endpoint
@@ -79,6 +94,12 @@ resource "azurerm_container_app" "this" {
     identity            = "System"
   }
 
+  secret {
+    name                = azurerm_key_vault_secret.blob_storage_connection_string.name
+    key_vault_secret_id = azurerm_key_vault_secret.blob_storage_connection_string.versionless_id
+    identity            = "System"
+  }
+
   secret {
     name                = azurerm_key_vault_secret.jwt_secret.name
     key_vault_secret_id = azurerm_key_vault_secret.jwt_secret.versionless_id
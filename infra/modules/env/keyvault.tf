@@ -38,6 +38,12 @@ resource "azurerm_key_vault_secret" "jwt_secret" {
   value        = random_bytes.jwt_secret.base64
 }
 
+resource "azurerm_key_vault_secret" "blob_storage_connection_string" {
+  key_vault_id = azurerm_key_vault.this.id
+  name         = "blob-storage-connection-string"
+  value        = azurerm_storage_account.storage.primary_connection_string
+}
+
 resource "random_bytes" "jwt_secret" {
   length = 32
   keepers = {